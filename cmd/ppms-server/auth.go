@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aakanksha/ppms/internal/auth"
+)
+
+// newAuthn builds the authentication middleware from cfg. Running without any
+// credentials configured has to be asked for explicitly with -no-auth.
+func newAuthn(cfg *config) (func(http.Handler) http.Handler, error) {
+	var authenticators []auth.Authenticator
+	if cfg.JWTKeyFile != "" || cfg.JWKSFile != "" {
+		j := auth.NewJWT()
+		j.Issuer = cfg.JWTIssuer
+		j.Audience = cfg.JWTAudience
+		if cfg.JWTKeyFile != "" {
+			if err := j.LoadKeyFile(cfg.JWTKeyFile); err != nil {
+				return nil, err
+			}
+		}
+		if cfg.JWKSFile != "" {
+			if err := j.LoadJWKS(cfg.JWKSFile); err != nil {
+				return nil, err
+			}
+		}
+		authenticators = append(authenticators, j)
+	}
+	if cfg.APIKeysFile != "" {
+		keys, err := auth.LoadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, keys)
+	}
+	if len(authenticators) == 0 {
+		if !cfg.NoAuth {
+			return nil, errors.New("no authentication configured: set -jwt-key-file, -jwks-file or -api-keys-file, or pass -no-auth")
+		}
+		log.Print("warning: patient routes are served without authentication")
+		return func(next http.Handler) http.Handler { return next }, nil
+	}
+	return auth.Middleware(authenticators...), nil
+}
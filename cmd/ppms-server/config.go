@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	patientService "github.com/aakanksha/ppms/internal/service/patient"
+	"github.com/go-sql-driver/mysql"
+)
+
+type config struct {
+	Addr            string
+	DBUser          string
+	DBPassword      string
+	DBHost          string
+	DBName          string
+	ShutdownTimeout time.Duration
+	ShutdownDelay   time.Duration
+	Timeouts        patientService.Timeouts
+	PurgeRetention  time.Duration
+	PurgeInterval   time.Duration
+	TriageAging     time.Duration
+	ClinicOpens     time.Duration
+	ClinicCloses    time.Duration
+	JWTKeyFile      string
+	JWKSFile        string
+	JWTIssuer       string
+	JWTAudience     string
+	APIKeysFile     string
+	NoAuth          bool
+	PolicyFile      string
+	KeyringFile     string
+	Format          string
+	DryRun          bool
+	Args            []string
+}
+
+func loadConfig(args []string) (*config, error) {
+	cfg := &config{}
+	fs := flag.NewFlagSet("ppms-server", flag.ContinueOnError)
+	fs.StringVar(&cfg.Addr, "addr", getEnv("PPMS_ADDR", ":8000"), "address the http server listens on")
+	fs.StringVar(&cfg.DBUser, "db-user", getEnv("PPMS_DB_USER", "root"), "mysql user")
+	fs.StringVar(&cfg.DBPassword, "db-password", getEnv("PPMS_DB_PASSWORD", ""), "mysql password")
+	fs.StringVar(&cfg.DBHost, "db-host", getEnv("PPMS_DB_HOST", "localhost:3306"), "mysql host:port")
+	fs.StringVar(&cfg.DBName, "db-name", getEnv("PPMS_DB_NAME", "ppms"), "mysql database name")
+	fs.StringVar(&cfg.JWTKeyFile, "jwt-key-file", getEnv("PPMS_JWT_KEY_FILE", ""), "PEM RSA public key or HMAC secret that signs bearer tokens")
+	fs.StringVar(&cfg.JWKSFile, "jwks-file", getEnv("PPMS_JWKS_FILE", ""), "JSON Web Key Set of keys that sign bearer tokens")
+	fs.StringVar(&cfg.JWTIssuer, "jwt-issuer", getEnv("PPMS_JWT_ISSUER", ""), "required iss claim of bearer tokens")
+	fs.StringVar(&cfg.JWTAudience, "jwt-audience", getEnv("PPMS_JWT_AUDIENCE", ""), "required aud claim of bearer tokens")
+	fs.StringVar(&cfg.APIKeysFile, "api-keys-file", getEnv("PPMS_API_KEYS_FILE", ""), "JSON file of static API keys")
+	fs.BoolVar(&cfg.NoAuth, "no-auth", getEnv("PPMS_NO_AUTH", "") == "true", "serve the patient routes without authentication")
+	fs.StringVar(&cfg.PolicyFile, "policy-file", getEnv("PPMS_POLICY_FILE", ""), "JSON role policy replacing the built-in one")
+	fs.StringVar(&cfg.KeyringFile, "keyring-file", getEnv("PPMS_KEYRING_FILE", ""), "JSON keyring encrypting phone and description at rest")
+	fs.StringVar(&cfg.Format, "format", "", "format of the file to import, csv or ndjson; defaults to its extension")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "validate the file to import without writing anything")
+	durations := []struct {
+		target *time.Duration
+		name   string
+		env    string
+		value  string
+		usage  string
+	}{
+		{&cfg.ShutdownTimeout, "shutdown-timeout", "PPMS_SHUTDOWN_TIMEOUT", "15s", "time allowed for in-flight requests on shutdown"},
+		{&cfg.ShutdownDelay, "shutdown-delay", "PPMS_SHUTDOWN_DELAY", "5s", "time /readyz fails before the server stops accepting requests"},
+		{&cfg.Timeouts.Default, "timeout", "PPMS_TIMEOUT", "10s", "default deadline for a patient operation"},
+		{&cfg.Timeouts.Read, "read-timeout", "PPMS_READ_TIMEOUT", "0s", "deadline for patient reads, 0 uses -timeout"},
+		{&cfg.Timeouts.Write, "write-timeout", "PPMS_WRITE_TIMEOUT", "0s", "deadline for patient writes, 0 uses -timeout"},
+		{&cfg.Timeouts.Search, "search-timeout", "PPMS_SEARCH_TIMEOUT", "0s", "deadline for patient search, 0 uses -timeout"},
+		{&cfg.PurgeRetention, "purge-retention", "PPMS_PURGE_RETENTION", "0s", "how long soft-deleted patients are kept, 0 disables purging"},
+		{&cfg.PurgeInterval, "purge-interval", "PPMS_PURGE_INTERVAL", "24h", "how often the purge job runs"},
+		{&cfg.TriageAging, "triage-aging", "PPMS_TRIAGE_AGING", "30m", "wait that raises a triaged patient's priority by one level, 0 disables aging"},
+		{&cfg.ClinicOpens, "clinic-opens", "PPMS_CLINIC_OPENS", "9h", "time after midnight UTC appointment availability starts each day"},
+		{&cfg.ClinicCloses, "clinic-closes", "PPMS_CLINIC_CLOSES", "17h", "time after midnight UTC appointment availability ends each day"},
+	}
+	for _, d := range durations {
+		value, err := time.ParseDuration(getEnv(d.env, d.value))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.env, err)
+		}
+		fs.DurationVar(d.target, d.name, value, d.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if cfg.ClinicOpens < 0 || cfg.ClinicCloses <= cfg.ClinicOpens || cfg.ClinicCloses > 24*time.Hour {
+		return nil, fmt.Errorf("clinic hours %v to %v must lie within a day", cfg.ClinicOpens, cfg.ClinicCloses)
+	}
+	cfg.Args = fs.Args()
+	return cfg, nil
+}
+
+func (c *config) DSN() string {
+	dsn := mysql.NewConfig()
+	dsn.User = c.DBUser
+	dsn.Passwd = c.DBPassword
+	dsn.Net = "tcp"
+	dsn.Addr = c.DBHost
+	dsn.DBName = c.DBName
+	dsn.ParseTime = true
+	return dsn.FormatDSN()
+}
+
+func getEnv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aakanksha/ppms/internal/audit"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	patientService "github.com/aakanksha/ppms/internal/service/patient"
+	patientStore "github.com/aakanksha/ppms/internal/stores/patient"
+	"github.com/aakanksha/ppms/internal/transfer"
+)
+
+// importActor is recorded in the audit trail for patients the CLI imports.
+const importActor = "import"
+
+// runImport creates patients from the CSV or NDJSON file named by the first
+// argument, "-" for stdin, with the validation of the HTTP import. Line errors
+// are printed and fail the command.
+func runImport(cfg *config) error {
+	if len(cfg.Args) != 1 {
+		return errors.New("usage: import [-format csv|ndjson] [-dry-run] <file>")
+	}
+	path := cfg.Args[0]
+	name := cfg.Format
+	if name == "" {
+		name = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	format, ok := transfer.ParseFormat(name)
+	if !ok {
+		return fmt.Errorf("cannot tell the format of %q, use -format csv or -format ndjson", path)
+	}
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	keys, err := loadKeyring(cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	svc := patientService.New(patientStore.New(db).WithKeyring(keys)).WithTimeouts(cfg.Timeouts)
+	ctx := audit.WithActor(context.Background(), importActor)
+	report, err := svc.Import(ctx, transfer.NewReader(format, in), cfg.DryRun)
+	if err != nil {
+		return err
+	}
+	if report.Imported > 0 {
+		log.Printf("imported %d patient(s)", report.Imported)
+	}
+	for _, le := range report.Errors {
+		fmt.Fprintf(os.Stderr, "line %d: %s\n", le.Line, describe(le.Err))
+	}
+	switch {
+	case len(report.Errors) > 0:
+		return fmt.Errorf("%d of %d row(s) invalid, nothing imported", len(report.Errors), report.Rows)
+	case report.DryRun:
+		log.Printf("dry run: %d row(s) valid", report.Rows)
+	}
+	return nil
+}
+
+// describe spells out each field of a validation error.
+func describe(err error) string {
+	var verr *perrors.Validation
+	if !errors.As(err, &verr) {
+		return err.Error()
+	}
+	parts := make([]string, len(verr.Fields))
+	for i, f := range verr.Fields {
+		parts[i] = f.Field + " " + f.Message
+	}
+	return strings.Join(parts, "; ")
+}
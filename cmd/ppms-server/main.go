@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aakanksha/ppms/internal/fhir"
+	"github.com/aakanksha/ppms/internal/health"
+	appointmentHTTP "github.com/aakanksha/ppms/internal/http/appointment"
+	encounterHTTP "github.com/aakanksha/ppms/internal/http/encounter"
+	patientHTTP "github.com/aakanksha/ppms/internal/http/patient"
+	staffHTTP "github.com/aakanksha/ppms/internal/http/staff"
+	triageHTTP "github.com/aakanksha/ppms/internal/http/triage"
+	wardHTTP "github.com/aakanksha/ppms/internal/http/ward"
+	"github.com/aakanksha/ppms/internal/logging"
+	"github.com/aakanksha/ppms/internal/metrics"
+	"github.com/aakanksha/ppms/internal/migrations"
+	"github.com/aakanksha/ppms/internal/policy"
+	appointmentService "github.com/aakanksha/ppms/internal/service/appointment"
+	encounterService "github.com/aakanksha/ppms/internal/service/encounter"
+	patientService "github.com/aakanksha/ppms/internal/service/patient"
+	staffService "github.com/aakanksha/ppms/internal/service/staff"
+	triageService "github.com/aakanksha/ppms/internal/service/triage"
+	wardService "github.com/aakanksha/ppms/internal/service/ward"
+	appointmentStore "github.com/aakanksha/ppms/internal/stores/appointment"
+	encounterStore "github.com/aakanksha/ppms/internal/stores/encounter"
+	patientStore "github.com/aakanksha/ppms/internal/stores/patient"
+	staffStore "github.com/aakanksha/ppms/internal/stores/staff"
+	triageStore "github.com/aakanksha/ppms/internal/stores/triage"
+	wardStore "github.com/aakanksha/ppms/internal/stores/ward"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func main() {
+	args := os.Args[1:]
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	cfg, err := loadConfig(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch command {
+	case "serve":
+		err = run(cfg)
+	case "migrate":
+		err = runMigrate(cfg)
+	case "reencrypt":
+		err = runReencrypt(cfg)
+	case "import":
+		err = runImport(cfg)
+	default:
+		err = fmt.Errorf("unknown command %q, expected serve, migrate, reencrypt or import", command)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func openDB(cfg *config) (*sql.DB, error) {
+	db, err := sql.Open("mysql", cfg.DSN())
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func run(cfg *config) error {
+	authn, err := newAuthn(cfg)
+	if err != nil {
+		return err
+	}
+	pol := policy.Default()
+	if cfg.PolicyFile != "" {
+		if pol, err = policy.Load(cfg.PolicyFile); err != nil {
+			return err
+		}
+	}
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	keys, err := loadKeyring(cfg)
+	if err != nil {
+		return err
+	}
+	reg := metrics.NewRegistry()
+	reg.RegisterDB(db)
+	patients := patientStore.New(db).WithKeyring(keys)
+	store := reg.NewStore(patients)
+	svc := patientService.New(store).WithTimeouts(cfg.Timeouts).WithRetention(cfg.PurgeRetention)
+	guarded := policy.New(svc, pol)
+	beds := wardStore.New(db, patients)
+	patients.WithBeds(beds)
+	encounters := encounterService.New(encounterStore.New(db, patients).WithKeyring(keys).WithBeds(beds)).WithTimeouts(cfg.Timeouts)
+	wards := wardService.New(beds).WithTimeouts(cfg.Timeouts)
+	queue := triageService.New(triageStore.New(db, patients)).WithTimeouts(cfg.Timeouts).WithAging(cfg.TriageAging)
+	staff := staffService.New(staffStore.New(db, patients)).WithTimeouts(cfg.Timeouts)
+	appointments := appointmentService.New(appointmentStore.New(db, patients)).WithTimeouts(cfg.Timeouts).WithHours(cfg.ClinicOpens, cfg.ClinicCloses)
+	h := handlers{
+		patient:     patientHTTP.New(guarded),
+		fhir:        fhir.New(guarded),
+		encounter:   encounterHTTP.New(policy.NewEncounters(encounters, pol)),
+		ward:        wardHTTP.New(policy.NewWards(wards, pol)),
+		triage:      triageHTTP.New(policy.NewTriage(queue, pol)),
+		staff:       staffHTTP.New(policy.NewStaff(staff, pol)),
+		appointment: appointmentHTTP.New(policy.NewAppointments(appointments, pol)),
+	}
+	logger := logging.New(os.Stdout)
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	probes := health.New(2 * time.Second)
+	probes.Add("mysql", db.PingContext)
+	probes.Add("migrations", migrator.Check)
+
+	srv := &http.Server{
+		Addr:    cfg.Addr,
+		Handler: logging.Middleware(logger)(newRouter(h, authn, reg, probes)),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx = logging.WithLogger(ctx, logger)
+
+	if cfg.PurgeRetention > 0 {
+		go runPurge(ctx, svc, cfg.PurgeInterval)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("ppms-server listening on %s", cfg.Addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Fail readiness first and keep serving for a while, so the orchestrator
+	// stops sending requests before the listener closes. A second signal
+	// stops the process at once.
+	stop()
+	probes.Drain()
+	log.Printf("draining, stopping in %s", cfg.ShutdownDelay)
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(cfg.ShutdownDelay):
+	}
+
+	log.Printf("shutting down, waiting up to %s for in-flight requests", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/aakanksha/ppms/internal/migrations"
+)
+
+func runMigrate(cfg *config) error {
+	action := "up"
+	if len(cfg.Args) > 0 {
+		action = cfg.Args[0]
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "up":
+		n, err := m.Up()
+		if err != nil {
+			return err
+		}
+		log.Printf("applied %d migration(s), schema at version %d", n, m.Latest())
+	case "down":
+		steps := 1
+		if len(cfg.Args) > 1 {
+			steps, err = strconv.Atoi(cfg.Args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", cfg.Args[1])
+			}
+		}
+		n, err := m.Down(steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("rolled back %d migration(s)", n)
+	case "version":
+		current, err := m.Version()
+		if err != nil {
+			return err
+		}
+		log.Printf("schema at version %d, latest available %d", current, m.Latest())
+	default:
+		return fmt.Errorf("unknown migrate action %q, expected up, down or version", action)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aakanksha/ppms/internal/logging"
+)
+
+type purger interface {
+	Purge(ctx context.Context) (int64, error)
+}
+
+// runPurge purges expired soft-deleted patients every interval until ctx is done.
+func runPurge(ctx context.Context, p purger, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := p.Purge(ctx)
+			if err != nil {
+				logging.FromContext(ctx).Error("purge failed", "error", err)
+				continue
+			}
+			if n > 0 {
+				logging.FromContext(ctx).Info("purge completed", "purged", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/aakanksha/ppms/internal/encryption"
+	encounterStore "github.com/aakanksha/ppms/internal/stores/encounter"
+	patientStore "github.com/aakanksha/ppms/internal/stores/patient"
+)
+
+// loadKeyring returns the configured keyring, or nil to store plaintext.
+func loadKeyring(cfg *config) (*encryption.Keyring, error) {
+	if cfg.KeyringFile == "" {
+		log.Print("warning: no -keyring-file, phone and description are stored unencrypted")
+		return nil, nil
+	}
+	return encryption.LoadKeyring(cfg.KeyringFile)
+}
+
+// runReencrypt brings every stored phone, description and encounter reason
+// under the primary key, encrypting rows written before encryption was
+// enabled. Without -keyring-file it only fills in missing phone indexes, such
+// as those of rows written before the index existed.
+func runReencrypt(cfg *config) error {
+	keys, err := loadKeyring(cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	patients := patientStore.New(db).WithKeyring(keys)
+	n, err := patients.Reencrypt(context.Background())
+	if err != nil {
+		return err
+	}
+	if keys == nil {
+		log.Printf("indexed %d patient(s)", n)
+		return nil
+	}
+	log.Printf("re-encrypted %d patient(s)", n)
+	n, err = encounterStore.New(db, patients).WithKeyring(keys).Reencrypt(context.Background())
+	if err != nil {
+		return err
+	}
+	log.Printf("re-encrypted %d encounter(s)", n)
+	return nil
+}
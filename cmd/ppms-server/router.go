@@ -0,0 +1,189 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/aakanksha/ppms/internal/audit"
+	"github.com/aakanksha/ppms/internal/auth"
+	"github.com/aakanksha/ppms/internal/health"
+	"github.com/aakanksha/ppms/internal/logging"
+	"github.com/aakanksha/ppms/internal/metrics"
+	"github.com/gorilla/mux"
+)
+
+type patientHandler interface {
+	GetByID(w http.ResponseWriter, r *http.Request)
+	GetAll(w http.ResponseWriter, r *http.Request)
+	Search(w http.ResponseWriter, r *http.Request)
+	Insert(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Patch(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+	ListDeleted(w http.ResponseWriter, r *http.Request)
+	Restore(w http.ResponseWriter, r *http.Request)
+	Purge(w http.ResponseWriter, r *http.Request)
+	History(w http.ResponseWriter, r *http.Request)
+	BulkInsert(w http.ResponseWriter, r *http.Request)
+	BulkUpdate(w http.ResponseWriter, r *http.Request)
+	BulkDelete(w http.ResponseWriter, r *http.Request)
+	Export(w http.ResponseWriter, r *http.Request)
+	Import(w http.ResponseWriter, r *http.Request)
+}
+
+type fhirHandler interface {
+	Read(w http.ResponseWriter, r *http.Request)
+	Search(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+}
+
+type encounterHandler interface {
+	List(w http.ResponseWriter, r *http.Request)
+	Get(w http.ResponseWriter, r *http.Request)
+	Admit(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Discharge(w http.ResponseWriter, r *http.Request)
+}
+
+type wardHandler interface {
+	ListWards(w http.ResponseWriter, r *http.Request)
+	CreateWard(w http.ResponseWriter, r *http.Request)
+	GetWard(w http.ResponseWriter, r *http.Request)
+	AddBed(w http.ResponseWriter, r *http.Request)
+	Occupancy(w http.ResponseWriter, r *http.Request)
+	CurrentBed(w http.ResponseWriter, r *http.Request)
+	Assign(w http.ResponseWriter, r *http.Request)
+	Transfer(w http.ResponseWriter, r *http.Request)
+	Release(w http.ResponseWriter, r *http.Request)
+}
+
+type triageHandler interface {
+	Queue(w http.ResponseWriter, r *http.Request)
+	Enqueue(w http.ResponseWriter, r *http.Request)
+	Peek(w http.ResponseWriter, r *http.Request)
+	CallNext(w http.ResponseWriter, r *http.Request)
+	Retriage(w http.ResponseWriter, r *http.Request)
+}
+
+type staffHandler interface {
+	List(w http.ResponseWriter, r *http.Request)
+	Get(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+	Patients(w http.ResponseWriter, r *http.Request)
+	Team(w http.ResponseWriter, r *http.Request)
+	Assign(w http.ResponseWriter, r *http.Request)
+	Unassign(w http.ResponseWriter, r *http.Request)
+}
+
+type appointmentHandler interface {
+	Book(w http.ResponseWriter, r *http.Request)
+	Get(w http.ResponseWriter, r *http.Request)
+	Reschedule(w http.ResponseWriter, r *http.Request)
+	Cancel(w http.ResponseWriter, r *http.Request)
+	ForPatient(w http.ResponseWriter, r *http.Request)
+	ForStaff(w http.ResponseWriter, r *http.Request)
+	Availability(w http.ResponseWriter, r *http.Request)
+}
+
+// handlers are the API handlers newRouter serves.
+type handlers struct {
+	patient     patientHandler
+	fhir        fhirHandler
+	encounter   encounterHandler
+	ward        wardHandler
+	triage      triageHandler
+	staff       staffHandler
+	appointment appointmentHandler
+}
+
+// newRouter wires the API routes of h behind authn, which authenticates each
+// request. The metrics of reg and the probes are served unauthenticated.
+func newRouter(h handlers, authn func(http.Handler) http.Handler, reg *metrics.Registry, probes *health.Checker) *mux.Router {
+	ph, fh, eh, wh, th, sh, ah := h.patient, h.fhir, h.encounter, h.ward, h.triage, h.staff, h.appointment
+	r := mux.NewRouter()
+	r.Use(logging.RecordRoute, reg.HTTPMiddleware())
+	r.Handle("/metrics", reg.Handler()).Methods(http.MethodGet)
+	r.HandleFunc("/healthz", probes.Live).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", probes.Ready).Methods(http.MethodGet)
+	api := r.PathPrefix("/patient").Subrouter()
+	api.Use(authn, actorMiddleware)
+	api.HandleFunc("", ph.GetAll).Methods(http.MethodGet)
+	api.HandleFunc("", ph.Insert).Methods(http.MethodPost)
+	api.HandleFunc("/search", ph.Search).Methods(http.MethodGet)
+	api.HandleFunc("/deleted", ph.ListDeleted).Methods(http.MethodGet)
+	api.HandleFunc("/purge", ph.Purge).Methods(http.MethodPost)
+	api.HandleFunc("/bulk", ph.BulkInsert).Methods(http.MethodPost)
+	api.HandleFunc("/bulk", ph.BulkUpdate).Methods(http.MethodPut)
+	api.HandleFunc("/bulk/delete", ph.BulkDelete).Methods(http.MethodPost)
+	api.HandleFunc("/export", ph.Export).Methods(http.MethodGet)
+	api.HandleFunc("/import", ph.Import).Methods(http.MethodPost)
+	api.HandleFunc("/{id:[0-9]+}", ph.GetByID).Methods(http.MethodGet)
+	api.HandleFunc("/{id:[0-9]+}", ph.Update).Methods(http.MethodPut)
+	api.HandleFunc("/{id:[0-9]+}", ph.Patch).Methods(http.MethodPatch)
+	api.HandleFunc("/{id:[0-9]+}", ph.Delete).Methods(http.MethodDelete)
+	api.HandleFunc("/{id:[0-9]+}/restore", ph.Restore).Methods(http.MethodPost)
+	api.HandleFunc("/{id:[0-9]+}/history", ph.History).Methods(http.MethodGet)
+	api.HandleFunc("/{id:[0-9]+}/encounters", eh.List).Methods(http.MethodGet)
+	api.HandleFunc("/{id:[0-9]+}/encounters", eh.Admit).Methods(http.MethodPost)
+	api.HandleFunc("/{id:[0-9]+}/encounters/{eid:[0-9]+}", eh.Get).Methods(http.MethodGet)
+	api.HandleFunc("/{id:[0-9]+}/encounters/{eid:[0-9]+}", eh.Update).Methods(http.MethodPut)
+	api.HandleFunc("/{id:[0-9]+}/encounters/{eid:[0-9]+}/discharge", eh.Discharge).Methods(http.MethodPost)
+	api.HandleFunc("/{id:[0-9]+}/bed", wh.CurrentBed).Methods(http.MethodGet)
+	api.HandleFunc("/{id:[0-9]+}/bed", wh.Assign).Methods(http.MethodPost)
+	api.HandleFunc("/{id:[0-9]+}/bed", wh.Release).Methods(http.MethodDelete)
+	api.HandleFunc("/{id:[0-9]+}/bed/transfer", wh.Transfer).Methods(http.MethodPost)
+	api.HandleFunc("/{id:[0-9]+}/careteam", sh.Team).Methods(http.MethodGet)
+	api.HandleFunc("/{id:[0-9]+}/careteam/{sid:[0-9]+}", sh.Assign).Methods(http.MethodPut)
+	api.HandleFunc("/{id:[0-9]+}/careteam/{sid:[0-9]+}", sh.Unassign).Methods(http.MethodDelete)
+	api.HandleFunc("/{id:[0-9]+}/appointments", ah.ForPatient).Methods(http.MethodGet)
+	wards := r.PathPrefix("/wards").Subrouter()
+	wards.Use(authn, actorMiddleware)
+	wards.HandleFunc("", wh.ListWards).Methods(http.MethodGet)
+	wards.HandleFunc("", wh.CreateWard).Methods(http.MethodPost)
+	wards.HandleFunc("/{id:[0-9]+}", wh.GetWard).Methods(http.MethodGet)
+	wards.HandleFunc("/{id:[0-9]+}/beds", wh.AddBed).Methods(http.MethodPost)
+	wards.HandleFunc("/{id:[0-9]+}/occupancy", wh.Occupancy).Methods(http.MethodGet)
+	triage := r.PathPrefix("/triage").Subrouter()
+	triage.Use(authn, actorMiddleware)
+	triage.HandleFunc("", th.Queue).Methods(http.MethodGet)
+	triage.HandleFunc("", th.Enqueue).Methods(http.MethodPost)
+	triage.HandleFunc("/next", th.Peek).Methods(http.MethodGet)
+	triage.HandleFunc("/next", th.CallNext).Methods(http.MethodPost)
+	triage.HandleFunc("/{id:[0-9]+}/retriage", th.Retriage).Methods(http.MethodPost)
+	staff := r.PathPrefix("/staff").Subrouter()
+	staff.Use(authn, actorMiddleware)
+	staff.HandleFunc("", sh.List).Methods(http.MethodGet)
+	staff.HandleFunc("", sh.Create).Methods(http.MethodPost)
+	staff.HandleFunc("/{id:[0-9]+}", sh.Get).Methods(http.MethodGet)
+	staff.HandleFunc("/{id:[0-9]+}", sh.Update).Methods(http.MethodPut)
+	staff.HandleFunc("/{id:[0-9]+}", sh.Delete).Methods(http.MethodDelete)
+	staff.HandleFunc("/{id:[0-9]+}/patients", sh.Patients).Methods(http.MethodGet)
+	staff.HandleFunc("/{id:[0-9]+}/appointments", ah.ForStaff).Methods(http.MethodGet)
+	staff.HandleFunc("/{id:[0-9]+}/availability", ah.Availability).Methods(http.MethodGet)
+	appointments := r.PathPrefix("/appointments").Subrouter()
+	appointments.Use(authn, actorMiddleware)
+	appointments.HandleFunc("", ah.Book).Methods(http.MethodPost)
+	appointments.HandleFunc("/{id:[0-9]+}", ah.Get).Methods(http.MethodGet)
+	appointments.HandleFunc("/{id:[0-9]+}", ah.Reschedule).Methods(http.MethodPut)
+	appointments.HandleFunc("/{id:[0-9]+}/cancel", ah.Cancel).Methods(http.MethodPost)
+	fhir := r.PathPrefix("/fhir/Patient").Subrouter()
+	fhir.Use(authn, actorMiddleware)
+	fhir.HandleFunc("", fh.Search).Methods(http.MethodGet)
+	fhir.HandleFunc("", fh.Create).Methods(http.MethodPost)
+	fhir.HandleFunc("/{id:[0-9]+}", fh.Read).Methods(http.MethodGet)
+	fhir.HandleFunc("/{id:[0-9]+}", fh.Update).Methods(http.MethodPut)
+	return r
+}
+
+// actorMiddleware records the authenticated principal as the actor of any
+// patient change made by the request.
+func actorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := auth.FromContext(r.Context()); p != nil {
+			r = r.WithContext(audit.WithActor(r.Context(), p.Subject))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aakanksha/ppms/internal/audit"
+	"github.com/aakanksha/ppms/internal/auth"
+	"github.com/aakanksha/ppms/internal/health"
+	"github.com/aakanksha/ppms/internal/metrics"
+)
+
+type fakeHandler struct {
+	called string
+}
+
+func (f *fakeHandler) GetByID(w http.ResponseWriter, r *http.Request) { f.called = "GetByID" }
+func (f *fakeHandler) GetAll(w http.ResponseWriter, r *http.Request)  { f.called = "GetAll" }
+func (f *fakeHandler) Search(w http.ResponseWriter, r *http.Request)  { f.called = "Search" }
+func (f *fakeHandler) Insert(w http.ResponseWriter, r *http.Request)  { f.called = "Insert" }
+func (f *fakeHandler) Update(w http.ResponseWriter, r *http.Request)  { f.called = "Update" }
+func (f *fakeHandler) Patch(w http.ResponseWriter, r *http.Request)   { f.called = "Patch" }
+func (f *fakeHandler) Delete(w http.ResponseWriter, r *http.Request)  { f.called = "Delete" }
+func (f *fakeHandler) ListDeleted(w http.ResponseWriter, r *http.Request) {
+	f.called = "ListDeleted"
+}
+func (f *fakeHandler) Restore(w http.ResponseWriter, r *http.Request) { f.called = "Restore" }
+func (f *fakeHandler) Purge(w http.ResponseWriter, r *http.Request)   { f.called = "Purge" }
+func (f *fakeHandler) History(w http.ResponseWriter, r *http.Request) { f.called = "History" }
+func (f *fakeHandler) BulkInsert(w http.ResponseWriter, r *http.Request) {
+	f.called = "BulkInsert"
+}
+func (f *fakeHandler) BulkUpdate(w http.ResponseWriter, r *http.Request) {
+	f.called = "BulkUpdate"
+}
+func (f *fakeHandler) BulkDelete(w http.ResponseWriter, r *http.Request) {
+	f.called = "BulkDelete"
+}
+func (f *fakeHandler) Export(w http.ResponseWriter, r *http.Request) { f.called = "Export" }
+func (f *fakeHandler) Import(w http.ResponseWriter, r *http.Request) { f.called = "Import" }
+
+// fakeFHIR records the FHIR interaction a request was routed to in the
+// fakeHandler it shares with the patient routes.
+type fakeFHIR struct {
+	h *fakeHandler
+}
+
+func (f fakeFHIR) Read(w http.ResponseWriter, r *http.Request)   { f.h.called = "FHIRRead" }
+func (f fakeFHIR) Search(w http.ResponseWriter, r *http.Request) { f.h.called = "FHIRSearch" }
+func (f fakeFHIR) Create(w http.ResponseWriter, r *http.Request) { f.h.called = "FHIRCreate" }
+func (f fakeFHIR) Update(w http.ResponseWriter, r *http.Request) { f.h.called = "FHIRUpdate" }
+
+// fakeEncounters records the encounter handler a request was routed to.
+type fakeEncounters struct {
+	h *fakeHandler
+}
+
+func (f fakeEncounters) List(w http.ResponseWriter, r *http.Request)  { f.h.called = "EncounterList" }
+func (f fakeEncounters) Get(w http.ResponseWriter, r *http.Request)   { f.h.called = "EncounterGet" }
+func (f fakeEncounters) Admit(w http.ResponseWriter, r *http.Request) { f.h.called = "Admit" }
+func (f fakeEncounters) Update(w http.ResponseWriter, r *http.Request) {
+	f.h.called = "EncounterUpdate"
+}
+func (f fakeEncounters) Discharge(w http.ResponseWriter, r *http.Request) {
+	f.h.called = "Discharge"
+}
+
+// fakeWards records the ward handler a request was routed to.
+type fakeWards struct {
+	h *fakeHandler
+}
+
+func (f fakeWards) ListWards(w http.ResponseWriter, r *http.Request)  { f.h.called = "ListWards" }
+func (f fakeWards) CreateWard(w http.ResponseWriter, r *http.Request) { f.h.called = "CreateWard" }
+func (f fakeWards) GetWard(w http.ResponseWriter, r *http.Request)    { f.h.called = "GetWard" }
+func (f fakeWards) AddBed(w http.ResponseWriter, r *http.Request)     { f.h.called = "AddBed" }
+func (f fakeWards) Occupancy(w http.ResponseWriter, r *http.Request)  { f.h.called = "Occupancy" }
+func (f fakeWards) CurrentBed(w http.ResponseWriter, r *http.Request) { f.h.called = "CurrentBed" }
+func (f fakeWards) Assign(w http.ResponseWriter, r *http.Request)     { f.h.called = "Assign" }
+func (f fakeWards) Transfer(w http.ResponseWriter, r *http.Request)   { f.h.called = "Transfer" }
+func (f fakeWards) Release(w http.ResponseWriter, r *http.Request)    { f.h.called = "Release" }
+
+// fakeTriage records the triage handler a request was routed to.
+type fakeTriage struct {
+	h *fakeHandler
+}
+
+func (f fakeTriage) Queue(w http.ResponseWriter, r *http.Request)    { f.h.called = "Queue" }
+func (f fakeTriage) Enqueue(w http.ResponseWriter, r *http.Request)  { f.h.called = "Enqueue" }
+func (f fakeTriage) Peek(w http.ResponseWriter, r *http.Request)     { f.h.called = "Peek" }
+func (f fakeTriage) CallNext(w http.ResponseWriter, r *http.Request) { f.h.called = "CallNext" }
+func (f fakeTriage) Retriage(w http.ResponseWriter, r *http.Request) { f.h.called = "Retriage" }
+
+// fakeStaff records the staff handler a request was routed to.
+type fakeStaff struct {
+	h *fakeHandler
+}
+
+func (f fakeStaff) List(w http.ResponseWriter, r *http.Request)     { f.h.called = "StaffList" }
+func (f fakeStaff) Get(w http.ResponseWriter, r *http.Request)      { f.h.called = "StaffGet" }
+func (f fakeStaff) Create(w http.ResponseWriter, r *http.Request)   { f.h.called = "StaffCreate" }
+func (f fakeStaff) Update(w http.ResponseWriter, r *http.Request)   { f.h.called = "StaffUpdate" }
+func (f fakeStaff) Delete(w http.ResponseWriter, r *http.Request)   { f.h.called = "StaffDelete" }
+func (f fakeStaff) Patients(w http.ResponseWriter, r *http.Request) { f.h.called = "Patients" }
+func (f fakeStaff) Team(w http.ResponseWriter, r *http.Request)     { f.h.called = "Team" }
+func (f fakeStaff) Assign(w http.ResponseWriter, r *http.Request)   { f.h.called = "CareTeamAssign" }
+func (f fakeStaff) Unassign(w http.ResponseWriter, r *http.Request) { f.h.called = "Unassign" }
+
+// fakeAppointments records the appointment handler a request was routed to.
+type fakeAppointments struct {
+	h *fakeHandler
+}
+
+func (f fakeAppointments) Book(w http.ResponseWriter, r *http.Request) { f.h.called = "Book" }
+func (f fakeAppointments) Get(w http.ResponseWriter, r *http.Request)  { f.h.called = "AppointmentGet" }
+func (f fakeAppointments) Reschedule(w http.ResponseWriter, r *http.Request) {
+	f.h.called = "Reschedule"
+}
+func (f fakeAppointments) Cancel(w http.ResponseWriter, r *http.Request) { f.h.called = "Cancel" }
+func (f fakeAppointments) ForPatient(w http.ResponseWriter, r *http.Request) {
+	f.h.called = "ForPatient"
+}
+func (f fakeAppointments) ForStaff(w http.ResponseWriter, r *http.Request) { f.h.called = "ForStaff" }
+func (f fakeAppointments) Availability(w http.ResponseWriter, r *http.Request) {
+	f.h.called = "Availability"
+}
+
+func TestNewRouter(t *testing.T) {
+	tests := []struct {
+		desc      string
+		method    string
+		target    string
+		expected  string
+		status    int
+		anonymous bool
+	}{
+		{desc: "get all", method: http.MethodGet, target: "/patient", expected: "GetAll", status: http.StatusOK},
+		{desc: "search", method: http.MethodGet, target: "/patient/search?q=ram", expected: "Search", status: http.StatusOK},
+		{desc: "insert", method: http.MethodPost, target: "/patient", expected: "Insert", status: http.StatusOK},
+		{desc: "get by id", method: http.MethodGet, target: "/patient/1", expected: "GetByID", status: http.StatusOK},
+		{desc: "update", method: http.MethodPut, target: "/patient/1", expected: "Update", status: http.StatusOK},
+		{desc: "patch", method: http.MethodPatch, target: "/patient/1", expected: "Patch", status: http.StatusOK},
+		{desc: "delete", method: http.MethodDelete, target: "/patient/1", expected: "Delete", status: http.StatusOK},
+		{desc: "list deleted", method: http.MethodGet, target: "/patient/deleted", expected: "ListDeleted", status: http.StatusOK},
+		{desc: "restore", method: http.MethodPost, target: "/patient/1/restore", expected: "Restore", status: http.StatusOK},
+		{desc: "purge", method: http.MethodPost, target: "/patient/purge", expected: "Purge", status: http.StatusOK},
+		{desc: "history", method: http.MethodGet, target: "/patient/1/history", expected: "History", status: http.StatusOK},
+		{desc: "bulk insert", method: http.MethodPost, target: "/patient/bulk", expected: "BulkInsert", status: http.StatusOK},
+		{desc: "bulk update", method: http.MethodPut, target: "/patient/bulk", expected: "BulkUpdate", status: http.StatusOK},
+		{desc: "bulk delete", method: http.MethodPost, target: "/patient/bulk/delete", expected: "BulkDelete", status: http.StatusOK},
+		{desc: "export", method: http.MethodGet, target: "/patient/export?format=ndjson", expected: "Export", status: http.StatusOK},
+		{desc: "import", method: http.MethodPost, target: "/patient/import?dryRun=true", expected: "Import", status: http.StatusOK},
+		{desc: "list encounters", method: http.MethodGet, target: "/patient/1/encounters", expected: "EncounterList", status: http.StatusOK},
+		{desc: "admit", method: http.MethodPost, target: "/patient/1/encounters", expected: "Admit", status: http.StatusOK},
+		{desc: "get encounter", method: http.MethodGet, target: "/patient/1/encounters/2", expected: "EncounterGet", status: http.StatusOK},
+		{desc: "update encounter", method: http.MethodPut, target: "/patient/1/encounters/2", expected: "EncounterUpdate", status: http.StatusOK},
+		{desc: "discharge", method: http.MethodPost, target: "/patient/1/encounters/2/discharge", expected: "Discharge", status: http.StatusOK},
+		{desc: "current bed", method: http.MethodGet, target: "/patient/1/bed", expected: "CurrentBed", status: http.StatusOK},
+		{desc: "assign bed", method: http.MethodPost, target: "/patient/1/bed", expected: "Assign", status: http.StatusOK},
+		{desc: "release bed", method: http.MethodDelete, target: "/patient/1/bed", expected: "Release", status: http.StatusOK},
+		{desc: "transfer", method: http.MethodPost, target: "/patient/1/bed/transfer", expected: "Transfer", status: http.StatusOK},
+		{desc: "list wards", method: http.MethodGet, target: "/wards", expected: "ListWards", status: http.StatusOK},
+		{desc: "create ward", method: http.MethodPost, target: "/wards", expected: "CreateWard", status: http.StatusOK},
+		{desc: "get ward", method: http.MethodGet, target: "/wards/2", expected: "GetWard", status: http.StatusOK},
+		{desc: "add bed", method: http.MethodPost, target: "/wards/2/beds", expected: "AddBed", status: http.StatusOK},
+		{desc: "occupancy", method: http.MethodGet, target: "/wards/2/occupancy", expected: "Occupancy", status: http.StatusOK},
+		{desc: "wards unauthenticated", method: http.MethodGet, target: "/wards", expected: "", status: http.StatusUnauthorized, anonymous: true},
+		{desc: "triage queue", method: http.MethodGet, target: "/triage", expected: "Queue", status: http.StatusOK},
+		{desc: "enqueue", method: http.MethodPost, target: "/triage", expected: "Enqueue", status: http.StatusOK},
+		{desc: "peek", method: http.MethodGet, target: "/triage/next", expected: "Peek", status: http.StatusOK},
+		{desc: "call next", method: http.MethodPost, target: "/triage/next", expected: "CallNext", status: http.StatusOK},
+		{desc: "retriage", method: http.MethodPost, target: "/triage/5/retriage", expected: "Retriage", status: http.StatusOK},
+		{desc: "list staff", method: http.MethodGet, target: "/staff?profession=nurse", expected: "StaffList", status: http.StatusOK},
+		{desc: "create staff", method: http.MethodPost, target: "/staff", expected: "StaffCreate", status: http.StatusOK},
+		{desc: "get staff", method: http.MethodGet, target: "/staff/3", expected: "StaffGet", status: http.StatusOK},
+		{desc: "update staff", method: http.MethodPut, target: "/staff/3", expected: "StaffUpdate", status: http.StatusOK},
+		{desc: "delete staff", method: http.MethodDelete, target: "/staff/3", expected: "StaffDelete", status: http.StatusOK},
+		{desc: "patients of staff", method: http.MethodGet, target: "/staff/3/patients", expected: "Patients", status: http.StatusOK},
+		{desc: "care team", method: http.MethodGet, target: "/patient/1/careteam", expected: "Team", status: http.StatusOK},
+		{desc: "assign care team", method: http.MethodPut, target: "/patient/1/careteam/3", expected: "CareTeamAssign", status: http.StatusOK},
+		{desc: "unassign care team", method: http.MethodDelete, target: "/patient/1/careteam/3", expected: "Unassign", status: http.StatusOK},
+		{desc: "book", method: http.MethodPost, target: "/appointments", expected: "Book", status: http.StatusOK},
+		{desc: "get appointment", method: http.MethodGet, target: "/appointments/7", expected: "AppointmentGet", status: http.StatusOK},
+		{desc: "reschedule", method: http.MethodPut, target: "/appointments/7", expected: "Reschedule", status: http.StatusOK},
+		{desc: "cancel", method: http.MethodPost, target: "/appointments/7/cancel", expected: "Cancel", status: http.StatusOK},
+		{desc: "patient appointments", method: http.MethodGet, target: "/patient/1/appointments", expected: "ForPatient", status: http.StatusOK},
+		{desc: "staff appointments", method: http.MethodGet, target: "/staff/3/appointments", expected: "ForStaff", status: http.StatusOK},
+		{desc: "availability", method: http.MethodGet, target: "/staff/3/availability?from=2022-03-01T00:00:00Z", expected: "Availability", status: http.StatusOK},
+		{desc: "appointments unauthenticated", method: http.MethodPost, target: "/appointments", expected: "", status: http.StatusUnauthorized, anonymous: true},
+		{desc: "fhir search", method: http.MethodGet, target: "/fhir/Patient?name=ram", expected: "FHIRSearch", status: http.StatusOK},
+		{desc: "fhir create", method: http.MethodPost, target: "/fhir/Patient", expected: "FHIRCreate", status: http.StatusOK},
+		{desc: "fhir read", method: http.MethodGet, target: "/fhir/Patient/1", expected: "FHIRRead", status: http.StatusOK},
+		{desc: "fhir update", method: http.MethodPut, target: "/fhir/Patient/1", expected: "FHIRUpdate", status: http.StatusOK},
+		{desc: "fhir unauthenticated", method: http.MethodGet, target: "/fhir/Patient/1", expected: "", status: http.StatusUnauthorized, anonymous: true},
+		{desc: "metrics without credentials", method: http.MethodGet, target: "/metrics", expected: "", status: http.StatusOK, anonymous: true},
+		{desc: "liveness without credentials", method: http.MethodGet, target: "/healthz", expected: "", status: http.StatusOK, anonymous: true},
+		{desc: "readiness without credentials", method: http.MethodGet, target: "/readyz", expected: "", status: http.StatusOK, anonymous: true},
+		{desc: "unauthenticated", method: http.MethodGet, target: "/patient", expected: "", status: http.StatusUnauthorized, anonymous: true},
+		{desc: "non numeric id", method: http.MethodGet, target: "/patient/abc", expected: "", status: http.StatusNotFound},
+		{desc: "method not allowed", method: http.MethodPatch, target: "/patient", expected: "", status: http.StatusMethodNotAllowed},
+	}
+
+	keys := auth.NewAPIKeys()
+	keys.Add("k-123", "dr.rao")
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			h := &fakeHandler{}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(test.method, test.target, nil)
+			if !test.anonymous {
+				r.Header.Set("X-API-Key", "k-123")
+			}
+			newRouter(handlers{patient: h, fhir: fakeFHIR{h}, encounter: fakeEncounters{h}, ward: fakeWards{h}, triage: fakeTriage{h}, staff: fakeStaff{h}, appointment: fakeAppointments{h}}, auth.Middleware(keys), metrics.NewRegistry(), health.New(time.Second)).ServeHTTP(w, r)
+			if h.called != test.expected {
+				t.Errorf("Expected: %v, Got: %v", test.expected, h.called)
+			}
+			if w.Code != test.status {
+				t.Errorf("Expected status: %v, Got: %v", test.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := loadConfig([]string{"-addr", ":9000", "-db-host", "db:3306", "-db-name", "hospital", "-read-timeout", "2s"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Addr != ":9000" {
+		t.Errorf("Expected: %v, Got: %v", ":9000", cfg.Addr)
+	}
+	if cfg.Timeouts.Read != 2*time.Second || cfg.Timeouts.Default != 10*time.Second {
+		t.Errorf("unexpected timeouts: %+v", cfg.Timeouts)
+	}
+	if cfg.ShutdownDelay != 5*time.Second {
+		t.Errorf("Expected: %v, Got: %v", 5*time.Second, cfg.ShutdownDelay)
+	}
+	if cfg.PurgeRetention != 0 || cfg.PurgeInterval != 24*time.Hour {
+		t.Errorf("unexpected purge settings: %v, %v", cfg.PurgeRetention, cfg.PurgeInterval)
+	}
+	if cfg.TriageAging != 30*time.Minute {
+		t.Errorf("Expected: %v, Got: %v", 30*time.Minute, cfg.TriageAging)
+	}
+	if cfg.ClinicOpens != 9*time.Hour || cfg.ClinicCloses != 17*time.Hour {
+		t.Errorf("unexpected clinic hours: %v, %v", cfg.ClinicOpens, cfg.ClinicCloses)
+	}
+	expected := "root@tcp(db:3306)/hospital?parseTime=true"
+	if cfg.DSN() != expected {
+		t.Errorf("Expected: %v, Got: %v", expected, cfg.DSN())
+	}
+}
+
+func TestActorMiddleware(t *testing.T) {
+	var actor string
+	h := actorMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = audit.Actor(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/patient", nil)
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if actor != audit.System {
+		t.Errorf("Expected: %v, Got: %v", audit.System, actor)
+	}
+
+	r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Subject: "dr.rao"}))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if actor != "dr.rao" {
+		t.Errorf("Expected: %v, Got: %v", "dr.rao", actor)
+	}
+}
+
+func TestNewAuthn(t *testing.T) {
+	if _, err := newAuthn(&config{}); err == nil {
+		t.Error("expected error when no authentication is configured")
+	}
+	if _, err := newAuthn(&config{NoAuth: true}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := newAuthn(&config{APIKeysFile: "does-not-exist.json"}); err == nil {
+		t.Error("expected error for a missing api keys file")
+	}
+}
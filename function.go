@@ -1,5 +1,26 @@
 package patient
 
+import (
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxNameLength        = 100
+	maxDescriptionLength = 2000
+	// defaultCountryCode is assumed for phone numbers given without one.
+	defaultCountryCode = "91"
+)
+
+var bloodGroups = map[string]bool{
+	"A+": true, "A-": true,
+	"B+": true, "B-": true,
+	"AB+": true, "AB-": true,
+	"O+": true, "O-": true,
+}
+
 func validatename(name string) bool {
 	if name == "" {
 		return name != ""
@@ -12,3 +33,110 @@ func validId(id int) bool {
 	return id > 0
 }
 
+func validSort(sort string) bool {
+	switch sort {
+	case "", "id", "name", "createdAt", "updatedAt":
+		return true
+	}
+	return false
+}
+
+func validOrder(order string) bool {
+	switch strings.ToLower(order) {
+	case "", "asc", "desc":
+		return true
+	}
+	return false
+}
+
+// validatePatient normalises p in place and reports every invalid field at once.
+func validatePatient(p *models.Patient) error {
+	verr := &perrors.Validation{}
+
+	p.Name = strings.TrimSpace(p.Name)
+	if !validatename(p.Name) {
+		verr.Add("name", "must not be empty")
+	} else if utf8.RuneCountInString(p.Name) > maxNameLength {
+		verr.Add("name", "must be at most 100 characters")
+	}
+
+	if p.Phone != "" {
+		phone, ok := NormalisePhone(p.Phone)
+		if ok {
+			p.Phone = phone
+		} else {
+			verr.Add("phone", "must be a valid phone number in E.164 format")
+		}
+	}
+
+	if p.BloodGroup != "" {
+		group, ok := normaliseBloodGroup(p.BloodGroup)
+		if ok {
+			p.BloodGroup = group
+		} else {
+			verr.Add("bloodGroup", "must be one of A+, A-, B+, B-, AB+, AB-, O+, O-")
+		}
+	}
+
+	p.Description = strings.TrimSpace(p.Description)
+	if utf8.RuneCountInString(p.Description) > maxDescriptionLength {
+		verr.Add("description", "must be at most 2000 characters")
+	}
+
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// NormalisePhone returns the E.164 form of phone, treating national numbers as
+// belonging to defaultCountryCode.
+func NormalisePhone(phone string) (string, bool) {
+	phone = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "").Replace(phone)
+	international := false
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		phone, international = phone[1:], true
+	case strings.HasPrefix(phone, "00"):
+		phone, international = phone[2:], true
+	}
+	if phone == "" || strings.IndexFunc(phone, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return "", false
+	}
+	if !international {
+		switch {
+		case len(phone) == 10:
+			phone = defaultCountryCode + phone
+		case len(phone) == 11 && phone[0] == '0':
+			phone = defaultCountryCode + phone[1:]
+		case len(phone) == 12 && strings.HasPrefix(phone, defaultCountryCode):
+		default:
+			return "", false
+		}
+	}
+	if phone[0] == '0' || len(phone) < 8 || len(phone) > 15 {
+		return "", false
+	}
+	return "+" + phone, true
+}
+
+// normaliseBloodGroup accepts spellings such as "a+", "+A", "AB pos", "B+ve"
+// or "O negative" and returns the canonical form, e.g. "AB+".
+func normaliseBloodGroup(group string) (string, bool) {
+	g := strings.ToUpper(strings.Join(strings.Fields(group), ""))
+	for _, suffix := range []struct{ word, sign string }{
+		{"POSITIVE", "+"}, {"NEGATIVE", "-"}, {"POS", "+"}, {"NEG", "-"}, {"+VE", "+"}, {"-VE", "-"},
+	} {
+		if strings.HasSuffix(g, suffix.word) {
+			g = strings.TrimSuffix(g, suffix.word) + suffix.sign
+			break
+		}
+	}
+	if strings.HasPrefix(g, "+") || strings.HasPrefix(g, "-") {
+		g = g[1:] + g[:1]
+	}
+	if !bloodGroups[g] {
+		return "", false
+	}
+	return g, true
+}
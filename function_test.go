@@ -1,6 +1,13 @@
 package patient
 
-import "testing"
+import (
+	"errors"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestValidateName(t *testing.T) {
 	tests := []struct {
@@ -58,3 +65,118 @@ func TestValidateId(t *testing.T) {
 	}
 }
 
+func TestValidSortAndOrder(t *testing.T) {
+	tests := []struct {
+		desc     string
+		sort     string
+		order    string
+		expected bool
+	}{
+		{desc: "defaults", expected: true},
+		{desc: "name desc", sort: "name", order: "DESC", expected: true},
+		{desc: "unknown sort", sort: "phone", order: "asc", expected: false},
+		{desc: "unknown order", sort: "createdAt", order: "sideways", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			isValid := validSort(test.sort) && validOrder(test.order)
+			if isValid != test.expected {
+				t.Errorf("Expected: %v, Got: %v", test.expected, isValid)
+			}
+		})
+	}
+}
+
+func TestNormalisePhone(t *testing.T) {
+	tests := []struct {
+		desc     string
+		input    string
+		expected string
+		valid    bool
+	}{
+		{desc: "e164", input: "+919172681679", expected: "+919172681679", valid: true},
+		{desc: "formatted", input: "+1 (415) 555-2671", expected: "+14155552671", valid: true},
+		{desc: "international prefix", input: "00447911123456", expected: "+447911123456", valid: true},
+		{desc: "national", input: "9172681679", expected: "+919172681679", valid: true},
+		{desc: "trunk prefix", input: "09172681679", expected: "+919172681679", valid: true},
+		{desc: "country code without plus", input: "919172681679", expected: "+919172681679", valid: true},
+		{desc: "letters", input: "+91917268167x", valid: false},
+		{desc: "too short", input: "12345", valid: false},
+		{desc: "too long", input: "+1234567890123456", valid: false},
+		{desc: "leading zero country code", input: "+0123456789", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			phone, ok := NormalisePhone(test.input)
+			if ok != test.valid || phone != test.expected {
+				t.Errorf("Expected: %q %v, Got: %q %v", test.expected, test.valid, phone, ok)
+			}
+		})
+	}
+}
+
+func TestNormaliseBloodGroup(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{input: "A+", expected: "A+", valid: true},
+		{input: "+A", expected: "A+", valid: true},
+		{input: "ab-", expected: "AB-", valid: true},
+		{input: "O negative", expected: "O-", valid: true},
+		{input: "B pos", expected: "B+", valid: true},
+		{input: "b+ve", expected: "B+", valid: true},
+		{input: "C+", valid: false},
+		{input: "A", valid: false},
+		{input: "A+1133", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			group, ok := normaliseBloodGroup(test.input)
+			if ok != test.valid || group != test.expected {
+				t.Errorf("Expected: %q %v, Got: %q %v", test.expected, test.valid, group, ok)
+			}
+		})
+	}
+}
+
+func TestValidatePatient(t *testing.T) {
+	t.Run("normalises", func(t *testing.T) {
+		p := &models.Patient{Name: "  Aakanksha ", Phone: "9172681679", BloodGroup: "+a", Description: " cold "}
+		if err := validatePatient(p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := &models.Patient{Name: "Aakanksha", Phone: "+919172681679", BloodGroup: "A+", Description: "cold"}
+		if !reflect.DeepEqual(expected, p) {
+			t.Errorf("Expected: %+v, Got: %+v", expected, p)
+		}
+	})
+
+	t.Run("reports every field", func(t *testing.T) {
+		p := &models.Patient{Name: "", Phone: "abc", BloodGroup: "Z", Description: strings.Repeat("x", maxDescriptionLength+1)}
+		err := validatePatient(p)
+		var verr *perrors.Validation
+		if !errors.As(err, &verr) {
+			t.Fatalf("expected validation error, got: %v", err)
+		}
+		var fields []string
+		for _, f := range verr.Fields {
+			fields = append(fields, f.Field)
+		}
+		expected := []string{"name", "phone", "bloodGroup", "description"}
+		if !reflect.DeepEqual(expected, fields) {
+			t.Errorf("Expected: %v, Got: %v", expected, fields)
+		}
+	})
+
+	t.Run("name too long", func(t *testing.T) {
+		err := validatePatient(&models.Patient{Name: strings.Repeat("n", maxNameLength+1)})
+		if err == nil || err.Error() != "invalid name" {
+			t.Errorf("Expected: invalid name, Got: %v", err)
+		}
+	})
+}
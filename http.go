@@ -1,12 +1,21 @@
 package patient
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/logging"
 	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/patch"
 	"github.com/aakanksha/ppms/internal/service"
+	"github.com/aakanksha/ppms/internal/transfer"
 	"github.com/gorilla/mux"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 type https struct {
@@ -18,21 +27,119 @@ func New(svc service.ServiceInterface) *https {
 }
 
 type ErrorStruct struct {
-	Code    int    `json:"code"`
-	Status  string `json:"status"`
-	Message string `json:"Message"`
+	Code    int                  `json:"code"`
+	Status  string               `json:"status"`
+	Message string               `json:"Message"`
+	Details []perrors.FieldError `json:"details,omitempty"`
 }
 type ResponseStruct struct {
 	Code   int         `json:"code"`
 	Status string      `json:"status"`
 	Data   interface{} `json:"data"`
+	Meta   interface{} `json:"meta,omitempty"`
 }
 
-func Writer(w http.ResponseWriter, response interface{}, status int) {
-	res, _ := json.Marshal(response)
+type pageMeta struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+func Writer(w http.ResponseWriter, r *http.Request, response interface{}, status int) {
+	res, err := json.Marshal(response)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "error", err)
+		res, status = []byte(`{"code":500,"status":"Error","Message":"internal server error"}`), http.StatusInternalServerError
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	w.Write([]byte(res))
+	if _, err := w.Write(res); err != nil {
+		logging.FromContext(r.Context()).Warn("writing response", "error", err)
+	}
+}
+
+// writeError is the single place domain errors are turned into HTTP statuses.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	response := errorResponse(err)
+	if response.Code >= http.StatusInternalServerError {
+		logging.FromContext(r.Context()).Error("request failed", "error", err)
+	}
+	Writer(w, r, response, response.Code)
+}
+
+// WriteError lets the handlers of related resources answer errors the same
+// way patient handlers do.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, err)
+}
+
+// errorResponse maps err to the status code and body clients see.
+func errorResponse(err error) ErrorStruct {
+	var (
+		notFound     *perrors.NotFound
+		validation   *perrors.Validation
+		conflict     *perrors.Conflict
+		precondition *perrors.PreconditionFailed
+		forbidden    *perrors.Forbidden
+		aborted      *perrors.Aborted
+	)
+	response := ErrorStruct{Status: "Error", Message: err.Error()}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		response.Code = http.StatusGatewayTimeout
+		response.Message = "request timed out"
+	case errors.As(err, &notFound):
+		response.Code = http.StatusNotFound
+	case errors.As(err, &validation):
+		response.Code = http.StatusUnprocessableEntity
+		response.Details = validation.Fields
+	case errors.As(err, &conflict):
+		response.Code = http.StatusConflict
+	case errors.As(err, &precondition):
+		response.Code = http.StatusPreconditionFailed
+	case errors.As(err, &forbidden):
+		response.Code = http.StatusForbidden
+	case errors.As(err, &aborted):
+		response.Code = http.StatusFailedDependency
+	default:
+		response.Code = http.StatusInternalServerError
+		response.Message = "internal server error"
+	}
+	return response
+}
+
+// ETag returns the strong entity tag of a resource version.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// IfMatch returns the version required by the If-Match header, or 0 when the
+// request is unconditional. Weak or malformed tags can never match.
+func IfMatch(r *http.Request, entity string, id int) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version <= 0 || !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) {
+		return 0, &perrors.PreconditionFailed{Entity: entity, ID: strconv.Itoa(id)}
+	}
+	return version, nil
+}
+
+// Decode reads the JSON request body into v. On failure it answers 400 and
+// reports false.
+func Decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		Writer(w, r, ErrorStruct{
+			Code:    http.StatusBadRequest,
+			Status:  "Error",
+			Message: err.Error(),
+		}, http.StatusBadRequest)
+		return false
+	}
+	return true
 }
 
 type data struct {
@@ -43,14 +150,14 @@ func (p *https) GetByID(w http.ResponseWriter, r *http.Request) {
 	var response interface{}
 	vars := mux.Vars(r)
 	id, _ := strconv.Atoi(vars["id"])
-	patient, err := p.svc.GetByID(id)
+	patient, err := p.svc.GetByID(r.Context(), id)
 	if err != nil {
-		response = ErrorStruct{
-			Code:    http.StatusBadRequest,
-			Status:  "Error",
-			Message: "Invalid ID",
-		}
-		Writer(w, response, http.StatusBadRequest)
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", ETag(patient.Version))
+	if r.Header.Get("If-None-Match") == ETag(patient.Version) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 	response = ResponseStruct{
@@ -58,59 +165,122 @@ func (p *https) GetByID(w http.ResponseWriter, r *http.Request) {
 		Status: "Success",
 		Data:   data{patient},
 	}
-	Writer(w, response, http.StatusOK)
+	Writer(w, r, response, http.StatusOK)
 }
 
 func (p *https) GetAll(w http.ResponseWriter, r *http.Request) {
+	p.list(w, r, false)
+}
+
+// ListDeleted lists soft-deleted patients, which GetAll never returns.
+func (p *https) ListDeleted(w http.ResponseWriter, r *http.Request) {
+	p.list(w, r, true)
+}
+
+func (p *https) list(w http.ResponseWriter, r *http.Request, deleted bool) {
 	var response interface{}
-	patients, err := p.svc.GetAll()
+	opts, err := listOptions(r)
 	if err != nil {
-		response = ErrorStruct{
-			Code:    http.StatusBadRequest,
-			Status:  "Error",
-			Message: "Invalid ID",
-		}
-		Writer(w, response, http.StatusBadRequest)
+		writeError(w, r, err)
+		return
+	}
+	opts.Deleted = deleted
+	page, err := p.svc.List(r.Context(), opts)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 	response = ResponseStruct{
 		Code:   http.StatusOK,
 		Status: "Success",
-		Data:   data{patients},
+		Data:   data{page.Patients},
+		Meta: pageMeta{
+			Total:      page.Total,
+			Limit:      len(page.Patients),
+			Offset:     opts.Offset,
+			NextCursor: page.NextCursor,
+		},
 	}
-	Writer(w, response, http.StatusOK)
+	Writer(w, r, response, http.StatusOK)
 }
 
-func (p *https) Insert(w http.ResponseWriter, r *http.Request) {
+func (p *https) Search(w http.ResponseWriter, r *http.Request) {
 	var response interface{}
-	var patient models.Patient
-	err := json.NewDecoder(r.Body).Decode(&patient)
+	opts, err := listOptions(r)
 	if err != nil {
-		response = ErrorStruct{
-			Code:    http.StatusBadRequest,
-			Status:  "Error",
-			Message: err.Error(),
-		}
-		Writer(w, response, http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
-	patientvalue, err := p.svc.Insert(&patient)
+	page, err := p.svc.Search(r.Context(), r.URL.Query().Get("q"), opts)
 	if err != nil {
-		response = ErrorStruct{
-			Code:    http.StatusBadRequest,
-			Status:  "Error",
-			Message: err.Error(),
+		writeError(w, r, err)
+		return
+	}
+	response = ResponseStruct{
+		Code:   http.StatusOK,
+		Status: "Success",
+		Data:   data{page.Patients},
+		Meta: pageMeta{
+			Total:  page.Total,
+			Limit:  len(page.Patients),
+			Offset: opts.Offset,
+		},
+	}
+	Writer(w, r, response, http.StatusOK)
+}
+
+func listOptions(r *http.Request) (models.ListOptions, error) {
+	q := r.URL.Query()
+	opts := models.ListOptions{
+		Cursor:     q.Get("cursor"),
+		Sort:       q.Get("sort"),
+		Order:      q.Get("order"),
+		BloodGroup: q.Get("bloodGroup"),
+		Phone:      q.Get("phone"),
+		Name:       strings.TrimSpace(q.Get("name")),
+	}
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil {
+			return opts, perrors.NewValidation("limit", "must be an integer")
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if opts.Offset, err = strconv.Atoi(v); err != nil {
+			return opts, perrors.NewValidation("offset", "must be an integer")
+		}
+	}
+	if v := q.Get("discharge"); v != "" {
+		discharge, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, perrors.NewValidation("discharge", "must be true or false")
 		}
-		Writer(w, response, http.StatusBadRequest)
+		opts.Discharge = &discharge
+	}
+	return opts, nil
+}
+
+func (p *https) Insert(w http.ResponseWriter, r *http.Request) {
+	var response interface{}
+	// A new patient starts discharged, so only an explicit false asks for a
+	// change.
+	patient := models.Patient{Discharge: true}
+	if !Decode(w, r, &patient) {
+		return
+	}
+	patientvalue, err := p.svc.Insert(r.Context(), &patient)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	patientvalue = &models.Patient{Id: patientvalue.Id, Name: patientvalue.Name, Phone: patientvalue.Phone, Discharge: patientvalue.Discharge, BloodGroup: patientvalue.BloodGroup, Description: patientvalue.Description}
+	patientvalue = &models.Patient{Id: patientvalue.Id, Name: patientvalue.Name, Phone: patientvalue.Phone, Discharge: patientvalue.Discharge, BloodGroup: patientvalue.BloodGroup, Description: patientvalue.Description, Version: patientvalue.Version}
+	w.Header().Set("ETag", ETag(patientvalue.Version))
 	response = ResponseStruct{
 		Code:   http.StatusOK,
 		Status: "Success",
 		Data:   data{*patientvalue},
 	}
-	Writer(w, response, http.StatusOK)
+	Writer(w, r, response, http.StatusOK)
 }
 
 func (p *https) Update(w http.ResponseWriter, r *http.Request) {
@@ -118,49 +288,102 @@ func (p *https) Update(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, _ := strconv.Atoi(vars["id"])
 	var patient *models.Patient
-	err := json.NewDecoder(r.Body).Decode(&patient)
+	if !Decode(w, r, &patient) {
+		return
+	}
+	version, err := IfMatch(r, "patient", id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if version > 0 {
+		patient.Version = version
+	}
+	patient, err = p.svc.Update(r.Context(), patient, id)
+
 	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", ETag(patient.Version))
+
+	response = ResponseStruct{
+		Code:   http.StatusOK,
+		Status: "Success",
+
+		Data: data{patient},
+	}
+	Writer(w, r, response, http.StatusOK)
+}
+
+const maxPatchBytes = 1 << 20
+
+func (p *https) Patch(w http.ResponseWriter, r *http.Request) {
+	var response interface{}
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	format := patch.Format(mediaType)
+	if mediaType == "application/json" {
+		format = patch.MergePatch
+	}
+	if format != patch.MergePatch && format != patch.JSONPatch {
+		w.Header().Set("Accept-Patch", string(patch.MergePatch)+", "+string(patch.JSONPatch))
 		response = ErrorStruct{
-			Code:    http.StatusBadRequest,
+			Code:    http.StatusUnsupportedMediaType,
 			Status:  "Error",
-			Message: err.Error(),
+			Message: "unsupported patch format " + mediaType,
 		}
-		Writer(w, response, http.StatusBadRequest)
+		Writer(w, r, response, http.StatusUnsupportedMediaType)
 		return
 	}
-	patient, err = p.svc.Update(patient, id)
 
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	if err == nil && !json.Valid(body) {
+		err = errors.New("patch body is not valid JSON")
+	}
 	if err != nil {
 		response = ErrorStruct{
 			Code:    http.StatusBadRequest,
 			Status:  "Error",
 			Message: err.Error(),
 		}
-		Writer(w, response, http.StatusBadRequest)
+		Writer(w, r, response, http.StatusBadRequest)
 		return
 	}
 
+	version, err := IfMatch(r, "patient", id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	patient, err := p.svc.Patch(r.Context(), id, version, format, body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", ETag(patient.Version))
 	response = ResponseStruct{
 		Code:   http.StatusOK,
 		Status: "Success",
-
-		Data: data{patient},
+		Data:   data{patient},
 	}
-	Writer(w, response, http.StatusOK)
+	Writer(w, r, response, http.StatusOK)
 }
 
 func (p *https) Delete(w http.ResponseWriter, r *http.Request) {
 	var response interface{}
 	vars := mux.Vars(r)
 	id, _ := strconv.Atoi(vars["id"])
-	err := p.svc.Delete(id)
+	version, err := IfMatch(r, "patient", id)
 	if err != nil {
-		response = ErrorStruct{
-			Code:    http.StatusBadRequest,
-			Status:  "Error",
-			Message: err.Error(),
-		}
-		Writer(w, response, http.StatusBadRequest)
+		writeError(w, r, err)
+		return
+	}
+	err = p.svc.Delete(r.Context(), id, version)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 	response = ResponseStruct{
@@ -168,6 +391,303 @@ func (p *https) Delete(w http.ResponseWriter, r *http.Request) {
 		Status: "Success",
 		Data:   "Patient deleted Successfully",
 	}
-	Writer(w, response, http.StatusOK)
+	Writer(w, r, response, http.StatusOK)
+}
+
+func (p *https) Restore(w http.ResponseWriter, r *http.Request) {
+	var response interface{}
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	patient, err := p.svc.Restore(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", ETag(patient.Version))
+	response = ResponseStruct{
+		Code:   http.StatusOK,
+		Status: "Success",
+		Data:   data{patient},
+	}
+	Writer(w, r, response, http.StatusOK)
+}
+
+func (p *https) Purge(w http.ResponseWriter, r *http.Request) {
+	var response interface{}
+	purged, err := p.svc.Purge(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response = ResponseStruct{
+		Code:   http.StatusOK,
+		Status: "Success",
+		Data: struct {
+			Purged int64 `json:"purged"`
+		}{purged},
+	}
+	Writer(w, r, response, http.StatusOK)
+}
+
+func (p *https) History(w http.ResponseWriter, r *http.Request) {
+	var response interface{}
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	entries, err := p.svc.History(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response = ResponseStruct{
+		Code:   http.StatusOK,
+		Status: "Success",
+		Data:   entries,
+	}
+	Writer(w, r, response, http.StatusOK)
+}
+
+const maxBulkBytes = 8 << 20
+
+type bulkRequest struct {
+	Patients json.RawMessage `json:"patients"`
+}
+
+type bulkItem struct {
+	Index int             `json:"index"`
+	Code  int             `json:"code"`
+	Data  *models.Patient `json:"data,omitempty"`
+	Error *ErrorStruct    `json:"error,omitempty"`
+}
+
+type bulkMeta struct {
+	Written int `json:"written"`
+	Failed  int `json:"failed"`
+}
+
+// decodeBulk reads the patients array of a bulk request body into v.
+func decodeBulk(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	var req bulkRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBulkBytes)).Decode(&req)
+	if err == nil {
+		err = json.Unmarshal(req.Patients, v)
+	}
+	if err != nil {
+		Writer(w, r, ErrorStruct{Code: http.StatusBadRequest, Status: "Error", Message: err.Error()}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func bulkMode(r *http.Request) models.BulkMode {
+	if mode := r.URL.Query().Get("mode"); mode != "" {
+		return models.BulkMode(mode)
+	}
+	return models.BulkAtomic
+}
+
+// writeBulk reports one result per item. The response is 200 when every item
+// was written, 207 when only some were, and otherwise carries the status of
+// the first item that failed for its own reasons.
+func writeBulk(w http.ResponseWriter, r *http.Request, results []models.BulkResult, err error, written int) {
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	items := make([]bulkItem, len(results))
+	meta := bulkMeta{}
+	code := 0
+	for i, res := range results {
+		items[i] = bulkItem{Index: i, Code: written, Data: res.Patient}
+		if res.Err != nil {
+			e := errorResponse(res.Err)
+			items[i] = bulkItem{Index: i, Code: e.Code, Error: &e}
+			meta.Failed++
+			if code == 0 && e.Code != http.StatusFailedDependency {
+				code = e.Code
+			}
+			continue
+		}
+		meta.Written++
+	}
+	status := "Success"
+	switch {
+	case meta.Failed == 0:
+		code = http.StatusOK
+	case meta.Written > 0:
+		code = http.StatusMultiStatus
+	default:
+		status = "Error"
+	}
+	Writer(w, r, ResponseStruct{Code: code, Status: status, Data: items, Meta: meta}, code)
+}
+
+// newPatient decodes an item of a bulk create. Like Insert, it leaves a new
+// patient discharged unless the item says otherwise.
+type newPatient struct {
+	*models.Patient
+}
+
+func (n *newPatient) UnmarshalJSON(b []byte) error {
+	n.Patient = &models.Patient{Discharge: true}
+	return json.Unmarshal(b, n.Patient)
+}
+
+func (p *https) BulkInsert(w http.ResponseWriter, r *http.Request) {
+	var items []newPatient
+	if !decodeBulk(w, r, &items) {
+		return
+	}
+	patients := make([]*models.Patient, len(items))
+	for i, item := range items {
+		patients[i] = item.Patient
+	}
+	results, err := p.svc.BulkInsert(r.Context(), patients, bulkMode(r))
+	writeBulk(w, r, results, err, http.StatusCreated)
+}
+
+func (p *https) BulkUpdate(w http.ResponseWriter, r *http.Request) {
+	var patients []*models.Patient
+	if !decodeBulk(w, r, &patients) {
+		return
+	}
+	results, err := p.svc.BulkUpdate(r.Context(), patients, bulkMode(r))
+	writeBulk(w, r, results, err, http.StatusOK)
+}
+
+func (p *https) BulkDelete(w http.ResponseWriter, r *http.Request) {
+	var refs []models.PatientRef
+	if !decodeBulk(w, r, &refs) {
+		return
+	}
+	results, err := p.svc.BulkDelete(r.Context(), refs, bulkMode(r))
+	writeBulk(w, r, results, err, http.StatusOK)
+}
+
+// Export streams every live patient as CSV (the default) or NDJSON, chosen by
+// ?format. Once the first row has been sent the status can no longer change,
+// so a later failure aborts the connection and the client sees a truncated
+// response rather than a complete-looking file.
+func (p *https) Export(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = string(transfer.CSV)
+	}
+	format, ok := transfer.ParseFormat(name)
+	if !ok {
+		writeError(w, r, perrors.NewValidation("format", "must be csv or ndjson"))
+		return
+	}
+	enc := transfer.NewWriter(format, w)
+	started := false
+	start := func() {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", format.ContentType())
+			w.Header().Set("Content-Disposition", `attachment; filename="patients.`+string(format)+`"`)
+		}
+	}
+	err := p.svc.Export(r.Context(), func(pt *models.Patient) error {
+		start()
+		return enc.Write(pt)
+	})
+	if err == nil {
+		start()
+		err = enc.Close()
+	}
+	if err != nil && !started {
+		writeError(w, r, err)
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("export aborted", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+const maxImportBytes = 32 << 20
+
+type importLine struct {
+	Line  int         `json:"line"`
+	Error ErrorStruct `json:"error"`
 }
 
+type importReport struct {
+	Rows     int          `json:"rows"`
+	Imported int          `json:"imported"`
+	DryRun   bool         `json:"dryRun"`
+	Errors   []importLine `json:"errors,omitempty"`
+}
+
+// importFormat takes the format from ?format or else from the Content-Type.
+func importFormat(r *http.Request) (transfer.Format, error) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		switch mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType {
+		case "text/csv":
+			name = "csv"
+		case "application/x-ndjson", "application/jsonl":
+			name = "ndjson"
+		}
+	}
+	format, ok := transfer.ParseFormat(name)
+	if !ok {
+		return "", perrors.NewValidation("format", "must be csv or ndjson")
+	}
+	return format, nil
+}
+
+// limitedBody fails reads past n bytes with a validation error, so an oversized
+// file is reported like any other invalid one.
+type limitedBody struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedBody) Read(b []byte) (int, error) {
+	if l.n <= 0 {
+		var probe [1]byte
+		if n, _ := l.r.Read(probe[:]); n == 0 {
+			return 0, io.EOF
+		}
+		return 0, perrors.NewValidation("file", "must be at most 32 MB")
+	}
+	if int64(len(b)) > l.n {
+		b = b[:l.n]
+	}
+	n, err := l.r.Read(b)
+	l.n -= int64(n)
+	return n, err
+}
+
+// Import creates patients from a CSV or NDJSON body. Every row is validated
+// first; errors are reported by line and nothing is written unless all rows
+// are valid and all of them can be written. ?dryRun=true only validates.
+func (p *https) Import(w http.ResponseWriter, r *http.Request) {
+	format, err := importFormat(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	dryRun := false
+	if v := r.URL.Query().Get("dryRun"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeError(w, r, perrors.NewValidation("dryRun", "must be true or false"))
+			return
+		}
+	}
+	src := transfer.NewReader(format, &limitedBody{r: r.Body, n: maxImportBytes})
+	report, err := p.svc.Import(r.Context(), src, dryRun)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	body := importReport{Rows: report.Rows, Imported: report.Imported, DryRun: report.DryRun}
+	for _, le := range report.Errors {
+		body.Errors = append(body.Errors, importLine{Line: le.Line, Error: errorResponse(le.Err)})
+	}
+	code, status := http.StatusOK, "Success"
+	if len(body.Errors) > 0 {
+		code, status = http.StatusUnprocessableEntity, "Error"
+	}
+	Writer(w, r, ResponseStruct{Code: code, Status: status, Data: body}, code)
+}
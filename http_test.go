@@ -2,16 +2,24 @@ package patient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/logging"
 	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/patch"
 	"github.com/aakanksha/ppms/internal/service"
+	"github.com/aakanksha/ppms/internal/transfer"
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
+	"io"
+	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -51,7 +59,7 @@ func Test_Insert(t *testing.T) {
 				"description": "patient description"
 				}`),
 			input:         patient,
-			mockCall:      mockPatientService.EXPECT().Insert(gomock.Any()).Return(&patient, nil),
+			mockCall:      mockPatientService.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(&patient, nil),
 			expectedError: nil,
 			status:        200,
 		},
@@ -65,9 +73,9 @@ func Test_Insert(t *testing.T) {
 				"description": "patient description"
 				}`),
 			input:         patient,
-			mockCall:      mockPatientService.EXPECT().Insert(gomock.Any()).Return(nil, errors.New("error")),
-			expectedError: errors.New("error"),
-			status:        400,
+			mockCall:      mockPatientService.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil, perrors.NewValidation("name", "must not be empty")),
+			expectedError: perrors.NewValidation("name", "must not be empty"),
+			status:        422,
 		},
 		{
 			body: []byte(`{{
@@ -82,6 +90,14 @@ func Test_Insert(t *testing.T) {
 			expectedError: errors.New("id does not exists"),
 			status:        400,
 		},
+		// A new patient starts discharged unless the body says otherwise.
+		{
+			body:          []byte(`{"name": "Zopsmart", "phone": "+919172681679"}`),
+			input:         patient,
+			mockCall:      mockPatientService.EXPECT().Insert(gomock.Any(), &models.Patient{Name: "Zopsmart", Phone: "+919172681679", Discharge: true}).Return(&patient, nil),
+			expectedError: nil,
+			status:        200,
+		},
 	}
 	p := New(mockPatientService)
 	for _, testCase := range testCases {
@@ -97,30 +113,43 @@ func Test_GetAll(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 	mockPatientService := service.NewMockServiceInterface(mockCtrl)
+	discharged := true
 	testCases := []struct {
-		//body          []byte
+		target        string
 		mockCall      *gomock.Call
 		expectedError error
 		status        int
 	}{
 		// Success
 		{
-			mockCall:      mockPatientService.EXPECT().GetAll().Return([]*models.Patient{&patient}, nil),
+			target: "/patients?limit=1&sort=name&order=desc&discharge=true&bloodGroup=A%2B",
+			mockCall: mockPatientService.EXPECT().List(gomock.Any(), models.ListOptions{Limit: 1, Sort: "name", Order: "desc", Discharge: &discharged, BloodGroup: "A+"}).
+				Return(&models.PatientPage{Patients: []*models.Patient{&patient}, Total: 3, NextCursor: "abc"}, nil),
 			expectedError: nil,
 			status:        200,
 		},
 		//Failure
 		{
-			mockCall:      mockPatientService.EXPECT().GetAll().Return(nil, errors.New("error")),
+			target:        "/patients",
+			mockCall:      mockPatientService.EXPECT().List(gomock.Any(), models.ListOptions{}).Return(nil, errors.New("error")),
 			expectedError: errors.New("error"),
-			status:        400,
+			status:        500,
+		},
+		//Invalid query
+		{
+			target:        "/patients?limit=ten",
+			expectedError: errors.New("invalid limit"),
+			status:        422,
+		},
+		{
+			target:        "/patients?discharge=maybe",
+			expectedError: errors.New("invalid discharge"),
+			status:        422,
 		},
 	}
 	p := New(mockPatientService)
 	for _, testCase := range testCases {
-		l, _ := json.Marshal(patient)
-		m := bytes.NewBuffer(l)
-		r := httptest.NewRequest("GET", "/patients", m)
+		r := httptest.NewRequest("GET", testCase.target, nil)
 		w := httptest.NewRecorder()
 		p.GetAll(w, r)
 		if !reflect.DeepEqual(testCase.status, w.Result().StatusCode) {
@@ -129,6 +158,63 @@ func Test_GetAll(t *testing.T) {
 	}
 }
 
+func Test_GetAllMeta(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockPatientService := service.NewMockServiceInterface(mockCtrl)
+	mockPatientService.EXPECT().List(gomock.Any(), gomock.Any()).Return(&models.PatientPage{Patients: []*models.Patient{&patient}, Total: 3, NextCursor: "abc"}, nil)
+
+	w := httptest.NewRecorder()
+	New(mockPatientService).GetAll(w, httptest.NewRequest("GET", "/patients?limit=1", nil))
+
+	var body struct {
+		Meta pageMeta `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := pageMeta{Total: 3, Limit: 1, NextCursor: "abc"}
+	if !reflect.DeepEqual(expected, body.Meta) {
+		t.Errorf("Expected: %v Got %v", expected, body.Meta)
+	}
+}
+
+func Test_Search(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockPatientService := service.NewMockServiceInterface(mockCtrl)
+	testCases := []struct {
+		target   string
+		mockCall *gomock.Call
+		status   int
+	}{
+		// Success
+		{
+			target:   "/patient/search?q=ram&limit=5",
+			mockCall: mockPatientService.EXPECT().Search(gomock.Any(), "ram", models.ListOptions{Limit: 5}).Return(&models.PatientPage{Patients: []*models.Patient{&patient}, Total: 1}, nil),
+			status:   200,
+		},
+		//Failure
+		{
+			target:   "/patient/search",
+			mockCall: mockPatientService.EXPECT().Search(gomock.Any(), "", models.ListOptions{}).Return(nil, perrors.NewValidation("q", "must not be empty")),
+			status:   422,
+		},
+		{
+			target: "/patient/search?q=ram&offset=x",
+			status: 422,
+		},
+	}
+	p := New(mockPatientService)
+	for _, testCase := range testCases {
+		w := httptest.NewRecorder()
+		p.Search(w, httptest.NewRequest("GET", testCase.target, nil))
+		if !reflect.DeepEqual(testCase.status, w.Result().StatusCode) {
+			t.Errorf("Expected error: %v Got %v", testCase.status, w.Result().StatusCode)
+		}
+	}
+}
+
 func TestGetByID(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -144,16 +230,29 @@ func TestGetByID(t *testing.T) {
 		//Success
 		{
 			id:            idString,
-			mockCall:      mockPatientService.EXPECT().GetByID(patient.Id).Return(&patient, nil),
+			mockCall:      mockPatientService.EXPECT().GetByID(gomock.Any(), patient.Id).Return(&patient, nil),
 			expectedError: nil,
 			status:        200,
 		},
 		// Failure
 		{
 			id:            idString,
-			mockCall:      mockPatientService.EXPECT().GetByID(patient.Id).Return(&models.Patient{}, errors.New("error")),
-			expectedError: errors.New("error"),
-			status:        400,
+			mockCall:      mockPatientService.EXPECT().GetByID(gomock.Any(), patient.Id).Return(nil, &perrors.NotFound{Entity: "patient", ID: idString}),
+			expectedError: &perrors.NotFound{Entity: "patient", ID: idString},
+			status:        404,
+		},
+		{
+			id:            idString,
+			mockCall:      mockPatientService.EXPECT().GetByID(gomock.Any(), patient.Id).Return(nil, &perrors.Internal{Err: errors.New("connection refused")}),
+			expectedError: errors.New("connection refused"),
+			status:        500,
+		},
+		// Timeout
+		{
+			id:            idString,
+			mockCall:      mockPatientService.EXPECT().GetByID(gomock.Any(), patient.Id).Return(nil, context.DeadlineExceeded),
+			expectedError: context.DeadlineExceeded,
+			status:        504,
 		},
 	}
 	p := New(mockPatientService)
@@ -187,16 +286,16 @@ func Test_DeletePatientService(t *testing.T) {
 		// Success
 		{
 			id:            idString,
-			mockCall:      mockPatientService.EXPECT().Delete(patient.Id).Return(nil),
+			mockCall:      mockPatientService.EXPECT().Delete(gomock.Any(), patient.Id, 0).Return(nil),
 			expectedError: nil,
 			status:        200,
 		},
 		//Failure
 		{
 			id:            idString,
-			mockCall:      mockPatientService.EXPECT().Delete(patient.Id).Return(errors.New("error")),
+			mockCall:      mockPatientService.EXPECT().Delete(gomock.Any(), patient.Id, 0).Return(errors.New("error")),
 			expectedError: errors.New("error"),
-			status:        400,
+			status:        500,
 		},
 	}
 	p := New(mockPatientService)
@@ -239,7 +338,7 @@ func Test_UpdatePatientService(t *testing.T) {
 		   "description": "goo1133d"
 					}`),
 			id:            idString,
-			mockCall:      mockPatientService.EXPECT().Update(gomock.Any(), patient.Id).Return(&patient, nil),
+			mockCall:      mockPatientService.EXPECT().Update(gomock.Any(), gomock.Any(), patient.Id).Return(&patient, nil),
 			expectedError: nil,
 			status:        200,
 		},
@@ -248,9 +347,9 @@ func Test_UpdatePatientService(t *testing.T) {
 			body: []byte(`{
 				}`),
 			id:            idString,
-			mockCall:      mockPatientService.EXPECT().Update(gomock.Any(), patient.Id).Return(&models.Patient{}, errors.New("error")),
-			expectedError: errors.New("error"),
-			status:        400,
+			mockCall:      mockPatientService.EXPECT().Update(gomock.Any(), gomock.Any(), patient.Id).Return(nil, &perrors.Conflict{Entity: "patient", Reason: "duplicate phone"}),
+			expectedError: &perrors.Conflict{Entity: "patient", Reason: "duplicate phone"},
+			status:        409,
 		},
 		//failure
 		{
@@ -281,3 +380,634 @@ func Test_UpdatePatientService(t *testing.T) {
 	}
 }
 
+func Test_WriteErrorBody(t *testing.T) {
+	validation := perrors.NewValidation("name", "must not be empty")
+	validation.Add("phone", "must be E.164")
+	tests := []struct {
+		desc     string
+		err      error
+		expected ErrorStruct
+		logged   string
+	}{
+		{
+			desc:     "validation details",
+			err:      validation,
+			expected: ErrorStruct{Code: 422, Status: "Error", Message: "invalid name, phone", Details: validation.Fields},
+		},
+		{
+			desc:     "internal message hidden",
+			err:      &perrors.Internal{Err: errors.New("dial tcp: connection refused")},
+			expected: ErrorStruct{Code: 500, Status: "Error", Message: "internal server error"},
+			logged:   "dial tcp: connection refused",
+		},
+		{
+			desc:     "forbidden",
+			err:      &perrors.Forbidden{Action: "write", Fields: []string{"description"}},
+			expected: ErrorStruct{Code: 403, Status: "Error", Message: "not allowed to write description"},
+		},
+		{
+			desc:     "wrapped timeout",
+			err:      &perrors.Internal{Err: context.DeadlineExceeded},
+			expected: ErrorStruct{Code: 504, Status: "Error", Message: "request timed out"},
+			logged:   "context deadline exceeded",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var logs bytes.Buffer
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/patient", nil)
+			r = r.WithContext(logging.WithLogger(r.Context(), logging.New(&logs)))
+			writeError(w, r, test.err)
+			var body ErrorStruct
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, body) || w.Code != test.expected.Code {
+				t.Errorf("Expected: %+v Got %+v (%d)", test.expected, body, w.Code)
+			}
+			if test.logged == "" && logs.Len() != 0 {
+				t.Errorf("Expected nothing logged, Got: %s", logs.String())
+			}
+			if test.logged != "" && !strings.Contains(logs.String(), test.logged) {
+				t.Errorf("Expected log containing %q, Got: %s", test.logged, logs.String())
+			}
+		})
+	}
+}
+
+func Test_Patch(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	idString := strconv.Itoa(patient.Id)
+	mockPatientService := service.NewMockServiceInterface(mockCtrl)
+	testCases := []struct {
+		desc        string
+		contentType string
+		body        string
+		mockCall    *gomock.Call
+		status      int
+	}{
+		{
+			desc:        "merge patch",
+			contentType: "application/merge-patch+json",
+			body:        `{"discharge": true}`,
+			mockCall:    mockPatientService.EXPECT().Patch(gomock.Any(), patient.Id, 0, patch.MergePatch, []byte(`{"discharge": true}`)).Return(&patient, nil),
+			status:      200,
+		},
+		{
+			desc:        "plain json is a merge patch",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name": "ak"}`,
+			mockCall:    mockPatientService.EXPECT().Patch(gomock.Any(), patient.Id, 0, patch.MergePatch, []byte(`{"name": "ak"}`)).Return(&patient, nil),
+			status:      200,
+		},
+		{
+			desc:        "json patch",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"replace","path":"/discharge","value":true}]`,
+			mockCall:    mockPatientService.EXPECT().Patch(gomock.Any(), patient.Id, 0, patch.JSONPatch, gomock.Any()).Return(&patient, nil),
+			status:      200,
+		},
+		{
+			desc:        "validation failure",
+			contentType: "application/merge-patch+json",
+			body:        `{"name": null}`,
+			mockCall:    mockPatientService.EXPECT().Patch(gomock.Any(), patient.Id, 0, patch.MergePatch, gomock.Any()).Return(nil, perrors.NewValidation("name", "must not be empty")),
+			status:      422,
+		},
+		{
+			desc:        "unsupported media type",
+			contentType: "text/plain",
+			body:        `discharge=true`,
+			status:      415,
+		},
+		{
+			desc:        "malformed json",
+			contentType: "application/merge-patch+json",
+			body:        `{"discharge": `,
+			status:      400,
+		},
+	}
+	p := New(mockPatientService)
+	for _, testCase := range testCases {
+		t.Run(testCase.desc, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", fmt.Sprintf("/patient/%s", idString), bytes.NewBufferString(testCase.body))
+			r.Header.Set("Content-Type", testCase.contentType)
+			r = mux.SetURLVars(r, map[string]string{"id": idString})
+			w := httptest.NewRecorder()
+			p.Patch(w, r)
+			if !reflect.DeepEqual(testCase.status, w.Result().StatusCode) {
+				t.Errorf("Expected error: %v Got %v", testCase.status, w.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_ConditionalRequests(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	idString := strconv.Itoa(patient.Id)
+	mockPatientService := service.NewMockServiceInterface(mockCtrl)
+	versioned := patient
+	versioned.Version = 3
+
+	testCases := []struct {
+		desc     string
+		method   string
+		header   map[string]string
+		mockCall *gomock.Call
+		status   int
+		etag     string
+	}{
+		{
+			desc:     "get sets etag",
+			method:   "GET",
+			mockCall: mockPatientService.EXPECT().GetByID(gomock.Any(), patient.Id).Return(&versioned, nil),
+			status:   200,
+			etag:     `"3"`,
+		},
+		{
+			desc:     "get not modified",
+			method:   "GET",
+			header:   map[string]string{"If-None-Match": `"3"`},
+			mockCall: mockPatientService.EXPECT().GetByID(gomock.Any(), patient.Id).Return(&versioned, nil),
+			status:   304,
+			etag:     `"3"`,
+		},
+		{
+			desc:     "delete with if-match",
+			method:   "DELETE",
+			header:   map[string]string{"If-Match": `"3"`},
+			mockCall: mockPatientService.EXPECT().Delete(gomock.Any(), patient.Id, 3).Return(nil),
+			status:   200,
+		},
+		{
+			desc:     "delete with stale if-match",
+			method:   "DELETE",
+			header:   map[string]string{"If-Match": `"2"`},
+			mockCall: mockPatientService.EXPECT().Delete(gomock.Any(), patient.Id, 2).Return(&perrors.PreconditionFailed{Entity: "patient", ID: idString}),
+			status:   412,
+		},
+		{
+			desc:   "weak if-match never matches",
+			method: "DELETE",
+			header: map[string]string{"If-Match": `W/"3"`},
+			status: 412,
+		},
+		{
+			desc:     "patch with if-match",
+			method:   "PATCH",
+			header:   map[string]string{"If-Match": `"3"`, "Content-Type": "application/merge-patch+json"},
+			mockCall: mockPatientService.EXPECT().Patch(gomock.Any(), patient.Id, 3, patch.MergePatch, gomock.Any()).Return(&versioned, nil),
+			status:   200,
+			etag:     `"3"`,
+		},
+		{
+			desc:   "put with if-match",
+			method: "PUT",
+			header: map[string]string{"If-Match": `"3"`},
+			mockCall: mockPatientService.EXPECT().Update(gomock.Any(), gomock.Any(), patient.Id).DoAndReturn(func(_ context.Context, p *models.Patient, _ int) (*models.Patient, error) {
+				if p.Version != 3 {
+					return nil, &perrors.PreconditionFailed{Entity: "patient", ID: idString}
+				}
+				return &versioned, nil
+			}),
+			status: 200,
+			etag:   `"3"`,
+		},
+	}
+	p := New(mockPatientService)
+	handlers := map[string]func(http.ResponseWriter, *http.Request){"GET": p.GetByID, "DELETE": p.Delete, "PATCH": p.Patch, "PUT": p.Update}
+	for _, testCase := range testCases {
+		t.Run(testCase.desc, func(t *testing.T) {
+			r := httptest.NewRequest(testCase.method, fmt.Sprintf("/patient/%s", idString), bytes.NewBufferString(`{"name":"ZopSmart"}`))
+			for k, v := range testCase.header {
+				r.Header.Set(k, v)
+			}
+			r = mux.SetURLVars(r, map[string]string{"id": idString})
+			w := httptest.NewRecorder()
+			handlers[testCase.method](w, r)
+			if w.Result().StatusCode != testCase.status {
+				t.Errorf("Expected error: %v Got %v", testCase.status, w.Result().StatusCode)
+			}
+			if etag := w.Header().Get("ETag"); etag != testCase.etag {
+				t.Errorf("Expected etag: %v Got %v", testCase.etag, etag)
+			}
+		})
+	}
+}
+
+func Test_RestoreAndPurge(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockPatientService := service.NewMockServiceInterface(mockCtrl)
+	p := New(mockPatientService)
+
+	testCases := []struct {
+		desc    string
+		method  string
+		target  string
+		handler http.HandlerFunc
+		setup   func()
+		status  int
+	}{
+		{
+			desc:    "restore",
+			method:  http.MethodPost,
+			target:  "/patient/1/restore",
+			handler: p.Restore,
+			setup: func() {
+				mockPatientService.EXPECT().Restore(gomock.Any(), 1).Return(&models.Patient{Id: 1, Name: "ZopSmart", Version: 2}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			desc:    "restore of patient that is not deleted",
+			method:  http.MethodPost,
+			target:  "/patient/1/restore",
+			handler: p.Restore,
+			setup: func() {
+				mockPatientService.EXPECT().Restore(gomock.Any(), 1).Return(nil, &perrors.NotFound{Entity: "deleted patient", ID: "1"})
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			desc:    "list deleted",
+			method:  http.MethodGet,
+			target:  "/patient/deleted",
+			handler: p.ListDeleted,
+			setup: func() {
+				mockPatientService.EXPECT().List(gomock.Any(), models.ListOptions{Deleted: true}).Return(&models.PatientPage{Total: 0}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			desc:    "purge",
+			method:  http.MethodPost,
+			target:  "/patient/purge",
+			handler: p.Purge,
+			setup: func() {
+				mockPatientService.EXPECT().Purge(gomock.Any()).Return(int64(3), nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			desc:    "purge disabled",
+			method:  http.MethodPost,
+			target:  "/patient/purge",
+			handler: p.Purge,
+			setup: func() {
+				mockPatientService.EXPECT().Purge(gomock.Any()).Return(int64(0), perrors.NewValidation("retention", "purging is disabled"))
+			},
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.desc, func(t *testing.T) {
+			testCase.setup()
+			r := httptest.NewRequest(testCase.method, testCase.target, nil)
+			r = mux.SetURLVars(r, map[string]string{"id": "1"})
+			w := httptest.NewRecorder()
+			testCase.handler(w, r)
+			if w.Code != testCase.status {
+				t.Errorf("Expected: %v, Got: %v", testCase.status, w.Code)
+			}
+		})
+	}
+}
+
+func Test_History(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockPatientService := service.NewMockServiceInterface(mockCtrl)
+	p := New(mockPatientService)
+
+	testCases := []struct {
+		desc   string
+		id     string
+		mock   *gomock.Call
+		status int
+	}{
+		{
+			desc: "success",
+			id:   "1",
+			mock: mockPatientService.EXPECT().History(gomock.Any(), 1).Return([]*models.AuditEntry{
+				{ID: 1, PatientID: 1, Actor: "system", Operation: "create"},
+			}, nil),
+			status: http.StatusOK,
+		},
+		{
+			desc:   "unknown patient",
+			id:     "2",
+			mock:   mockPatientService.EXPECT().History(gomock.Any(), 2).Return(nil, &perrors.NotFound{Entity: "patient", ID: "2"}),
+			status: http.StatusNotFound,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.desc, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/patient/"+testCase.id+"/history", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": testCase.id})
+			w := httptest.NewRecorder()
+			p.History(w, r)
+			if w.Code != testCase.status {
+				t.Errorf("Expected: %v, Got: %v", testCase.status, w.Code)
+			}
+		})
+	}
+}
+
+func Test_Bulk(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockPatientService := service.NewMockServiceInterface(mockCtrl)
+	p := New(mockPatientService)
+	notFound := &perrors.NotFound{Entity: "patient", ID: "9"}
+
+	testCases := []struct {
+		desc     string
+		target   string
+		body     string
+		handler  http.HandlerFunc
+		setup    func()
+		status   int
+		expected []int
+	}{
+		{
+			desc:    "insert",
+			target:  "/patient/bulk",
+			body:    `{"patients": [{"name": "Ram"}, {"name": "Shyam", "discharge": false}]}`,
+			handler: p.BulkInsert,
+			setup: func() {
+				mockPatientService.EXPECT().BulkInsert(gomock.Any(), []*models.Patient{{Name: "Ram", Discharge: true}, {Name: "Shyam", Discharge: false}}, models.BulkAtomic).
+					Return([]models.BulkResult{{Patient: &models.Patient{Id: 1}}, {Patient: &models.Patient{Id: 2}}}, nil)
+			},
+			status:   http.StatusOK,
+			expected: []int{http.StatusCreated, http.StatusCreated},
+		},
+		{
+			desc:    "partial update",
+			target:  "/patient/bulk?mode=partial",
+			body:    `{"patients": [{"id": 1, "name": "Ram"}, {"id": 9, "name": "Shyam"}]}`,
+			handler: p.BulkUpdate,
+			setup: func() {
+				mockPatientService.EXPECT().BulkUpdate(gomock.Any(), gomock.Len(2), models.BulkPartial).
+					Return([]models.BulkResult{{Patient: &models.Patient{Id: 1}}, {Err: notFound}}, nil)
+			},
+			status:   http.StatusMultiStatus,
+			expected: []int{http.StatusOK, http.StatusNotFound},
+		},
+		{
+			desc:    "atomic delete",
+			target:  "/patient/bulk/delete",
+			body:    `{"patients": [{"id": 1, "version": 2}, {"id": 9}]}`,
+			handler: p.BulkDelete,
+			setup: func() {
+				mockPatientService.EXPECT().BulkDelete(gomock.Any(), []models.PatientRef{{ID: 1, Version: 2}, {ID: 9}}, models.BulkAtomic).
+					Return([]models.BulkResult{{Err: &perrors.Aborted{}}, {Err: notFound}}, nil)
+			},
+			status:   http.StatusNotFound,
+			expected: []int{http.StatusFailedDependency, http.StatusNotFound},
+		},
+		{
+			desc:    "invalid mode",
+			target:  "/patient/bulk?mode=best-effort",
+			body:    `{"patients": [{"name": "Ram"}]}`,
+			handler: p.BulkInsert,
+			setup: func() {
+				mockPatientService.EXPECT().BulkInsert(gomock.Any(), gomock.Any(), models.BulkMode("best-effort")).
+					Return(nil, perrors.NewValidation("mode", "must be atomic or partial"))
+			},
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			desc:    "patients is not an array",
+			target:  "/patient/bulk",
+			body:    `{"patients": {"name": "Ram"}}`,
+			handler: p.BulkInsert,
+			setup:   func() {},
+			status:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tc.setup()
+			w := httptest.NewRecorder()
+			tc.handler(w, httptest.NewRequest(http.MethodPost, tc.target, bytes.NewBufferString(tc.body)))
+			if w.Code != tc.status {
+				t.Errorf("Expected: %v, Got: %v", tc.status, w.Code)
+			}
+			if tc.expected == nil {
+				return
+			}
+			var body struct {
+				Data []bulkItem
+				Meta bulkMeta
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i, item := range body.Data {
+				if item.Index != i || item.Code != tc.expected[i] || (item.Error == nil) != (item.Data != nil) {
+					t.Errorf("item %d: Expected code %v, Got: %+v", i, tc.expected[i], item)
+				}
+			}
+		})
+	}
+}
+
+func Test_Export(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockPatientService := service.NewMockServiceInterface(mockCtrl)
+	p := New(mockPatientService)
+	created := time.Date(2022, 2, 22, 13, 23, 22, 0, time.UTC)
+	each := func(ctx context.Context, fn func(*models.Patient) error) error {
+		return fn(&models.Patient{Id: 1, Name: "Ram", CreatedAt: created, UpdatedAt: created, Version: 1})
+	}
+
+	testCases := []struct {
+		desc        string
+		target      string
+		setup       func()
+		status      int
+		contentType string
+		expected    string
+	}{
+		{
+			desc:        "csv by default",
+			target:      "/patient/export",
+			setup:       func() { mockPatientService.EXPECT().Export(gomock.Any(), gomock.Any()).DoAndReturn(each) },
+			status:      http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			expected:    "id,name,phone,discharge,bloodGroup,description,createdAt,updatedAt,version\n1,Ram,,false,,,2022-02-22T13:23:22Z,2022-02-22T13:23:22Z,1\n",
+		},
+		{
+			desc:        "ndjson",
+			target:      "/patient/export?format=ndjson",
+			setup:       func() { mockPatientService.EXPECT().Export(gomock.Any(), gomock.Any()).DoAndReturn(each) },
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			expected:    `{"id":1,"name":"Ram","phone":"","discharge":false,"createdAt":"2022-02-22T13:23:22Z","updatedAt":"2022-02-22T13:23:22Z","bloodGroup":"","description":"","version":1}` + "\n",
+		},
+		{
+			desc:   "forbidden before the first row",
+			target: "/patient/export",
+			setup: func() {
+				mockPatientService.EXPECT().Export(gomock.Any(), gomock.Any()).Return(&perrors.Forbidden{Action: "read patients"})
+			},
+			status:      http.StatusForbidden,
+			contentType: "application/json",
+		},
+		{
+			desc:        "unknown format",
+			target:      "/patient/export?format=xlsx",
+			setup:       func() {},
+			status:      http.StatusUnprocessableEntity,
+			contentType: "application/json",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tc.setup()
+			w := httptest.NewRecorder()
+			p.Export(w, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			if w.Code != tc.status {
+				t.Errorf("Expected: %v, Got: %v", tc.status, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != tc.contentType {
+				t.Errorf("Expected: %v, Got: %v", tc.contentType, got)
+			}
+			if tc.expected != "" && w.Body.String() != tc.expected {
+				t.Errorf("Expected: %q, Got: %q", tc.expected, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_ExportAbortsAfterFirstRow(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockPatientService := service.NewMockServiceInterface(mockCtrl)
+	mockPatientService.EXPECT().Export(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, fn func(*models.Patient) error) error {
+			fn(&models.Patient{Id: 1, Name: "Ram"})
+			return errors.New("connection lost")
+		})
+
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("Expected: %v, Got: %v", http.ErrAbortHandler, r)
+		}
+	}()
+	ctx := logging.WithLogger(context.Background(), logging.New(io.Discard))
+	New(mockPatientService).Export(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/patient/export", nil).WithContext(ctx))
+}
+
+func Test_Import(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockPatientService := service.NewMockServiceInterface(mockCtrl)
+	p := New(mockPatientService)
+	// readRows drains src the way the service does and reports its rows.
+	readRows := func(ctx context.Context, src transfer.Reader, dryRun bool) (*models.ImportReport, error) {
+		report := &models.ImportReport{DryRun: dryRun}
+		for {
+			row, err := src.Next()
+			if err == io.EOF {
+				return report, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			report.Rows++
+			if row.Err != nil {
+				report.Errors = append(report.Errors, models.LineError{Line: row.Line, Err: row.Err})
+			}
+		}
+	}
+
+	testCases := []struct {
+		desc        string
+		target      string
+		contentType string
+		body        string
+		dryRun      bool
+		status      int
+		expected    string
+	}{
+		{
+			desc:        "format from content type",
+			target:      "/patient/import?dryRun=true",
+			contentType: "text/csv",
+			body:        "name\nRam\n",
+			dryRun:      true,
+			status:      http.StatusOK,
+			expected:    `{"code":200,"status":"Success","data":{"rows":1,"imported":0,"dryRun":true}}`,
+		},
+		{
+			desc:     "line errors",
+			target:   "/patient/import?format=ndjson",
+			body:     "{\"name\":\"Ram\"}\n{\"name\":\"Sita\",\"ward\":1}\n",
+			status:   http.StatusUnprocessableEntity,
+			expected: `{"code":422,"status":"Error","data":{"rows":2,"imported":0,"dryRun":false,"errors":[{"line":2,"error":{"code":422,"status":"Error","Message":"invalid row","details":[{"field":"row","message":"json: unknown field \"ward\""}]}}]}}`,
+		},
+		{
+			desc:     "missing format",
+			target:   "/patient/import",
+			body:     "name\nRam\n",
+			status:   http.StatusUnprocessableEntity,
+			expected: `{"code":422,"status":"Error","Message":"invalid format","details":[{"field":"format","message":"must be csv or ndjson"}]}`,
+		},
+		{
+			desc:     "invalid dryRun",
+			target:   "/patient/import?format=csv&dryRun=maybe",
+			body:     "name\nRam\n",
+			status:   http.StatusUnprocessableEntity,
+			expected: `{"code":422,"status":"Error","Message":"invalid dryRun","details":[{"field":"dryRun","message":"must be true or false"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if tc.expected != "" && strings.Contains(tc.expected, `"data"`) {
+				mockPatientService.EXPECT().Import(gomock.Any(), gomock.Any(), tc.dryRun).DoAndReturn(readRows)
+			}
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			p.Import(w, req)
+			if w.Code != tc.status {
+				t.Errorf("Expected: %v, Got: %v", tc.status, w.Code)
+			}
+			if got := w.Body.String(); got != tc.expected {
+				t.Errorf("Expected: %v, Got: %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func Test_LimitedBody(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		input       string
+		expectError error
+	}{
+		{desc: "at the limit", input: "abcd"},
+		{desc: "over the limit", input: "abcde", expectError: perrors.NewValidation("file", "must be at most 32 MB")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := io.ReadAll(&limitedBody{r: strings.NewReader(tc.input), n: 4})
+			if !reflect.DeepEqual(err, tc.expectError) {
+				t.Errorf("expected error :%v, got :%v ", tc.expectError, err)
+			}
+			if string(got) != "abcd" {
+				t.Errorf("Expected: %v, Got: %v", "abcd", string(got))
+			}
+		})
+	}
+}
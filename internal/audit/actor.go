@@ -0,0 +1,21 @@
+package audit
+
+import "context"
+
+// System is recorded as the actor of changes made outside a user request, such
+// as the scheduled purge.
+const System = "system"
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the actor stored in ctx, or System when there is none.
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return System
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+)
+
+// APIKeys authenticates requests by a static key in the X-API-Key header.
+// Keys are held only as SHA-256 digests.
+type APIKeys struct {
+	keys map[[sha256.Size]byte]*Principal
+}
+
+type apiKeyEntry struct {
+	Key     string   `json:"key"`
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+}
+
+func NewAPIKeys() *APIKeys {
+	return &APIKeys{keys: map[[sha256.Size]byte]*Principal{}}
+}
+
+// LoadAPIKeys reads a JSON array of {"key", "subject", "roles"} objects.
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []apiKeyEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, err
+	}
+	a := NewAPIKeys()
+	for _, e := range entries {
+		if e.Key == "" || e.Subject == "" {
+			return nil, errors.New("api key entries need a key and a subject")
+		}
+		a.Add(e.Key, e.Subject, e.Roles...)
+	}
+	return a, nil
+}
+
+func (a *APIKeys) Add(key, subject string, roles ...string) {
+	a.keys[sha256.Sum256([]byte(key))] = &Principal{Subject: subject, Roles: roles, Method: "apikey"}
+}
+
+func (a *APIKeys) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+	p, ok := a.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, errors.New("unknown api key")
+	}
+	copied := *p
+	return &copied, nil
+}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// JWT authenticates requests carrying an HS256 or RS256 bearer token. Keys are
+// HMAC secrets ([]byte) or *rsa.PublicKey values, looked up by the token's kid.
+type JWT struct {
+	keys     map[string]interface{}
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking exp and nbf.
+	Leeway time.Duration
+	now    func() time.Time
+}
+
+func NewJWT() *JWT {
+	return &JWT{keys: map[string]interface{}{}, Leeway: time.Minute, now: time.Now}
+}
+
+// AddKey registers a key under kid; a key registered under "" is used for
+// tokens without a kid.
+func (j *JWT) AddKey(kid string, key interface{}) error {
+	switch key.(type) {
+	case []byte, *rsa.PublicKey:
+		j.keys[kid] = key
+		return nil
+	}
+	return fmt.Errorf("unsupported key type %T", key)
+}
+
+// LoadKeyFile registers the key in path for tokens without a kid. A PEM file
+// holds an RSA public key or certificate; anything else is an HMAC secret.
+func (j *JWT) LoadKeyFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		secret := []byte(strings.TrimSpace(string(b)))
+		if len(secret) < 32 {
+			return errors.New("hmac secret must be at least 32 bytes")
+		}
+		return j.AddKey("", secret)
+	}
+	var pub interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		pub, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			pub = cert.PublicKey
+		}
+	default:
+		return fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return err
+	}
+	return j.AddKey("", pub)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// LoadJWKS registers every RSA and oct key of a JSON Web Key Set file. An oct
+// key shorter than 32 bytes fails the whole set, like a short secret file.
+func (j *JWT) LoadJWKS(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return err
+	}
+	for _, k := range set.Keys {
+		var key interface{}
+		switch k.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return fmt.Errorf("jwk %q: %w", k.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				return fmt.Errorf("jwk %q: %w", k.Kid, err)
+			}
+			key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil {
+				return fmt.Errorf("jwk %q: %w", k.Kid, err)
+			}
+			if len(secret) < 32 {
+				return fmt.Errorf("jwk %q: hmac secret must be at least 32 bytes", k.Kid)
+			}
+			key = secret
+		default:
+			continue
+		}
+		if err := j.AddKey(k.Kid, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *JWT) Authenticate(r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return nil, ErrNoCredentials
+	}
+	return j.Verify(strings.TrimSpace(header[7:]))
+}
+
+type claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	Roles     []string `json:"roles"`
+}
+
+// audience accepts both forms of the aud claim: a string or an array of them.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Verify checks the signature and claims of a compact JWS and returns the
+// principal it names. Tokens must carry sub and exp.
+func (j *JWT) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var head struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &head); err != nil {
+		return nil, errors.New("malformed token header")
+	}
+	key, ok := j.keys[head.Kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", head.Kid)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	switch k := key.(type) {
+	case []byte:
+		if head.Alg != "HS256" {
+			return nil, fmt.Errorf("algorithm %q not allowed for this key", head.Alg)
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, errors.New("invalid token signature")
+		}
+	case *rsa.PublicKey:
+		if head.Alg != "RS256" {
+			return nil, fmt.Errorf("algorithm %q not allowed for this key", head.Alg)
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig); err != nil {
+			return nil, errors.New("invalid token signature")
+		}
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, errors.New("malformed token claims")
+	}
+	now := j.now()
+	switch {
+	case c.Subject == "":
+		return nil, errors.New("token has no subject")
+	case c.ExpiresAt == nil:
+		return nil, errors.New("token has no expiry")
+	case now.After(time.Unix(*c.ExpiresAt, 0).Add(j.Leeway)):
+		return nil, errors.New("token expired")
+	case c.NotBefore != nil && now.Add(j.Leeway).Before(time.Unix(*c.NotBefore, 0)):
+		return nil, errors.New("token not yet valid")
+	case j.Issuer != "" && c.Issuer != j.Issuer:
+		return nil, errors.New("unexpected token issuer")
+	case j.Audience != "" && !c.Audience.contains(j.Audience):
+		return nil, errors.New("unexpected token audience")
+	}
+	return &Principal{Subject: c.Subject, Roles: c.Roles, Method: "jwt"}, nil
+}
+
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func sign(t *testing.T, header, claims map[string]interface{}, key interface{}) string {
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	j := NewJWT()
+	j.now = func() time.Time { return now }
+	j.Audience = "ppms"
+	j.AddKey("", secret)
+	j.AddKey("rsa1", &rsaKey.PublicKey)
+
+	valid := map[string]interface{}{"sub": "dr.rao", "exp": now.Add(time.Hour).Unix(), "aud": []string{"ppms"}, "roles": []string{"doctor"}}
+	tests := []struct {
+		desc        string
+		token       string
+		expectError bool
+	}{
+		{desc: "hs256", token: sign(t, map[string]interface{}{"alg": "HS256"}, valid, secret)},
+		{desc: "rs256 with kid", token: sign(t, map[string]interface{}{"alg": "RS256", "kid": "rsa1"}, valid, rsaKey)},
+		{
+			desc:        "wrong secret",
+			token:       sign(t, map[string]interface{}{"alg": "HS256"}, valid, []byte("another secret that is long enough")),
+			expectError: true,
+		},
+		{
+			desc:        "hs256 signed with the rsa public key",
+			token:       sign(t, map[string]interface{}{"alg": "HS256", "kid": "rsa1"}, valid, x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)),
+			expectError: true,
+		},
+		{desc: "alg none", token: sign(t, map[string]interface{}{"alg": "none"}, valid, []byte{}), expectError: true},
+		{desc: "unknown kid", token: sign(t, map[string]interface{}{"alg": "HS256", "kid": "x"}, valid, secret), expectError: true},
+		{
+			desc:        "expired",
+			token:       sign(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "dr.rao", "exp": now.Add(-time.Hour).Unix(), "aud": "ppms"}, secret),
+			expectError: true,
+		},
+		{
+			desc:        "no expiry",
+			token:       sign(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "dr.rao", "aud": "ppms"}, secret),
+			expectError: true,
+		},
+		{
+			desc:        "wrong audience",
+			token:       sign(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "dr.rao", "exp": now.Add(time.Hour).Unix(), "aud": "billing"}, secret),
+			expectError: true,
+		},
+		{desc: "malformed", token: "abc.def", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			p, err := j.Verify(test.token)
+			if (err != nil) != test.expectError {
+				t.Fatalf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if err == nil && (p.Subject != "dr.rao" || !p.HasRole("doctor") || p.Method != "jwt") {
+				t.Errorf("unexpected principal: %+v", p)
+			}
+		})
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	pemFile := filepath.Join(dir, "key.pem")
+	os.WriteFile(pemFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+	jwksFile := filepath.Join(dir, "jwks.json")
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa1", "n": base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()), "e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "oct", "kid": "hmac1", "k": base64.RawURLEncoding.EncodeToString(secret)},
+		{"kty": "EC", "kid": "ec1"},
+	}})
+	os.WriteFile(jwksFile, jwks, 0600)
+	shortSecret := filepath.Join(dir, "secret")
+	os.WriteFile(shortSecret, []byte("short\n"), 0600)
+
+	j := NewJWT()
+	if err := j.LoadKeyFile(pemFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.LoadJWKS(jwksFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.LoadKeyFile(shortSecret); err == nil {
+		t.Error("expected error for a short hmac secret")
+	}
+	shortJWKS := filepath.Join(dir, "short.json")
+	short, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hmac2", "k": base64.RawURLEncoding.EncodeToString([]byte("short"))},
+	}})
+	os.WriteFile(shortJWKS, short, 0600)
+	if err := j.LoadJWKS(shortJWKS); err == nil {
+		t.Error("expected error for a short jwks oct key")
+	}
+
+	claims := map[string]interface{}{"sub": "dr.rao", "exp": time.Now().Add(time.Hour).Unix(), "roles": []string{"doctor"}}
+	for _, token := range []string{
+		sign(t, map[string]interface{}{"alg": "RS256"}, claims, rsaKey),
+		sign(t, map[string]interface{}{"alg": "RS256", "kid": "rsa1"}, claims, rsaKey),
+		sign(t, map[string]interface{}{"alg": "HS256", "kid": "hmac1"}, claims, secret),
+	} {
+		if _, err := j.Verify(token); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
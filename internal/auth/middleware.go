@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrNoCredentials is returned by an Authenticator when the request carries no
+// credentials of the kind it handles, so the next one can be tried.
+var ErrNoCredentials = errors.New("no credentials")
+
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Middleware rejects requests that none of the authenticators accept with 401
+// and stores the principal of those they do in the request context.
+func Middleware(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := ErrNoCredentials
+			for _, a := range authenticators {
+				var p *Principal
+				p, err = a.Authenticate(r)
+				if err == nil {
+					next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+					return
+				}
+				if !errors.Is(err, ErrNoCredentials) {
+					break
+				}
+			}
+			unauthorized(w, err)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, err error) {
+	message := "invalid credentials"
+	if errors.Is(err, ErrNoCredentials) {
+		message = "authentication required"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `Bearer realm="ppms"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(struct {
+		Code    int    `json:"code"`
+		Status  string `json:"status"`
+		Message string `json:"Message"`
+	}{http.StatusUnauthorized, "Error", message})
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	j := NewJWT()
+	j.AddKey("", secret)
+	keys := NewAPIKeys()
+	keys.Add("k-123", "billing", "billing")
+	token := sign(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "dr.rao", "exp": time.Now().Add(time.Hour).Unix()}, secret)
+
+	var got *Principal
+	h := Middleware(j, keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+
+	tests := []struct {
+		desc    string
+		header  string
+		value   string
+		status  int
+		subject string
+	}{
+		{desc: "bearer token", header: "Authorization", value: "Bearer " + token, status: http.StatusOK, subject: "dr.rao"},
+		{desc: "api key", header: "X-API-Key", value: "k-123", status: http.StatusOK, subject: "billing"},
+		{desc: "no credentials", status: http.StatusUnauthorized},
+		{desc: "bad token", header: "Authorization", value: "Bearer " + token + "x", status: http.StatusUnauthorized},
+		{desc: "unknown api key", header: "X-API-Key", value: "nope", status: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got = nil
+			r := httptest.NewRequest(http.MethodGet, "/patient", nil)
+			if test.header != "" {
+				r.Header.Set(test.header, test.value)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != test.status {
+				t.Errorf("Expected: %v, Got: %v", test.status, w.Code)
+			}
+			if test.subject != "" && (got == nil || got.Subject != test.subject) {
+				t.Errorf("Expected: %v, Got: %+v", test.subject, got)
+			}
+			if test.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected a WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	os.WriteFile(path, []byte(`[{"key": "k-123", "subject": "billing", "roles": ["billing"]}]`), 0600)
+	keys, err := LoadAPIKeys(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/patient", nil)
+	r.Header.Set("X-API-Key", "k-123")
+	p, err := keys.Authenticate(r)
+	if err != nil || p.Subject != "billing" || !p.HasRole("billing") || p.Method != "apikey" {
+		t.Errorf("unexpected principal: %+v, %v", p, err)
+	}
+
+	os.WriteFile(path, []byte(`[{"key": "k-123"}]`), 0600)
+	if _, err := LoadAPIKeys(path); err == nil {
+		t.Error("expected error for an entry without a subject")
+	}
+}
@@ -0,0 +1,263 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// prefix marks values sealed by EncryptFor, bound to a column and row id.
+	prefix = "enc:v2:"
+	// unboundPrefix marks values sealed before they were bound to their row.
+	// They are still read, and RotateFor binds them.
+	unboundPrefix = "enc:v1:"
+	// escaped marks a plaintext value that would otherwise start like an
+	// encrypted one, so that it is never mistaken for ciphertext.
+	escaped = "enc:plain:"
+)
+
+// Keyring encrypts field values with envelope encryption: every value gets a
+// fresh AES-256-GCM data key, which is itself sealed with a master key named by
+// its key id. New values use the primary key; any key in the ring decrypts.
+//
+// A nil *Keyring leaves values in plaintext.
+type Keyring struct {
+	primary  string
+	keys     map[string][]byte
+	indexKey []byte
+}
+
+type keyringFile struct {
+	Primary  string            `json:"primary"`
+	Keys     map[string]string `json:"keys"`
+	IndexKey string            `json:"indexKey"`
+}
+
+func NewKeyring(primary string, keys map[string][]byte, indexKey []byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary key %q is not in the keyring", primary)
+	}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes", id)
+		}
+	}
+	if len(indexKey) < 32 {
+		return nil, errors.New("index key must be at least 32 bytes")
+	}
+	return &Keyring{primary: primary, keys: keys, indexKey: indexKey}, nil
+}
+
+// LoadKeyring reads a JSON file of the form
+// {"primary": "k2", "keys": {"k1": "<base64>", "k2": "<base64>"}, "indexKey": "<base64>"}.
+func LoadKeyring(path string) (*Keyring, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f keyringFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	keys := make(map[string][]byte, len(f.Keys))
+	for id, encoded := range f.Keys {
+		if keys[id], err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(f.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("index key: %w", err)
+	}
+	return NewKeyring(f.Primary, keys, indexKey)
+}
+
+// EncryptFor seals plaintext under the primary key, bound to the column and
+// row id it is stored in: DecryptFor only opens it for that same column and id,
+// so a ciphertext copied to another row or column fails authentication. Empty
+// values stay empty. Without a keyring plaintext is stored as is, escaped if
+// it starts with "enc:".
+func (k *Keyring) EncryptFor(column string, id int, plaintext string) (string, error) {
+	if plaintext == "" {
+		return plaintext, nil
+	}
+	if k == nil {
+		if strings.HasPrefix(plaintext, "enc:") {
+			return escaped + plaintext, nil
+		}
+		return plaintext, nil
+	}
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	sealed, err := seal(dataKey, []byte(plaintext), binding(column, id))
+	if err != nil {
+		return "", err
+	}
+	return k.wrap(dataKey, sealed)
+}
+
+// DecryptFor opens a value stored in column of row id. Values sealed before
+// they were bound to their row open anywhere; values without the encryption
+// prefix were written in plaintext and are returned as is, unescaped.
+func (k *Keyring) DecryptFor(column string, id int, value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, escaped):
+		return strings.TrimPrefix(value, escaped), nil
+	case strings.HasPrefix(value, prefix):
+		return k.open(value, binding(column, id))
+	case strings.HasPrefix(value, unboundPrefix):
+		return k.open(value, nil)
+	}
+	return value, nil
+}
+
+// open unwraps the data key of an encrypted value and opens the value with it.
+func (k *Keyring) open(value string, additional []byte) (string, error) {
+	if k == nil {
+		return "", errors.New("value is encrypted but no keyring is configured")
+	}
+	_, dataKey, sealed, err := k.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, sealed, additional)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether value is plaintext, not bound to its row, or
+// sealed under a key other than the primary one.
+func (k *Keyring) NeedsRotation(value string) bool {
+	if k == nil || value == "" {
+		return false
+	}
+	return !strings.HasPrefix(value, prefix+k.primary+":")
+}
+
+// RotateFor brings value, stored in column of row id, under the primary key
+// and binds it to its row. Bound values only have their data key re-wrapped;
+// anything else is decrypted and sealed again.
+func (k *Keyring) RotateFor(column string, id int, value string) (string, error) {
+	if !k.NeedsRotation(value) {
+		return value, nil
+	}
+	if !strings.HasPrefix(value, prefix) {
+		plaintext, err := k.DecryptFor(column, id, value)
+		if err != nil {
+			return "", err
+		}
+		return k.EncryptFor(column, id, plaintext)
+	}
+	_, dataKey, sealed, err := k.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+	return k.wrap(dataKey, sealed)
+}
+
+// BlindIndex returns a keyed hash of value for equality lookups on encrypted
+// columns. Without a keyring it is an unkeyed hash.
+func (k *Keyring) BlindIndex(value string) string {
+	var key []byte
+	if k != nil {
+		key = k.indexKey
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// binding is the additional data that ties a sealed value to its place.
+func binding(column string, id int) []byte {
+	return []byte(column + ":" + strconv.Itoa(id))
+}
+
+// wrap seals dataKey under the primary key and formats the encrypted value.
+func (k *Keyring) wrap(dataKey, sealed []byte) (string, error) {
+	wrapped, err := seal(k.keys[k.primary], dataKey, []byte(k.primary))
+	if err != nil {
+		return "", err
+	}
+	return prefix + k.primary + ":" + base64.RawStdEncoding.EncodeToString(wrapped) + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (k *Keyring) unwrap(value string) (string, []byte, []byte, error) {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, prefix), unboundPrefix)
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return "", nil, nil, errors.New("malformed encrypted value")
+	}
+	id := parts[0]
+	key, ok := k.keys[id]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("unknown encryption key %q", id)
+	}
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, errors.New("malformed encrypted value")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, errors.New("malformed encrypted value")
+	}
+	dataKey, err := open(key, wrapped, []byte(id))
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return id, dataKey, sealed, nil
+}
+
+// seal returns nonce || AES-GCM ciphertext.
+func seal(key, plaintext, additional []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(key, sealed, additional []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("malformed encrypted value")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additional)
+	if err != nil {
+		return nil, errors.New("encrypted value failed authentication")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,171 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKeyring(t *testing.T, primary string) *Keyring {
+	k, err := NewKeyring(primary, map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	}, bytes.Repeat([]byte{9}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// unbound seals plaintext the way values were sealed before they were bound
+// to their row.
+func unbound(t *testing.T, k *Keyring, plaintext string) string {
+	dataKey := bytes.Repeat([]byte{3}, 32)
+	sealed, err := seal(dataKey, []byte(plaintext), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := seal(k.keys[k.primary], dataKey, []byte(k.primary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return unboundPrefix + k.primary + ":" + base64.RawStdEncoding.EncodeToString(wrapped) + ":" + base64.RawStdEncoding.EncodeToString(sealed)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k := testKeyring(t, "k1")
+	enc, err := k.EncryptFor("phone", 7, "+919172681679")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(enc, "enc:v2:k1:") || strings.Contains(enc, "9172681679") {
+		t.Errorf("unexpected ciphertext: %v", enc)
+	}
+	again, _ := k.EncryptFor("phone", 7, "+919172681679")
+	if again == enc {
+		t.Error("expected a fresh ciphertext per encryption")
+	}
+	dec, err := k.DecryptFor("phone", 7, enc)
+	if err != nil || dec != "+919172681679" {
+		t.Errorf("Expected: %v, Got: %v (%v)", "+919172681679", dec, err)
+	}
+
+	if dec, err := k.DecryptFor("phone", 7, "legacy plaintext"); err != nil || dec != "legacy plaintext" {
+		t.Errorf("Expected: %v, Got: %v (%v)", "legacy plaintext", dec, err)
+	}
+	if dec, err := k.DecryptFor("phone", 7, unbound(t, k, "asthma")); err != nil || dec != "asthma" {
+		t.Errorf("Expected: %v, Got: %v (%v)", "asthma", dec, err)
+	}
+	if enc, _ := k.EncryptFor("phone", 7, ""); enc != "" {
+		t.Errorf("Expected empty value, Got: %v", enc)
+	}
+	tampered := enc[:len(enc)-2] + "AA"
+	if _, err := k.DecryptFor("phone", 7, tampered); err == nil {
+		t.Error("expected error for a tampered value")
+	}
+	if _, err := k.DecryptFor("phone", 8, enc); err == nil {
+		t.Error("expected error for a value copied to another row")
+	}
+	if _, err := k.DecryptFor("description", 7, enc); err == nil {
+		t.Error("expected error for a value copied to another column")
+	}
+	var none *Keyring
+	if _, err := none.DecryptFor("phone", 7, enc); err == nil {
+		t.Error("expected error decrypting without a keyring")
+	}
+	if plain, _ := none.EncryptFor("phone", 7, "x"); plain != "x" {
+		t.Errorf("Expected: %v, Got: %v", "x", plain)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	var none *Keyring
+	lookalike := "enc:v2:k1:not:ciphertext"
+	stored, err := none.EncryptFor("description", 7, lookalike)
+	if err != nil || stored == lookalike {
+		t.Fatalf("expected the value to be escaped, Got: %v (%v)", stored, err)
+	}
+	for _, k := range []*Keyring{none, testKeyring(t, "k1")} {
+		if dec, err := k.DecryptFor("description", 7, stored); err != nil || dec != lookalike {
+			t.Errorf("Expected: %v, Got: %v (%v)", lookalike, dec, err)
+		}
+	}
+	k := testKeyring(t, "k1")
+	rotated, err := k.RotateFor("description", 7, stored)
+	if err != nil || k.NeedsRotation(rotated) {
+		t.Fatalf("unexpected rotation: %v, %v", rotated, err)
+	}
+	if dec, err := k.DecryptFor("description", 7, rotated); err != nil || dec != lookalike {
+		t.Errorf("Expected: %v, Got: %v (%v)", lookalike, dec, err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	old := testKeyring(t, "k1")
+	enc, _ := old.EncryptFor("description", 7, "asthma")
+
+	k := testKeyring(t, "k2")
+	if !k.NeedsRotation(enc) || !k.NeedsRotation("plain") || k.NeedsRotation("") {
+		t.Error("unexpected NeedsRotation result")
+	}
+	rotated, err := k.RotateFor("description", 7, enc)
+	if err != nil || !strings.HasPrefix(rotated, "enc:v2:k2:") || k.NeedsRotation(rotated) {
+		t.Fatalf("unexpected rotation: %v, %v", rotated, err)
+	}
+	// Only the data key is re-wrapped; the sealed value is unchanged.
+	if rotated[strings.LastIndex(rotated, ":"):] != enc[strings.LastIndex(enc, ":"):] {
+		t.Error("expected the sealed value to be kept")
+	}
+	if dec, err := k.DecryptFor("description", 7, rotated); err != nil || dec != "asthma" {
+		t.Errorf("Expected: %v, Got: %v (%v)", "asthma", dec, err)
+	}
+	if encrypted, err := k.RotateFor("description", 7, "plain"); err != nil || !strings.HasPrefix(encrypted, "enc:v2:k2:") {
+		t.Errorf("unexpected rotation: %v, %v", encrypted, err)
+	}
+	// Values sealed before they were bound to their row get bound.
+	legacy := unbound(t, k, "asthma")
+	if !k.NeedsRotation(legacy) {
+		t.Error("expected an unbound value to need rotation")
+	}
+	bound, err := k.RotateFor("description", 7, legacy)
+	if err != nil || !strings.HasPrefix(bound, "enc:v2:k2:") {
+		t.Fatalf("unexpected rotation: %v, %v", bound, err)
+	}
+	if dec, err := k.DecryptFor("description", 7, bound); err != nil || dec != "asthma" {
+		t.Errorf("Expected: %v, Got: %v (%v)", "asthma", dec, err)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	k := testKeyring(t, "k1")
+	if k.BlindIndex("+919172681679") != testKeyring(t, "k2").BlindIndex("+919172681679") {
+		t.Error("expected the blind index not to depend on the primary key")
+	}
+	if k.BlindIndex("+919172681679") == k.BlindIndex("+919172681670") {
+		t.Error("expected different values to index differently")
+	}
+	var none *Keyring
+	if none.BlindIndex("x") == k.BlindIndex("x") {
+		t.Error("expected the keyed index to differ from the unkeyed one")
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	os.WriteFile(path, []byte(`{"primary": "k1", "keys": {"k1": "`+key+`"}, "indexKey": "`+key+`"}`), 0600)
+	if _, err := LoadKeyring(path); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	os.WriteFile(path, []byte(`{"primary": "k2", "keys": {"k1": "`+key+`"}, "indexKey": "`+key+`"}`), 0600)
+	if _, err := LoadKeyring(path); err == nil {
+		t.Error("expected error for a missing primary key")
+	}
+	os.WriteFile(path, []byte(`{"primary": "k1", "keys": {"k1": "c2hvcnQ="}, "indexKey": "`+key+`"}`), 0600)
+	if _, err := LoadKeyring(path); err == nil {
+		t.Error("expected error for a short key")
+	}
+}
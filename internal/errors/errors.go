@@ -0,0 +1,97 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+)
+
+type NotFound struct {
+	Entity string
+	ID     string
+}
+
+func (e *NotFound) Error() string {
+	if e.ID == "" {
+		return e.Entity + " not found"
+	}
+	return fmt.Sprintf("%s %s not found", e.Entity, e.ID)
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Validation struct {
+	Fields []FieldError
+}
+
+func NewValidation(field, message string) *Validation {
+	return &Validation{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+func (e *Validation) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+func (e *Validation) Error() string {
+	names := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		names[i] = f.Field
+	}
+	return "invalid " + strings.Join(names, ", ")
+}
+
+type Conflict struct {
+	Entity string
+	Reason string
+}
+
+func (e *Conflict) Error() string {
+	return fmt.Sprintf("%s conflict: %s", e.Entity, e.Reason)
+}
+
+type PreconditionFailed struct {
+	Entity string
+	ID     string
+}
+
+func (e *PreconditionFailed) Error() string {
+	return fmt.Sprintf("%s %s was modified by another request", e.Entity, e.ID)
+}
+
+// Forbidden reports that the caller may not perform Action, or may not touch
+// Fields when they are given.
+type Forbidden struct {
+	Action string
+	Fields []string
+}
+
+func (e *Forbidden) Error() string {
+	if len(e.Fields) > 0 {
+		return fmt.Sprintf("not allowed to %s %s", e.Action, strings.Join(e.Fields, ", "))
+	}
+	return fmt.Sprintf("not allowed to %s", e.Action)
+}
+
+// Aborted marks an item of an all-or-nothing bulk write that was not written
+// because another item failed.
+type Aborted struct{}
+
+func (e *Aborted) Error() string {
+	return "not written: another item of the batch failed"
+}
+
+// Internal wraps an unexpected failure, typically from the database; its
+// message is never shown to API clients.
+type Internal struct {
+	Err error
+}
+
+func (e *Internal) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Internal) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,43 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestErrorMessages(t *testing.T) {
+	validation := NewValidation("name", "is required")
+	validation.Add("phone", "is not a valid phone number")
+
+	tests := []struct {
+		desc     string
+		err      error
+		expected string
+	}{
+		{desc: "not found", err: &NotFound{Entity: "patient", ID: "5"}, expected: "patient 5 not found"},
+		{desc: "not found without id", err: &NotFound{Entity: "patient"}, expected: "patient not found"},
+		{desc: "validation", err: validation, expected: "invalid name, phone"},
+		{desc: "conflict", err: &Conflict{Entity: "patient", Reason: "duplicate phone"}, expected: "patient conflict: duplicate phone"},
+		{desc: "precondition failed", err: &PreconditionFailed{Entity: "patient", ID: "5"}, expected: "patient 5 was modified by another request"},
+		{desc: "forbidden", err: &Forbidden{Action: "delete patients"}, expected: "not allowed to delete patients"},
+		{desc: "forbidden fields", err: &Forbidden{Action: "write", Fields: []string{"description"}}, expected: "not allowed to write description"},
+		{desc: "aborted", err: &Aborted{}, expected: "not written: another item of the batch failed"},
+		{desc: "internal", err: &Internal{Err: errors.New("connection refused")}, expected: "connection refused"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if test.err.Error() != test.expected {
+				t.Errorf("Expected: %v, Got: %v", test.expected, test.err.Error())
+			}
+		})
+	}
+}
+
+func TestInternalUnwrap(t *testing.T) {
+	var err error = &Internal{Err: context.DeadlineExceeded}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected Internal to unwrap to context.DeadlineExceeded")
+	}
+}
@@ -0,0 +1,237 @@
+package fhir
+
+import (
+	"bytes"
+	"encoding/json"
+	patientHTTP "github.com/aakanksha/ppms/internal/http/patient"
+	"github.com/aakanksha/ppms/internal/logging"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the media type of FHIR JSON resources.
+const ContentType = "application/fhir+json"
+
+const maxResourceBytes = 1 << 20
+
+// Handler serves the FHIR R4 Patient interactions read, search-type, create
+// and update on top of the patient service.
+type Handler struct {
+	svc service.ServiceInterface
+}
+
+func New(svc service.ServiceInterface) *Handler {
+	return &Handler{svc: svc}
+}
+
+func write(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	// URLs in bundle links keep their & unescaped.
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(v)
+	body := bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+	if err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "error", err)
+		status, body = http.StatusInternalServerError, []byte(`{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"exception"}]}`)
+	}
+	w.Header().Set("Content-Type", ContentType+"; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := w.Write(body); err != nil {
+		logging.FromContext(r.Context()).Warn("writing response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, oo := outcome(err)
+	if status >= http.StatusInternalServerError {
+		logging.FromContext(r.Context()).Error("request failed", "error", err)
+	}
+	write(w, r, status, oo)
+}
+
+// writePatient sends pt with the version and modification headers FHIR
+// clients use for conditional requests.
+func writePatient(w http.ResponseWriter, r *http.Request, status int, pt *models.Patient) {
+	w.Header().Set("ETag", patientHTTP.ETag(pt.Version))
+	w.Header().Set("Last-Modified", pt.UpdatedAt.UTC().Format(http.TimeFormat))
+	write(w, r, status, ToResource(pt))
+}
+
+// baseURL is the absolute URL of the FHIR endpoint the request was sent to.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/fhir"
+}
+
+func (h *Handler) Read(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	pt, err := h.svc.GetByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writePatient(w, r, http.StatusOK, pt)
+}
+
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Total        int           `json:"total"`
+	Link         []BundleLink  `json:"link,omitempty"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+type BundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+type BundleEntry struct {
+	FullURL  string       `json:"fullUrl"`
+	Resource *Patient     `json:"resource"`
+	Search   *EntrySearch `json:"search,omitempty"`
+}
+
+type EntrySearch struct {
+	Mode string `json:"mode"`
+}
+
+// Search supports the name, phone, telecom and active parameters, paged by
+// _count and the _cursor of the next link. Other parameters are ignored, as
+// FHIR servers do by default.
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts := models.ListOptions{
+		Name:   strings.TrimSpace(q.Get("name")),
+		Phone:  q.Get("phone"),
+		Cursor: q.Get("_cursor"),
+	}
+	if v := q.Get("telecom"); v != "" {
+		opts.Phone = strings.TrimPrefix(v, "phone|")
+	}
+	if v := q.Get("_count"); v != "" {
+		count, err := strconv.Atoi(v)
+		if err != nil {
+			write(w, r, http.StatusBadRequest, invalid("_count must be an integer"))
+			return
+		}
+		opts.Limit = count
+	}
+	if v := q.Get("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			write(w, r, http.StatusBadRequest, invalid("active must be true or false"))
+			return
+		}
+		discharge := !active
+		opts.Discharge = &discharge
+	}
+	page, err := h.svc.List(r.Context(), opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	base := baseURL(r)
+	bundle := Bundle{ResourceType: "Bundle", Type: "searchset", Total: page.Total}
+	bundle.Link = append(bundle.Link, BundleLink{Relation: "self", URL: base + "/Patient?" + q.Encode()})
+	if page.NextCursor != "" {
+		next := url.Values{}
+		for k, v := range q {
+			next[k] = v
+		}
+		next.Set("_cursor", page.NextCursor)
+		bundle.Link = append(bundle.Link, BundleLink{Relation: "next", URL: base + "/Patient?" + next.Encode()})
+	}
+	for _, pt := range page.Patients {
+		bundle.Entry = append(bundle.Entry, BundleEntry{
+			FullURL:  base + "/Patient/" + strconv.Itoa(pt.Id),
+			Resource: ToResource(pt),
+			Search:   &EntrySearch{Mode: "match"},
+		})
+	}
+	write(w, r, http.StatusOK, bundle)
+}
+
+// decode reads a Patient resource from the request body, answering 400 with an
+// OperationOutcome when it is not one.
+func decode(w http.ResponseWriter, r *http.Request) (*Patient, bool) {
+	var res Patient
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxResourceBytes)).Decode(&res); err != nil {
+		write(w, r, http.StatusBadRequest, invalid(err.Error()))
+		return nil, false
+	}
+	if res.ResourceType != "Patient" {
+		write(w, r, http.StatusBadRequest, invalid(`resourceType must be "Patient"`))
+		return nil, false
+	}
+	return &res, true
+}
+
+// Create ignores any id in the resource; the server assigns one. A new
+// patient starts inactive, so only an explicit active of true is refused.
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	res, ok := decode(w, r)
+	if !ok {
+		return
+	}
+	in := FromResource(res)
+	if res.Active == nil {
+		in.Discharge = true
+	}
+	pt, err := h.svc.Insert(r.Context(), in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", baseURL(r)+"/Patient/"+strconv.Itoa(pt.Id)+"/_history/"+strconv.Itoa(pt.Version))
+	writePatient(w, r, http.StatusCreated, pt)
+}
+
+// Update replaces the patient; the resource id must match the URL. An If-Match
+// header with the version makes the update conditional, as on /patient. active
+// is derived from encounters: a resource without it leaves it alone, and one
+// that changes it is refused.
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	res, ok := decode(w, r)
+	if !ok {
+		return
+	}
+	if res.ID != strconv.Itoa(id) {
+		write(w, r, http.StatusBadRequest, invalid("resource id must match the id in the URL"))
+		return
+	}
+	version, err := patientHTTP.IfMatch(r, "patient", id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	pt := FromResource(res)
+	if version > 0 {
+		pt.Version = version
+	}
+	if res.Active == nil {
+		current, err := h.svc.GetByID(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		pt.Discharge = current.Discharge
+	}
+	updated, err := h.svc.Update(r.Context(), pt, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writePatient(w, r, http.StatusOK, updated)
+}
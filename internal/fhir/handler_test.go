@@ -0,0 +1,216 @@
+package fhir
+
+import (
+	"errors"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updated = time.Date(2022, 2, 22, 13, 23, 22, 0, time.UTC)
+
+func stored() *models.Patient {
+	return &models.Patient{Id: 5, Name: "Ram", Phone: "+919172681679", UpdatedAt: updated, BloodGroup: "A+", Version: 3}
+}
+
+const storedJSON = `{"resourceType":"Patient","id":"5","meta":{"versionId":"3","lastUpdated":"2022-02-22T13:23:22Z"},` +
+	`"extension":[{"url":"` + BloodGroupURL + `","valueCode":"A+"}],"active":true,"name":[{"use":"official","text":"Ram"}],` +
+	`"telecom":[{"system":"phone","value":"+919172681679"}]}`
+
+func TestHandler(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockService := service.NewMockServiceInterface(mockCtrl)
+	h := New(mockService)
+	router := mux.NewRouter()
+	router.HandleFunc("/fhir/Patient", h.Search).Methods(http.MethodGet)
+	router.HandleFunc("/fhir/Patient", h.Create).Methods(http.MethodPost)
+	router.HandleFunc("/fhir/Patient/{id:[0-9]+}", h.Read).Methods(http.MethodGet)
+	router.HandleFunc("/fhir/Patient/{id:[0-9]+}", h.Update).Methods(http.MethodPut)
+	discharged := false
+
+	testCases := []struct {
+		desc     string
+		method   string
+		target   string
+		body     string
+		ifMatch  string
+		setup    func()
+		status   int
+		headers  map[string]string
+		expected string
+	}{
+		{
+			desc:     "read",
+			method:   http.MethodGet,
+			target:   "/fhir/Patient/5",
+			setup:    func() { mockService.EXPECT().GetByID(gomock.Any(), 5).Return(stored(), nil) },
+			status:   http.StatusOK,
+			headers:  map[string]string{"ETag": `"3"`, "Content-Type": "application/fhir+json; charset=utf-8"},
+			expected: storedJSON,
+		},
+		{
+			desc:   "read missing",
+			method: http.MethodGet,
+			target: "/fhir/Patient/9",
+			setup: func() {
+				mockService.EXPECT().GetByID(gomock.Any(), 9).Return(nil, &perrors.NotFound{Entity: "patient", ID: "9"})
+			},
+			status:   http.StatusNotFound,
+			expected: `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"not-found","diagnostics":"patient 9 not found"}]}`,
+		},
+		{
+			desc:   "search",
+			method: http.MethodGet,
+			target: "/fhir/Patient?name=ram&telecom=phone|%2B919172681679&active=true&_count=1",
+			setup: func() {
+				mockService.EXPECT().List(gomock.Any(), models.ListOptions{Name: "ram", Phone: "+919172681679", Discharge: &discharged, Limit: 1}).
+					Return(&models.PatientPage{Patients: []*models.Patient{stored()}, Total: 2, NextCursor: "abc"}, nil)
+			},
+			status: http.StatusOK,
+			expected: `{"resourceType":"Bundle","type":"searchset","total":2,"link":[` +
+				`{"relation":"self","url":"http://example.com/fhir/Patient?_count=1&active=true&name=ram&telecom=phone%7C%2B919172681679"},` +
+				`{"relation":"next","url":"http://example.com/fhir/Patient?_count=1&_cursor=abc&active=true&name=ram&telecom=phone%7C%2B919172681679"}],` +
+				`"entry":[{"fullUrl":"http://example.com/fhir/Patient/5","resource":` + storedJSON + `,"search":{"mode":"match"}}]}`,
+		},
+		{
+			desc:     "search with a bad count",
+			method:   http.MethodGet,
+			target:   "/fhir/Patient?_count=many",
+			setup:    func() {},
+			status:   http.StatusBadRequest,
+			expected: `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"structure","diagnostics":"_count must be an integer"}]}`,
+		},
+		{
+			desc:   "create",
+			method: http.MethodPost,
+			target: "/fhir/Patient",
+			body:   `{"resourceType":"Patient","id":"77","name":[{"text":"Ram"}],"telecom":[{"system":"phone","value":"9172681679"}]}`,
+			setup: func() {
+				mockService.EXPECT().Insert(gomock.Any(), &models.Patient{Name: "Ram", Phone: "9172681679", Discharge: true}).Return(stored(), nil)
+			},
+			status:   http.StatusCreated,
+			headers:  map[string]string{"Location": "http://example.com/fhir/Patient/5/_history/3"},
+			expected: storedJSON,
+		},
+		{
+			desc:   "create active",
+			method: http.MethodPost,
+			target: "/fhir/Patient",
+			body:   `{"resourceType":"Patient","active":true,"name":[{"text":"Ram"}]}`,
+			setup: func() {
+				mockService.EXPECT().Insert(gomock.Any(), &models.Patient{Name: "Ram"}).
+					Return(nil, perrors.NewValidation("discharge", "is derived from encounters; admit or discharge the patient instead"))
+			},
+			status: http.StatusUnprocessableEntity,
+			expected: `{"resourceType":"OperationOutcome","issue":[` +
+				`{"severity":"error","code":"value","diagnostics":"discharge is derived from encounters; admit or discharge the patient instead","expression":["Patient.active"]}]}`,
+		},
+		{
+			desc:     "create another resource type",
+			method:   http.MethodPost,
+			target:   "/fhir/Patient",
+			body:     `{"resourceType":"Observation"}`,
+			setup:    func() {},
+			status:   http.StatusBadRequest,
+			expected: `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"structure","diagnostics":"resourceType must be \"Patient\""}]}`,
+		},
+		{
+			desc:   "create invalid",
+			method: http.MethodPost,
+			target: "/fhir/Patient",
+			body:   `{"resourceType":"Patient","extension":[{"url":"` + BloodGroupURL + `","valueCode":"Z"}]}`,
+			setup: func() {
+				verr := perrors.NewValidation("name", "must not be empty")
+				verr.Add("bloodGroup", "must be one of A+, A-, B+, B-, AB+, AB-, O+, O-")
+				mockService.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil, verr)
+			},
+			status: http.StatusUnprocessableEntity,
+			expected: `{"resourceType":"OperationOutcome","issue":[` +
+				`{"severity":"error","code":"value","diagnostics":"name must not be empty","expression":["Patient.name"]},` +
+				`{"severity":"error","code":"value","diagnostics":"bloodGroup must be one of A+, A-, B+, B-, AB+, AB-, O+, O-","expression":["Patient.extension('` + BloodGroupURL + `')"]}]}`,
+		},
+		{
+			desc:    "conditional update",
+			method:  http.MethodPut,
+			target:  "/fhir/Patient/5",
+			body:    `{"resourceType":"Patient","id":"5","active":false,"name":[{"text":"Ram"}]}`,
+			ifMatch: `"3"`,
+			setup: func() {
+				mockService.EXPECT().Update(gomock.Any(), &models.Patient{Name: "Ram", Discharge: true, Version: 3}, 5).Return(stored(), nil)
+			},
+			status:   http.StatusOK,
+			expected: storedJSON,
+		},
+		{
+			desc:    "stale update",
+			method:  http.MethodPut,
+			target:  "/fhir/Patient/5",
+			body:    `{"resourceType":"Patient","id":"5","name":[{"text":"Ram"}]}`,
+			ifMatch: `"2"`,
+			setup: func() {
+				mockService.EXPECT().GetByID(gomock.Any(), 5).Return(stored(), nil)
+				mockService.EXPECT().Update(gomock.Any(), &models.Patient{Name: "Ram", Version: 2}, 5).Return(nil, &perrors.PreconditionFailed{Entity: "patient", ID: "5"})
+			},
+			status:   http.StatusPreconditionFailed,
+			expected: `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"conflict","diagnostics":"patient 5 was modified by another request"}]}`,
+		},
+		{
+			desc:     "malformed If-Match",
+			method:   http.MethodPut,
+			target:   "/fhir/Patient/5",
+			body:     `{"resourceType":"Patient","id":"5","name":[{"text":"Ram"}]}`,
+			ifMatch:  `W/"3"`,
+			setup:    func() {},
+			status:   http.StatusPreconditionFailed,
+			expected: `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"conflict","diagnostics":"patient 5 was modified by another request"}]}`,
+		},
+		{
+			desc:     "update with another id",
+			method:   http.MethodPut,
+			target:   "/fhir/Patient/5",
+			body:     `{"resourceType":"Patient","name":[{"text":"Ram"}]}`,
+			setup:    func() {},
+			status:   http.StatusBadRequest,
+			expected: `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"structure","diagnostics":"resource id must match the id in the URL"}]}`,
+		},
+		{
+			desc:     "server error is not exposed",
+			method:   http.MethodGet,
+			target:   "/fhir/Patient/5",
+			setup:    func() { mockService.EXPECT().GetByID(gomock.Any(), 5).Return(nil, errors.New("connection refused")) },
+			status:   http.StatusInternalServerError,
+			expected: `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"exception","diagnostics":"internal server error"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tc.setup()
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.ifMatch != "" {
+				r.Header.Set("If-Match", tc.ifMatch)
+			}
+			router.ServeHTTP(w, r)
+			if w.Code != tc.status {
+				t.Errorf("Expected: %v, Got: %v", tc.status, w.Code)
+			}
+			for k, v := range tc.headers {
+				if got := w.Header().Get(k); got != v {
+					t.Errorf("%s: Expected: %v, Got: %v", k, v, got)
+				}
+			}
+			if got := w.Body.String(); got != tc.expected {
+				t.Errorf("Expected: %v, Got: %v", tc.expected, got)
+			}
+		})
+	}
+}
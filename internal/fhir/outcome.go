@@ -0,0 +1,79 @@
+package fhir
+
+import (
+	"context"
+	"errors"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"net/http"
+)
+
+// OperationOutcome is the FHIR resource errors are reported with.
+type OperationOutcome struct {
+	ResourceType string  `json:"resourceType"`
+	Issue        []Issue `json:"issue"`
+}
+
+type Issue struct {
+	Severity    string   `json:"severity"`
+	Code        string   `json:"code"`
+	Diagnostics string   `json:"diagnostics,omitempty"`
+	Expression  []string `json:"expression,omitempty"`
+}
+
+// expressions locates the fields of validation errors in the resource, or in
+// the search parameters.
+var expressions = map[string]string{
+	"name":        "Patient.name",
+	"phone":       "Patient.telecom",
+	"discharge":   "Patient.active",
+	"bloodGroup":  "Patient.extension('" + BloodGroupURL + "')",
+	"description": "Patient.extension('" + DescriptionURL + "')",
+	"id":          "Patient.id",
+	"limit":       "_count",
+}
+
+// outcome maps err to the status and OperationOutcome clients see, like the
+// patient API does for its own error body.
+func outcome(err error) (int, *OperationOutcome) {
+	var (
+		notFound     *perrors.NotFound
+		validation   *perrors.Validation
+		conflict     *perrors.Conflict
+		precondition *perrors.PreconditionFailed
+		forbidden    *perrors.Forbidden
+	)
+	issue := func(code, diagnostics string) []Issue {
+		return []Issue{{Severity: "error", Code: code, Diagnostics: diagnostics}}
+	}
+	oo := &OperationOutcome{ResourceType: "OperationOutcome"}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		status, oo.Issue = http.StatusGatewayTimeout, issue("timeout", "request timed out")
+	case errors.As(err, &notFound):
+		status, oo.Issue = http.StatusNotFound, issue("not-found", err.Error())
+	case errors.As(err, &validation):
+		status = http.StatusUnprocessableEntity
+		for _, f := range validation.Fields {
+			i := Issue{Severity: "error", Code: "value", Diagnostics: f.Field + " " + f.Message}
+			if expr, ok := expressions[f.Field]; ok {
+				i.Expression = []string{expr}
+			}
+			oo.Issue = append(oo.Issue, i)
+		}
+	case errors.As(err, &conflict):
+		status, oo.Issue = http.StatusConflict, issue("duplicate", err.Error())
+	case errors.As(err, &precondition):
+		status, oo.Issue = http.StatusPreconditionFailed, issue("conflict", err.Error())
+	case errors.As(err, &forbidden):
+		status, oo.Issue = http.StatusForbidden, issue("forbidden", err.Error())
+	default:
+		oo.Issue = issue("exception", "internal server error")
+	}
+	return status, oo
+}
+
+// invalid reports a request the server cannot parse.
+func invalid(diagnostics string) *OperationOutcome {
+	return &OperationOutcome{ResourceType: "OperationOutcome", Issue: []Issue{{Severity: "error", Code: "structure", Diagnostics: diagnostics}}}
+}
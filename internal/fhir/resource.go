@@ -0,0 +1,120 @@
+package fhir
+
+import (
+	"github.com/aakanksha/ppms/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Extension URLs for the patient fields FHIR R4 Patient has no element for.
+const (
+	BloodGroupURL  = "https://github.com/aakanksha/ppms/fhir/StructureDefinition/blood-group"
+	DescriptionURL = "https://github.com/aakanksha/ppms/fhir/StructureDefinition/description"
+)
+
+// Patient is the subset of the FHIR R4 Patient resource the registry maps.
+type Patient struct {
+	ResourceType string         `json:"resourceType"`
+	ID           string         `json:"id,omitempty"`
+	Meta         *Meta          `json:"meta,omitempty"`
+	Extension    []Extension    `json:"extension,omitempty"`
+	Active       *bool          `json:"active,omitempty"`
+	Name         []HumanName    `json:"name,omitempty"`
+	Telecom      []ContactPoint `json:"telecom,omitempty"`
+}
+
+type Meta struct {
+	VersionID   string `json:"versionId,omitempty"`
+	LastUpdated string `json:"lastUpdated,omitempty"`
+}
+
+type Extension struct {
+	URL         string `json:"url"`
+	ValueCode   string `json:"valueCode,omitempty"`
+	ValueString string `json:"valueString,omitempty"`
+}
+
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+type ContactPoint struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Use    string `json:"use,omitempty"`
+}
+
+// ToResource maps a patient to its FHIR representation. A discharged patient
+// is not active.
+func ToResource(pt *models.Patient) *Patient {
+	active := !pt.Discharge
+	r := &Patient{
+		ResourceType: "Patient",
+		ID:           strconv.Itoa(pt.Id),
+		Meta: &Meta{
+			VersionID:   strconv.Itoa(pt.Version),
+			LastUpdated: pt.UpdatedAt.UTC().Format(time.RFC3339),
+		},
+		Active: &active,
+	}
+	if pt.Name != "" {
+		r.Name = []HumanName{{Use: "official", Text: pt.Name}}
+	}
+	if pt.Phone != "" {
+		r.Telecom = []ContactPoint{{System: "phone", Value: pt.Phone}}
+	}
+	if pt.BloodGroup != "" {
+		r.Extension = append(r.Extension, Extension{URL: BloodGroupURL, ValueCode: pt.BloodGroup})
+	}
+	if pt.Description != "" {
+		r.Extension = append(r.Extension, Extension{URL: DescriptionURL, ValueString: pt.Description})
+	}
+	return r
+}
+
+// FromResource maps a FHIR Patient to a patient. It takes the official name,
+// or else the first one, preferring its text over its parts, and the first
+// phone number that is not marked old. A resource without active is active.
+// Other elements are ignored, and validation is left to the service.
+func FromResource(r *Patient) *models.Patient {
+	pt := &models.Patient{
+		Name:      nameOf(r.Name),
+		Discharge: r.Active != nil && !*r.Active,
+	}
+	for _, t := range r.Telecom {
+		if t.System == "phone" && t.Use != "old" {
+			pt.Phone = t.Value
+			break
+		}
+	}
+	for _, e := range r.Extension {
+		switch e.URL {
+		case BloodGroupURL:
+			pt.BloodGroup = e.ValueCode
+		case DescriptionURL:
+			pt.Description = e.ValueString
+		}
+	}
+	return pt
+}
+
+func nameOf(names []HumanName) string {
+	if len(names) == 0 {
+		return ""
+	}
+	name := names[0]
+	for _, n := range names {
+		if n.Use == "official" {
+			name = n
+			break
+		}
+	}
+	if name.Text != "" {
+		return name.Text
+	}
+	return strings.Join(append(append([]string{}, name.Given...), name.Family), " ")
+}
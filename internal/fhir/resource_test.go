@@ -0,0 +1,63 @@
+package fhir
+
+import (
+	"encoding/json"
+	"github.com/aakanksha/ppms/internal/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestToResource(t *testing.T) {
+	updated := time.Date(2022, 2, 22, 13, 23, 22, 0, time.UTC)
+	pt := &models.Patient{Id: 5, Name: "Ram Kumar", Phone: "+919172681679", Discharge: true, UpdatedAt: updated, BloodGroup: "O-", Description: "asthma", Version: 3}
+
+	got, err := json.Marshal(ToResource(pt))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"resourceType":"Patient","id":"5","meta":{"versionId":"3","lastUpdated":"2022-02-22T13:23:22Z"},` +
+		`"extension":[{"url":"` + BloodGroupURL + `","valueCode":"O-"},{"url":"` + DescriptionURL + `","valueString":"asthma"}],` +
+		`"active":false,"name":[{"use":"official","text":"Ram Kumar"}],"telecom":[{"system":"phone","value":"+919172681679"}]}`
+	if string(got) != expected {
+		t.Errorf("Expected: %v, Got: %v", expected, string(got))
+	}
+}
+
+func TestFromResource(t *testing.T) {
+	active, inactive := true, false
+	tests := []struct {
+		desc     string
+		input    *Patient
+		expected *models.Patient
+	}{
+		{
+			desc: "official name text and current phone",
+			input: &Patient{
+				Active:    &inactive,
+				Name:      []HumanName{{Use: "nickname", Text: "Ramu"}, {Use: "official", Text: "Ram Kumar"}},
+				Telecom:   []ContactPoint{{System: "email", Value: "ram@example.com"}, {System: "phone", Value: "9000000000", Use: "old"}, {System: "phone", Value: "9172681679"}},
+				Extension: []Extension{{URL: BloodGroupURL, ValueCode: "a pos"}, {URL: "http://example.com/other", ValueString: "x"}},
+			},
+			expected: &models.Patient{Name: "Ram Kumar", Phone: "9172681679", Discharge: true, BloodGroup: "a pos"},
+		},
+		{
+			desc:     "name from its parts",
+			input:    &Patient{Active: &active, Name: []HumanName{{Given: []string{"Ram", "Prasad"}, Family: "Kumar"}}},
+			expected: &models.Patient{Name: "Ram Prasad Kumar"},
+		},
+		{
+			desc:     "active by default",
+			input:    &Patient{Extension: []Extension{{URL: DescriptionURL, ValueString: "fever"}}},
+			expected: &models.Patient{Description: "fever"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got := FromResource(test.input)
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected: %+v, Got: %+v", test.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aakanksha/ppms/internal/logging"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports whether a dependency is usable.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker serves the liveness and readiness probes. The process is live as
+// long as it answers; it is ready when every check passes and it is not
+// shutting down.
+type Checker struct {
+	checks   []namedCheck
+	timeout  time.Duration
+	draining int32
+}
+
+type status struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// New returns a Checker that gives all checks of a probe timeout to complete.
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a readiness check. Checks run concurrently on every probe.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Drain makes the readiness probe fail from now on, so the orchestrator stops
+// routing new requests while in-flight ones complete.
+func (c *Checker) Drain() {
+	atomic.StoreInt32(&c.draining, 1)
+}
+
+func (c *Checker) Live(w http.ResponseWriter, r *http.Request) {
+	write(w, http.StatusOK, status{Status: "ok"})
+}
+
+// Ready answers 503 while draining or when a check fails. The causes of
+// failed checks are logged, not returned, since the probe is unauthenticated.
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&c.draining) == 1 {
+		write(w, http.StatusServiceUnavailable, status{Status: "draining"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+	defer cancel()
+
+	results := make([]error, len(c.checks))
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = check(ctx)
+		}(i, nc.check)
+	}
+	wg.Wait()
+
+	code, body := http.StatusOK, status{Status: "ok", Checks: map[string]string{}}
+	for i, nc := range c.checks {
+		if results[i] != nil {
+			logging.FromContext(r.Context()).Warn("readiness check failed", "check", nc.name, "error", results[i])
+			code, body.Status = http.StatusServiceUnavailable, "unavailable"
+			body.Checks[nc.name] = "failed"
+			continue
+		}
+		body.Checks[nc.name] = "ok"
+	}
+	write(w, code, body)
+}
+
+func write(w http.ResponseWriter, code int, body status) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func ok(ctx context.Context) error { return nil }
+
+func TestReady(t *testing.T) {
+	tests := []struct {
+		desc     string
+		checks   map[string]Check
+		drain    bool
+		status   int
+		expected status
+	}{
+		{
+			desc:     "all checks pass",
+			checks:   map[string]Check{"mysql": ok, "migrations": ok},
+			status:   http.StatusOK,
+			expected: status{Status: "ok", Checks: map[string]string{"mysql": "ok", "migrations": "ok"}},
+		},
+		{
+			desc: "failing check",
+			checks: map[string]Check{"mysql": ok, "migrations": func(ctx context.Context) error {
+				return errors.New("schema at version 4, expected 6")
+			}},
+			status:   http.StatusServiceUnavailable,
+			expected: status{Status: "unavailable", Checks: map[string]string{"mysql": "ok", "migrations": "failed"}},
+		},
+		{
+			desc: "check exceeding the timeout",
+			checks: map[string]Check{"mysql": func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}},
+			status:   http.StatusServiceUnavailable,
+			expected: status{Status: "unavailable", Checks: map[string]string{"mysql": "failed"}},
+		},
+		{
+			desc:     "draining",
+			checks:   map[string]Check{"mysql": ok},
+			drain:    true,
+			status:   http.StatusServiceUnavailable,
+			expected: status{Status: "draining"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			c := New(10 * time.Millisecond)
+			for name, check := range test.checks {
+				c.Add(name, check)
+			}
+			if test.drain {
+				c.Drain()
+			}
+			w := httptest.NewRecorder()
+			c.Ready(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			var body status
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if w.Code != test.status || !reflect.DeepEqual(body, test.expected) {
+				t.Errorf("Expected: %d %+v, Got: %d %+v", test.status, test.expected, w.Code, body)
+			}
+		})
+	}
+}
+
+func TestLive(t *testing.T) {
+	c := New(time.Second)
+	c.Add("mysql", func(ctx context.Context) error { return errors.New("down") })
+	c.Drain()
+	w := httptest.NewRecorder()
+	c.Live(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected: %v, Got: %v", http.StatusOK, w.Code)
+	}
+}
@@ -0,0 +1,164 @@
+package appointment
+
+import (
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	patientHTTP "github.com/aakanksha/ppms/internal/http/patient"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type https struct {
+	svc service.AppointmentInterface
+}
+
+func New(svc service.AppointmentInterface) *https {
+	return &https{svc}
+}
+
+// appointmentBody is what clients send: a reschedule only reads start and
+// end, and the id and version come from the URL and the If-Match header.
+type appointmentBody struct {
+	PatientID int       `json:"patientId"`
+	StaffID   int       `json:"staffId"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+}
+
+func id(r *http.Request) int {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	return id
+}
+
+// timeRange reads the from and to query parameters as RFC 3339 times; absent
+// ones are zero.
+func timeRange(q url.Values) (time.Time, time.Time, error) {
+	verr := &perrors.Validation{}
+	var times [2]time.Time
+	for i, name := range []string{"from", "to"} {
+		if value := q.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				verr.Add(name, "must be an RFC 3339 time")
+			}
+			times[i] = t
+		}
+	}
+	if len(verr.Fields) > 0 {
+		return time.Time{}, time.Time{}, verr
+	}
+	return times[0], times[1], nil
+}
+
+func write(w http.ResponseWriter, r *http.Request, data interface{}, err error, status int) {
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	if a, ok := data.(*models.Appointment); ok {
+		w.Header().Set("ETag", patientHTTP.ETag(a.Version))
+	}
+	response := patientHTTP.ResponseStruct{
+		Code:   status,
+		Status: "Success",
+		Data:   data,
+	}
+	patientHTTP.Writer(w, r, response, status)
+}
+
+// Book books a patient with a staff member.
+func (h *https) Book(w http.ResponseWriter, r *http.Request) {
+	var body appointmentBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	a, err := h.svc.Book(r.Context(), &models.Appointment{PatientID: body.PatientID, StaffID: body.StaffID, Start: body.Start, End: body.End})
+	if err == nil {
+		w.Header().Set("Location", "/appointments/"+strconv.Itoa(a.ID))
+	}
+	write(w, r, a, err, http.StatusCreated)
+}
+
+func (h *https) Get(w http.ResponseWriter, r *http.Request) {
+	a, err := h.svc.Get(r.Context(), id(r))
+	if err == nil && r.Header.Get("If-None-Match") == patientHTTP.ETag(a.Version) {
+		w.Header().Set("ETag", patientHTTP.ETag(a.Version))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	write(w, r, a, err, http.StatusOK)
+}
+
+// Reschedule moves an appointment to the start and end of the body,
+// conditionally on If-Match when given.
+func (h *https) Reschedule(w http.ResponseWriter, r *http.Request) {
+	var body appointmentBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	version, err := patientHTTP.IfMatch(r, "appointment", id(r))
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	a, err := h.svc.Reschedule(r.Context(), &models.Appointment{ID: id(r), Start: body.Start, End: body.End, Version: version})
+	write(w, r, a, err, http.StatusOK)
+}
+
+// Cancel frees the time of an appointment, conditionally on If-Match when
+// given.
+func (h *https) Cancel(w http.ResponseWriter, r *http.Request) {
+	version, err := patientHTTP.IfMatch(r, "appointment", id(r))
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	a, err := h.svc.Cancel(r.Context(), id(r), version)
+	write(w, r, a, err, http.StatusOK)
+}
+
+// ForPatient returns the appointments of a patient between the optional from
+// and to query parameters.
+func (h *https) ForPatient(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, models.AppointmentFilter{PatientID: id(r)})
+}
+
+// ForStaff returns the appointments of a staff member between the optional
+// from and to query parameters.
+func (h *https) ForStaff(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, models.AppointmentFilter{StaffID: id(r)})
+}
+
+func (h *https) list(w http.ResponseWriter, r *http.Request, f models.AppointmentFilter) {
+	var err error
+	if f.From, f.To, err = timeRange(r.URL.Query()); err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	appointments, err := h.svc.List(r.Context(), f)
+	write(w, r, appointments, err, http.StatusOK)
+}
+
+// Availability returns the free slots of a staff member between the from and
+// to query parameters, only those at least length long when it is given.
+func (h *https) Availability(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to, err := timeRange(q)
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	var length time.Duration
+	if value := q.Get("length"); value != "" {
+		if length, err = time.ParseDuration(value); err != nil {
+			patientHTTP.WriteError(w, r, perrors.NewValidation("length", "must be a duration such as 30m"))
+			return
+		}
+	}
+	slots, err := h.svc.Availability(r.Context(), id(r), from, to, length)
+	write(w, r, slots, err, http.StatusOK)
+}
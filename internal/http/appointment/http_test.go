@@ -0,0 +1,159 @@
+package appointment
+
+import (
+	"bytes"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func route(h *https) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/appointments", h.Book).Methods(http.MethodPost)
+	r.HandleFunc("/appointments/{id:[0-9]+}", h.Get).Methods(http.MethodGet)
+	r.HandleFunc("/appointments/{id:[0-9]+}", h.Reschedule).Methods(http.MethodPut)
+	r.HandleFunc("/appointments/{id:[0-9]+}/cancel", h.Cancel).Methods(http.MethodPost)
+	r.HandleFunc("/patient/{id:[0-9]+}/appointments", h.ForPatient).Methods(http.MethodGet)
+	r.HandleFunc("/staff/{id:[0-9]+}/appointments", h.ForStaff).Methods(http.MethodGet)
+	r.HandleFunc("/staff/{id:[0-9]+}/availability", h.Availability).Methods(http.MethodGet)
+	return r
+}
+
+func TestHandlers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockService := service.NewMockAppointmentInterface(mockCtrl)
+	router := route(New(mockService))
+	nine := time.Date(2022, 3, 1, 9, 0, 0, 0, time.UTC)
+	ten := nine.Add(time.Hour)
+	booked := &models.Appointment{ID: 7, PatientID: 1, StaffID: 3, Start: nine, End: ten, Status: models.Booked, Version: 1}
+
+	tests := []struct {
+		desc    string
+		method  string
+		target  string
+		body    string
+		ifMatch string
+		mock    func()
+		status  int
+		etag    string
+	}{
+		{
+			desc:   "book",
+			method: http.MethodPost,
+			target: "/appointments",
+			body:   `{"patientId": 1, "staffId": 3, "start": "2022-03-01T09:00:00Z", "end": "2022-03-01T10:00:00Z"}`,
+			mock: func() {
+				mockService.EXPECT().Book(gomock.Any(), &models.Appointment{PatientID: 1, StaffID: 3, Start: nine, End: ten}).Return(booked, nil)
+			},
+			status: http.StatusCreated,
+			etag:   `"1"`,
+		},
+		{
+			desc:   "book a double-booked doctor",
+			method: http.MethodPost,
+			target: "/appointments",
+			body:   `{"patientId": 1, "staffId": 3, "start": "2022-03-01T09:00:00Z", "end": "2022-03-01T10:00:00Z"}`,
+			mock: func() {
+				mockService.EXPECT().Book(gomock.Any(), gomock.Any()).
+					Return(nil, &perrors.Conflict{Entity: "appointment", Reason: "the staff member is already booked by appointment 5"})
+			},
+			status: http.StatusConflict,
+		},
+		{
+			desc:   "malformed body",
+			method: http.MethodPost,
+			target: "/appointments",
+			body:   `{"start": "at nine"}`,
+			mock:   func() {},
+			status: http.StatusBadRequest,
+		},
+		{
+			desc:   "get",
+			method: http.MethodGet,
+			target: "/appointments/7",
+			mock:   func() { mockService.EXPECT().Get(gomock.Any(), 7).Return(booked, nil) },
+			status: http.StatusOK,
+			etag:   `"1"`,
+		},
+		{
+			desc:    "reschedule",
+			method:  http.MethodPut,
+			target:  "/appointments/7",
+			body:    `{"start": "2022-03-01T09:00:00Z", "end": "2022-03-01T10:00:00Z"}`,
+			ifMatch: `"1"`,
+			mock: func() {
+				mockService.EXPECT().Reschedule(gomock.Any(), &models.Appointment{ID: 7, Start: nine, End: ten, Version: 1}).
+					Return(&models.Appointment{ID: 7, Version: 2}, nil)
+			},
+			status: http.StatusOK,
+			etag:   `"2"`,
+		},
+		{
+			desc:   "cancel twice",
+			method: http.MethodPost,
+			target: "/appointments/7/cancel",
+			mock: func() {
+				mockService.EXPECT().Cancel(gomock.Any(), 7, 0).Return(nil, &perrors.Conflict{Entity: "appointment", Reason: "7 is cancelled"})
+			},
+			status: http.StatusConflict,
+		},
+		{
+			desc:   "patient appointments",
+			method: http.MethodGet,
+			target: "/patient/1/appointments?from=2022-03-01T09:00:00Z",
+			mock: func() {
+				mockService.EXPECT().List(gomock.Any(), models.AppointmentFilter{PatientID: 1, From: nine}).Return([]*models.Appointment{booked}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			desc:   "staff appointments with a bad range",
+			method: http.MethodGet,
+			target: "/staff/3/appointments?to=tomorrow",
+			mock:   func() {},
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			desc:   "availability",
+			method: http.MethodGet,
+			target: "/staff/3/availability?from=2022-03-01T09:00:00Z&to=2022-03-01T10:00:00Z&length=30m",
+			mock: func() {
+				mockService.EXPECT().Availability(gomock.Any(), 3, nine, ten, 30*time.Minute).Return([]*models.Slot{{Start: nine, End: ten}}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			desc:   "availability with a bad length",
+			method: http.MethodGet,
+			target: "/staff/3/availability?from=2022-03-01T09:00:00Z&to=2022-03-01T10:00:00Z&length=half",
+			mock:   func() {},
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			test.mock()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.body))
+			if test.ifMatch != "" {
+				req.Header.Set("If-Match", test.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Errorf("Expected: %v, Got: %v (%s)", test.status, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("ETag"); got != test.etag {
+				t.Errorf("Expected: %v, Got: %v", test.etag, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package encounter
+
+import (
+	patientHTTP "github.com/aakanksha/ppms/internal/http/patient"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type https struct {
+	svc service.EncounterInterface
+}
+
+func New(svc service.EncounterInterface) *https {
+	return &https{svc}
+}
+
+// encounterBody is what clients send: the patient, id and version come from
+// the URL and the If-Match header.
+type encounterBody struct {
+	AdmittedAt   time.Time  `json:"admittedAt"`
+	DischargedAt *time.Time `json:"dischargedAt"`
+	Reason       string     `json:"reason"`
+	Doctor       string     `json:"doctor"`
+	Ward         string     `json:"ward"`
+}
+
+func ids(r *http.Request) (int, int) {
+	vars := mux.Vars(r)
+	patientID, _ := strconv.Atoi(vars["id"])
+	id, _ := strconv.Atoi(vars["eid"])
+	return patientID, id
+}
+
+func write(w http.ResponseWriter, r *http.Request, e *models.Encounter, status int) {
+	w.Header().Set("ETag", patientHTTP.ETag(e.Version))
+	response := patientHTTP.ResponseStruct{
+		Code:   status,
+		Status: "Success",
+		Data:   e,
+	}
+	patientHTTP.Writer(w, r, response, status)
+}
+
+// List returns the encounters of a patient, the latest admission first.
+func (h *https) List(w http.ResponseWriter, r *http.Request) {
+	patientID, _ := ids(r)
+	encounters, err := h.svc.List(r.Context(), patientID)
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	response := patientHTTP.ResponseStruct{
+		Code:   http.StatusOK,
+		Status: "Success",
+		Data:   encounters,
+	}
+	patientHTTP.Writer(w, r, response, http.StatusOK)
+}
+
+func (h *https) Get(w http.ResponseWriter, r *http.Request) {
+	patientID, id := ids(r)
+	e, err := h.svc.Get(r.Context(), patientID, id)
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	if r.Header.Get("If-None-Match") == patientHTTP.ETag(e.Version) {
+		w.Header().Set("ETag", patientHTTP.ETag(e.Version))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	write(w, r, e, http.StatusOK)
+}
+
+// Admit opens an encounter for the patient, or records a past one when the
+// body has a dischargedAt.
+func (h *https) Admit(w http.ResponseWriter, r *http.Request) {
+	patientID, _ := ids(r)
+	var body encounterBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	e, err := h.svc.Admit(r.Context(), &models.Encounter{
+		PatientID:    patientID,
+		AdmittedAt:   body.AdmittedAt,
+		DischargedAt: body.DischargedAt,
+		Reason:       body.Reason,
+		Doctor:       body.Doctor,
+		Ward:         body.Ward,
+	})
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/patient/"+strconv.Itoa(patientID)+"/encounters/"+strconv.Itoa(e.ID))
+	write(w, r, e, http.StatusCreated)
+}
+
+// Update overwrites an encounter, conditionally on If-Match when given.
+func (h *https) Update(w http.ResponseWriter, r *http.Request) {
+	patientID, id := ids(r)
+	var body encounterBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	version, err := patientHTTP.IfMatch(r, "encounter", id)
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	e, err := h.svc.Update(r.Context(), &models.Encounter{
+		ID:           id,
+		PatientID:    patientID,
+		AdmittedAt:   body.AdmittedAt,
+		DischargedAt: body.DischargedAt,
+		Reason:       body.Reason,
+		Doctor:       body.Doctor,
+		Ward:         body.Ward,
+		Version:      version,
+	})
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	write(w, r, e, http.StatusOK)
+}
+
+// Discharge closes an open encounter now, conditionally on If-Match when
+// given.
+func (h *https) Discharge(w http.ResponseWriter, r *http.Request) {
+	patientID, id := ids(r)
+	version, err := patientHTTP.IfMatch(r, "encounter", id)
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	e, err := h.svc.Discharge(r.Context(), patientID, id, version)
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	write(w, r, e, http.StatusOK)
+}
@@ -0,0 +1,199 @@
+package encounter
+
+import (
+	"bytes"
+	"encoding/json"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var admitted = time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+
+func stored() *models.Encounter {
+	return &models.Encounter{ID: 2, PatientID: 1, AdmittedAt: admitted, Reason: "fever", Ward: "B2", Version: 3}
+}
+
+func route(h *https) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/patient/{id:[0-9]+}/encounters", h.List).Methods(http.MethodGet)
+	r.HandleFunc("/patient/{id:[0-9]+}/encounters", h.Admit).Methods(http.MethodPost)
+	r.HandleFunc("/patient/{id:[0-9]+}/encounters/{eid:[0-9]+}", h.Get).Methods(http.MethodGet)
+	r.HandleFunc("/patient/{id:[0-9]+}/encounters/{eid:[0-9]+}", h.Update).Methods(http.MethodPut)
+	r.HandleFunc("/patient/{id:[0-9]+}/encounters/{eid:[0-9]+}/discharge", h.Discharge).Methods(http.MethodPost)
+	return r
+}
+
+func TestHandlers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockService := service.NewMockEncounterInterface(mockCtrl)
+	router := route(New(mockService))
+
+	tests := []struct {
+		desc     string
+		method   string
+		target   string
+		body     string
+		header   map[string]string
+		mock     func()
+		status   int
+		etag     string
+		location string
+	}{
+		{
+			desc:   "list",
+			method: http.MethodGet,
+			target: "/patient/1/encounters",
+			mock:   func() { mockService.EXPECT().List(gomock.Any(), 1).Return([]*models.Encounter{stored()}, nil) },
+			status: http.StatusOK,
+		},
+		{
+			desc:   "list of an unknown patient",
+			method: http.MethodGet,
+			target: "/patient/9/encounters",
+			mock: func() {
+				mockService.EXPECT().List(gomock.Any(), 9).Return(nil, &perrors.NotFound{Entity: "patient", ID: "9"})
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			desc:   "admit",
+			method: http.MethodPost,
+			target: "/patient/1/encounters",
+			body:   `{"reason": "fever", "ward": "B2", "patientId": 7, "version": 9}`,
+			mock: func() {
+				mockService.EXPECT().Admit(gomock.Any(), &models.Encounter{PatientID: 1, Reason: "fever", Ward: "B2"}).Return(stored(), nil)
+			},
+			status:   http.StatusCreated,
+			etag:     `"3"`,
+			location: "/patient/1/encounters/2",
+		},
+		{
+			desc:   "admit while admitted",
+			method: http.MethodPost,
+			target: "/patient/1/encounters",
+			body:   `{}`,
+			mock: func() {
+				mockService.EXPECT().Admit(gomock.Any(), &models.Encounter{PatientID: 1}).
+					Return(nil, &perrors.Conflict{Entity: "encounter", Reason: "overlaps encounter 2"})
+			},
+			status: http.StatusConflict,
+		},
+		{
+			desc:   "malformed body",
+			method: http.MethodPost,
+			target: "/patient/1/encounters",
+			body:   `{"admittedAt": "yesterday"}`,
+			mock:   func() {},
+			status: http.StatusBadRequest,
+		},
+		{
+			desc:   "get",
+			method: http.MethodGet,
+			target: "/patient/1/encounters/2",
+			mock:   func() { mockService.EXPECT().Get(gomock.Any(), 1, 2).Return(stored(), nil) },
+			status: http.StatusOK,
+			etag:   `"3"`,
+		},
+		{
+			desc:   "get not modified",
+			method: http.MethodGet,
+			target: "/patient/1/encounters/2",
+			header: map[string]string{"If-None-Match": `"3"`},
+			mock:   func() { mockService.EXPECT().Get(gomock.Any(), 1, 2).Return(stored(), nil) },
+			status: http.StatusNotModified,
+			etag:   `"3"`,
+		},
+		{
+			desc:   "conditional update",
+			method: http.MethodPut,
+			target: "/patient/1/encounters/2",
+			body:   `{"admittedAt": "2022-03-01T10:00:00Z", "reason": "fever", "ward": "B2"}`,
+			header: map[string]string{"If-Match": `"3"`},
+			mock: func() {
+				mockService.EXPECT().Update(gomock.Any(), &models.Encounter{ID: 2, PatientID: 1, AdmittedAt: admitted, Reason: "fever", Ward: "B2", Version: 3}).
+					Return(stored(), nil)
+			},
+			status: http.StatusOK,
+			etag:   `"3"`,
+		},
+		{
+			desc:   "weak if-match",
+			method: http.MethodPut,
+			target: "/patient/1/encounters/2",
+			body:   `{}`,
+			header: map[string]string{"If-Match": `W/"3"`},
+			mock:   func() {},
+			status: http.StatusPreconditionFailed,
+		},
+		{
+			desc:   "discharge",
+			method: http.MethodPost,
+			target: "/patient/1/encounters/2/discharge",
+			header: map[string]string{"If-Match": `"3"`},
+			mock:   func() { mockService.EXPECT().Discharge(gomock.Any(), 1, 2, 3).Return(stored(), nil) },
+			status: http.StatusOK,
+			etag:   `"3"`,
+		},
+		{
+			desc:   "discharge twice",
+			method: http.MethodPost,
+			target: "/patient/1/encounters/2/discharge",
+			mock: func() {
+				mockService.EXPECT().Discharge(gomock.Any(), 1, 2, 0).Return(nil, &perrors.Conflict{Entity: "encounter", Reason: "is already discharged"})
+			},
+			status: http.StatusConflict,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			test.mock()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.body))
+			for k, v := range test.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Errorf("Expected: %v, Got: %v (%s)", test.status, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("ETag"); got != test.etag {
+				t.Errorf("Expected: %v, Got: %v", test.etag, got)
+			}
+			if got := w.Header().Get("Location"); got != test.location {
+				t.Errorf("Expected: %v, Got: %v", test.location, got)
+			}
+		})
+	}
+}
+
+func TestBody(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockService := service.NewMockEncounterInterface(mockCtrl)
+	discharged := admitted.Add(48 * time.Hour)
+	e := stored()
+	e.DischargedAt = &discharged
+	mockService.EXPECT().Get(gomock.Any(), 1, 2).Return(e, nil)
+
+	w := httptest.NewRecorder()
+	route(New(mockService)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/patient/1/encounters/2", nil))
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data["patientId"] != float64(1) || body.Data["dischargedAt"] != "2022-03-03T10:00:00Z" || body.Data["reason"] != "fever" {
+		t.Errorf("unexpected body: %v", body.Data)
+	}
+}
@@ -0,0 +1,164 @@
+package staff
+
+import (
+	patientHTTP "github.com/aakanksha/ppms/internal/http/patient"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+type https struct {
+	svc service.StaffInterface
+}
+
+func New(svc service.StaffInterface) *https {
+	return &https{svc}
+}
+
+// staffBody is what clients send: the id and version come from the URL and
+// the If-Match header.
+type staffBody struct {
+	Name       string            `json:"name"`
+	Profession models.Profession `json:"profession"`
+	Specialty  string            `json:"specialty"`
+	Phone      string            `json:"phone"`
+	Email      string            `json:"email"`
+}
+
+// roleBody is what clients send to put a staff member on a care team.
+type roleBody struct {
+	Role models.CareRole `json:"role"`
+}
+
+// ids returns the id and sid path variables; sid is the staff member on a
+// patient's care team.
+func ids(r *http.Request) (int, int) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	staffID, _ := strconv.Atoi(vars["sid"])
+	return id, staffID
+}
+
+func write(w http.ResponseWriter, r *http.Request, data interface{}, err error, status int) {
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	if st, ok := data.(*models.Staff); ok {
+		w.Header().Set("ETag", patientHTTP.ETag(st.Version))
+	}
+	response := patientHTTP.ResponseStruct{
+		Code:   status,
+		Status: "Success",
+		Data:   data,
+	}
+	patientHTTP.Writer(w, r, response, status)
+}
+
+// List returns the staff by name, filtered by the profession query parameter
+// when it is given.
+func (h *https) List(w http.ResponseWriter, r *http.Request) {
+	profession := models.Profession(r.URL.Query().Get("profession"))
+	staff, err := h.svc.List(r.Context(), profession)
+	write(w, r, staff, err, http.StatusOK)
+}
+
+func (h *https) Get(w http.ResponseWriter, r *http.Request) {
+	id, _ := ids(r)
+	st, err := h.svc.Get(r.Context(), id)
+	if err == nil && r.Header.Get("If-None-Match") == patientHTTP.ETag(st.Version) {
+		w.Header().Set("ETag", patientHTTP.ETag(st.Version))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	write(w, r, st, err, http.StatusOK)
+}
+
+func (h *https) Create(w http.ResponseWriter, r *http.Request) {
+	var body staffBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	st, err := h.svc.Insert(r.Context(), &models.Staff{
+		Name:       body.Name,
+		Profession: body.Profession,
+		Specialty:  body.Specialty,
+		Phone:      body.Phone,
+		Email:      body.Email,
+	})
+	if err == nil {
+		w.Header().Set("Location", "/staff/"+strconv.Itoa(st.ID))
+	}
+	write(w, r, st, err, http.StatusCreated)
+}
+
+// Update overwrites a staff member, conditionally on If-Match when given.
+func (h *https) Update(w http.ResponseWriter, r *http.Request) {
+	id, _ := ids(r)
+	var body staffBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	version, err := patientHTTP.IfMatch(r, "staff", id)
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	st, err := h.svc.Update(r.Context(), &models.Staff{
+		ID:         id,
+		Name:       body.Name,
+		Profession: body.Profession,
+		Specialty:  body.Specialty,
+		Phone:      body.Phone,
+		Email:      body.Email,
+		Version:    version,
+	})
+	write(w, r, st, err, http.StatusOK)
+}
+
+// Delete removes a staff member and takes them off every care team,
+// conditionally on If-Match when given.
+func (h *https) Delete(w http.ResponseWriter, r *http.Request) {
+	id, _ := ids(r)
+	version, err := patientHTTP.IfMatch(r, "staff", id)
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	err = h.svc.Delete(r.Context(), id, version)
+	write(w, r, "Staff deleted Successfully", err, http.StatusOK)
+}
+
+// Patients returns the patients whose care team a staff member is on.
+func (h *https) Patients(w http.ResponseWriter, r *http.Request) {
+	id, _ := ids(r)
+	members, err := h.svc.Patients(r.Context(), id)
+	write(w, r, members, err, http.StatusOK)
+}
+
+// Team returns the care team of a patient.
+func (h *https) Team(w http.ResponseWriter, r *http.Request) {
+	id, _ := ids(r)
+	members, err := h.svc.Team(r.Context(), id)
+	write(w, r, members, err, http.StatusOK)
+}
+
+// Assign puts a staff member on the care team of a patient, or changes their
+// role on it.
+func (h *https) Assign(w http.ResponseWriter, r *http.Request) {
+	var body roleBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	id, staffID := ids(r)
+	m, err := h.svc.Assign(r.Context(), &models.CareTeamMember{PatientID: id, StaffID: staffID, Role: body.Role})
+	write(w, r, m, err, http.StatusOK)
+}
+
+func (h *https) Unassign(w http.ResponseWriter, r *http.Request) {
+	id, staffID := ids(r)
+	err := h.svc.Unassign(r.Context(), id, staffID)
+	write(w, r, "Care team member removed Successfully", err, http.StatusOK)
+}
@@ -0,0 +1,159 @@
+package staff
+
+import (
+	"bytes"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func route(h *https) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/staff", h.List).Methods(http.MethodGet)
+	r.HandleFunc("/staff", h.Create).Methods(http.MethodPost)
+	r.HandleFunc("/staff/{id:[0-9]+}", h.Get).Methods(http.MethodGet)
+	r.HandleFunc("/staff/{id:[0-9]+}", h.Update).Methods(http.MethodPut)
+	r.HandleFunc("/staff/{id:[0-9]+}", h.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/staff/{id:[0-9]+}/patients", h.Patients).Methods(http.MethodGet)
+	r.HandleFunc("/patient/{id:[0-9]+}/careteam", h.Team).Methods(http.MethodGet)
+	r.HandleFunc("/patient/{id:[0-9]+}/careteam/{sid:[0-9]+}", h.Assign).Methods(http.MethodPut)
+	r.HandleFunc("/patient/{id:[0-9]+}/careteam/{sid:[0-9]+}", h.Unassign).Methods(http.MethodDelete)
+	return r
+}
+
+func TestHandlers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockService := service.NewMockStaffInterface(mockCtrl)
+	router := route(New(mockService))
+	rao := &models.Staff{ID: 3, Name: "Dr. Rao", Profession: models.Doctor, Version: 2}
+	member := &models.CareTeamMember{PatientID: 1, StaffID: 3, Role: models.Attending}
+
+	tests := []struct {
+		desc    string
+		method  string
+		target  string
+		body    string
+		ifMatch string
+		mock    func()
+		status  int
+		etag    string
+	}{
+		{
+			desc:   "list doctors",
+			method: http.MethodGet,
+			target: "/staff?profession=doctor",
+			mock: func() {
+				mockService.EXPECT().List(gomock.Any(), models.Doctor).Return([]*models.Staff{rao}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			desc:   "create",
+			method: http.MethodPost,
+			target: "/staff",
+			body:   `{"name": "Dr. Rao", "profession": "doctor", "id": 9}`,
+			mock: func() {
+				mockService.EXPECT().Insert(gomock.Any(), &models.Staff{Name: "Dr. Rao", Profession: models.Doctor}).Return(rao, nil)
+			},
+			status: http.StatusCreated,
+			etag:   `"2"`,
+		},
+		{
+			desc:   "malformed body",
+			method: http.MethodPost,
+			target: "/staff",
+			body:   `{"name": 7}`,
+			mock:   func() {},
+			status: http.StatusBadRequest,
+		},
+		{
+			desc:   "get",
+			method: http.MethodGet,
+			target: "/staff/3",
+			mock:   func() { mockService.EXPECT().Get(gomock.Any(), 3).Return(rao, nil) },
+			status: http.StatusOK,
+			etag:   `"2"`,
+		},
+		{
+			desc:    "update a stale version",
+			method:  http.MethodPut,
+			target:  "/staff/3",
+			body:    `{"name": "Dr. Rao", "profession": "doctor"}`,
+			ifMatch: `"1"`,
+			mock: func() {
+				mockService.EXPECT().Update(gomock.Any(), &models.Staff{ID: 3, Name: "Dr. Rao", Profession: models.Doctor, Version: 1}).
+					Return(nil, &perrors.PreconditionFailed{Entity: "staff", ID: "3"})
+			},
+			status: http.StatusPreconditionFailed,
+		},
+		{
+			desc:    "delete with a weak tag",
+			method:  http.MethodDelete,
+			target:  "/staff/3",
+			ifMatch: `W/"2"`,
+			mock:    func() {},
+			status:  http.StatusPreconditionFailed,
+		},
+		{
+			desc:   "patients of a doctor",
+			method: http.MethodGet,
+			target: "/staff/3/patients",
+			mock: func() {
+				mockService.EXPECT().Patients(gomock.Any(), 3).Return([]*models.CareTeamMember{member}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			desc:   "team of a patient",
+			method: http.MethodGet,
+			target: "/patient/1/careteam",
+			mock: func() {
+				mockService.EXPECT().Team(gomock.Any(), 1).Return([]*models.CareTeamMember{member}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			desc:   "assign a taken role",
+			method: http.MethodPut,
+			target: "/patient/1/careteam/3",
+			body:   `{"role": "attending"}`,
+			mock: func() {
+				mockService.EXPECT().Assign(gomock.Any(), &models.CareTeamMember{PatientID: 1, StaffID: 3, Role: models.Attending}).
+					Return(nil, &perrors.Conflict{Entity: "care team", Reason: "the attending role is taken"})
+			},
+			status: http.StatusConflict,
+		},
+		{
+			desc:   "unassign",
+			method: http.MethodDelete,
+			target: "/patient/1/careteam/3",
+			mock:   func() { mockService.EXPECT().Unassign(gomock.Any(), 1, 3).Return(nil) },
+			status: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			test.mock()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.body))
+			if test.ifMatch != "" {
+				req.Header.Set("If-Match", test.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Errorf("Expected: %v, Got: %v (%s)", test.status, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("ETag"); got != test.etag {
+				t.Errorf("Expected: %v, Got: %v", test.etag, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package triage
+
+import (
+	patientHTTP "github.com/aakanksha/ppms/internal/http/patient"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+type https struct {
+	svc service.TriageInterface
+}
+
+func New(svc service.TriageInterface) *https {
+	return &https{svc}
+}
+
+// triageBody is what clients send: arrival and triage times are set by the
+// server.
+type triageBody struct {
+	PatientID int `json:"patientId"`
+	Level     int `json:"level"`
+}
+
+func write(w http.ResponseWriter, r *http.Request, data interface{}, err error, status int) {
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	response := patientHTTP.ResponseStruct{
+		Code:   status,
+		Status: "Success",
+		Data:   data,
+	}
+	patientHTTP.Writer(w, r, response, status)
+}
+
+// Queue returns the waiting patients, the next to be called first.
+func (h *https) Queue(w http.ResponseWriter, r *http.Request) {
+	queue, err := h.svc.Queue(r.Context())
+	write(w, r, queue, err, http.StatusOK)
+}
+
+// Enqueue puts a patient in the queue at an ESI level.
+func (h *https) Enqueue(w http.ResponseWriter, r *http.Request) {
+	var body triageBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	t, err := h.svc.Enqueue(r.Context(), &models.Triage{PatientID: body.PatientID, Level: body.Level})
+	write(w, r, t, err, http.StatusCreated)
+}
+
+// Peek returns the patient who would be called next.
+func (h *https) Peek(w http.ResponseWriter, r *http.Request) {
+	t, err := h.svc.Peek(r.Context())
+	write(w, r, t, err, http.StatusOK)
+}
+
+// CallNext takes the next patient off the queue.
+func (h *https) CallNext(w http.ResponseWriter, r *http.Request) {
+	t, err := h.svc.CallNext(r.Context())
+	write(w, r, t, err, http.StatusOK)
+}
+
+// Retriage changes the ESI level of a waiting patient; only level is read
+// from the body.
+func (h *https) Retriage(w http.ResponseWriter, r *http.Request) {
+	var body triageBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	t, err := h.svc.Retriage(r.Context(), id, body.Level)
+	write(w, r, t, err, http.StatusOK)
+}
@@ -0,0 +1,124 @@
+package triage
+
+import (
+	"bytes"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func route(h *https) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/triage", h.Queue).Methods(http.MethodGet)
+	r.HandleFunc("/triage", h.Enqueue).Methods(http.MethodPost)
+	r.HandleFunc("/triage/next", h.Peek).Methods(http.MethodGet)
+	r.HandleFunc("/triage/next", h.CallNext).Methods(http.MethodPost)
+	r.HandleFunc("/triage/{id:[0-9]+}/retriage", h.Retriage).Methods(http.MethodPost)
+	return r
+}
+
+func TestHandlers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockService := service.NewMockTriageInterface(mockCtrl)
+	router := route(New(mockService))
+	visit := &models.Triage{ID: 5, PatientID: 4, Level: 3, Priority: 3}
+
+	tests := []struct {
+		desc   string
+		method string
+		target string
+		body   string
+		mock   func()
+		status int
+	}{
+		{
+			desc:   "queue",
+			method: http.MethodGet,
+			target: "/triage",
+			mock:   func() { mockService.EXPECT().Queue(gomock.Any()).Return([]*models.Triage{visit}, nil) },
+			status: http.StatusOK,
+		},
+		{
+			desc:   "enqueue",
+			method: http.MethodPost,
+			target: "/triage",
+			body:   `{"patientId": 4, "level": 3}`,
+			mock: func() {
+				mockService.EXPECT().Enqueue(gomock.Any(), &models.Triage{PatientID: 4, Level: 3}).Return(visit, nil)
+			},
+			status: http.StatusCreated,
+		},
+		{
+			desc:   "enqueue a waiting patient",
+			method: http.MethodPost,
+			target: "/triage",
+			body:   `{"patientId": 4, "level": 3}`,
+			mock: func() {
+				mockService.EXPECT().Enqueue(gomock.Any(), &models.Triage{PatientID: 4, Level: 3}).
+					Return(nil, &perrors.Conflict{Entity: "triage", Reason: "patient is already waiting"})
+			},
+			status: http.StatusConflict,
+		},
+		{
+			desc:   "malformed body",
+			method: http.MethodPost,
+			target: "/triage",
+			body:   `{"level": "urgent"}`,
+			mock:   func() {},
+			status: http.StatusBadRequest,
+		},
+		{
+			desc:   "peek at an empty queue",
+			method: http.MethodGet,
+			target: "/triage/next",
+			mock: func() {
+				mockService.EXPECT().Peek(gomock.Any()).Return(nil, &perrors.NotFound{Entity: "waiting patient"})
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			desc:   "call next",
+			method: http.MethodPost,
+			target: "/triage/next",
+			mock:   func() { mockService.EXPECT().CallNext(gomock.Any()).Return(visit, nil) },
+			status: http.StatusOK,
+		},
+		{
+			desc:   "retriage",
+			method: http.MethodPost,
+			target: "/triage/5/retriage",
+			body:   `{"level": 2}`,
+			mock:   func() { mockService.EXPECT().Retriage(gomock.Any(), 5, 2).Return(visit, nil) },
+			status: http.StatusOK,
+		},
+		{
+			desc:   "retriage a called patient",
+			method: http.MethodPost,
+			target: "/triage/5/retriage",
+			body:   `{"level": 2}`,
+			mock: func() {
+				mockService.EXPECT().Retriage(gomock.Any(), 5, 2).Return(nil, &perrors.Conflict{Entity: "triage", Reason: "5 has already been called"})
+			},
+			status: http.StatusConflict,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			test.mock()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Errorf("Expected: %v, Got: %v (%s)", test.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package ward
+
+import (
+	patientHTTP "github.com/aakanksha/ppms/internal/http/patient"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+type https struct {
+	svc service.WardInterface
+}
+
+func New(svc service.WardInterface) *https {
+	return &https{svc}
+}
+
+// wardBody is what clients send to create a ward: its name and the labels of
+// its beds.
+type wardBody struct {
+	Name string   `json:"name"`
+	Beds []string `json:"beds"`
+}
+
+type bedBody struct {
+	Label string `json:"label"`
+}
+
+type assignBody struct {
+	BedID int `json:"bedId"`
+}
+
+func id(r *http.Request) int {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	return id
+}
+
+func write(w http.ResponseWriter, r *http.Request, data interface{}, err error, status int) {
+	if err != nil {
+		patientHTTP.WriteError(w, r, err)
+		return
+	}
+	response := patientHTTP.ResponseStruct{
+		Code:   status,
+		Status: "Success",
+		Data:   data,
+	}
+	patientHTTP.Writer(w, r, response, status)
+}
+
+// ListWards returns every ward, by name, without its beds.
+func (h *https) ListWards(w http.ResponseWriter, r *http.Request) {
+	wards, err := h.svc.ListWards(r.Context())
+	write(w, r, wards, err, http.StatusOK)
+}
+
+// CreateWard creates a ward together with its beds.
+func (h *https) CreateWard(w http.ResponseWriter, r *http.Request) {
+	var body wardBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	ward := &models.Ward{Name: body.Name}
+	for _, label := range body.Beds {
+		ward.Beds = append(ward.Beds, &models.Bed{Label: label})
+	}
+	created, err := h.svc.CreateWard(r.Context(), ward)
+	if err == nil {
+		w.Header().Set("Location", "/wards/"+strconv.Itoa(created.ID))
+	}
+	write(w, r, created, err, http.StatusCreated)
+}
+
+// GetWard returns a ward with its beds.
+func (h *https) GetWard(w http.ResponseWriter, r *http.Request) {
+	ward, err := h.svc.GetWard(r.Context(), id(r))
+	write(w, r, ward, err, http.StatusOK)
+}
+
+func (h *https) AddBed(w http.ResponseWriter, r *http.Request) {
+	var body bedBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	bed, err := h.svc.AddBed(r.Context(), &models.Bed{WardID: id(r), Label: body.Label})
+	write(w, r, bed, err, http.StatusCreated)
+}
+
+// Occupancy reports which beds of a ward are taken, and by whom.
+func (h *https) Occupancy(w http.ResponseWriter, r *http.Request) {
+	o, err := h.svc.Occupancy(r.Context(), id(r))
+	write(w, r, o, err, http.StatusOK)
+}
+
+// CurrentBed returns the bed a patient lies in.
+func (h *https) CurrentBed(w http.ResponseWriter, r *http.Request) {
+	a, err := h.svc.CurrentBed(r.Context(), id(r))
+	write(w, r, a, err, http.StatusOK)
+}
+
+// Assign puts a patient without a bed into the bed given by bedId.
+func (h *https) Assign(w http.ResponseWriter, r *http.Request) {
+	var body assignBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	a, err := h.svc.Assign(r.Context(), id(r), body.BedID)
+	write(w, r, a, err, http.StatusCreated)
+}
+
+// Transfer moves a patient from their bed to the one given by bedId.
+func (h *https) Transfer(w http.ResponseWriter, r *http.Request) {
+	var body assignBody
+	if !patientHTTP.Decode(w, r, &body) {
+		return
+	}
+	a, err := h.svc.Transfer(r.Context(), id(r), body.BedID)
+	write(w, r, a, err, http.StatusOK)
+}
+
+// Release frees a patient's bed and returns the closed assignment.
+func (h *https) Release(w http.ResponseWriter, r *http.Request) {
+	a, err := h.svc.Release(r.Context(), id(r))
+	write(w, r, a, err, http.StatusOK)
+}
@@ -0,0 +1,168 @@
+package ward
+
+import (
+	"bytes"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func route(h *https) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/wards", h.ListWards).Methods(http.MethodGet)
+	r.HandleFunc("/wards", h.CreateWard).Methods(http.MethodPost)
+	r.HandleFunc("/wards/{id:[0-9]+}", h.GetWard).Methods(http.MethodGet)
+	r.HandleFunc("/wards/{id:[0-9]+}/beds", h.AddBed).Methods(http.MethodPost)
+	r.HandleFunc("/wards/{id:[0-9]+}/occupancy", h.Occupancy).Methods(http.MethodGet)
+	r.HandleFunc("/patient/{id:[0-9]+}/bed", h.CurrentBed).Methods(http.MethodGet)
+	r.HandleFunc("/patient/{id:[0-9]+}/bed", h.Assign).Methods(http.MethodPost)
+	r.HandleFunc("/patient/{id:[0-9]+}/bed", h.Release).Methods(http.MethodDelete)
+	r.HandleFunc("/patient/{id:[0-9]+}/bed/transfer", h.Transfer).Methods(http.MethodPost)
+	return r
+}
+
+func TestHandlers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockService := service.NewMockWardInterface(mockCtrl)
+	router := route(New(mockService))
+	assignment := &models.BedAssignment{ID: 3, PatientID: 1, BedLocation: models.BedLocation{BedID: 4, WardID: 2, Ward: "ICU", Label: "A1"}}
+
+	tests := []struct {
+		desc     string
+		method   string
+		target   string
+		body     string
+		mock     func()
+		status   int
+		location string
+	}{
+		{
+			desc:   "list wards",
+			method: http.MethodGet,
+			target: "/wards",
+			mock:   func() { mockService.EXPECT().ListWards(gomock.Any()).Return([]*models.Ward{{ID: 2, Name: "ICU"}}, nil) },
+			status: http.StatusOK,
+		},
+		{
+			desc:   "create ward",
+			method: http.MethodPost,
+			target: "/wards",
+			body:   `{"name": "ICU", "beds": ["A1", "A2"]}`,
+			mock: func() {
+				mockService.EXPECT().CreateWard(gomock.Any(), &models.Ward{Name: "ICU", Beds: []*models.Bed{{Label: "A1"}, {Label: "A2"}}}).
+					Return(&models.Ward{ID: 2, Name: "ICU"}, nil)
+			},
+			status:   http.StatusCreated,
+			location: "/wards/2",
+		},
+		{
+			desc:   "duplicate ward",
+			method: http.MethodPost,
+			target: "/wards",
+			body:   `{"name": "ICU"}`,
+			mock: func() {
+				mockService.EXPECT().CreateWard(gomock.Any(), &models.Ward{Name: "ICU"}).
+					Return(nil, &perrors.Conflict{Entity: "ward", Reason: "a ward named ICU exists"})
+			},
+			status: http.StatusConflict,
+		},
+		{
+			desc:   "unknown ward",
+			method: http.MethodGet,
+			target: "/wards/9",
+			mock: func() {
+				mockService.EXPECT().GetWard(gomock.Any(), 9).Return(nil, &perrors.NotFound{Entity: "ward", ID: "9"})
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			desc:   "add bed",
+			method: http.MethodPost,
+			target: "/wards/2/beds",
+			body:   `{"label": "A3"}`,
+			mock: func() {
+				mockService.EXPECT().AddBed(gomock.Any(), &models.Bed{WardID: 2, Label: "A3"}).Return(&models.Bed{ID: 5, WardID: 2, Label: "A3"}, nil)
+			},
+			status: http.StatusCreated,
+		},
+		{
+			desc:   "occupancy",
+			method: http.MethodGet,
+			target: "/wards/2/occupancy",
+			mock:   func() { mockService.EXPECT().Occupancy(gomock.Any(), 2).Return(&models.Occupancy{WardID: 2}, nil) },
+			status: http.StatusOK,
+		},
+		{
+			desc:   "assign",
+			method: http.MethodPost,
+			target: "/patient/1/bed",
+			body:   `{"bedId": 4}`,
+			mock:   func() { mockService.EXPECT().Assign(gomock.Any(), 1, 4).Return(assignment, nil) },
+			status: http.StatusCreated,
+		},
+		{
+			desc:   "assign an occupied bed",
+			method: http.MethodPost,
+			target: "/patient/1/bed",
+			body:   `{"bedId": 4}`,
+			mock: func() {
+				mockService.EXPECT().Assign(gomock.Any(), 1, 4).Return(nil, &perrors.Conflict{Entity: "bed", Reason: "ICU A1 is occupied"})
+			},
+			status: http.StatusConflict,
+		},
+		{
+			desc:   "malformed body",
+			method: http.MethodPost,
+			target: "/patient/1/bed",
+			body:   `{"bedId": "A1"}`,
+			mock:   func() {},
+			status: http.StatusBadRequest,
+		},
+		{
+			desc:   "transfer",
+			method: http.MethodPost,
+			target: "/patient/1/bed/transfer",
+			body:   `{"bedId": 5}`,
+			mock:   func() { mockService.EXPECT().Transfer(gomock.Any(), 1, 5).Return(assignment, nil) },
+			status: http.StatusOK,
+		},
+		{
+			desc:   "release",
+			method: http.MethodDelete,
+			target: "/patient/1/bed",
+			mock:   func() { mockService.EXPECT().Release(gomock.Any(), 1).Return(assignment, nil) },
+			status: http.StatusOK,
+		},
+		{
+			desc:   "no bed",
+			method: http.MethodGet,
+			target: "/patient/1/bed",
+			mock: func() {
+				mockService.EXPECT().CurrentBed(gomock.Any(), 1).Return(nil, &perrors.NotFound{Entity: "bed assignment", ID: "1"})
+			},
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			test.mock()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Errorf("Expected: %v, Got: %v (%s)", test.status, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Location"); got != test.location {
+				t.Errorf("Expected: %v, Got: %v", test.location, got)
+			}
+		})
+	}
+}
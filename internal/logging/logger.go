@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Logger writes one JSON object per line. Fields added with With are repeated
+// on every line, after time, level and msg.
+type Logger struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	fields []interface{}
+	now    func() time.Time
+}
+
+func New(out io.Writer) *Logger {
+	return &Logger{mu: &sync.Mutex{}, out: out, now: time.Now}
+}
+
+var defaultLogger = New(os.Stderr)
+
+// With returns a logger that adds the given key/value pairs to every line.
+func (l *Logger) With(kv ...interface{}) *Logger {
+	child := *l
+	child.fields = append(append([]interface{}{}, l.fields...), kv...)
+	return &child
+}
+
+func (l *Logger) Info(msg string, kv ...interface{}) {
+	l.log("info", msg, kv)
+}
+
+func (l *Logger) Warn(msg string, kv ...interface{}) {
+	l.log("warn", msg, kv)
+}
+
+func (l *Logger) Error(msg string, kv ...interface{}) {
+	l.log("error", msg, kv)
+}
+
+func (l *Logger) log(level, msg string, kv []interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	writeValue(&buf, l.now().UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeValue(&buf, level)
+	buf.WriteString(`,"msg":`)
+	writeValue(&buf, msg)
+	fields := append(append([]interface{}{}, l.fields...), kv...)
+	for i := 0; i < len(fields); i += 2 {
+		buf.WriteByte(',')
+		writeValue(&buf, fmt.Sprint(fields[i]))
+		buf.WriteByte(':')
+		if i+1 < len(fields) {
+			writeValue(&buf, fields[i+1])
+		} else {
+			buf.WriteString("null")
+		}
+	}
+	buf.WriteString("}\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(buf.Bytes())
+}
+
+func writeValue(buf *bytes.Buffer, v interface{}) {
+	switch val := v.(type) {
+	case error:
+		v = val.Error()
+	case time.Duration:
+		v = val.String()
+	case fmt.Stringer:
+		v = val.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
+}
+
+type loggerKey struct{}
+
+func WithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger of the request, or a logger to stderr.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+		return l
+	}
+	return defaultLogger
+}
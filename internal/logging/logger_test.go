@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		desc     string
+		log      func(l *Logger)
+		expected string
+	}{
+		{
+			desc:     "info",
+			log:      func(l *Logger) { l.Info("started", "addr", ":8000") },
+			expected: `{"time":"2024-06-01T10:00:00Z","level":"info","msg":"started","addr":":8000"}` + "\n",
+		},
+		{
+			desc:     "error value and duration",
+			log:      func(l *Logger) { l.Error("failed", "error", errors.New("boom"), "after", 2*time.Second) },
+			expected: `{"time":"2024-06-01T10:00:00Z","level":"error","msg":"failed","error":"boom","after":"2s"}` + "\n",
+		},
+		{
+			desc:     "fields from With come first",
+			log:      func(l *Logger) { l.With("requestId", "abc").Warn("slow", "status", 200) },
+			expected: `{"time":"2024-06-01T10:00:00Z","level":"warn","msg":"slow","requestId":"abc","status":200}` + "\n",
+		},
+		{
+			desc:     "missing value",
+			log:      func(l *Logger) { l.Info("odd", "key") },
+			expected: `{"time":"2024-06-01T10:00:00Z","level":"info","msg":"odd","key":null}` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := New(&buf)
+			l.now = func() time.Time { return time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC) }
+			test.log(l)
+			if buf.String() != test.expected {
+				t.Errorf("Expected: %v, Got: %v", test.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != defaultLogger {
+		t.Errorf("Expected the default logger without one in the context")
+	}
+	l := New(&bytes.Buffer{})
+	if FromContext(WithLogger(context.Background(), l)) != l {
+		t.Errorf("Expected the logger stored in the context")
+	}
+}
@@ -0,0 +1,131 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID returns the id of the request ctx belongs to, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// routeInfo is filled in by RecordRoute once the router has matched, so the
+// access log written by Middleware, outside the router, can include it.
+type routeInfo struct {
+	template  string
+	patientID string
+}
+
+type routeInfoKey struct{}
+
+// Middleware gives each request an id, taken from a well-formed X-Request-ID
+// header or generated, echoes it in the response and puts a logger carrying it
+// in the request context. When the request completes it writes an access log
+// line, also when the handler aborts it by panicking.
+func Middleware(base *Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := r.Header.Get("X-Request-ID")
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set("X-Request-ID", id)
+
+			logger := base.With("requestId", id)
+			info := &routeInfo{}
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			ctx = context.WithValue(ctx, routeInfoKey{}, info)
+			ctx = WithLogger(ctx, logger)
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				fields := []interface{}{
+					"method", r.Method,
+					"route", info.template,
+					"path", r.URL.Path,
+					"status", rec.status,
+					"latencyMs", float64(time.Since(start).Microseconds()) / 1000,
+					"bytes", rec.bytes,
+				}
+				if info.patientID != "" {
+					fields = append(fields, "patientId", info.patientID)
+				}
+				if !completed {
+					fields = append(fields, "aborted", true)
+				}
+				if !completed || rec.status >= http.StatusInternalServerError {
+					logger.Error("request", fields...)
+				} else {
+					logger.Info("request", fields...)
+				}
+			}()
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			completed = true
+		})
+	}
+}
+
+// RecordRoute is a mux middleware that reports the matched route template and
+// patient id to Middleware.
+func RecordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info, ok := r.Context().Value(routeInfoKey{}).(*routeInfo); ok {
+			if route := mux.CurrentRoute(r); route != nil {
+				info.template, _ = route.GetPathTemplate()
+			}
+			info.patientID = mux.Vars(r)["id"]
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
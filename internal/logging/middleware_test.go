@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		desc      string
+		target    string
+		requestID string
+		keepID    bool
+		status    int
+		aborted   bool
+		level     string
+		route     string
+		patientID string
+	}{
+		{desc: "propagates request id", target: "/patient/7", requestID: "req-1", keepID: true, status: http.StatusOK, level: "info", route: "/patient/{id:[0-9]+}", patientID: "7"},
+		{desc: "generates request id", target: "/patient/7", status: http.StatusOK, level: "info", route: "/patient/{id:[0-9]+}", patientID: "7"},
+		{desc: "replaces malformed request id", target: "/patient/7", requestID: "bad id\n", status: http.StatusOK, level: "info", route: "/patient/{id:[0-9]+}", patientID: "7"},
+		{desc: "server error", target: "/patient/fail", status: http.StatusInternalServerError, level: "error", route: "/patient/fail"},
+		{desc: "unmatched route", target: "/nowhere", status: http.StatusNotFound, level: "info"},
+		{desc: "aborted response", target: "/patient/abort", status: http.StatusOK, aborted: true, level: "error", route: "/patient/abort"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			var ctxID string
+			r := mux.NewRouter()
+			r.Use(RecordRoute)
+			r.HandleFunc("/patient/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+				ctxID = RequestID(r.Context())
+				FromContext(r.Context()).Info("handled")
+				w.Write([]byte("ok"))
+			})
+			r.HandleFunc("/patient/fail", func(w http.ResponseWriter, r *http.Request) {
+				ctxID = RequestID(r.Context())
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+
+			r.HandleFunc("/patient/abort", func(w http.ResponseWriter, r *http.Request) {
+				ctxID = RequestID(r.Context())
+				w.Write([]byte("partial"))
+				panic(http.ErrAbortHandler)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.target, nil)
+			if test.requestID != "" {
+				req.Header.Set("X-Request-ID", test.requestID)
+			}
+			func() {
+				defer func() {
+					if p := recover(); (p != nil) != test.aborted {
+						t.Errorf("unexpected panic: %v", p)
+					}
+				}()
+				Middleware(New(&buf))(r).ServeHTTP(w, req)
+			}()
+
+			id := w.Header().Get("X-Request-ID")
+			if test.keepID && id != test.requestID {
+				t.Errorf("Expected: %v, Got: %v", test.requestID, id)
+			}
+			if !test.keepID && (id == "" || id == test.requestID) {
+				t.Errorf("Expected a generated request id, Got: %q", id)
+			}
+			if test.status != http.StatusNotFound && ctxID != id {
+				t.Errorf("Expected context request id: %v, Got: %v", id, ctxID)
+			}
+
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+			var access map[string]interface{}
+			if err := json.Unmarshal(lines[len(lines)-1], &access); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := map[string]interface{}{
+				"level":     test.level,
+				"msg":       "request",
+				"requestId": id,
+				"method":    http.MethodGet,
+				"route":     test.route,
+				"status":    float64(test.status),
+			}
+			for k, v := range expected {
+				if access[k] != v {
+					t.Errorf("%s: Expected: %v, Got: %v", k, v, access[k])
+				}
+			}
+			if test.patientID != "" && access["patientId"] != test.patientID {
+				t.Errorf("Expected: %v, Got: %v", test.patientID, access["patientId"])
+			}
+			if (access["aborted"] == true) != test.aborted {
+				t.Errorf("Expected aborted %v in %s", test.aborted, lines[len(lines)-1])
+			}
+			if _, ok := access["latencyMs"]; !ok {
+				t.Errorf("Expected latencyMs in %s", lines[len(lines)-1])
+			}
+			for _, line := range lines[:len(lines)-1] {
+				if !bytes.Contains(line, []byte(`"requestId":"`+id+`"`)) {
+					t.Errorf("Expected request id in %s", line)
+				}
+			}
+		})
+	}
+}
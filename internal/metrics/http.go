@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPMiddleware returns a mux middleware counting requests and timing them
+// per route template, so /patient/7 and /patient/8 share a series. Requests
+// whose handler aborts them by panicking are counted with code "aborted".
+func (r *Registry) HTTPMiddleware() mux.MiddlewareFunc {
+	requests := r.NewCounterVec("ppms_http_requests_total", "HTTP requests by route, method and status code.", "route", "method", "code")
+	duration := r.NewHistogramVec("ppms_http_request_duration_seconds", "HTTP request latency by route and method.", DefaultBuckets, "route", "method")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			route := ""
+			if current := mux.CurrentRoute(req); current != nil {
+				route, _ = current.GetPathTemplate()
+			}
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			code := "aborted"
+			defer func() {
+				duration.Observe(time.Since(start).Seconds(), route, req.Method)
+				requests.Inc(route, req.Method, code)
+			}()
+			next.ServeHTTP(rec, req)
+			code = strconv.Itoa(rec.status)
+		})
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
@@ -0,0 +1,280 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are latency buckets in seconds, from 5ms to 10s.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// collector writes one or more metric families in the Prometheus text format.
+type collector interface {
+	write(b *bytes.Buffer)
+}
+
+// Registry holds the metrics exposed by Handler.
+type Registry struct {
+	mu         sync.Mutex
+	names      map[string]bool
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+func (r *Registry) register(c collector, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic("metrics: " + name + " registered twice")
+	}
+	r.names[name] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// Handler serves every registered metric in the Prometheus text exposition
+// format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var b bytes.Buffer
+		r.mu.Lock()
+		collectors := append([]collector{}, r.collectors...)
+		r.mu.Unlock()
+		for _, c := range collectors {
+			c.write(&b)
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(b.Bytes())
+	})
+}
+
+// vec holds the children of a labelled metric, keyed by their label values.
+type vec struct {
+	name     string
+	help     string
+	kind     string
+	labels   []string
+	mu       sync.Mutex
+	children map[string]interface{}
+	values   map[string][]string
+}
+
+func newVec(name, help, kind string, labels []string) *vec {
+	return &vec{name: name, help: help, kind: kind, labels: labels, children: map[string]interface{}{}, values: map[string][]string{}}
+}
+
+func (v *vec) child(values []string, create func() interface{}) interface{} {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, ok := v.children[key]
+	if !ok {
+		c = create()
+		v.children[key] = c
+		v.values[key] = append([]string{}, values...)
+	}
+	return c
+}
+
+// each calls fn for every child, ordered by label values.
+func (v *vec) each(fn func(values []string, child interface{})) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.children))
+	for k := range v.children {
+		keys = append(keys, k)
+	}
+	v.mu.Unlock()
+	sort.Strings(keys)
+	for _, k := range keys {
+		v.mu.Lock()
+		values, c := v.values[k], v.children[k]
+		v.mu.Unlock()
+		fn(values, c)
+	}
+}
+
+func (v *vec) header(b *bytes.Buffer) {
+	writeHeader(b, v.name, v.help, v.kind)
+}
+
+func writeHeader(b *bytes.Buffer, name, help, kind string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+}
+
+// CounterVec is a set of counters partitioned by label values.
+type CounterVec struct {
+	*vec
+}
+
+type counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, "counter", labels)}
+	r.register(c, name)
+	return c
+}
+
+// Add increases the counter for values by delta, which must not be negative.
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic("metrics: counter " + c.name + " cannot decrease")
+	}
+	ctr := c.child(values, func() interface{} { return &counter{} }).(*counter)
+	ctr.mu.Lock()
+	ctr.value += delta
+	ctr.mu.Unlock()
+}
+
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *CounterVec) write(b *bytes.Buffer) {
+	c.header(b)
+	c.each(func(values []string, child interface{}) {
+		ctr := child.(*counter)
+		ctr.mu.Lock()
+		v := ctr.value
+		ctr.mu.Unlock()
+		writeSample(b, c.name, c.labels, values, "", v)
+	})
+}
+
+// HistogramVec is a set of histograms partitioned by label values.
+type HistogramVec struct {
+	*vec
+	buckets []float64
+}
+
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec registers a histogram with the given upper bucket bounds,
+// which must be sorted; a +Inf bucket is implied.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !sort.Float64sAreSorted(buckets) {
+		panic("metrics: buckets of " + name + " are not sorted")
+	}
+	h := &HistogramVec{vec: newVec(name, help, "histogram", labels), buckets: buckets}
+	r.register(h, name)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	hist := h.child(values, func() interface{} {
+		return &histogram{counts: make([]uint64, len(h.buckets))}
+	}).(*histogram)
+	hist.mu.Lock()
+	defer hist.mu.Unlock()
+	for i, bound := range h.buckets {
+		if v <= bound {
+			hist.counts[i]++
+			break
+		}
+	}
+	hist.count++
+	hist.sum += v
+}
+
+func (h *HistogramVec) write(b *bytes.Buffer) {
+	h.header(b)
+	labels := append(append([]string{}, h.labels...), "le")
+	h.each(func(values []string, child interface{}) {
+		hist := child.(*histogram)
+		hist.mu.Lock()
+		counts := append([]uint64{}, hist.counts...)
+		count, sum := hist.count, hist.sum
+		hist.mu.Unlock()
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += counts[i]
+			writeSample(b, h.name, labels, append(append([]string{}, values...), formatFloat(bound)), "_bucket", float64(cumulative))
+		}
+		writeSample(b, h.name, labels, append(append([]string{}, values...), "+Inf"), "_bucket", float64(count))
+		writeSample(b, h.name, h.labels, values, "_sum", sum)
+		writeSample(b, h.name, h.labels, values, "_count", float64(count))
+	})
+}
+
+// valueFunc is an unlabelled metric whose value is read when scraped.
+type valueFunc struct {
+	name, help, kind string
+	fn               func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn on every scrape.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&valueFunc{name: name, help: help, kind: "gauge", fn: fn}, name)
+}
+
+// NewCounterFunc registers a counter whose value is read from fn on every
+// scrape; fn must never return less than it did before.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&valueFunc{name: name, help: help, kind: "counter", fn: fn}, name)
+}
+
+func (v *valueFunc) write(b *bytes.Buffer) {
+	writeHeader(b, v.name, v.help, v.kind)
+	writeSample(b, v.name, nil, nil, "", v.fn())
+}
+
+func writeSample(b *bytes.Buffer, name string, labels, values []string, suffix string, v float64) {
+	b.WriteString(name)
+	b.WriteString(suffix)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", l, escapeLabel(values[i]))
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(v))
+	b.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, reg *Registry) string {
+	w := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Expected: text/plain; version=0.0.4, Got: %v", ct)
+	}
+	return w.Body.String()
+}
+
+func TestHandler(t *testing.T) {
+	reg := NewRegistry()
+	counter := reg.NewCounterVec("test_requests_total", "Requests.\nPer path.", "path")
+	counter.Inc("/b")
+	counter.Add(2, `/a"\`)
+	hist := reg.NewHistogramVec("test_duration_seconds", "Duration.", []float64{0.1, 1}, "path")
+	hist.Observe(0.05, "/a")
+	hist.Observe(0.5, "/a")
+	hist.Observe(3, "/a")
+	reg.NewGaugeFunc("test_open", "Open things.", func() float64 { return 4 })
+
+	expected := `# HELP test_requests_total Requests.\nPer path.
+# TYPE test_requests_total counter
+test_requests_total{path="/a\"\\"} 2
+test_requests_total{path="/b"} 1
+# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{path="/a",le="0.1"} 1
+test_duration_seconds_bucket{path="/a",le="1"} 2
+test_duration_seconds_bucket{path="/a",le="+Inf"} 3
+test_duration_seconds_sum{path="/a"} 3.55
+test_duration_seconds_count{path="/a"} 3
+# HELP test_open Open things.
+# TYPE test_open gauge
+test_open 4
+`
+	if got := scrape(t, reg); got != expected {
+		t.Errorf("Expected: %v, Got: %v", expected, got)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic registering a name twice")
+		}
+	}()
+	reg := NewRegistry()
+	reg.NewCounterVec("test_total", "Test.")
+	reg.NewGaugeFunc("test_total", "Test.", func() float64 { return 0 })
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	reg := NewRegistry()
+	r := mux.NewRouter()
+	r.Use(reg.HTTPMiddleware())
+	r.HandleFunc("/patient/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "9" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	r.HandleFunc("/patient/export", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic(http.ErrAbortHandler)
+	})
+	for _, target := range []string{"/patient/1", "/patient/2", "/patient/9", "/patient/export"} {
+		func() {
+			defer func() { recover() }()
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+		}()
+	}
+
+	got := scrape(t, reg)
+	for _, line := range []string{
+		`ppms_http_requests_total{route="/patient/{id:[0-9]+}",method="GET",code="200"} 2`,
+		`ppms_http_requests_total{route="/patient/{id:[0-9]+}",method="GET",code="404"} 1`,
+		`ppms_http_request_duration_seconds_count{route="/patient/{id:[0-9]+}",method="GET"} 3`,
+		`ppms_http_requests_total{route="/patient/export",method="GET",code="aborted"} 1`,
+		`ppms_http_request_duration_seconds_count{route="/patient/export",method="GET"} 1`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("Expected %q in:\n%v", line, got)
+		}
+	}
+}
+
+func TestRegisterDB(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(7)
+	reg := NewRegistry()
+	reg.RegisterDB(db)
+
+	got := scrape(t, reg)
+	for _, line := range []string{
+		"# TYPE ppms_db_open_connections gauge",
+		"ppms_db_max_open_connections 7",
+		"ppms_db_in_use_connections 0",
+		"# TYPE ppms_db_wait_count_total counter",
+		"ppms_db_wait_count_total 0",
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("Expected %q in:\n%v", line, got)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"context"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores"
+	"time"
+)
+
+// Store counts and times the calls made to another StoreInterface, by method
+// and by result ("ok" or "error").
+type Store struct {
+	next     stores.StoreInterface
+	calls    *CounterVec
+	duration *HistogramVec
+}
+
+var _ stores.StoreInterface = (*Store)(nil)
+
+func (r *Registry) NewStore(next stores.StoreInterface) *Store {
+	return &Store{
+		next:     next,
+		calls:    r.NewCounterVec("ppms_store_calls_total", "Patient store calls by method and result.", "method", "result"),
+		duration: r.NewHistogramVec("ppms_store_call_duration_seconds", "Patient store call latency by method.", DefaultBuckets, "method"),
+	}
+}
+
+func (s *Store) observe(method string, start time.Time, err error) {
+	s.duration.Observe(time.Since(start).Seconds(), method)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	s.calls.Inc(method, result)
+}
+
+func (s *Store) Insert(ctx context.Context, pt *models.Patient) (*models.Patient, error) {
+	start := time.Now()
+	created, err := s.next.Insert(ctx, pt)
+	s.observe("Insert", start, err)
+	return created, err
+}
+
+func (s *Store) GetByID(ctx context.Context, id int) (*models.Patient, error) {
+	start := time.Now()
+	pt, err := s.next.GetByID(ctx, id)
+	s.observe("GetByID", start, err)
+	return pt, err
+}
+
+func (s *Store) GetAll(ctx context.Context) ([]*models.Patient, error) {
+	start := time.Now()
+	patients, err := s.next.GetAll(ctx)
+	s.observe("GetAll", start, err)
+	return patients, err
+}
+
+// Each is observed once, over the whole iteration.
+func (s *Store) Each(ctx context.Context, fn func(*models.Patient) error) error {
+	start := time.Now()
+	err := s.next.Each(ctx, fn)
+	s.observe("Each", start, err)
+	return err
+}
+
+func (s *Store) List(ctx context.Context, opts models.ListOptions) (*models.PatientPage, error) {
+	start := time.Now()
+	page, err := s.next.List(ctx, opts)
+	s.observe("List", start, err)
+	return page, err
+}
+
+func (s *Store) Search(ctx context.Context, query string, opts models.ListOptions) (*models.PatientPage, error) {
+	start := time.Now()
+	page, err := s.next.Search(ctx, query, opts)
+	s.observe("Search", start, err)
+	return page, err
+}
+
+func (s *Store) Update(ctx context.Context, pt *models.Patient, id int) (*models.Patient, error) {
+	start := time.Now()
+	updated, err := s.next.Update(ctx, pt, id)
+	s.observe("Update", start, err)
+	return updated, err
+}
+
+func (s *Store) Patch(ctx context.Context, id int, version int, columns map[string]interface{}) (*models.Patient, error) {
+	start := time.Now()
+	patched, err := s.next.Patch(ctx, id, version, columns)
+	s.observe("Patch", start, err)
+	return patched, err
+}
+
+func (s *Store) Delete(ctx context.Context, id int, version int) error {
+	start := time.Now()
+	err := s.next.Delete(ctx, id, version)
+	s.observe("Delete", start, err)
+	return err
+}
+
+func (s *Store) Restore(ctx context.Context, id int) (*models.Patient, error) {
+	start := time.Now()
+	restored, err := s.next.Restore(ctx, id)
+	s.observe("Restore", start, err)
+	return restored, err
+}
+
+func (s *Store) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	start := time.Now()
+	n, err := s.next.Purge(ctx, deletedBefore)
+	s.observe("Purge", start, err)
+	return n, err
+}
+
+func (s *Store) History(ctx context.Context, id int) ([]*models.AuditEntry, error) {
+	start := time.Now()
+	entries, err := s.next.History(ctx, id)
+	s.observe("History", start, err)
+	return entries, err
+}
+
+func (s *Store) BulkInsert(ctx context.Context, pts []*models.Patient) ([]*models.Patient, error) {
+	start := time.Now()
+	created, err := s.next.BulkInsert(ctx, pts)
+	s.observe("BulkInsert", start, err)
+	return created, err
+}
+
+func (s *Store) BulkUpdate(ctx context.Context, pts []*models.Patient, mode models.BulkMode) ([]models.BulkResult, error) {
+	start := time.Now()
+	results, err := s.next.BulkUpdate(ctx, pts, mode)
+	s.observe("BulkUpdate", start, err)
+	return results, err
+}
+
+func (s *Store) BulkDelete(ctx context.Context, refs []models.PatientRef, mode models.BulkMode) ([]models.BulkResult, error) {
+	start := time.Now()
+	results, err := s.next.BulkDelete(ctx, refs, mode)
+	s.observe("BulkDelete", start, err)
+	return results, err
+}
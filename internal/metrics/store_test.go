@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores"
+	"github.com/golang/mock/gomock"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockStore := stores.NewMockStoreInterface(mockCtrl)
+	ctx := context.Background()
+	mockStore.EXPECT().GetByID(ctx, 1).Return(&models.Patient{Id: 1}, nil)
+	mockStore.EXPECT().GetByID(ctx, 2).Return(nil, errors.New("connection refused"))
+	mockStore.EXPECT().Delete(ctx, 1, 0).Return(nil)
+
+	reg := NewRegistry()
+	s := reg.NewStore(mockStore)
+	if pt, err := s.GetByID(ctx, 1); err != nil || pt.Id != 1 {
+		t.Errorf("Expected: patient 1, Got: %v, %v", pt, err)
+	}
+	if _, err := s.GetByID(ctx, 2); err == nil {
+		t.Errorf("Expected the store error")
+	}
+	if err := s.Delete(ctx, 1, 0); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	got := scrape(t, reg)
+	for _, line := range []string{
+		`ppms_store_calls_total{method="Delete",result="ok"} 1`,
+		`ppms_store_calls_total{method="GetByID",result="error"} 1`,
+		`ppms_store_calls_total{method="GetByID",result="ok"} 1`,
+		`ppms_store_call_duration_seconds_count{method="GetByID"} 2`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("Expected %q in:\n%v", line, got)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load reads the embedded NNNN_name.up.sql / NNNN_name.down.sql pairs ordered by version.
+func Load() ([]Migration, error) {
+	entries, err := files.ReadDir("sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql suffix", name)
+		}
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name prefix", name)
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", name, parts[0])
+		}
+		body, err := files.ReadFile(path.Join("sql", name))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = m
+		}
+		if m.Name != parts[1] {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, parts[1])
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d: missing up or down file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// ensureTable creates schema_migrations, which lists the applied versions, and
+// schema_migration_steps, which counts the statements of an unfinished
+// migration that already ran.
+func (m *Migrator) ensureTable() error {
+	query := "CREATE TABLE IF NOT EXISTS schema_migrations (version INT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, appliedat DATETIME NOT NULL)"
+	if _, err := m.db.Exec(query); err != nil {
+		return err
+	}
+	query = "CREATE TABLE IF NOT EXISTS schema_migration_steps (version INT NOT NULL, direction VARCHAR(4) NOT NULL, step INT NOT NULL, PRIMARY KEY (version, direction))"
+	_, err := m.db.Exec(query)
+	return err
+}
+
+func (m *Migrator) Version() (int, error) {
+	if err := m.ensureTable(); err != nil {
+		return 0, err
+	}
+	var version int
+	query := "select coalesce(max(version), 0) from schema_migrations"
+	if err := m.db.QueryRow(query).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Check reports an error unless the schema is at least at the latest embedded
+// version. Unlike Version it never creates the bookkeeping table, so it is safe
+// to call from a readiness probe.
+func (m *Migrator) Check(ctx context.Context) error {
+	var version int
+	query := "select coalesce(max(version), 0) from schema_migrations"
+	if err := m.db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return err
+	}
+	if latest := m.Latest(); version < latest {
+		return fmt.Errorf("schema at version %d, expected %d", version, latest)
+	}
+	return nil
+}
+
+// Up applies every migration newer than the current version and returns how many ran.
+func (m *Migrator) Up() (int, error) {
+	current, err := m.Version()
+	if err != nil {
+		return 0, err
+	}
+	applied := 0
+	for _, mg := range m.migrations {
+		if mg.Version <= current {
+			continue
+		}
+		query := "insert into schema_migrations (version, name, appliedat) values (?, ?, ?)"
+		if err := m.apply(mg.Version, "up", mg.Up, query, mg.Version, mg.Name, time.Now()); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// Down rolls back the given number of applied migrations, newest first.
+func (m *Migrator) Down(steps int) (int, error) {
+	current, err := m.Version()
+	if err != nil {
+		return 0, err
+	}
+	rolledBack := 0
+	for i := len(m.migrations) - 1; i >= 0 && rolledBack < steps; i-- {
+		mg := m.migrations[i]
+		if mg.Version > current {
+			continue
+		}
+		query := "delete from schema_migrations where version = ?"
+		if err := m.apply(mg.Version, "down", mg.Down, query, mg.Version); err != nil {
+			return rolledBack, err
+		}
+		rolledBack++
+	}
+	return rolledBack, nil
+}
+
+// apply runs the statements of script one by one. MySQL commits every DDL
+// statement on its own, so a migration cannot be rolled back as a whole;
+// instead each statement that succeeds is counted in schema_migration_steps,
+// and a migration that failed halfway resumes after the last one that ran.
+// Only the bookkeeping, which is plain DML, is committed atomically.
+func (m *Migrator) apply(version int, direction, script string, bookkeeping string, args ...interface{}) error {
+	var done int
+	query := "select step from schema_migration_steps where version=? and direction=?"
+	if err := m.db.QueryRow(query, version, direction).Scan(&done); err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("migration %d: %w", version, err)
+	}
+	for i, stmt := range statements(script) {
+		if i < done {
+			continue
+		}
+		if _, err := m.db.Exec(stmt); err != nil {
+			return fmt.Errorf("migration %d: statement %d: %w", version, i+1, err)
+		}
+		query := "insert into schema_migration_steps (version, direction, step) values (?, ?, ?) on duplicate key update step=values(step)"
+		if _, err := m.db.Exec(query, version, direction, i+1); err != nil {
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+	}
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(bookkeeping, args...); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d: %w", version, err)
+	}
+	if _, err := tx.Exec("delete from schema_migration_steps where version=?", version); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d: %w", version, err)
+	}
+	return tx.Commit()
+}
+
+func statements(script string) []string {
+	var stmts []string
+	for _, stmt := range strings.Split(script, ";") {
+		stmt = strings.TrimSpace(stmt)
+		if stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
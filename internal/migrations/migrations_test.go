@@ -0,0 +1,214 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const ensureTable = "CREATE TABLE IF NOT EXISTS schema_migrations (version INT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, appliedat DATETIME NOT NULL)"
+const ensureSteps = "CREATE TABLE IF NOT EXISTS schema_migration_steps (version INT NOT NULL, direction VARCHAR(4) NOT NULL, step INT NOT NULL, PRIMARY KEY (version, direction))"
+const currentVersion = "select coalesce(max(version), 0) from schema_migrations"
+const selectSteps = "select step from schema_migration_steps where version=? and direction=?"
+const recordStep = "insert into schema_migration_steps (version, direction, step) values (?, ?, ?) on duplicate key update step=values(step)"
+const clearSteps = "delete from schema_migration_steps where version=?"
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+	for i, m := range migrations {
+		if i > 0 && migrations[i-1].Version >= m.Version {
+			t.Errorf("migrations not ordered: %d before %d", migrations[i-1].Version, m.Version)
+		}
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("migration %d missing up or down script", m.Version)
+		}
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create_patient" {
+		t.Errorf("Expected: 1 create_patient, Got: %d %s", migrations[0].Version, migrations[0].Name)
+	}
+}
+
+func TestUp(t *testing.T) {
+	testMigrations := []Migration{
+		{Version: 1, Name: "one", Up: "CREATE TABLE a (id INT);", Down: "DROP TABLE a;"},
+		{Version: 2, Name: "two", Up: "CREATE TABLE b (id INT); CREATE TABLE c (id INT);", Down: "DROP TABLE c; DROP TABLE b;"},
+	}
+	tests := []struct {
+		desc        string
+		current     int
+		setup       func(mock sqlmock.Sqlmock)
+		applied     int
+		expectError error
+	}{
+		{
+			desc:    "applies pending",
+			current: 1,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectSteps).WithArgs(2, "up").WillReturnError(sql.ErrNoRows)
+				mock.ExpectExec("CREATE TABLE b (id INT)").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(recordStep).WithArgs(2, "up", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("CREATE TABLE c (id INT)").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(recordStep).WithArgs(2, "up", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectBegin()
+				mock.ExpectExec("insert into schema_migrations (version, name, appliedat) values (?, ?, ?)").
+					WithArgs(2, "two", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(clearSteps).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			applied: 1,
+		},
+		{
+			desc:    "resumes after the statements that ran",
+			current: 1,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectSteps).WithArgs(2, "up").WillReturnRows(sqlmock.NewRows([]string{"step"}).AddRow(1))
+				mock.ExpectExec("CREATE TABLE c (id INT)").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(recordStep).WithArgs(2, "up", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectBegin()
+				mock.ExpectExec("insert into schema_migrations (version, name, appliedat) values (?, ?, ?)").
+					WithArgs(2, "two", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(clearSteps).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			applied: 1,
+		},
+		{
+			desc:    "up to date",
+			current: 2,
+			setup:   func(mock sqlmock.Sqlmock) {},
+			applied: 0,
+		},
+		{
+			desc:    "failing statement keeps the statements before it",
+			current: 1,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectSteps).WithArgs(2, "up").WillReturnError(sql.ErrNoRows)
+				mock.ExpectExec("CREATE TABLE b (id INT)").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(recordStep).WithArgs(2, "up", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("CREATE TABLE c (id INT)").WillReturnError(errors.New("syntax error"))
+			},
+			applied:     0,
+			expectError: errors.New("migration 2: statement 2: syntax error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			mock.ExpectExec(ensureTable).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(ensureSteps).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(currentVersion).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(test.current))
+			test.setup(mock)
+
+			m := &Migrator{db: db, migrations: testMigrations}
+			applied, err := m.Up()
+			if (err == nil) != (test.expectError == nil) || (err != nil && err.Error() != test.expectError.Error()) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if applied != test.applied {
+				t.Errorf("Expected: %v, Got: %v", test.applied, applied)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDown(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	mock.ExpectExec(ensureTable).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(ensureSteps).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(currentVersion).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	mock.ExpectQuery(selectSteps).WithArgs(2, "down").WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("DROP TABLE b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(recordStep).WithArgs(2, "down", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec("delete from schema_migrations where version = ?").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(clearSteps).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	m := &Migrator{db: db, migrations: []Migration{
+		{Version: 1, Name: "one", Up: "CREATE TABLE a (id INT)", Down: "DROP TABLE a"},
+		{Version: 2, Name: "two", Up: "CREATE TABLE b (id INT)", Down: "DROP TABLE b"},
+	}}
+	n, err := m.Down(1)
+	if err != nil || n != 1 {
+		t.Errorf("Expected: 1 <nil>, Got: %v %v", n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		desc        string
+		setup       func(mock sqlmock.Sqlmock)
+		expectError error
+	}{
+		{
+			desc: "up to date",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(currentVersion).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+			},
+		},
+		{
+			desc: "ahead of the binary",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(currentVersion).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+			},
+		},
+		{
+			desc: "behind",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(currentVersion).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+			},
+			expectError: errors.New("schema at version 1, expected 2"),
+		},
+		{
+			desc: "no bookkeeping table",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(currentVersion).WillReturnError(errors.New("table doesn't exist"))
+			},
+			expectError: errors.New("table doesn't exist"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			test.setup(mock)
+
+			m := &Migrator{db: db, migrations: []Migration{{Version: 1}, {Version: 2}}}
+			err = m.Check(context.Background())
+			if (err == nil) != (test.expectError == nil) || (err != nil && err.Error() != test.expectError.Error()) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS patient (
+    id          INT          NOT NULL AUTO_INCREMENT,
+    name        VARCHAR(255) NOT NULL,
+    phone       VARCHAR(32)  NOT NULL DEFAULT '',
+    discharge   BOOLEAN      NOT NULL DEFAULT FALSE,
+    createdat   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    udatedat    DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deletedat   DATETIME     NULL DEFAULT NULL,
+    bloodgroup  VARCHAR(8)   NOT NULL DEFAULT '',
+    description TEXT         NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_patient_deletedat (deletedat)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,4 @@
+DROP INDEX idx_patient_filters ON patient;
+DROP INDEX idx_patient_udatedat ON patient;
+DROP INDEX idx_patient_createdat ON patient;
+DROP INDEX idx_patient_name ON patient;
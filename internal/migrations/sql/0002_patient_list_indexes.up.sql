@@ -0,0 +1,4 @@
+CREATE INDEX idx_patient_name ON patient (name, id);
+CREATE INDEX idx_patient_createdat ON patient (createdat, id);
+CREATE INDEX idx_patient_udatedat ON patient (udatedat, id);
+CREATE INDEX idx_patient_filters ON patient (discharge, bloodgroup);
@@ -0,0 +1 @@
+CREATE FULLTEXT INDEX ft_patient_name_description ON patient (name, description);
@@ -0,0 +1 @@
+ALTER TABLE patient ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS patient_audit_no_delete;
+DROP TRIGGER IF EXISTS patient_audit_no_update;
+DROP TABLE IF EXISTS patient_audit;
@@ -0,0 +1,11 @@
+CREATE TABLE patient_audit (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    patientid INT NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    operation VARCHAR(16) NOT NULL,
+    changes JSON NOT NULL,
+    createdat DATETIME(6) NOT NULL,
+    INDEX idx_patient_audit_patient (patientid, id)
+);
+CREATE TRIGGER patient_audit_no_update BEFORE UPDATE ON patient_audit FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'patient_audit is append-only';
+CREATE TRIGGER patient_audit_no_delete BEFORE DELETE ON patient_audit FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'patient_audit is append-only';
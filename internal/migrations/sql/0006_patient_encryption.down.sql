@@ -0,0 +1,3 @@
+DROP INDEX idx_patient_phoneindex ON patient;
+ALTER TABLE patient DROP COLUMN phoneindex;
+ALTER TABLE patient MODIFY phone VARCHAR(32) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE patient MODIFY phone VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE patient ADD COLUMN phoneindex CHAR(64) NULL DEFAULT NULL AFTER phone;
+CREATE INDEX idx_patient_phoneindex ON patient (phoneindex);
@@ -0,0 +1,15 @@
+CREATE TABLE encounter (
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    patientid INT NOT NULL,
+    admittedat DATETIME NOT NULL,
+    dischargedat DATETIME NULL DEFAULT NULL,
+    reason TEXT NOT NULL,
+    doctor VARCHAR(100) NOT NULL DEFAULT '',
+    ward VARCHAR(50) NOT NULL DEFAULT '',
+    version INT NOT NULL DEFAULT 1,
+    openpatientid INT AS (IF(dischargedat IS NULL, patientid, NULL)) STORED,
+    UNIQUE KEY uq_encounter_open (openpatientid),
+    INDEX idx_encounter_patient (patientid, admittedat),
+    CONSTRAINT fk_encounter_patient FOREIGN KEY (patientid) REFERENCES patient (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+INSERT INTO encounter (patientid, admittedat, reason) SELECT id, createdat, '' FROM patient WHERE discharge = FALSE;
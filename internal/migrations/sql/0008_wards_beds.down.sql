@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS bedassignment;
+DROP TABLE IF EXISTS bed;
+DROP TABLE IF EXISTS ward;
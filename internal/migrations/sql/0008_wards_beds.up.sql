@@ -0,0 +1,27 @@
+CREATE TABLE ward (
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    createdat DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_ward_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE bed (
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    wardid INT NOT NULL,
+    label VARCHAR(20) NOT NULL,
+    UNIQUE KEY uq_bed_label (wardid, label),
+    CONSTRAINT fk_bed_ward FOREIGN KEY (wardid) REFERENCES ward (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE bedassignment (
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    bedid INT NOT NULL,
+    patientid INT NOT NULL,
+    assignedat DATETIME NOT NULL,
+    releasedat DATETIME NULL DEFAULT NULL,
+    openbedid INT AS (IF(releasedat IS NULL, bedid, NULL)) STORED,
+    openpatientid INT AS (IF(releasedat IS NULL, patientid, NULL)) STORED,
+    UNIQUE KEY uq_bedassignment_bed (openbedid),
+    UNIQUE KEY uq_bedassignment_patient (openpatientid),
+    INDEX idx_bedassignment_patient (patientid, assignedat),
+    CONSTRAINT fk_bedassignment_bed FOREIGN KEY (bedid) REFERENCES bed (id),
+    CONSTRAINT fk_bedassignment_patient FOREIGN KEY (patientid) REFERENCES patient (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
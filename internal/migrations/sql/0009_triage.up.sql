@@ -0,0 +1,13 @@
+CREATE TABLE triage (
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    patientid INT NOT NULL,
+    level TINYINT NOT NULL,
+    arrivedat DATETIME NOT NULL,
+    triagedat DATETIME NOT NULL,
+    calledat DATETIME NULL DEFAULT NULL,
+    waitingpatientid INT AS (IF(calledat IS NULL, patientid, NULL)) STORED,
+    UNIQUE KEY uq_triage_waiting (waitingpatientid),
+    INDEX idx_triage_queue (calledat, level, arrivedat),
+    CONSTRAINT chk_triage_level CHECK (level BETWEEN 1 AND 5),
+    CONSTRAINT fk_triage_patient FOREIGN KEY (patientid) REFERENCES patient (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
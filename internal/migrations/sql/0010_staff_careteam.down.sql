@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS careteam;
+DROP TABLE IF EXISTS staff;
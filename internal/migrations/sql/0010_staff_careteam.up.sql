@@ -0,0 +1,25 @@
+CREATE TABLE staff (
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    profession VARCHAR(16) NOT NULL,
+    specialty VARCHAR(100) NOT NULL DEFAULT '',
+    phone VARCHAR(16) NOT NULL DEFAULT '',
+    email VARCHAR(254) NOT NULL DEFAULT '',
+    createdat DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updatedat DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deletedat DATETIME NULL DEFAULT NULL,
+    version INT NOT NULL DEFAULT 1,
+    INDEX idx_staff_name (deletedat, name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE careteam (
+    patientid INT NOT NULL,
+    staffid INT NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    assignedat DATETIME NOT NULL,
+    solerole VARCHAR(16) AS (IF(role = 'consulting', NULL, role)) STORED,
+    PRIMARY KEY (patientid, staffid),
+    UNIQUE KEY uq_careteam_sole_role (patientid, solerole),
+    INDEX idx_careteam_staff (staffid),
+    CONSTRAINT fk_careteam_patient FOREIGN KEY (patientid) REFERENCES patient (id) ON DELETE CASCADE,
+    CONSTRAINT fk_careteam_staff FOREIGN KEY (staffid) REFERENCES staff (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
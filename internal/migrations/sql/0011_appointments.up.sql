@@ -0,0 +1,14 @@
+CREATE TABLE appointment (
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    patientid INT NOT NULL,
+    staffid INT NOT NULL,
+    startat DATETIME NOT NULL,
+    endat DATETIME NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'booked',
+    version INT NOT NULL DEFAULT 1,
+    INDEX idx_appointment_staff (staffid, startat),
+    INDEX idx_appointment_patient (patientid, startat),
+    CONSTRAINT chk_appointment_times CHECK (endat > startat),
+    CONSTRAINT fk_appointment_patient FOREIGN KEY (patientid) REFERENCES patient (id) ON DELETE CASCADE,
+    CONSTRAINT fk_appointment_staff FOREIGN KEY (staffid) REFERENCES staff (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
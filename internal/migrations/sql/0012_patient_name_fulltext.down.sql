@@ -0,0 +1 @@
+DROP INDEX ft_patient_name ON patient;
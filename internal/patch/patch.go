@@ -0,0 +1,332 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	// MergePatch is RFC 7386 JSON Merge Patch.
+	MergePatch Format = "application/merge-patch+json"
+	// JSONPatch is RFC 6902 JSON Patch.
+	JSONPatch Format = "application/json-patch+json"
+)
+
+type Operation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from,omitempty"`
+	Value *json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies patch, encoded in the given format, to the JSON document doc.
+func Apply(format Format, doc []byte, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	switch format {
+	case MergePatch:
+		var p interface{}
+		if err := json.Unmarshal(patch, &p); err != nil {
+			return nil, perrors.NewValidation("patch", "must be a JSON document")
+		}
+		target = Merge(target, p)
+	case JSONPatch:
+		var ops []Operation
+		if err := json.Unmarshal(patch, &ops); err != nil {
+			return nil, perrors.NewValidation("patch", "must be an array of JSON Patch operations")
+		}
+		var err error
+		if target, err = ApplyOperations(target, ops); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported patch format %q", format)
+	}
+	return json.Marshal(target)
+}
+
+// Members returns the top-level members of the document that patch reads or
+// writes, without applying it.
+func Members(format Format, patch []byte) ([]string, error) {
+	seen := map[string]bool{}
+	var members []string
+	addPointer := func(pointer string) error {
+		tokens, err := parsePointer(pointer)
+		if err != nil {
+			return perrors.NewValidation("patch", err.Error())
+		}
+		if len(tokens) == 0 {
+			tokens = []string{""}
+		}
+		if !seen[tokens[0]] {
+			seen[tokens[0]] = true
+			members = append(members, tokens[0])
+		}
+		return nil
+	}
+	switch format {
+	case MergePatch:
+		var p map[string]json.RawMessage
+		if err := json.Unmarshal(patch, &p); err != nil {
+			return nil, perrors.NewValidation("patch", "must be a JSON object")
+		}
+		for name := range p {
+			members = append(members, name)
+		}
+		sort.Strings(members)
+	case JSONPatch:
+		var ops []Operation
+		if err := json.Unmarshal(patch, &ops); err != nil {
+			return nil, perrors.NewValidation("patch", "must be an array of JSON Patch operations")
+		}
+		for _, op := range ops {
+			if err := addPointer(op.Path); err != nil {
+				return nil, err
+			}
+			if op.Op == "move" || op.Op == "copy" {
+				if err := addPointer(op.From); err != nil {
+					return nil, err
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported patch format %q", format)
+	}
+	return members, nil
+}
+
+// Merge implements the MergePatch algorithm from RFC 7386 section 2.
+func Merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+			continue
+		}
+		t[name] = Merge(t[name], value)
+	}
+	return t
+}
+
+func ApplyOperations(doc interface{}, ops []Operation) (interface{}, error) {
+	var err error
+	for i, op := range ops {
+		field := fmt.Sprintf("patch[%d]", i)
+		var value interface{}
+		if op.Value != nil {
+			if err := json.Unmarshal(*op.Value, &value); err != nil {
+				return nil, perrors.NewValidation(field, "value is not valid JSON")
+			}
+		}
+		switch op.Op {
+		case "add":
+			if op.Value == nil {
+				return nil, perrors.NewValidation(field, "add requires a value")
+			}
+			doc, err = add(doc, op.Path, value)
+		case "remove":
+			doc, _, err = remove(doc, op.Path)
+		case "replace":
+			if op.Value == nil {
+				return nil, perrors.NewValidation(field, "replace requires a value")
+			}
+			if op.Path == "" {
+				doc = value
+			} else if doc, _, err = remove(doc, op.Path); err == nil {
+				doc, err = add(doc, op.Path, value)
+			}
+		case "move":
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, perrors.NewValidation(field, "cannot move a value into one of its children")
+			}
+			var moved interface{}
+			if doc, moved, err = remove(doc, op.From); err == nil {
+				doc, err = add(doc, op.Path, moved)
+			}
+		case "copy":
+			var copied interface{}
+			if copied, err = get(doc, op.From); err == nil {
+				doc, err = add(doc, op.Path, copied)
+			}
+		case "test":
+			if op.Value == nil {
+				return nil, perrors.NewValidation(field, "test requires a value")
+			}
+			var current interface{}
+			if current, err = get(doc, op.Path); err == nil && !reflect.DeepEqual(current, value) {
+				return nil, &perrors.Conflict{Entity: "patient", Reason: fmt.Sprintf("test failed at %s", op.Path)}
+			}
+		default:
+			return nil, perrors.NewValidation(field, fmt.Sprintf("unknown op %q", op.Op))
+		}
+		if err != nil {
+			return nil, perrors.NewValidation(field, err.Error())
+		}
+	}
+	return doc, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i > length || (!allowEnd && i == length) {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+func get(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	current := doc
+	for _, t := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			v, ok := node[t]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", pointer)
+			}
+			current = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[i]
+		default:
+			return nil, fmt.Errorf("path %q does not exist", pointer)
+		}
+	}
+	return current, nil
+}
+
+// update walks to the parent of pointer and replaces it with fn's result.
+func update(doc interface{}, pointer string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return fn(nil, "")
+	}
+	parentPath := ""
+	for _, t := range tokens[:len(tokens)-1] {
+		parentPath += "/" + strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1")
+	}
+	parent, err := get(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	newParent, err := fn(parent, tokens[len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return newParent, nil
+	}
+	return update(doc, parentPath, func(grandparent interface{}, key string) (interface{}, error) {
+		return set(grandparent, key, newParent)
+	})
+}
+
+func set(parent interface{}, key string, value interface{}) (interface{}, error) {
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[key] = value
+		return node, nil
+	case []interface{}:
+		i, err := arrayIndex(key, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = value
+		return node, nil
+	}
+	return nil, fmt.Errorf("cannot set %q on a scalar", key)
+}
+
+func add(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	return update(doc, pointer, func(parent interface{}, key string) (interface{}, error) {
+		if parent == nil && key == "" {
+			return value, nil
+		}
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[key] = value
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("cannot add %q to a scalar", key)
+	})
+}
+
+func remove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	var removed interface{}
+	result, err := update(doc, pointer, func(parent interface{}, key string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			v, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", pointer)
+			}
+			removed = v
+			delete(node, key)
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[i]
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove the whole document")
+	})
+	return result, removed, err
+}
@@ -0,0 +1,143 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"reflect"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Examples from RFC 7386 appendix A.
+	tests := []struct {
+		original string
+		patch    string
+		expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.patch, func(t *testing.T) {
+			out, err := Apply(MergePatch, []byte(test.original), []byte(test.patch))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertJSONEqual(t, test.expected, out)
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	// Mostly examples from RFC 6902 appendix A.
+	tests := []struct {
+		desc     string
+		original string
+		patch    string
+		expected string
+		err      error
+	}{
+		{desc: "add object member", original: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":"qux"}]`, expected: `{"baz":"qux","foo":"bar"}`},
+		{desc: "add array element", original: `{"foo":["bar","baz"]}`, patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`, expected: `{"foo":["bar","qux","baz"]}`},
+		{desc: "append array element", original: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/-","value":"qux"}]`, expected: `{"foo":["bar","qux"]}`},
+		{desc: "remove object member", original: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, expected: `{"foo":"bar"}`},
+		{desc: "remove array element", original: `{"foo":["bar","qux","baz"]}`, patch: `[{"op":"remove","path":"/foo/1"}]`, expected: `{"foo":["bar","baz"]}`},
+		{desc: "replace", original: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":"boo"}]`, expected: `{"baz":"boo","foo":"bar"}`},
+		{desc: "move", original: `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, expected: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{desc: "move array element", original: `{"foo":["all","grass","cows","eat"]}`, patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, expected: `{"foo":["all","cows","eat","grass"]}`},
+		{desc: "copy", original: `{"a":{"b":1}}`, patch: `[{"op":"copy","from":"/a","path":"/c"}]`, expected: `{"a":{"b":1},"c":{"b":1}}`},
+		{desc: "test success", original: `{"baz":"qux","foo":["a",2,"c"]}`, patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, expected: `{"baz":"qux","foo":["a",2,"c"]}`},
+		{desc: "escaped pointer", original: `{"/":9,"~1":10}`, patch: `[{"op":"replace","path":"/~01","value":11}]`, expected: `{"/":9,"~1":11}`},
+		{desc: "nested add", original: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, expected: `{"foo":"bar","child":{"grandchild":{}}}`},
+		{desc: "test failure", original: `{"baz":"qux"}`, patch: `[{"op":"test","path":"/baz","value":"bar"}]`, err: &perrors.Conflict{}},
+		{desc: "missing target", original: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`, err: &perrors.Validation{}},
+		{desc: "remove missing", original: `{"foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, err: &perrors.Validation{}},
+		{desc: "index out of range", original: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/5","value":1}]`, err: &perrors.Validation{}},
+		{desc: "unknown op", original: `{"foo":"bar"}`, patch: `[{"op":"nope","path":"/foo"}]`, err: &perrors.Validation{}},
+		{desc: "not an array", original: `{"foo":"bar"}`, patch: `{"op":"remove","path":"/foo"}`, err: &perrors.Validation{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			out, err := Apply(JSONPatch, []byte(test.original), []byte(test.patch))
+			if test.err != nil {
+				if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+					t.Fatalf("expected %T, got: %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertJSONEqual(t, test.expected, out)
+		})
+	}
+}
+
+func TestApplyUnknownFormat(t *testing.T) {
+	_, err := Apply(Format("text/plain"), []byte(`{}`), []byte(`{}`))
+	var verr *perrors.Validation
+	if err == nil || errors.As(err, &verr) {
+		t.Errorf("expected a plain error for an unsupported format, got: %v", err)
+	}
+}
+
+func TestMembers(t *testing.T) {
+	tests := []struct {
+		desc        string
+		format      Format
+		patch       string
+		expected    []string
+		expectError bool
+	}{
+		{desc: "merge", format: MergePatch, patch: `{"phone": "1", "discharge": true}`, expected: []string{"discharge", "phone"}},
+		{desc: "merge of non object", format: MergePatch, patch: `[1]`, expectError: true},
+		{
+			desc:     "json patch",
+			format:   JSONPatch,
+			patch:    `[{"op": "replace", "path": "/phone", "value": "1"}, {"op": "copy", "from": "/description", "path": "/name"}, {"op": "test", "path": "/phone", "value": "1"}]`,
+			expected: []string{"phone", "name", "description"},
+		},
+		{desc: "whole document", format: JSONPatch, patch: `[{"op": "replace", "path": "", "value": {}}]`, expected: []string{""}},
+		{desc: "bad pointer", format: JSONPatch, patch: `[{"op": "remove", "path": "phone"}]`, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			members, err := Members(test.format, []byte(test.patch))
+			if (err != nil) != test.expectError {
+				t.Fatalf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if !test.expectError && !reflect.DeepEqual(members, test.expected) {
+				t.Errorf("Expected: %v, Got: %v", test.expected, members)
+			}
+		})
+	}
+}
+
+func assertJSONEqual(t *testing.T, expected string, actual []byte) {
+	t.Helper()
+	var e, a interface{}
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(actual, &a); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected: %s, Got: %s", expected, actual)
+	}
+}
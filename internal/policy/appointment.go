@@ -0,0 +1,69 @@
+package policy
+
+import (
+	"context"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+	"time"
+)
+
+// Appointments enforces a Policy in front of an AppointmentInterface. Reading
+// appointments and availability takes the Read action; booking, rescheduling
+// and cancelling take the Update action.
+type Appointments struct {
+	next   service.AppointmentInterface
+	policy Policy
+}
+
+var _ service.AppointmentInterface = (*Appointments)(nil)
+
+func NewAppointments(next service.AppointmentInterface, policy Policy) *Appointments {
+	return &Appointments{next: next, policy: policy}
+}
+
+func (s *Appointments) authorize(ctx context.Context, a Action) error {
+	_, err := s.policy.authorize(ctx, a)
+	return err
+}
+
+func (s *Appointments) List(ctx context.Context, f models.AppointmentFilter) ([]*models.Appointment, error) {
+	if err := s.authorize(ctx, Read); err != nil {
+		return nil, err
+	}
+	return s.next.List(ctx, f)
+}
+
+func (s *Appointments) Get(ctx context.Context, id int) (*models.Appointment, error) {
+	if err := s.authorize(ctx, Read); err != nil {
+		return nil, err
+	}
+	return s.next.Get(ctx, id)
+}
+
+func (s *Appointments) Book(ctx context.Context, a *models.Appointment) (*models.Appointment, error) {
+	if err := s.authorize(ctx, Update); err != nil {
+		return nil, err
+	}
+	return s.next.Book(ctx, a)
+}
+
+func (s *Appointments) Reschedule(ctx context.Context, a *models.Appointment) (*models.Appointment, error) {
+	if err := s.authorize(ctx, Update); err != nil {
+		return nil, err
+	}
+	return s.next.Reschedule(ctx, a)
+}
+
+func (s *Appointments) Cancel(ctx context.Context, id, version int) (*models.Appointment, error) {
+	if err := s.authorize(ctx, Update); err != nil {
+		return nil, err
+	}
+	return s.next.Cancel(ctx, id, version)
+}
+
+func (s *Appointments) Availability(ctx context.Context, staffID int, from, to time.Time, length time.Duration) ([]*models.Slot, error) {
+	if err := s.authorize(ctx, Read); err != nil {
+		return nil, err
+	}
+	return s.next.Availability(ctx, staffID, from, to, length)
+}
@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"context"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+)
+
+// Encounters enforces a Policy in front of an EncounterInterface. Reading
+// encounters takes the Read action and read access to discharge; admitting,
+// changing and discharging them takes the Update action and write access to
+// discharge. An encounter's reason is guarded like the description.
+type Encounters struct {
+	next   service.EncounterInterface
+	policy Policy
+}
+
+var _ service.EncounterInterface = (*Encounters)(nil)
+
+func NewEncounters(next service.EncounterInterface, policy Policy) *Encounters {
+	return &Encounters{next: next, policy: policy}
+}
+
+func (s *Encounters) authorize(ctx context.Context, a Action) (*grant, error) {
+	g, err := s.policy.authorize(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	if a == Read && !g.canRead("discharge") {
+		return nil, &perrors.Forbidden{Action: "read encounters"}
+	}
+	if a == Update && !g.canWrite("discharge") {
+		return nil, &perrors.Forbidden{Action: "admit or discharge patients"}
+	}
+	return g, nil
+}
+
+func (g *grant) redactEncounter(e *models.Encounter) *models.Encounter {
+	if e == nil || g.canRead("description") {
+		return e
+	}
+	copied := *e
+	copied.Reason = ""
+	return &copied
+}
+
+func (s *Encounters) List(ctx context.Context, patientID int) ([]*models.Encounter, error) {
+	g, err := s.authorize(ctx, Read)
+	if err != nil {
+		return nil, err
+	}
+	encounters, err := s.next.List(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range encounters {
+		encounters[i] = g.redactEncounter(encounters[i])
+	}
+	return encounters, nil
+}
+
+func (s *Encounters) Get(ctx context.Context, patientID, id int) (*models.Encounter, error) {
+	g, err := s.authorize(ctx, Read)
+	if err != nil {
+		return nil, err
+	}
+	e, err := s.next.Get(ctx, patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	return g.redactEncounter(e), nil
+}
+
+// Admit requires write access to the description to give a reason.
+func (s *Encounters) Admit(ctx context.Context, e *models.Encounter) (*models.Encounter, error) {
+	g, err := s.authorize(ctx, Update)
+	if err != nil {
+		return nil, err
+	}
+	if e.Reason != "" && !g.canWrite("description") {
+		return nil, &perrors.Forbidden{Action: "write", Fields: []string{"reason"}}
+	}
+	created, err := s.next.Admit(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	return g.redactEncounter(created), nil
+}
+
+// Update requires write access to the description to change the reason. A
+// caller who cannot read the reason keeps the stored one.
+func (s *Encounters) Update(ctx context.Context, e *models.Encounter) (*models.Encounter, error) {
+	g, err := s.authorize(ctx, Update)
+	if err != nil {
+		return nil, err
+	}
+	if !g.canWrite("description") {
+		current, err := s.next.Get(ctx, e.PatientID, e.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !g.canRead("description") {
+			e.Reason = current.Reason
+		} else if e.Reason != current.Reason {
+			return nil, &perrors.Forbidden{Action: "write", Fields: []string{"reason"}}
+		}
+	}
+	updated, err := s.next.Update(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	return g.redactEncounter(updated), nil
+}
+
+func (s *Encounters) Discharge(ctx context.Context, patientID, id int, version int) (*models.Encounter, error) {
+	g, err := s.authorize(ctx, Update)
+	if err != nil {
+		return nil, err
+	}
+	e, err := s.next.Discharge(ctx, patientID, id, version)
+	if err != nil {
+		return nil, err
+	}
+	return g.redactEncounter(e), nil
+}
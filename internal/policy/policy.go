@@ -0,0 +1,199 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aakanksha/ppms/internal/auth"
+	"github.com/aakanksha/ppms/internal/models"
+	"os"
+)
+
+type Action string
+
+const (
+	// Read covers fetching, listing and searching live patients.
+	Read   Action = "read"
+	Create Action = "create"
+	// Update covers both PUT and PATCH.
+	Update Action = "update"
+	Delete Action = "delete"
+	// Restore covers listing deleted patients and restoring them.
+	Restore Action = "restore"
+	Purge   Action = "purge"
+	History Action = "history"
+	// ManageWards covers creating wards and adding beds to them.
+	ManageWards Action = "wards"
+	// ManageStaff covers adding, changing and removing staff.
+	ManageStaff Action = "staff"
+)
+
+var actions = map[Action]bool{Read: true, Create: true, Update: true, Delete: true, Restore: true, Purge: true, History: true, ManageWards: true, ManageStaff: true}
+
+// Role lists the actions a role may perform and the patient fields, by JSON
+// name, it may read and write. "*" stands for every action or field.
+type Role struct {
+	Actions []Action `json:"actions"`
+	Read    []string `json:"read"`
+	Write   []string `json:"write"`
+}
+
+// Policy maps role names to their rights. A principal holding several roles
+// gets the union of them.
+type Policy map[string]Role
+
+// patientFields gives access to the access-controlled fields of a patient;
+// id, timestamps and version are always visible and never writable.
+var patientFields = map[string]func(pt *models.Patient) interface{}{
+	"name":        func(pt *models.Patient) interface{} { return &pt.Name },
+	"phone":       func(pt *models.Patient) interface{} { return &pt.Phone },
+	"discharge":   func(pt *models.Patient) interface{} { return &pt.Discharge },
+	"bloodGroup":  func(pt *models.Patient) interface{} { return &pt.BloodGroup },
+	"description": func(pt *models.Patient) interface{} { return &pt.Description },
+}
+
+// auditedAs maps the encounter fields recorded in a patient's audit trail to
+// the patient field that guards them: an encounter's reason is a clinical
+// note like the description, and a bed tells where the patient was admitted.
+var auditedAs = map[string]string{"encounter.reason": "description", "bed": "discharge"}
+
+// derivedFields are maintained from other records and ignored when a patient
+// is written: discharge follows the patient's encounters. Write access to
+// discharge is what allows admitting and discharging patients.
+var derivedFields = map[string]bool{"discharge": true}
+
+// Default is the built-in policy: receptionists register patients but never
+// see clinical notes, nurses maintain contact and discharge details, only
+// doctors write clinical notes and only admins delete, restore and purge
+// patients, set up wards and maintain the staff directory.
+func Default() Policy {
+	return Policy{
+		"receptionist": {
+			Actions: []Action{Read, Create, Update},
+			Read:    []string{"name", "phone", "discharge", "bloodGroup"},
+			Write:   []string{"name", "phone"},
+		},
+		"nurse": {
+			Actions: []Action{Read, Update, History},
+			Read:    []string{"*"},
+			Write:   []string{"phone", "discharge", "bloodGroup"},
+		},
+		"doctor": {
+			Actions: []Action{Read, Create, Update, History},
+			Read:    []string{"*"},
+			Write:   []string{"*"},
+		},
+		"admin": {
+			Actions: []Action{Read, Create, Update, Delete, Restore, Purge, History, ManageWards, ManageStaff},
+			Read:    []string{"*"},
+			Write:   []string{"name", "phone", "discharge", "bloodGroup"},
+		},
+	}
+}
+
+// Load reads a policy from a JSON object of role name to Role.
+func Load(path string) (Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	for name, role := range p {
+		for _, a := range role.Actions {
+			if a != "*" && !actions[a] {
+				return nil, fmt.Errorf("role %q: unknown action %q", name, a)
+			}
+		}
+		for _, f := range append(append([]string{}, role.Read...), role.Write...) {
+			if _, ok := patientFields[f]; f != "*" && !ok {
+				return nil, fmt.Errorf("role %q: unknown field %q", name, f)
+			}
+		}
+	}
+	return p, nil
+}
+
+// grant is what a policy allows one principal.
+type grant struct {
+	unrestricted bool
+	actions      map[Action]bool
+	read         map[string]bool
+	write        map[string]bool
+}
+
+// grantFor resolves the rights of p. Requests without a principal only occur
+// when authentication is switched off, and are not restricted.
+func (pol Policy) grantFor(p *auth.Principal) *grant {
+	if p == nil {
+		return &grant{unrestricted: true}
+	}
+	g := &grant{actions: map[Action]bool{}, read: map[string]bool{}, write: map[string]bool{}}
+	for _, name := range p.Roles {
+		role, ok := pol[name]
+		if !ok {
+			continue
+		}
+		for _, a := range role.Actions {
+			g.actions[a] = true
+		}
+		for _, f := range role.Read {
+			g.read[f] = true
+		}
+		for _, f := range role.Write {
+			g.write[f] = true
+		}
+	}
+	return g
+}
+
+func (g *grant) can(a Action) bool {
+	return g.unrestricted || g.actions[a] || g.actions["*"]
+}
+
+func (g *grant) canRead(field string) bool {
+	return g.unrestricted || g.read[field] || g.read["*"]
+}
+
+func (g *grant) canWrite(field string) bool {
+	return g.unrestricted || (g.write[field] || g.write["*"]) && g.canRead(field)
+}
+
+// redact returns a copy of pt with the fields g may not read cleared.
+func (g *grant) redact(pt *models.Patient) *models.Patient {
+	if pt == nil || g.unrestricted {
+		return pt
+	}
+	copied := *pt
+	for name, field := range patientFields {
+		if !g.canRead(name) {
+			switch v := field(&copied).(type) {
+			case *string:
+				*v = ""
+			case *bool:
+				*v = false
+			}
+		}
+	}
+	return &copied
+}
+
+func fieldValue(pt *models.Patient, name string) interface{} {
+	switch v := patientFields[name](pt).(type) {
+	case *string:
+		return *v
+	case *bool:
+		return *v
+	}
+	return nil
+}
+
+func copyField(dst, src *models.Patient, name string) {
+	switch v := patientFields[name](dst).(type) {
+	case *string:
+		*v = fieldValue(src, name).(string)
+	case *bool:
+		*v = fieldValue(src, name).(bool)
+	}
+}
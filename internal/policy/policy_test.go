@@ -0,0 +1,581 @@
+package policy
+
+import (
+	"context"
+	"github.com/aakanksha/ppms/internal/auth"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/patch"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/aakanksha/ppms/internal/transfer"
+	"github.com/golang/mock/gomock"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func as(roles ...string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "someone", Roles: roles})
+}
+
+func stored() *models.Patient {
+	return &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", BloodGroup: "A+", Description: "asthma", Version: 2}
+}
+
+func TestActions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	next := service.NewMockServiceInterface(mockCtrl)
+	s := New(next, Default())
+
+	tests := []struct {
+		desc        string
+		ctx         context.Context
+		setup       func()
+		call        func(ctx context.Context) error
+		expectError error
+	}{
+		{
+			desc:  "admin deletes",
+			ctx:   as("admin"),
+			setup: func() { next.EXPECT().Delete(gomock.Any(), 1, 0).Return(nil) },
+			call:  func(ctx context.Context) error { return s.Delete(ctx, 1, 0) },
+		},
+		{
+			desc:        "doctor may not delete",
+			ctx:         as("doctor"),
+			setup:       func() {},
+			call:        func(ctx context.Context) error { return s.Delete(ctx, 1, 0) },
+			expectError: &perrors.Forbidden{Action: "delete patients"},
+		},
+		{
+			desc:        "receptionist may not read history",
+			ctx:         as("receptionist"),
+			setup:       func() {},
+			call:        func(ctx context.Context) error { _, err := s.History(ctx, 1); return err },
+			expectError: &perrors.Forbidden{Action: "read patient history"},
+		},
+		{
+			desc:        "nurse may not list deleted patients",
+			ctx:         as("nurse"),
+			setup:       func() {},
+			call:        func(ctx context.Context) error { _, err := s.List(ctx, models.ListOptions{Deleted: true}); return err },
+			expectError: &perrors.Forbidden{Action: "restore deleted patients"},
+		},
+		{
+			desc:        "unknown role has no rights",
+			ctx:         as("visitor"),
+			setup:       func() {},
+			call:        func(ctx context.Context) error { _, err := s.GetByID(ctx, 1); return err },
+			expectError: &perrors.Forbidden{Action: "read patients"},
+		},
+		{
+			desc:  "no principal is unrestricted",
+			ctx:   context.Background(),
+			setup: func() { next.EXPECT().Purge(gomock.Any()).Return(int64(0), nil) },
+			call:  func(ctx context.Context) error { _, err := s.Purge(ctx); return err },
+		},
+		{
+			desc:  "admin may not write clinical notes",
+			ctx:   as("admin"),
+			setup: func() {},
+			call: func(ctx context.Context) error {
+				_, err := s.Insert(ctx, &models.Patient{Name: "a", Description: "b"})
+				return err
+			},
+			expectError: &perrors.Forbidden{Action: "write", Fields: []string{"description"}},
+		},
+		{
+			desc: "nurse may not rename",
+			ctx:  as("nurse"),
+			setup: func() {
+				next.EXPECT().GetByID(gomock.Any(), 1).Return(stored(), nil)
+			},
+			call: func(ctx context.Context) error {
+				pt := stored()
+				pt.Name, pt.Discharge = "Zop", true
+				_, err := s.Update(ctx, pt, 1)
+				return err
+			},
+			expectError: &perrors.Forbidden{Action: "write", Fields: []string{"name"}},
+		},
+		{
+			desc:  "nurse patches discharge",
+			ctx:   as("nurse"),
+			setup: func() { next.EXPECT().Patch(gomock.Any(), 1, 0, patch.MergePatch, gomock.Any()).Return(stored(), nil) },
+			call: func(ctx context.Context) error {
+				_, err := s.Patch(ctx, 1, 0, patch.MergePatch, []byte(`{"discharge": true}`))
+				return err
+			},
+		},
+		{
+			desc:  "discharge is derived, not written through a patient",
+			ctx:   as("receptionist"),
+			setup: func() { next.EXPECT().Patch(gomock.Any(), 1, 0, patch.MergePatch, gomock.Any()).Return(stored(), nil) },
+			call: func(ctx context.Context) error {
+				_, err := s.Patch(ctx, 1, 0, patch.MergePatch, []byte(`{"discharge": true}`))
+				return err
+			},
+		},
+		{
+			desc:  "receptionist may not copy clinical notes",
+			ctx:   as("receptionist"),
+			setup: func() {},
+			call: func(ctx context.Context) error {
+				_, err := s.Patch(ctx, 1, 0, patch.JSONPatch, []byte(`[{"op": "copy", "from": "/description", "path": "/name"}]`))
+				return err
+			},
+			expectError: &perrors.Forbidden{Action: "write", Fields: []string{"description"}},
+		},
+		{
+			desc:  "roles combine",
+			ctx:   as("nurse", "receptionist"),
+			setup: func() { next.EXPECT().Patch(gomock.Any(), 1, 0, patch.MergePatch, gomock.Any()).Return(stored(), nil) },
+			call: func(ctx context.Context) error {
+				_, err := s.Patch(ctx, 1, 0, patch.MergePatch, []byte(`{"name": "Zop", "discharge": true}`))
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			test.setup()
+			err := test.call(test.ctx)
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	next := service.NewMockServiceInterface(mockCtrl)
+	s := New(next, Default())
+
+	next.EXPECT().GetByID(gomock.Any(), 1).Return(stored(), nil)
+	pt, err := s.GetByID(as("receptionist"), 1)
+	if err != nil || pt.Description != "" || pt.Name != "ZopSmart" {
+		t.Errorf("unexpected result: %+v, %v", pt, err)
+	}
+
+	next.EXPECT().List(gomock.Any(), gomock.Any()).Return(&models.PatientPage{Patients: []*models.Patient{stored()}, Total: 1}, nil)
+	page, err := s.List(as("doctor"), models.ListOptions{})
+	if err != nil || page.Patients[0].Description != "asthma" {
+		t.Errorf("unexpected result: %+v, %v", page, err)
+	}
+
+	// A receptionist's search must not probe the clinical notes.
+	next.EXPECT().Search(gomock.Any(), "asthma", models.ListOptions{NameOnly: true}).Return(&models.PatientPage{}, nil)
+	if _, err := s.Search(as("receptionist"), "asthma", models.ListOptions{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	next.EXPECT().Search(gomock.Any(), "asthma", models.ListOptions{}).Return(&models.PatientPage{}, nil)
+	if _, err := s.Search(as("doctor"), "asthma", models.ListOptions{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// A receptionist's PUT cannot see, and so must not clear, the clinical notes.
+	next.EXPECT().GetByID(gomock.Any(), 1).Return(stored(), nil)
+	next.EXPECT().Update(gomock.Any(), gomock.Any(), 1).DoAndReturn(func(_ context.Context, pt *models.Patient, _ int) (*models.Patient, error) {
+		if pt.Description != "asthma" {
+			t.Errorf("Expected: %v, Got: %v", "asthma", pt.Description)
+		}
+		return pt, nil
+	})
+	pt, err = s.Update(as("receptionist"), &models.Patient{Name: "Zop", Phone: "+919172681679", BloodGroup: "A+"}, 1)
+	if err != nil || pt.Description != "" {
+		t.Errorf("unexpected result: %+v, %v", pt, err)
+	}
+
+	next.EXPECT().History(gomock.Any(), 1).Return([]*models.AuditEntry{{Changes: map[string]models.FieldChange{
+		"description": {Before: "", After: "asthma"},
+		"discharge":   {Before: false, After: true},
+	}}}, nil)
+	next.EXPECT().History(gomock.Any(), 1).Return([]*models.AuditEntry{{Changes: map[string]models.FieldChange{
+		"description": {Before: "", After: "asthma"},
+	}}}, nil)
+	custom := Policy{"auditor": {Actions: []Action{History}, Read: []string{"discharge"}}}
+	entries, err := New(next, custom).History(as("auditor"), 1)
+	if _, ok := entries[0].Changes["description"]; err != nil || ok || len(entries[0].Changes) != 1 {
+		t.Errorf("unexpected entries: %+v, %v", entries[0], err)
+	}
+	entries, err = s.History(as("doctor"), 1)
+	if err != nil || len(entries[0].Changes) != 1 {
+		t.Errorf("unexpected entries: %+v, %v", entries[0], err)
+	}
+}
+
+func TestBulk(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	next := service.NewMockServiceInterface(mockCtrl)
+	s := New(next, Default())
+
+	// An admin may not write clinical notes: in an atomic batch that stops
+	// every item, in a partial one only the offending item.
+	refused := &perrors.Forbidden{Action: "write", Fields: []string{"description"}}
+	batch := []*models.Patient{{Name: "a"}, {Name: "b", Description: "c"}}
+	results, err := s.BulkInsert(as("admin"), batch, models.BulkAtomic)
+	expected := []models.BulkResult{{Err: &perrors.Aborted{}}, {Err: refused}}
+	if err != nil || !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected: %+v, Got: %+v (%v)", expected, results, err)
+	}
+
+	next.EXPECT().BulkInsert(gomock.Any(), []*models.Patient{{Name: "a"}}, models.BulkPartial).
+		Return([]models.BulkResult{{Patient: stored()}}, nil)
+	results, err = s.BulkInsert(as("admin"), batch, models.BulkPartial)
+	if err != nil || results[0].Patient == nil || !reflect.DeepEqual(results[1].Err, refused) {
+		t.Errorf("unexpected results: %+v, %v", results, err)
+	}
+
+	// A nurse's update is checked against the stored patient and the result
+	// is redacted like any other.
+	renamed := stored()
+	renamed.Name = "Zop"
+	next.EXPECT().GetByID(gomock.Any(), 1).Return(stored(), nil)
+	results, err = s.BulkUpdate(as("nurse"), []*models.Patient{renamed}, models.BulkPartial)
+	if err != nil || !reflect.DeepEqual(results[0].Err, &perrors.Forbidden{Action: "write", Fields: []string{"name"}}) {
+		t.Errorf("unexpected results: %+v, %v", results, err)
+	}
+
+	// A doctor may write every field, so nothing is read first.
+	next.EXPECT().BulkUpdate(gomock.Any(), []*models.Patient{renamed}, models.BulkAtomic).
+		Return([]models.BulkResult{{Patient: renamed}}, nil)
+	if _, err := s.BulkUpdate(as("doctor"), []*models.Patient{renamed}, models.BulkAtomic); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = s.BulkDelete(as("doctor"), []models.PatientRef{{ID: 1}}, models.BulkAtomic)
+	if !reflect.DeepEqual(err, &perrors.Forbidden{Action: "delete patients"}) {
+		t.Errorf("expected error :%v, got :%v ", &perrors.Forbidden{Action: "delete patients"}, err)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	next := service.NewMockServiceInterface(mockCtrl)
+	s := New(next, Default())
+
+	// Exported patients are redacted like any other read.
+	next.EXPECT().Export(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, fn func(*models.Patient) error) error { return fn(stored()) })
+	var exported []*models.Patient
+	err := s.Export(as("receptionist"), func(pt *models.Patient) error {
+		exported = append(exported, pt)
+		return nil
+	})
+	if err != nil || len(exported) != 1 || exported[0].Description != "" {
+		t.Errorf("unexpected export: %+v, %v", exported, err)
+	}
+
+	// Rows writing fields the caller may not write fail on their line.
+	next.EXPECT().Import(gomock.Any(), gomock.Any(), true).
+		DoAndReturn(func(ctx context.Context, src transfer.Reader, dryRun bool) (*models.ImportReport, error) {
+			var rows []*transfer.Row
+			for row, err := src.Next(); err == nil; row, err = src.Next() {
+				rows = append(rows, row)
+			}
+			expected := []*transfer.Row{
+				{Line: 2, Patient: &models.Patient{Name: "Ram", Discharge: true}},
+				{Line: 3, Err: &perrors.Forbidden{Action: "write", Fields: []string{"description"}}},
+			}
+			if !reflect.DeepEqual(rows, expected) {
+				t.Errorf("Expected: %+v, Got: %+v", expected, rows)
+			}
+			return &models.ImportReport{}, nil
+		})
+	src := transfer.NewReader(transfer.CSV, strings.NewReader("name,description\nRam,\nSita,asthma\n"))
+	if _, err := s.Import(as("receptionist"), src, true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = s.Import(as("nurse"), src, false)
+	if !reflect.DeepEqual(err, &perrors.Forbidden{Action: "create patients"}) {
+		t.Errorf("expected error :%v, got :%v ", &perrors.Forbidden{Action: "create patients"}, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	os.WriteFile(path, []byte(`{"auditor": {"actions": ["history"], "read": ["*"]}}`), 0600)
+	p, err := Load(path)
+	if err != nil || !p.grantFor(&auth.Principal{Roles: []string{"auditor"}}).can(History) {
+		t.Errorf("unexpected policy: %+v, %v", p, err)
+	}
+
+	os.WriteFile(path, []byte(`{"auditor": {"actions": ["history"], "read": ["ssn"]}}`), 0600)
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for an unknown field")
+	}
+	os.WriteFile(path, []byte(`{"auditor": {"actions": ["erase"]}}`), 0600)
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for an unknown action")
+	}
+}
+
+func TestEncounters(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	next := service.NewMockEncounterInterface(mockCtrl)
+	s := NewEncounters(next, Default())
+	stored := func() *models.Encounter {
+		return &models.Encounter{ID: 2, PatientID: 1, Reason: "chest pain", Ward: "ICU", Version: 1}
+	}
+
+	tests := []struct {
+		desc        string
+		ctx         context.Context
+		setup       func()
+		call        func(ctx context.Context) (*models.Encounter, error)
+		reason      string
+		expectError error
+	}{
+		{
+			desc:  "receptionist may not admit",
+			ctx:   as("receptionist"),
+			setup: func() {},
+			call: func(ctx context.Context) (*models.Encounter, error) {
+				return s.Admit(ctx, &models.Encounter{PatientID: 1})
+			},
+			expectError: &perrors.Forbidden{Action: "admit or discharge patients"},
+		},
+		{
+			desc:  "nurse may not give a reason",
+			ctx:   as("nurse"),
+			setup: func() {},
+			call: func(ctx context.Context) (*models.Encounter, error) {
+				return s.Admit(ctx, &models.Encounter{PatientID: 1, Reason: "fall"})
+			},
+			expectError: &perrors.Forbidden{Action: "write", Fields: []string{"reason"}},
+		},
+		{
+			desc:  "doctor admits",
+			ctx:   as("doctor"),
+			setup: func() { next.EXPECT().Admit(gomock.Any(), gomock.Any()).Return(stored(), nil) },
+			call: func(ctx context.Context) (*models.Encounter, error) {
+				return s.Admit(ctx, &models.Encounter{PatientID: 1, Reason: "chest pain"})
+			},
+			reason: "chest pain",
+		},
+		{
+			desc:  "receptionist reads without the reason",
+			ctx:   as("receptionist"),
+			setup: func() { next.EXPECT().Get(gomock.Any(), 1, 2).Return(stored(), nil) },
+			call:  func(ctx context.Context) (*models.Encounter, error) { return s.Get(ctx, 1, 2) },
+		},
+		{
+			desc: "nurse moves ward and keeps the reason",
+			ctx:  as("nurse"),
+			setup: func() {
+				next.EXPECT().Get(gomock.Any(), 1, 2).Return(stored(), nil)
+				next.EXPECT().Update(gomock.Any(), &models.Encounter{ID: 2, PatientID: 1, Reason: "chest pain", Ward: "B2"}).Return(stored(), nil)
+			},
+			call: func(ctx context.Context) (*models.Encounter, error) {
+				return s.Update(ctx, &models.Encounter{ID: 2, PatientID: 1, Reason: "chest pain", Ward: "B2"})
+			},
+			reason: "chest pain",
+		},
+		{
+			desc:  "admin may not change the reason",
+			ctx:   as("admin"),
+			setup: func() { next.EXPECT().Get(gomock.Any(), 1, 2).Return(stored(), nil) },
+			call: func(ctx context.Context) (*models.Encounter, error) {
+				return s.Update(ctx, &models.Encounter{ID: 2, PatientID: 1})
+			},
+			expectError: &perrors.Forbidden{Action: "write", Fields: []string{"reason"}},
+		},
+		{
+			desc:   "nurse discharges",
+			ctx:    as("nurse"),
+			setup:  func() { next.EXPECT().Discharge(gomock.Any(), 1, 2, 1).Return(stored(), nil) },
+			call:   func(ctx context.Context) (*models.Encounter, error) { return s.Discharge(ctx, 1, 2, 1) },
+			reason: "chest pain",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			test.setup()
+			e, err := test.call(test.ctx)
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if err == nil && e.Reason != test.reason {
+				t.Errorf("Expected: %v, Got: %v", test.reason, e.Reason)
+			}
+		})
+	}
+
+	patients := service.NewMockServiceInterface(mockCtrl)
+	patients.EXPECT().History(gomock.Any(), 1).Return([]*models.AuditEntry{{Changes: map[string]models.FieldChange{
+		"encounter.id":     {After: 2},
+		"encounter.reason": {Before: "", After: "chest pain"},
+		"discharge":        {Before: true, After: false},
+	}}}, nil)
+	entries, err := New(patients, Default()).History(as("nurse"), 1)
+	if err != nil || len(entries[0].Changes) != 3 {
+		t.Errorf("unexpected entries: %+v, %v", entries[0], err)
+	}
+	patients.EXPECT().History(gomock.Any(), 1).Return([]*models.AuditEntry{{Changes: map[string]models.FieldChange{
+		"encounter.id":     {After: 2},
+		"encounter.reason": {Before: "", After: "chest pain"},
+	}}}, nil)
+	custom := Policy{"auditor": {Actions: []Action{History}, Read: []string{"discharge"}}}
+	entries, err = New(patients, custom).History(as("auditor"), 1)
+	if _, ok := entries[0].Changes["encounter.reason"]; err != nil || ok || len(entries[0].Changes) != 1 {
+		t.Errorf("unexpected entries: %+v, %v", entries[0], err)
+	}
+}
+
+func TestWards(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	next := service.NewMockWardInterface(mockCtrl)
+	s := NewWards(next, Default())
+
+	tests := []struct {
+		desc        string
+		ctx         context.Context
+		setup       func()
+		call        func(ctx context.Context) error
+		expectError error
+	}{
+		{
+			desc:  "nurse may not create wards",
+			ctx:   as("nurse"),
+			setup: func() {},
+			call: func(ctx context.Context) error {
+				_, err := s.CreateWard(ctx, &models.Ward{Name: "ICU"})
+				return err
+			},
+			expectError: &perrors.Forbidden{Action: "manage wards"},
+		},
+		{
+			desc:  "admin adds a bed",
+			ctx:   as("admin"),
+			setup: func() { next.EXPECT().AddBed(gomock.Any(), gomock.Any()).Return(&models.Bed{ID: 1}, nil) },
+			call: func(ctx context.Context) error {
+				_, err := s.AddBed(ctx, &models.Bed{WardID: 1, Label: "A1"})
+				return err
+			},
+		},
+		{
+			desc:  "receptionist reads occupancy",
+			ctx:   as("receptionist"),
+			setup: func() { next.EXPECT().Occupancy(gomock.Any(), 1).Return(&models.Occupancy{}, nil) },
+			call: func(ctx context.Context) error {
+				_, err := s.Occupancy(ctx, 1)
+				return err
+			},
+		},
+		{
+			desc:  "receptionist may not assign beds",
+			ctx:   as("receptionist"),
+			setup: func() {},
+			call: func(ctx context.Context) error {
+				_, err := s.Assign(ctx, 1, 4)
+				return err
+			},
+			expectError: &perrors.Forbidden{Action: "assign beds"},
+		},
+		{
+			desc:  "nurse transfers",
+			ctx:   as("nurse"),
+			setup: func() { next.EXPECT().Transfer(gomock.Any(), 1, 5).Return(&models.BedAssignment{ID: 2}, nil) },
+			call: func(ctx context.Context) error {
+				_, err := s.Transfer(ctx, 1, 5)
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			test.setup()
+			if err := test.call(test.ctx); !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestTriage(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	next := service.NewMockTriageInterface(mockCtrl)
+	s := NewTriage(next, Default())
+
+	next.EXPECT().Queue(gomock.Any()).Return([]*models.Triage{}, nil)
+	if _, err := s.Queue(as("receptionist")); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+	expected := &perrors.Forbidden{Action: "triage patients"}
+	if _, err := s.CallNext(as("receptionist")); !reflect.DeepEqual(err, expected) {
+		t.Errorf("expected error :%v, got :%v ", expected, err)
+	}
+	next.EXPECT().Retriage(gomock.Any(), 5, 2).Return(&models.Triage{ID: 5, Level: 2}, nil)
+	if _, err := s.Retriage(as("nurse"), 5, 2); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+	expected = &perrors.Forbidden{Action: "read patients"}
+	if _, err := s.Peek(as("visitor")); !reflect.DeepEqual(err, expected) {
+		t.Errorf("expected error :%v, got :%v ", expected, err)
+	}
+}
+
+func TestStaff(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	next := service.NewMockStaffInterface(mockCtrl)
+	s := NewStaff(next, Default())
+
+	expected := &perrors.Forbidden{Action: "manage staff"}
+	if _, err := s.Insert(as("doctor"), &models.Staff{Name: "Meera"}); !reflect.DeepEqual(err, expected) {
+		t.Errorf("expected error :%v, got :%v ", expected, err)
+	}
+	next.EXPECT().Delete(gomock.Any(), 3, 0).Return(nil)
+	if err := s.Delete(as("admin"), 3, 0); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+	next.EXPECT().Team(gomock.Any(), 1).Return([]*models.CareTeamMember{}, nil)
+	if _, err := s.Team(as("receptionist"), 1); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+	expected = &perrors.Forbidden{Action: "assign care teams"}
+	if err := s.Unassign(as("receptionist"), 1, 3); !reflect.DeepEqual(err, expected) {
+		t.Errorf("expected error :%v, got :%v ", expected, err)
+	}
+	m := &models.CareTeamMember{PatientID: 1, StaffID: 3, Role: models.PrimaryNurse}
+	next.EXPECT().Assign(gomock.Any(), m).Return(m, nil)
+	if _, err := s.Assign(as("nurse"), m); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+}
+
+func TestAppointments(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	next := service.NewMockAppointmentInterface(mockCtrl)
+	s := NewAppointments(next, Default())
+
+	a := &models.Appointment{PatientID: 1, StaffID: 3}
+	next.EXPECT().Book(gomock.Any(), a).Return(a, nil)
+	if _, err := s.Book(as("receptionist"), a); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+	next.EXPECT().Availability(gomock.Any(), 3, time.Time{}, time.Time{}, time.Duration(0)).Return([]*models.Slot{}, nil)
+	if _, err := s.Availability(as("nurse"), 3, time.Time{}, time.Time{}, 0); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+	expected := &perrors.Forbidden{Action: "update patients"}
+	if _, err := s.Cancel(as("visitor"), 7, 0); !reflect.DeepEqual(err, expected) {
+		t.Errorf("expected error :%v, got :%v ", expected, err)
+	}
+}
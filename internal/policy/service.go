@@ -0,0 +1,416 @@
+package policy
+
+import (
+	"context"
+	"github.com/aakanksha/ppms/internal/auth"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/patch"
+	"github.com/aakanksha/ppms/internal/service"
+	"github.com/aakanksha/ppms/internal/transfer"
+	"sort"
+)
+
+// Service enforces a Policy in front of another ServiceInterface: it rejects
+// actions and field writes the caller's roles do not allow and redacts fields
+// they may not read from every patient it returns.
+type Service struct {
+	next   service.ServiceInterface
+	policy Policy
+}
+
+var _ service.ServiceInterface = (*Service)(nil)
+
+func New(next service.ServiceInterface, policy Policy) *Service {
+	return &Service{next: next, policy: policy}
+}
+
+var actionNames = map[Action]string{
+	Read:        "read patients",
+	Create:      "create patients",
+	Update:      "update patients",
+	Delete:      "delete patients",
+	Restore:     "restore deleted patients",
+	Purge:       "purge deleted patients",
+	History:     "read patient history",
+	ManageWards: "manage wards",
+	ManageStaff: "manage staff",
+}
+
+func (s *Service) authorize(ctx context.Context, a Action) (*grant, error) {
+	return s.policy.authorize(ctx, a)
+}
+
+func (pol Policy) authorize(ctx context.Context, a Action) (*grant, error) {
+	g := pol.grantFor(auth.FromContext(ctx))
+	if !g.can(a) {
+		return nil, &perrors.Forbidden{Action: actionNames[a]}
+	}
+	return g, nil
+}
+
+// checkWrites rejects writes to fields, by JSON name, that g may not write.
+func checkWrites(g *grant, fields []string) error {
+	var denied []string
+	for _, f := range fields {
+		if !g.canWrite(f) {
+			denied = append(denied, f)
+		}
+	}
+	if len(denied) > 0 {
+		sort.Strings(denied)
+		return &perrors.Forbidden{Action: "write", Fields: denied}
+	}
+	return nil
+}
+
+func (s *Service) GetAll(ctx context.Context) ([]*models.Patient, error) {
+	g, err := s.authorize(ctx, Read)
+	if err != nil {
+		return nil, err
+	}
+	patients, err := s.next.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range patients {
+		patients[i] = g.redact(patients[i])
+	}
+	return patients, nil
+}
+
+func (s *Service) List(ctx context.Context, opts models.ListOptions) (*models.PatientPage, error) {
+	action := Read
+	if opts.Deleted {
+		action = Restore
+	}
+	g, err := s.authorize(ctx, action)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.next.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return g.redactPage(page), nil
+}
+
+// Search matches names only for callers who may not read descriptions, so
+// that they cannot probe them.
+func (s *Service) Search(ctx context.Context, query string, opts models.ListOptions) (*models.PatientPage, error) {
+	g, err := s.authorize(ctx, Read)
+	if err != nil {
+		return nil, err
+	}
+	if !g.canRead("description") {
+		opts.NameOnly = true
+	}
+	page, err := s.next.Search(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	return g.redactPage(page), nil
+}
+
+func (s *Service) GetByID(ctx context.Context, id int) (*models.Patient, error) {
+	g, err := s.authorize(ctx, Read)
+	if err != nil {
+		return nil, err
+	}
+	pt, err := s.next.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return g.redact(pt), nil
+}
+
+// Insert requires write access to every field given a value.
+func (s *Service) Insert(ctx context.Context, pt *models.Patient) (*models.Patient, error) {
+	g, err := s.authorize(ctx, Create)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWrites(g, insertWrites(pt)); err != nil {
+		return nil, err
+	}
+	created, err := s.next.Insert(ctx, pt)
+	if err != nil {
+		return nil, err
+	}
+	return g.redact(created), nil
+}
+
+// Update requires write access to every field whose value changes. Fields the
+// caller cannot read keep their stored value, since the caller was never shown
+// it and cannot be expected to send it back.
+func (s *Service) Update(ctx context.Context, pt *models.Patient, id int) (*models.Patient, error) {
+	g, err := s.authorize(ctx, Update)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.next.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWrites(g, g.updateWrites(pt, current)); err != nil {
+		return nil, err
+	}
+	updated, err := s.next.Update(ctx, pt, id)
+	if err != nil {
+		return nil, err
+	}
+	return g.redact(updated), nil
+}
+
+// Patch requires write access to every field the patch refers to, including
+// fields it only tests or copies from.
+func (s *Service) Patch(ctx context.Context, id int, version int, format patch.Format, body []byte) (*models.Patient, error) {
+	g, err := s.authorize(ctx, Update)
+	if err != nil {
+		return nil, err
+	}
+	members, err := patch.Members(format, body)
+	if err != nil {
+		return nil, err
+	}
+	var touched []string
+	for _, m := range members {
+		if m == "" {
+			for name := range patientFields {
+				if !derivedFields[name] {
+					touched = append(touched, name)
+				}
+			}
+		} else if _, ok := patientFields[m]; ok && !derivedFields[m] {
+			touched = append(touched, m)
+		}
+	}
+	if err := checkWrites(g, touched); err != nil {
+		return nil, err
+	}
+	patched, err := s.next.Patch(ctx, id, version, format, body)
+	if err != nil {
+		return nil, err
+	}
+	return g.redact(patched), nil
+}
+
+// insertWrites lists the fields a new patient gives a value, other than derived
+// ones.
+func insertWrites(pt *models.Patient) []string {
+	var empty models.Patient
+	var written []string
+	for name := range patientFields {
+		if !derivedFields[name] && fieldValue(pt, name) != fieldValue(&empty, name) {
+			written = append(written, name)
+		}
+	}
+	return written
+}
+
+// updateWrites lists the fields pt changes from current, after resetting the
+// fields g may not read to their stored value.
+func (g *grant) updateWrites(pt, current *models.Patient) []string {
+	var written []string
+	for name := range patientFields {
+		if !g.canRead(name) {
+			copyField(pt, current, name)
+		} else if !derivedFields[name] && fieldValue(pt, name) != fieldValue(current, name) {
+			written = append(written, name)
+		}
+	}
+	return written
+}
+
+func (s *Service) Delete(ctx context.Context, id int, version int) error {
+	if _, err := s.authorize(ctx, Delete); err != nil {
+		return err
+	}
+	return s.next.Delete(ctx, id, version)
+}
+
+func (s *Service) Restore(ctx context.Context, id int) (*models.Patient, error) {
+	g, err := s.authorize(ctx, Restore)
+	if err != nil {
+		return nil, err
+	}
+	pt, err := s.next.Restore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return g.redact(pt), nil
+}
+
+func (s *Service) Purge(ctx context.Context) (int64, error) {
+	if _, err := s.authorize(ctx, Purge); err != nil {
+		return 0, err
+	}
+	return s.next.Purge(ctx)
+}
+
+// History drops the before and after values of fields the caller may not read,
+// including encounter fields guarded like a patient field.
+func (s *Service) History(ctx context.Context, id int) ([]*models.AuditEntry, error) {
+	g, err := s.authorize(ctx, History)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.next.History(ctx, id)
+	if err != nil || g.unrestricted {
+		return entries, err
+	}
+	for i, e := range entries {
+		copied := *e
+		copied.Changes = map[string]models.FieldChange{}
+		for name, change := range e.Changes {
+			field := name
+			if as, ok := auditedAs[name]; ok {
+				field = as
+			}
+			if _, ok := patientFields[field]; ok && !g.canRead(field) {
+				continue
+			}
+			copied.Changes[name] = change
+		}
+		entries[i] = &copied
+	}
+	return entries, nil
+}
+
+// forwardBulk passes the items in ok, those allowed here, on to the next
+// service and merges its results with the refused items. In BulkAtomic mode a
+// refusal stops the batch, as a failed item does further down.
+func (g *grant) forwardBulk(results []models.BulkResult, ok []int, mode models.BulkMode, next func(ok []int) ([]models.BulkResult, error)) ([]models.BulkResult, error) {
+	if len(ok) < len(results) {
+		if mode == models.BulkAtomic {
+			for _, i := range ok {
+				results[i].Err = &perrors.Aborted{}
+			}
+			return results, nil
+		}
+		if len(ok) == 0 {
+			return results, nil
+		}
+	}
+	written, err := next(ok)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range ok {
+		results[i] = models.BulkResult{Patient: g.redact(written[j].Patient), Err: written[j].Err}
+	}
+	return results, nil
+}
+
+// BulkInsert requires write access to every field given a value, per item.
+func (s *Service) BulkInsert(ctx context.Context, pts []*models.Patient, mode models.BulkMode) ([]models.BulkResult, error) {
+	g, err := s.authorize(ctx, Create)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]models.BulkResult, len(pts))
+	var ok []int
+	for i, pt := range pts {
+		if results[i].Err = checkWrites(g, insertWrites(pt)); results[i].Err == nil {
+			ok = append(ok, i)
+		}
+	}
+	return g.forwardBulk(results, ok, mode, func(ok []int) ([]models.BulkResult, error) {
+		batch := make([]*models.Patient, len(ok))
+		for j, i := range ok {
+			batch[j] = pts[i]
+		}
+		return s.next.BulkInsert(ctx, batch, mode)
+	})
+}
+
+// BulkUpdate checks each item like Update. The stored patients are only read
+// when g cannot write every field, since otherwise they cannot change the
+// outcome.
+func (s *Service) BulkUpdate(ctx context.Context, pts []*models.Patient, mode models.BulkMode) ([]models.BulkResult, error) {
+	g, err := s.authorize(ctx, Update)
+	if err != nil {
+		return nil, err
+	}
+	writesAll := true
+	for name := range patientFields {
+		writesAll = writesAll && (derivedFields[name] || g.canWrite(name))
+	}
+	results := make([]models.BulkResult, len(pts))
+	var ok []int
+	for i, pt := range pts {
+		if !writesAll {
+			current, err := s.next.GetByID(ctx, pt.Id)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			if results[i].Err = checkWrites(g, g.updateWrites(pt, current)); results[i].Err != nil {
+				continue
+			}
+		}
+		ok = append(ok, i)
+	}
+	return g.forwardBulk(results, ok, mode, func(ok []int) ([]models.BulkResult, error) {
+		batch := make([]*models.Patient, len(ok))
+		for j, i := range ok {
+			batch[j] = pts[i]
+		}
+		return s.next.BulkUpdate(ctx, batch, mode)
+	})
+}
+
+func (s *Service) BulkDelete(ctx context.Context, refs []models.PatientRef, mode models.BulkMode) ([]models.BulkResult, error) {
+	if _, err := s.authorize(ctx, Delete); err != nil {
+		return nil, err
+	}
+	return s.next.BulkDelete(ctx, refs, mode)
+}
+
+func (s *Service) Export(ctx context.Context, fn func(*models.Patient) error) error {
+	g, err := s.authorize(ctx, Read)
+	if err != nil {
+		return err
+	}
+	return s.next.Export(ctx, func(pt *models.Patient) error {
+		return fn(g.redact(pt))
+	})
+}
+
+// Import requires the Create action. A row that writes fields the caller may
+// not write is reported as an error on its line.
+func (s *Service) Import(ctx context.Context, src transfer.Reader, dryRun bool) (*models.ImportReport, error) {
+	g, err := s.authorize(ctx, Create)
+	if err != nil {
+		return nil, err
+	}
+	return s.next.Import(ctx, checkedReader{Reader: src, g: g}, dryRun)
+}
+
+type checkedReader struct {
+	transfer.Reader
+	g *grant
+}
+
+func (c checkedReader) Next() (*transfer.Row, error) {
+	row, err := c.Reader.Next()
+	if err != nil || row.Err != nil {
+		return row, err
+	}
+	if err := checkWrites(c.g, insertWrites(row.Patient)); err != nil {
+		return &transfer.Row{Line: row.Line, Err: err}, nil
+	}
+	return row, nil
+}
+
+func (g *grant) redactPage(page *models.PatientPage) *models.PatientPage {
+	if g.unrestricted {
+		return page
+	}
+	copied := *page
+	copied.Patients = make([]*models.Patient, len(page.Patients))
+	for i, pt := range page.Patients {
+		copied.Patients[i] = g.redact(pt)
+	}
+	return &copied
+}
@@ -0,0 +1,97 @@
+package policy
+
+import (
+	"context"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+)
+
+// Staff enforces a Policy in front of a StaffInterface. Reading the directory
+// and care teams takes the Read action and changing the directory the
+// ManageStaff action. Assigning care teams takes the Update action and write
+// access to discharge, like admitting patients.
+type Staff struct {
+	next   service.StaffInterface
+	policy Policy
+}
+
+var _ service.StaffInterface = (*Staff)(nil)
+
+func NewStaff(next service.StaffInterface, policy Policy) *Staff {
+	return &Staff{next: next, policy: policy}
+}
+
+func (s *Staff) authorize(ctx context.Context, a Action) error {
+	g, err := s.policy.authorize(ctx, a)
+	if err != nil {
+		return err
+	}
+	if a == Update && !g.canWrite("discharge") {
+		return &perrors.Forbidden{Action: "assign care teams"}
+	}
+	return nil
+}
+
+func (s *Staff) List(ctx context.Context, profession models.Profession) ([]*models.Staff, error) {
+	if err := s.authorize(ctx, Read); err != nil {
+		return nil, err
+	}
+	return s.next.List(ctx, profession)
+}
+
+func (s *Staff) Get(ctx context.Context, id int) (*models.Staff, error) {
+	if err := s.authorize(ctx, Read); err != nil {
+		return nil, err
+	}
+	return s.next.Get(ctx, id)
+}
+
+func (s *Staff) Insert(ctx context.Context, st *models.Staff) (*models.Staff, error) {
+	if err := s.authorize(ctx, ManageStaff); err != nil {
+		return nil, err
+	}
+	return s.next.Insert(ctx, st)
+}
+
+func (s *Staff) Update(ctx context.Context, st *models.Staff) (*models.Staff, error) {
+	if err := s.authorize(ctx, ManageStaff); err != nil {
+		return nil, err
+	}
+	return s.next.Update(ctx, st)
+}
+
+func (s *Staff) Delete(ctx context.Context, id, version int) error {
+	if err := s.authorize(ctx, ManageStaff); err != nil {
+		return err
+	}
+	return s.next.Delete(ctx, id, version)
+}
+
+func (s *Staff) Team(ctx context.Context, patientID int) ([]*models.CareTeamMember, error) {
+	if err := s.authorize(ctx, Read); err != nil {
+		return nil, err
+	}
+	return s.next.Team(ctx, patientID)
+}
+
+func (s *Staff) Patients(ctx context.Context, staffID int) ([]*models.CareTeamMember, error) {
+	if err := s.authorize(ctx, Read); err != nil {
+		return nil, err
+	}
+	return s.next.Patients(ctx, staffID)
+}
+
+func (s *Staff) Assign(ctx context.Context, m *models.CareTeamMember) (*models.CareTeamMember, error) {
+	if err := s.authorize(ctx, Update); err != nil {
+		return nil, err
+	}
+	return s.next.Assign(ctx, m)
+}
+
+func (s *Staff) Unassign(ctx context.Context, patientID, staffID int) error {
+	if err := s.authorize(ctx, Update); err != nil {
+		return err
+	}
+	return s.next.Unassign(ctx, patientID, staffID)
+}
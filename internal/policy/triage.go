@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"context"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+)
+
+// Triage enforces a Policy in front of a TriageInterface. Reading the queue
+// takes the Read action; triaging and calling patients takes the Update action
+// and write access to discharge, like admitting them.
+type Triage struct {
+	next   service.TriageInterface
+	policy Policy
+}
+
+var _ service.TriageInterface = (*Triage)(nil)
+
+func NewTriage(next service.TriageInterface, policy Policy) *Triage {
+	return &Triage{next: next, policy: policy}
+}
+
+func (s *Triage) authorize(ctx context.Context, a Action) error {
+	g, err := s.policy.authorize(ctx, a)
+	if err != nil {
+		return err
+	}
+	if a == Update && !g.canWrite("discharge") {
+		return &perrors.Forbidden{Action: "triage patients"}
+	}
+	return nil
+}
+
+func (s *Triage) Enqueue(ctx context.Context, t *models.Triage) (*models.Triage, error) {
+	if err := s.authorize(ctx, Update); err != nil {
+		return nil, err
+	}
+	return s.next.Enqueue(ctx, t)
+}
+
+func (s *Triage) Queue(ctx context.Context) ([]*models.Triage, error) {
+	if err := s.authorize(ctx, Read); err != nil {
+		return nil, err
+	}
+	return s.next.Queue(ctx)
+}
+
+func (s *Triage) Peek(ctx context.Context) (*models.Triage, error) {
+	if err := s.authorize(ctx, Read); err != nil {
+		return nil, err
+	}
+	return s.next.Peek(ctx)
+}
+
+func (s *Triage) CallNext(ctx context.Context) (*models.Triage, error) {
+	if err := s.authorize(ctx, Update); err != nil {
+		return nil, err
+	}
+	return s.next.CallNext(ctx)
+}
+
+func (s *Triage) Retriage(ctx context.Context, id, level int) (*models.Triage, error) {
+	if err := s.authorize(ctx, Update); err != nil {
+		return nil, err
+	}
+	return s.next.Retriage(ctx, id, level)
+}
@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"context"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service"
+)
+
+// Wards enforces a Policy in front of a WardInterface. Listing wards takes
+// the Read action and setting them up the ManageWards action. Where a patient
+// lies is guarded like discharge: seeing it takes read access to discharge and
+// moving patients between beds takes the Update action and write access to it.
+type Wards struct {
+	next   service.WardInterface
+	policy Policy
+}
+
+var _ service.WardInterface = (*Wards)(nil)
+
+func NewWards(next service.WardInterface, policy Policy) *Wards {
+	return &Wards{next: next, policy: policy}
+}
+
+func (s *Wards) authorize(ctx context.Context, a Action, beds bool) error {
+	g, err := s.policy.authorize(ctx, a)
+	if err != nil || !beds {
+		return err
+	}
+	if a == Read && !g.canRead("discharge") {
+		return &perrors.Forbidden{Action: "read bed occupancy"}
+	}
+	if a == Update && !g.canWrite("discharge") {
+		return &perrors.Forbidden{Action: "assign beds"}
+	}
+	return nil
+}
+
+func (s *Wards) CreateWard(ctx context.Context, w *models.Ward) (*models.Ward, error) {
+	if err := s.authorize(ctx, ManageWards, false); err != nil {
+		return nil, err
+	}
+	return s.next.CreateWard(ctx, w)
+}
+
+func (s *Wards) ListWards(ctx context.Context) ([]*models.Ward, error) {
+	if err := s.authorize(ctx, Read, false); err != nil {
+		return nil, err
+	}
+	return s.next.ListWards(ctx)
+}
+
+func (s *Wards) GetWard(ctx context.Context, id int) (*models.Ward, error) {
+	if err := s.authorize(ctx, Read, false); err != nil {
+		return nil, err
+	}
+	return s.next.GetWard(ctx, id)
+}
+
+func (s *Wards) AddBed(ctx context.Context, b *models.Bed) (*models.Bed, error) {
+	if err := s.authorize(ctx, ManageWards, false); err != nil {
+		return nil, err
+	}
+	return s.next.AddBed(ctx, b)
+}
+
+func (s *Wards) Occupancy(ctx context.Context, wardID int) (*models.Occupancy, error) {
+	if err := s.authorize(ctx, Read, true); err != nil {
+		return nil, err
+	}
+	return s.next.Occupancy(ctx, wardID)
+}
+
+func (s *Wards) CurrentBed(ctx context.Context, patientID int) (*models.BedAssignment, error) {
+	if err := s.authorize(ctx, Read, true); err != nil {
+		return nil, err
+	}
+	return s.next.CurrentBed(ctx, patientID)
+}
+
+func (s *Wards) Assign(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error) {
+	if err := s.authorize(ctx, Update, true); err != nil {
+		return nil, err
+	}
+	return s.next.Assign(ctx, patientID, bedID)
+}
+
+func (s *Wards) Transfer(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error) {
+	if err := s.authorize(ctx, Update, true); err != nil {
+		return nil, err
+	}
+	return s.next.Transfer(ctx, patientID, bedID)
+}
+
+func (s *Wards) Release(ctx context.Context, patientID int) (*models.BedAssignment, error) {
+	if err := s.authorize(ctx, Update, true); err != nil {
+		return nil, err
+	}
+	return s.next.Release(ctx, patientID)
+}
@@ -0,0 +1,210 @@
+package appointment
+
+import (
+	"context"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service/patient"
+	"github.com/aakanksha/ppms/internal/stores"
+	"time"
+)
+
+const (
+	// maxLength bounds a single appointment.
+	maxLength = 8 * time.Hour
+	// maxRange bounds the span of an availability query.
+	maxRange = 31 * 24 * time.Hour
+	day      = 24 * time.Hour
+)
+
+type Svc struct {
+	stores   stores.AppointmentInterface
+	timeouts patient.Timeouts
+	opens    time.Duration
+	closes   time.Duration
+	now      func() time.Time
+}
+
+func New(stores stores.AppointmentInterface) *Svc {
+	return &Svc{stores: stores, opens: 9 * time.Hour, closes: 17 * time.Hour, now: time.Now}
+}
+
+// WithTimeouts applies the read and write deadlines of the patient service to
+// appointment calls.
+func (as *Svc) WithTimeouts(t patient.Timeouts) *Svc {
+	as.timeouts = t
+	return as
+}
+
+// WithHours sets the working hours availability is offered in, as offsets
+// from midnight UTC. The default is 9:00 to 17:00.
+func (as *Svc) WithHours(opens, closes time.Duration) *Svc {
+	as.opens, as.closes = opens, closes
+	return as
+}
+
+// List returns the appointments of a patient or a staff member overlapping
+// f.From to f.To, by start time.
+func (as *Svc) List(ctx context.Context, f models.AppointmentFilter) ([]*models.Appointment, error) {
+	if f.PatientID <= 0 && f.StaffID <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.To.After(f.From) {
+		return nil, perrors.NewValidation("to", "must be after from")
+	}
+	ctx, cancel := as.timeouts.With(ctx, as.timeouts.Read)
+	defer cancel()
+	return as.stores.List(ctx, f)
+}
+
+func (as *Svc) Get(ctx context.Context, id int) (*models.Appointment, error) {
+	if id <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := as.timeouts.With(ctx, as.timeouts.Read)
+	defer cancel()
+	return as.stores.Get(ctx, id)
+}
+
+// Book books a patient with a staff member, unless either already has an
+// appointment at that time.
+func (as *Svc) Book(ctx context.Context, a *models.Appointment) (*models.Appointment, error) {
+	verr := &perrors.Validation{}
+	if a.PatientID <= 0 {
+		verr.Add("patientId", "must be a positive integer")
+	}
+	if a.StaffID <= 0 {
+		verr.Add("staffId", "must be a positive integer")
+	}
+	as.validateTimes(a, verr)
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	ctx, cancel := as.timeouts.With(ctx, as.timeouts.Write)
+	defer cancel()
+	return as.stores.Insert(ctx, a)
+}
+
+// Reschedule moves a booked appointment to a.Start and a.End; a non-zero
+// Version makes the write conditional on it.
+func (as *Svc) Reschedule(ctx context.Context, a *models.Appointment) (*models.Appointment, error) {
+	verr := &perrors.Validation{}
+	if a.ID <= 0 {
+		verr.Add("id", "must be a positive integer")
+	}
+	as.validateTimes(a, verr)
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	ctx, cancel := as.timeouts.With(ctx, as.timeouts.Write)
+	defer cancel()
+	return as.stores.Reschedule(ctx, a)
+}
+
+// Cancel frees the time of a booked appointment, conditionally on version
+// when it is set.
+func (as *Svc) Cancel(ctx context.Context, id, version int) (*models.Appointment, error) {
+	if id <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := as.timeouts.With(ctx, as.timeouts.Write)
+	defer cancel()
+	return as.stores.Cancel(ctx, id, version)
+}
+
+// validateTimes brings the times of a to whole seconds in UTC, as they are
+// stored, and adds what is wrong with them to verr.
+func (as *Svc) validateTimes(a *models.Appointment, verr *perrors.Validation) {
+	a.Start, a.End = a.Start.UTC().Truncate(time.Second), a.End.UTC().Truncate(time.Second)
+	switch {
+	case a.Start.IsZero():
+		verr.Add("start", "is required")
+	case a.Start.Before(as.now()):
+		verr.Add("start", "must not be in the past")
+	}
+	switch {
+	case !a.End.After(a.Start):
+		verr.Add("end", "must be after start")
+	case a.End.Sub(a.Start) > maxLength:
+		verr.Add("end", "must be at most 8 hours after start")
+	}
+}
+
+// Availability returns the free stretches of at least length in the working
+// hours of a staff member from from, or now if that is later, to to.
+func (as *Svc) Availability(ctx context.Context, staffID int, from, to time.Time, length time.Duration) ([]*models.Slot, error) {
+	verr := &perrors.Validation{}
+	if staffID <= 0 {
+		verr.Add("id", "must be a positive integer")
+	}
+	from, to = from.UTC().Truncate(time.Second), to.UTC().Truncate(time.Second)
+	if from.IsZero() {
+		verr.Add("from", "is required")
+	}
+	switch {
+	case to.IsZero():
+		verr.Add("to", "is required")
+	case from.IsZero():
+	case !to.After(from):
+		verr.Add("to", "must be after from")
+	case to.Sub(from) > maxRange:
+		verr.Add("to", "must be at most 31 days after from")
+	}
+	if length < 0 {
+		verr.Add("length", "must not be negative")
+	}
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	if now := as.now().UTC().Truncate(time.Second); from.Before(now) {
+		from = now
+	}
+	if !to.After(from) {
+		return []*models.Slot{}, nil
+	}
+	ctx, cancel := as.timeouts.With(ctx, as.timeouts.Read)
+	defer cancel()
+	appointments, err := as.stores.List(ctx, models.AppointmentFilter{StaffID: staffID, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	booked := make([]*models.Appointment, 0, len(appointments))
+	for _, a := range appointments {
+		if a.Status == models.Booked {
+			booked = append(booked, a)
+		}
+	}
+	return as.freeSlots(booked, from, to, length), nil
+}
+
+// freeSlots returns the stretches of at least length in the working hours of
+// each day from from to to that none of booked, sorted by start, covers.
+func (as *Svc) freeSlots(booked []*models.Appointment, from, to time.Time, length time.Duration) []*models.Slot {
+	slots := []*models.Slot{}
+	add := func(start, end time.Time) {
+		if gap := end.Sub(start); gap > 0 && gap >= length {
+			slots = append(slots, &models.Slot{Start: start, End: end})
+		}
+	}
+	for midnight := from.Truncate(day); midnight.Before(to); midnight = midnight.Add(day) {
+		start, end := midnight.Add(as.opens), midnight.Add(as.closes)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		for _, a := range booked {
+			if !a.Start.Before(end) {
+				continue
+			}
+			add(start, a.Start)
+			// An appointment inside an earlier one must not move start back.
+			if a.End.After(start) {
+				start = a.End
+			}
+		}
+		add(start, end)
+	}
+	return slots
+}
@@ -0,0 +1,188 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores"
+	"github.com/golang/mock/gomock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// at returns the time on 1 March 2022 plus days, at hour:minute UTC.
+func at(days, hour, minute int) time.Time {
+	return time.Date(2022, 3, 1+days, hour, minute, 0, 0, time.UTC)
+}
+
+func TestBook(t *testing.T) {
+	tests := []struct {
+		desc        string
+		input       *models.Appointment
+		stored      *models.Appointment
+		expectError error
+	}{
+		{
+			desc:   "times brought to UTC",
+			input:  &models.Appointment{PatientID: 1, StaffID: 3, Start: at(0, 15, 0).In(time.FixedZone("IST", 19800)), End: at(0, 15, 30).Add(500 * time.Millisecond)},
+			stored: &models.Appointment{PatientID: 1, StaffID: 3, Start: at(0, 15, 0), End: at(0, 15, 30)},
+		},
+		{
+			desc:        "in the past and backwards",
+			input:       &models.Appointment{PatientID: 1, StaffID: 3, Start: at(0, 9, 0), End: at(0, 8, 0)},
+			expectError: errors.New("invalid start, end"),
+		},
+		{
+			desc:        "missing everything",
+			input:       &models.Appointment{},
+			expectError: errors.New("invalid patientId, staffId, start, end"),
+		},
+		{
+			desc:        "too long",
+			input:       &models.Appointment{PatientID: 1, StaffID: 3, Start: at(0, 15, 0), End: at(1, 0, 0)},
+			expectError: errors.New("invalid end"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockStore := stores.NewMockAppointmentInterface(mockCtrl)
+			if test.stored != nil {
+				mockStore.EXPECT().Insert(gomock.Any(), test.stored).Return(test.stored, nil)
+			}
+			svc := New(mockStore)
+			svc.now = func() time.Time { return at(0, 12, 0) }
+
+			_, err := svc.Book(context.TODO(), test.input)
+			if (err == nil) != (test.expectError == nil) || (err != nil && err.Error() != test.expectError.Error()) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestAvailability(t *testing.T) {
+	booked := func(start, end time.Time) *models.Appointment {
+		return &models.Appointment{StaffID: 3, Start: start, End: end, Status: models.Booked}
+	}
+	tests := []struct {
+		desc     string
+		from, to time.Time
+		length   time.Duration
+		stored   []*models.Appointment
+		expected []*models.Slot
+	}{
+		{
+			desc:     "gaps around appointments",
+			from:     at(1, 0, 0),
+			to:       at(2, 0, 0),
+			stored:   []*models.Appointment{booked(at(1, 8, 30), at(1, 10, 0)), booked(at(1, 11, 0), at(1, 11, 30)), booked(at(1, 16, 0), at(1, 17, 30))},
+			expected: []*models.Slot{{Start: at(1, 10, 0), End: at(1, 11, 0)}, {Start: at(1, 11, 30), End: at(1, 16, 0)}},
+		},
+		{
+			desc:     "appointment inside another",
+			from:     at(1, 0, 0),
+			to:       at(2, 0, 0),
+			stored:   []*models.Appointment{booked(at(1, 9, 0), at(1, 12, 0)), booked(at(1, 10, 0), at(1, 11, 0)), booked(at(1, 14, 0), at(1, 17, 0))},
+			expected: []*models.Slot{{Start: at(1, 12, 0), End: at(1, 14, 0)}},
+		},
+		{
+			desc:     "cancelled appointments are free",
+			from:     at(1, 0, 0),
+			to:       at(2, 0, 0),
+			stored:   []*models.Appointment{{StaffID: 3, Start: at(1, 9, 0), End: at(1, 17, 0), Status: models.Cancelled}},
+			expected: []*models.Slot{{Start: at(1, 9, 0), End: at(1, 17, 0)}},
+		},
+		{
+			desc:     "from now on, over several days",
+			from:     at(0, 0, 0),
+			to:       at(1, 10, 0),
+			stored:   []*models.Appointment{},
+			expected: []*models.Slot{{Start: at(0, 12, 0), End: at(0, 17, 0)}, {Start: at(1, 9, 0), End: at(1, 10, 0)}},
+		},
+		{
+			desc:     "gaps shorter than length",
+			from:     at(1, 0, 0),
+			to:       at(2, 0, 0),
+			length:   time.Hour,
+			stored:   []*models.Appointment{booked(at(1, 9, 30), at(1, 16, 30))},
+			expected: []*models.Slot{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockStore := stores.NewMockAppointmentInterface(mockCtrl)
+			svc := New(mockStore)
+			svc.now = func() time.Time { return at(0, 12, 0) }
+			from := test.from
+			if from.Before(at(0, 12, 0)) {
+				from = at(0, 12, 0)
+			}
+			mockStore.EXPECT().List(gomock.Any(), models.AppointmentFilter{StaffID: 3, From: from, To: test.to}).Return(test.stored, nil)
+
+			got, err := svc.Availability(context.TODO(), 3, test.from, test.to, test.length)
+			if err != nil || !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected: %v, Got: %v (%v)", test.expected, got, err)
+			}
+		})
+	}
+}
+
+func TestValidation(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	svc := New(stores.NewMockAppointmentInterface(mockCtrl))
+
+	tests := []struct {
+		desc        string
+		call        func() error
+		expectError string
+	}{
+		{
+			desc: "availability without a range",
+			call: func() error {
+				_, err := svc.Availability(context.TODO(), 3, time.Time{}, time.Time{}, -time.Minute)
+				return err
+			},
+			expectError: "invalid from, to, length",
+		},
+		{
+			desc: "availability over too long a range",
+			call: func() error {
+				_, err := svc.Availability(context.TODO(), 3, at(0, 0, 0), at(40, 0, 0), 0)
+				return err
+			},
+			expectError: "invalid to",
+		},
+		{
+			desc: "list for nobody",
+			call: func() error {
+				_, err := svc.List(context.TODO(), models.AppointmentFilter{})
+				return err
+			},
+			expectError: "invalid id",
+		},
+		{
+			desc: "reschedule without an id",
+			call: func() error {
+				_, err := svc.Reschedule(context.TODO(), &models.Appointment{Start: time.Now().Add(time.Hour), End: time.Now().Add(2 * time.Hour)})
+				return err
+			},
+			expectError: "invalid id",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if err := test.call(); err == nil || err.Error() != test.expectError {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+		})
+	}
+}
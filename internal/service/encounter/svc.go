@@ -0,0 +1,168 @@
+package encounter
+
+import (
+	"context"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service/patient"
+	"github.com/aakanksha/ppms/internal/stores"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxReasonLength = 2000
+	maxDoctorLength = 100
+	maxWardLength   = 50
+)
+
+type Svc struct {
+	stores   stores.EncounterInterface
+	timeouts patient.Timeouts
+	now      func() time.Time
+}
+
+func New(stores stores.EncounterInterface) *Svc {
+	return &Svc{stores: stores, now: time.Now}
+}
+
+// WithTimeouts applies the read and write deadlines of the patient service to
+// encounter calls.
+func (es *Svc) WithTimeouts(t patient.Timeouts) *Svc {
+	es.timeouts = t
+	return es
+}
+
+func (es *Svc) List(ctx context.Context, patientID int) ([]*models.Encounter, error) {
+	if patientID <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := es.timeouts.With(ctx, es.timeouts.Read)
+	defer cancel()
+	return es.stores.List(ctx, patientID)
+}
+
+func (es *Svc) Get(ctx context.Context, patientID, id int) (*models.Encounter, error) {
+	if err := validIds(patientID, id); err != nil {
+		return nil, err
+	}
+	ctx, cancel := es.timeouts.With(ctx, es.timeouts.Read)
+	defer cancel()
+	return es.stores.Get(ctx, patientID, id)
+}
+
+// Admit opens an encounter, admitted now unless AdmittedAt says otherwise. An
+// encounter given a DischargedAt records a past stay instead.
+func (es *Svc) Admit(ctx context.Context, e *models.Encounter) (*models.Encounter, error) {
+	if e.PatientID <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	if e.AdmittedAt.IsZero() {
+		e.AdmittedAt = es.now()
+	}
+	if err := es.validate(e); err != nil {
+		return nil, err
+	}
+	ctx, cancel := es.timeouts.With(ctx, es.timeouts.Write)
+	defer cancel()
+	return es.stores.Insert(ctx, e)
+}
+
+// Update overwrites an encounter; a non-zero Version makes the write
+// conditional on it. Clearing DischargedAt reopens the encounter.
+func (es *Svc) Update(ctx context.Context, e *models.Encounter) (*models.Encounter, error) {
+	if err := validIds(e.PatientID, e.ID); err != nil {
+		return nil, err
+	}
+	if e.AdmittedAt.IsZero() {
+		return nil, perrors.NewValidation("admittedAt", "is required")
+	}
+	if err := es.validate(e); err != nil {
+		return nil, err
+	}
+	ctx, cancel := es.timeouts.With(ctx, es.timeouts.Write)
+	defer cancel()
+	return es.stores.Update(ctx, e)
+}
+
+// Discharge closes an open encounter now. The write is conditional on the
+// version that was read, or on version when it is non-zero.
+func (es *Svc) Discharge(ctx context.Context, patientID, id int, version int) (*models.Encounter, error) {
+	if err := validIds(patientID, id); err != nil {
+		return nil, err
+	}
+	ctx, cancel := es.timeouts.With(ctx, es.timeouts.Write)
+	defer cancel()
+	current, err := es.stores.Get(ctx, patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	if version > 0 && version != current.Version {
+		return nil, &perrors.PreconditionFailed{Entity: "encounter", ID: strconv.Itoa(id)}
+	}
+	if current.DischargedAt != nil {
+		return nil, &perrors.Conflict{Entity: "encounter", Reason: "is already discharged"}
+	}
+	now := es.now().UTC().Truncate(time.Second)
+	if now.Before(current.AdmittedAt) {
+		now = current.AdmittedAt
+	}
+	current.DischargedAt = &now
+	return es.stores.Update(ctx, current)
+}
+
+func validIds(patientID, id int) error {
+	verr := &perrors.Validation{}
+	if patientID <= 0 {
+		verr.Add("id", "must be a positive integer")
+	}
+	if id <= 0 {
+		verr.Add("encounterId", "must be a positive integer")
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// validate trims the text fields of e and brings its times to whole seconds in
+// UTC, as they are stored.
+func (es *Svc) validate(e *models.Encounter) error {
+	verr := &perrors.Validation{}
+	now := es.now()
+
+	e.AdmittedAt = e.AdmittedAt.UTC().Truncate(time.Second)
+	if e.AdmittedAt.After(now) {
+		verr.Add("admittedAt", "must not be in the future")
+	}
+	if e.DischargedAt != nil {
+		discharged := e.DischargedAt.UTC().Truncate(time.Second)
+		e.DischargedAt = &discharged
+		switch {
+		case discharged.After(now):
+			verr.Add("dischargedAt", "must not be in the future")
+		case discharged.Before(e.AdmittedAt):
+			verr.Add("dischargedAt", "must not be before admittedAt")
+		}
+	}
+
+	e.Reason = strings.TrimSpace(e.Reason)
+	if utf8.RuneCountInString(e.Reason) > maxReasonLength {
+		verr.Add("reason", "must be at most 2000 characters")
+	}
+	e.Doctor = strings.TrimSpace(e.Doctor)
+	if utf8.RuneCountInString(e.Doctor) > maxDoctorLength {
+		verr.Add("doctor", "must be at most 100 characters")
+	}
+	e.Ward = strings.TrimSpace(e.Ward)
+	if utf8.RuneCountInString(e.Ward) > maxWardLength {
+		verr.Add("ward", "must be at most 50 characters")
+	}
+
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
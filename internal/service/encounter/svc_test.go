@@ -0,0 +1,158 @@
+package encounter
+
+import (
+	"context"
+	"errors"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores"
+	"github.com/golang/mock/gomock"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)
+
+func at(hours int) *time.Time {
+	t := now.Add(time.Duration(hours) * time.Hour)
+	return &t
+}
+
+func TestAdmit(t *testing.T) {
+	tests := []struct {
+		desc        string
+		input       *models.Encounter
+		stored      *models.Encounter
+		expectError error
+	}{
+		{
+			desc:   "admitted now by default",
+			input:  &models.Encounter{PatientID: 1, Reason: " chest pain ", Doctor: "Dr. Rao", Ward: "ICU"},
+			stored: &models.Encounter{PatientID: 1, AdmittedAt: now, Reason: "chest pain", Doctor: "Dr. Rao", Ward: "ICU"},
+		},
+		{
+			desc:   "past stay",
+			input:  &models.Encounter{PatientID: 1, AdmittedAt: *at(-48), DischargedAt: at(-24)},
+			stored: &models.Encounter{PatientID: 1, AdmittedAt: *at(-48), DischargedAt: at(-24)},
+		},
+		{
+			desc:        "invalid patient id",
+			input:       &models.Encounter{PatientID: 0},
+			expectError: errors.New("invalid id"),
+		},
+		{
+			desc:        "admitted in the future",
+			input:       &models.Encounter{PatientID: 1, AdmittedAt: *at(1)},
+			expectError: errors.New("invalid admittedAt"),
+		},
+		{
+			desc:        "discharged before admission",
+			input:       &models.Encounter{PatientID: 1, AdmittedAt: *at(-1), DischargedAt: at(-2)},
+			expectError: errors.New("invalid dischargedAt"),
+		},
+		{
+			desc:        "fields too long",
+			input:       &models.Encounter{PatientID: 1, Reason: strings.Repeat("a", 2001), Doctor: strings.Repeat("d", 101), Ward: strings.Repeat("w", 51)},
+			expectError: errors.New("invalid reason, doctor, ward"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockStore := stores.NewMockEncounterInterface(mockCtrl)
+			if test.stored != nil {
+				mockStore.EXPECT().Insert(gomock.Any(), test.stored).Return(test.stored, nil)
+			}
+			svc := New(mockStore)
+			svc.now = func() time.Time { return now }
+
+			_, err := svc.Admit(context.TODO(), test.input)
+			if (err == nil) != (test.expectError == nil) || (err != nil && err.Error() != test.expectError.Error()) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockStore := stores.NewMockEncounterInterface(mockCtrl)
+	svc := New(mockStore)
+	svc.now = func() time.Time { return now }
+
+	_, err := svc.Update(context.TODO(), &models.Encounter{PatientID: 1, ID: 2})
+	if err == nil || err.Error() != "invalid admittedAt" {
+		t.Errorf("expected error :invalid admittedAt, got :%v ", err)
+	}
+	_, err = svc.Update(context.TODO(), &models.Encounter{PatientID: 1, ID: 0, AdmittedAt: now})
+	if err == nil || err.Error() != "invalid encounterId" {
+		t.Errorf("expected error :invalid encounterId, got :%v ", err)
+	}
+
+	e := &models.Encounter{PatientID: 1, ID: 2, AdmittedAt: now.Add(-time.Hour), Ward: "B2 ", Version: 3}
+	expected := &models.Encounter{PatientID: 1, ID: 2, AdmittedAt: now.Add(-time.Hour), Ward: "B2", Version: 3}
+	mockStore.EXPECT().Update(gomock.Any(), expected).Return(expected, nil)
+	if _, err := svc.Update(context.TODO(), e); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+}
+
+func TestDischarge(t *testing.T) {
+	open := func() *models.Encounter {
+		return &models.Encounter{PatientID: 1, ID: 2, AdmittedAt: *at(-5), Version: 4}
+	}
+	tests := []struct {
+		desc        string
+		version     int
+		current     *models.Encounter
+		written     *models.Encounter
+		expectError error
+	}{
+		{
+			desc:    "discharged now",
+			current: open(),
+			written: &models.Encounter{PatientID: 1, ID: 2, AdmittedAt: *at(-5), DischargedAt: &now, Version: 4},
+		},
+		{
+			desc:    "matching version",
+			version: 4,
+			current: open(),
+			written: &models.Encounter{PatientID: 1, ID: 2, AdmittedAt: *at(-5), DischargedAt: &now, Version: 4},
+		},
+		{
+			desc:        "stale version",
+			version:     3,
+			current:     open(),
+			expectError: &perrors.PreconditionFailed{Entity: "encounter", ID: "2"},
+		},
+		{
+			desc:        "already discharged",
+			current:     &models.Encounter{PatientID: 1, ID: 2, AdmittedAt: *at(-5), DischargedAt: at(-1), Version: 5},
+			expectError: &perrors.Conflict{Entity: "encounter", Reason: "is already discharged"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockStore := stores.NewMockEncounterInterface(mockCtrl)
+			mockStore.EXPECT().Get(gomock.Any(), 1, 2).Return(test.current, nil)
+			if test.written != nil {
+				mockStore.EXPECT().Update(gomock.Any(), test.written).Return(test.written, nil)
+			}
+			svc := New(mockStore)
+			svc.now = func() time.Time { return now }
+
+			_, err := svc.Discharge(context.TODO(), 1, 2, test.version)
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/patch"
+	"github.com/aakanksha/ppms/internal/transfer"
+	"time"
+)
+
+//go:generate mockgen -source=interface.go -destination=mock_interface.go -package=service
+
+type ServiceInterface interface {
+	Insert(ctx context.Context, pt *models.Patient) (*models.Patient, error)
+	GetByID(ctx context.Context, id int) (*models.Patient, error)
+	GetAll(ctx context.Context) ([]*models.Patient, error)
+	List(ctx context.Context, opts models.ListOptions) (*models.PatientPage, error)
+	Search(ctx context.Context, query string, opts models.ListOptions) (*models.PatientPage, error)
+	Update(ctx context.Context, pt *models.Patient, id int) (*models.Patient, error)
+	Patch(ctx context.Context, id int, version int, format patch.Format, body []byte) (*models.Patient, error)
+	Delete(ctx context.Context, id int, version int) error
+	Restore(ctx context.Context, id int) (*models.Patient, error)
+	Purge(ctx context.Context) (int64, error)
+	History(ctx context.Context, id int) ([]*models.AuditEntry, error)
+	BulkInsert(ctx context.Context, pts []*models.Patient, mode models.BulkMode) ([]models.BulkResult, error)
+	BulkUpdate(ctx context.Context, pts []*models.Patient, mode models.BulkMode) ([]models.BulkResult, error)
+	BulkDelete(ctx context.Context, refs []models.PatientRef, mode models.BulkMode) ([]models.BulkResult, error)
+	Export(ctx context.Context, fn func(*models.Patient) error) error
+	Import(ctx context.Context, src transfer.Reader, dryRun bool) (*models.ImportReport, error)
+}
+
+type EncounterInterface interface {
+	List(ctx context.Context, patientID int) ([]*models.Encounter, error)
+	Get(ctx context.Context, patientID, id int) (*models.Encounter, error)
+	Admit(ctx context.Context, e *models.Encounter) (*models.Encounter, error)
+	Update(ctx context.Context, e *models.Encounter) (*models.Encounter, error)
+	Discharge(ctx context.Context, patientID, id int, version int) (*models.Encounter, error)
+}
+
+type WardInterface interface {
+	CreateWard(ctx context.Context, w *models.Ward) (*models.Ward, error)
+	ListWards(ctx context.Context) ([]*models.Ward, error)
+	GetWard(ctx context.Context, id int) (*models.Ward, error)
+	AddBed(ctx context.Context, b *models.Bed) (*models.Bed, error)
+	Occupancy(ctx context.Context, wardID int) (*models.Occupancy, error)
+	CurrentBed(ctx context.Context, patientID int) (*models.BedAssignment, error)
+	Assign(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error)
+	Transfer(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error)
+	Release(ctx context.Context, patientID int) (*models.BedAssignment, error)
+}
+
+type TriageInterface interface {
+	Enqueue(ctx context.Context, t *models.Triage) (*models.Triage, error)
+	Queue(ctx context.Context) ([]*models.Triage, error)
+	Peek(ctx context.Context) (*models.Triage, error)
+	CallNext(ctx context.Context) (*models.Triage, error)
+	Retriage(ctx context.Context, id, level int) (*models.Triage, error)
+}
+
+type StaffInterface interface {
+	List(ctx context.Context, profession models.Profession) ([]*models.Staff, error)
+	Get(ctx context.Context, id int) (*models.Staff, error)
+	Insert(ctx context.Context, st *models.Staff) (*models.Staff, error)
+	Update(ctx context.Context, st *models.Staff) (*models.Staff, error)
+	Delete(ctx context.Context, id, version int) error
+	Team(ctx context.Context, patientID int) ([]*models.CareTeamMember, error)
+	Patients(ctx context.Context, staffID int) ([]*models.CareTeamMember, error)
+	Assign(ctx context.Context, m *models.CareTeamMember) (*models.CareTeamMember, error)
+	Unassign(ctx context.Context, patientID, staffID int) error
+}
+
+type AppointmentInterface interface {
+	List(ctx context.Context, f models.AppointmentFilter) ([]*models.Appointment, error)
+	Get(ctx context.Context, id int) (*models.Appointment, error)
+	Book(ctx context.Context, a *models.Appointment) (*models.Appointment, error)
+	Reschedule(ctx context.Context, a *models.Appointment) (*models.Appointment, error)
+	Cancel(ctx context.Context, id, version int) (*models.Appointment, error)
+	Availability(ctx context.Context, staffID int, from, to time.Time, length time.Duration) ([]*models.Slot, error)
+}
@@ -0,0 +1,900 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: interface.go
+
+// Package service is a generated GoMock package.
+package service
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	models "github.com/aakanksha/ppms/internal/models"
+	patch "github.com/aakanksha/ppms/internal/patch"
+	transfer "github.com/aakanksha/ppms/internal/transfer"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockServiceInterface is a mock of ServiceInterface interface.
+type MockServiceInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceInterfaceMockRecorder
+}
+
+// MockServiceInterfaceMockRecorder is the mock recorder for MockServiceInterface.
+type MockServiceInterfaceMockRecorder struct {
+	mock *MockServiceInterface
+}
+
+// NewMockServiceInterface creates a new mock instance.
+func NewMockServiceInterface(ctrl *gomock.Controller) *MockServiceInterface {
+	mock := &MockServiceInterface{ctrl: ctrl}
+	mock.recorder = &MockServiceInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockServiceInterface) EXPECT() *MockServiceInterfaceMockRecorder {
+	return m.recorder
+}
+
+// BulkDelete mocks base method.
+func (m *MockServiceInterface) BulkDelete(ctx context.Context, refs []models.PatientRef, mode models.BulkMode) ([]models.BulkResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkDelete", ctx, refs, mode)
+	ret0, _ := ret[0].([]models.BulkResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkDelete indicates an expected call of BulkDelete.
+func (mr *MockServiceInterfaceMockRecorder) BulkDelete(ctx, refs, mode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkDelete", reflect.TypeOf((*MockServiceInterface)(nil).BulkDelete), ctx, refs, mode)
+}
+
+// BulkInsert mocks base method.
+func (m *MockServiceInterface) BulkInsert(ctx context.Context, pts []*models.Patient, mode models.BulkMode) ([]models.BulkResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkInsert", ctx, pts, mode)
+	ret0, _ := ret[0].([]models.BulkResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkInsert indicates an expected call of BulkInsert.
+func (mr *MockServiceInterfaceMockRecorder) BulkInsert(ctx, pts, mode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkInsert", reflect.TypeOf((*MockServiceInterface)(nil).BulkInsert), ctx, pts, mode)
+}
+
+// BulkUpdate mocks base method.
+func (m *MockServiceInterface) BulkUpdate(ctx context.Context, pts []*models.Patient, mode models.BulkMode) ([]models.BulkResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkUpdate", ctx, pts, mode)
+	ret0, _ := ret[0].([]models.BulkResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkUpdate indicates an expected call of BulkUpdate.
+func (mr *MockServiceInterfaceMockRecorder) BulkUpdate(ctx, pts, mode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkUpdate", reflect.TypeOf((*MockServiceInterface)(nil).BulkUpdate), ctx, pts, mode)
+}
+
+// Delete mocks base method.
+func (m *MockServiceInterface) Delete(ctx context.Context, id, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockServiceInterfaceMockRecorder) Delete(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockServiceInterface)(nil).Delete), ctx, id, version)
+}
+
+// Export mocks base method.
+func (m *MockServiceInterface) Export(ctx context.Context, fn func(*models.Patient) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockServiceInterfaceMockRecorder) Export(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockServiceInterface)(nil).Export), ctx, fn)
+}
+
+// GetAll mocks base method.
+func (m *MockServiceInterface) GetAll(ctx context.Context) ([]*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret0, _ := ret[0].([]*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockServiceInterfaceMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockServiceInterface)(nil).GetAll), ctx)
+}
+
+// GetByID mocks base method.
+func (m *MockServiceInterface) GetByID(ctx context.Context, id int) (*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
+	ret0, _ := ret[0].(*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockServiceInterfaceMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockServiceInterface)(nil).GetByID), ctx, id)
+}
+
+// History mocks base method.
+func (m *MockServiceInterface) History(ctx context.Context, id int) ([]*models.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", ctx, id)
+	ret0, _ := ret[0].([]*models.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// History indicates an expected call of History.
+func (mr *MockServiceInterfaceMockRecorder) History(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockServiceInterface)(nil).History), ctx, id)
+}
+
+// Import mocks base method.
+func (m *MockServiceInterface) Import(ctx context.Context, src transfer.Reader, dryRun bool) (*models.ImportReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Import", ctx, src, dryRun)
+	ret0, _ := ret[0].(*models.ImportReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Import indicates an expected call of Import.
+func (mr *MockServiceInterfaceMockRecorder) Import(ctx, src, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Import", reflect.TypeOf((*MockServiceInterface)(nil).Import), ctx, src, dryRun)
+}
+
+// Insert mocks base method.
+func (m *MockServiceInterface) Insert(ctx context.Context, pt *models.Patient) (*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, pt)
+	ret0, _ := ret[0].(*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockServiceInterfaceMockRecorder) Insert(ctx, pt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockServiceInterface)(nil).Insert), ctx, pt)
+}
+
+// List mocks base method.
+func (m *MockServiceInterface) List(ctx context.Context, opts models.ListOptions) (*models.PatientPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, opts)
+	ret0, _ := ret[0].(*models.PatientPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockServiceInterfaceMockRecorder) List(ctx, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockServiceInterface)(nil).List), ctx, opts)
+}
+
+// Patch mocks base method.
+func (m *MockServiceInterface) Patch(ctx context.Context, id, version int, format patch.Format, body []byte) (*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", ctx, id, version, format, body)
+	ret0, _ := ret[0].(*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Patch indicates an expected call of Patch.
+func (mr *MockServiceInterfaceMockRecorder) Patch(ctx, id, version, format, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*MockServiceInterface)(nil).Patch), ctx, id, version, format, body)
+}
+
+// Purge mocks base method.
+func (m *MockServiceInterface) Purge(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockServiceInterfaceMockRecorder) Purge(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockServiceInterface)(nil).Purge), ctx)
+}
+
+// Restore mocks base method.
+func (m *MockServiceInterface) Restore(ctx context.Context, id int) (*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, id)
+	ret0, _ := ret[0].(*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockServiceInterfaceMockRecorder) Restore(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockServiceInterface)(nil).Restore), ctx, id)
+}
+
+// Search mocks base method.
+func (m *MockServiceInterface) Search(ctx context.Context, query string, opts models.ListOptions) (*models.PatientPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, query, opts)
+	ret0, _ := ret[0].(*models.PatientPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockServiceInterfaceMockRecorder) Search(ctx, query, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockServiceInterface)(nil).Search), ctx, query, opts)
+}
+
+// Update mocks base method.
+func (m *MockServiceInterface) Update(ctx context.Context, pt *models.Patient, id int) (*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, pt, id)
+	ret0, _ := ret[0].(*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockServiceInterfaceMockRecorder) Update(ctx, pt, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockServiceInterface)(nil).Update), ctx, pt, id)
+}
+
+// MockEncounterInterface is a mock of EncounterInterface interface.
+type MockEncounterInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockEncounterInterfaceMockRecorder
+}
+
+// MockEncounterInterfaceMockRecorder is the mock recorder for MockEncounterInterface.
+type MockEncounterInterfaceMockRecorder struct {
+	mock *MockEncounterInterface
+}
+
+// NewMockEncounterInterface creates a new mock instance.
+func NewMockEncounterInterface(ctrl *gomock.Controller) *MockEncounterInterface {
+	mock := &MockEncounterInterface{ctrl: ctrl}
+	mock.recorder = &MockEncounterInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEncounterInterface) EXPECT() *MockEncounterInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Admit mocks base method.
+func (m *MockEncounterInterface) Admit(ctx context.Context, e *models.Encounter) (*models.Encounter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Admit", ctx, e)
+	ret0, _ := ret[0].(*models.Encounter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Admit indicates an expected call of Admit.
+func (mr *MockEncounterInterfaceMockRecorder) Admit(ctx, e interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Admit", reflect.TypeOf((*MockEncounterInterface)(nil).Admit), ctx, e)
+}
+
+// Discharge mocks base method.
+func (m *MockEncounterInterface) Discharge(ctx context.Context, patientID, id, version int) (*models.Encounter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Discharge", ctx, patientID, id, version)
+	ret0, _ := ret[0].(*models.Encounter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Discharge indicates an expected call of Discharge.
+func (mr *MockEncounterInterfaceMockRecorder) Discharge(ctx, patientID, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Discharge", reflect.TypeOf((*MockEncounterInterface)(nil).Discharge), ctx, patientID, id, version)
+}
+
+// Get mocks base method.
+func (m *MockEncounterInterface) Get(ctx context.Context, patientID, id int) (*models.Encounter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, patientID, id)
+	ret0, _ := ret[0].(*models.Encounter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockEncounterInterfaceMockRecorder) Get(ctx, patientID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockEncounterInterface)(nil).Get), ctx, patientID, id)
+}
+
+// List mocks base method.
+func (m *MockEncounterInterface) List(ctx context.Context, patientID int) ([]*models.Encounter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, patientID)
+	ret0, _ := ret[0].([]*models.Encounter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockEncounterInterfaceMockRecorder) List(ctx, patientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockEncounterInterface)(nil).List), ctx, patientID)
+}
+
+// Update mocks base method.
+func (m *MockEncounterInterface) Update(ctx context.Context, e *models.Encounter) (*models.Encounter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, e)
+	ret0, _ := ret[0].(*models.Encounter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockEncounterInterfaceMockRecorder) Update(ctx, e interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockEncounterInterface)(nil).Update), ctx, e)
+}
+
+// MockWardInterface is a mock of WardInterface interface.
+type MockWardInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockWardInterfaceMockRecorder
+}
+
+// MockWardInterfaceMockRecorder is the mock recorder for MockWardInterface.
+type MockWardInterfaceMockRecorder struct {
+	mock *MockWardInterface
+}
+
+// NewMockWardInterface creates a new mock instance.
+func NewMockWardInterface(ctrl *gomock.Controller) *MockWardInterface {
+	mock := &MockWardInterface{ctrl: ctrl}
+	mock.recorder = &MockWardInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWardInterface) EXPECT() *MockWardInterfaceMockRecorder {
+	return m.recorder
+}
+
+// AddBed mocks base method.
+func (m *MockWardInterface) AddBed(ctx context.Context, b *models.Bed) (*models.Bed, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddBed", ctx, b)
+	ret0, _ := ret[0].(*models.Bed)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddBed indicates an expected call of AddBed.
+func (mr *MockWardInterfaceMockRecorder) AddBed(ctx, b interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBed", reflect.TypeOf((*MockWardInterface)(nil).AddBed), ctx, b)
+}
+
+// Assign mocks base method.
+func (m *MockWardInterface) Assign(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Assign", ctx, patientID, bedID)
+	ret0, _ := ret[0].(*models.BedAssignment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Assign indicates an expected call of Assign.
+func (mr *MockWardInterfaceMockRecorder) Assign(ctx, patientID, bedID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Assign", reflect.TypeOf((*MockWardInterface)(nil).Assign), ctx, patientID, bedID)
+}
+
+// CreateWard mocks base method.
+func (m *MockWardInterface) CreateWard(ctx context.Context, w *models.Ward) (*models.Ward, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWard", ctx, w)
+	ret0, _ := ret[0].(*models.Ward)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWard indicates an expected call of CreateWard.
+func (mr *MockWardInterfaceMockRecorder) CreateWard(ctx, w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWard", reflect.TypeOf((*MockWardInterface)(nil).CreateWard), ctx, w)
+}
+
+// CurrentBed mocks base method.
+func (m *MockWardInterface) CurrentBed(ctx context.Context, patientID int) (*models.BedAssignment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CurrentBed", ctx, patientID)
+	ret0, _ := ret[0].(*models.BedAssignment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CurrentBed indicates an expected call of CurrentBed.
+func (mr *MockWardInterfaceMockRecorder) CurrentBed(ctx, patientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CurrentBed", reflect.TypeOf((*MockWardInterface)(nil).CurrentBed), ctx, patientID)
+}
+
+// GetWard mocks base method.
+func (m *MockWardInterface) GetWard(ctx context.Context, id int) (*models.Ward, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWard", ctx, id)
+	ret0, _ := ret[0].(*models.Ward)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWard indicates an expected call of GetWard.
+func (mr *MockWardInterfaceMockRecorder) GetWard(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWard", reflect.TypeOf((*MockWardInterface)(nil).GetWard), ctx, id)
+}
+
+// ListWards mocks base method.
+func (m *MockWardInterface) ListWards(ctx context.Context) ([]*models.Ward, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWards", ctx)
+	ret0, _ := ret[0].([]*models.Ward)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWards indicates an expected call of ListWards.
+func (mr *MockWardInterfaceMockRecorder) ListWards(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWards", reflect.TypeOf((*MockWardInterface)(nil).ListWards), ctx)
+}
+
+// Occupancy mocks base method.
+func (m *MockWardInterface) Occupancy(ctx context.Context, wardID int) (*models.Occupancy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Occupancy", ctx, wardID)
+	ret0, _ := ret[0].(*models.Occupancy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Occupancy indicates an expected call of Occupancy.
+func (mr *MockWardInterfaceMockRecorder) Occupancy(ctx, wardID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Occupancy", reflect.TypeOf((*MockWardInterface)(nil).Occupancy), ctx, wardID)
+}
+
+// Release mocks base method.
+func (m *MockWardInterface) Release(ctx context.Context, patientID int) (*models.BedAssignment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, patientID)
+	ret0, _ := ret[0].(*models.BedAssignment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockWardInterfaceMockRecorder) Release(ctx, patientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockWardInterface)(nil).Release), ctx, patientID)
+}
+
+// Transfer mocks base method.
+func (m *MockWardInterface) Transfer(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", ctx, patientID, bedID)
+	ret0, _ := ret[0].(*models.BedAssignment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockWardInterfaceMockRecorder) Transfer(ctx, patientID, bedID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockWardInterface)(nil).Transfer), ctx, patientID, bedID)
+}
+
+// MockTriageInterface is a mock of TriageInterface interface.
+type MockTriageInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockTriageInterfaceMockRecorder
+}
+
+// MockTriageInterfaceMockRecorder is the mock recorder for MockTriageInterface.
+type MockTriageInterfaceMockRecorder struct {
+	mock *MockTriageInterface
+}
+
+// NewMockTriageInterface creates a new mock instance.
+func NewMockTriageInterface(ctrl *gomock.Controller) *MockTriageInterface {
+	mock := &MockTriageInterface{ctrl: ctrl}
+	mock.recorder = &MockTriageInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTriageInterface) EXPECT() *MockTriageInterfaceMockRecorder {
+	return m.recorder
+}
+
+// CallNext mocks base method.
+func (m *MockTriageInterface) CallNext(ctx context.Context) (*models.Triage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CallNext", ctx)
+	ret0, _ := ret[0].(*models.Triage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CallNext indicates an expected call of CallNext.
+func (mr *MockTriageInterfaceMockRecorder) CallNext(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CallNext", reflect.TypeOf((*MockTriageInterface)(nil).CallNext), ctx)
+}
+
+// Enqueue mocks base method.
+func (m *MockTriageInterface) Enqueue(ctx context.Context, t *models.Triage) (*models.Triage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enqueue", ctx, t)
+	ret0, _ := ret[0].(*models.Triage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enqueue indicates an expected call of Enqueue.
+func (mr *MockTriageInterfaceMockRecorder) Enqueue(ctx, t interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enqueue", reflect.TypeOf((*MockTriageInterface)(nil).Enqueue), ctx, t)
+}
+
+// Peek mocks base method.
+func (m *MockTriageInterface) Peek(ctx context.Context) (*models.Triage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Peek", ctx)
+	ret0, _ := ret[0].(*models.Triage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Peek indicates an expected call of Peek.
+func (mr *MockTriageInterfaceMockRecorder) Peek(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Peek", reflect.TypeOf((*MockTriageInterface)(nil).Peek), ctx)
+}
+
+// Queue mocks base method.
+func (m *MockTriageInterface) Queue(ctx context.Context) ([]*models.Triage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Queue", ctx)
+	ret0, _ := ret[0].([]*models.Triage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Queue indicates an expected call of Queue.
+func (mr *MockTriageInterfaceMockRecorder) Queue(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Queue", reflect.TypeOf((*MockTriageInterface)(nil).Queue), ctx)
+}
+
+// Retriage mocks base method.
+func (m *MockTriageInterface) Retriage(ctx context.Context, id, level int) (*models.Triage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Retriage", ctx, id, level)
+	ret0, _ := ret[0].(*models.Triage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Retriage indicates an expected call of Retriage.
+func (mr *MockTriageInterfaceMockRecorder) Retriage(ctx, id, level interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Retriage", reflect.TypeOf((*MockTriageInterface)(nil).Retriage), ctx, id, level)
+}
+
+// MockStaffInterface is a mock of StaffInterface interface.
+type MockStaffInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockStaffInterfaceMockRecorder
+}
+
+// MockStaffInterfaceMockRecorder is the mock recorder for MockStaffInterface.
+type MockStaffInterfaceMockRecorder struct {
+	mock *MockStaffInterface
+}
+
+// NewMockStaffInterface creates a new mock instance.
+func NewMockStaffInterface(ctrl *gomock.Controller) *MockStaffInterface {
+	mock := &MockStaffInterface{ctrl: ctrl}
+	mock.recorder = &MockStaffInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStaffInterface) EXPECT() *MockStaffInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Assign mocks base method.
+func (m_2 *MockStaffInterface) Assign(ctx context.Context, m *models.CareTeamMember) (*models.CareTeamMember, error) {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "Assign", ctx, m)
+	ret0, _ := ret[0].(*models.CareTeamMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Assign indicates an expected call of Assign.
+func (mr *MockStaffInterfaceMockRecorder) Assign(ctx, m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Assign", reflect.TypeOf((*MockStaffInterface)(nil).Assign), ctx, m)
+}
+
+// Delete mocks base method.
+func (m *MockStaffInterface) Delete(ctx context.Context, id, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockStaffInterfaceMockRecorder) Delete(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStaffInterface)(nil).Delete), ctx, id, version)
+}
+
+// Get mocks base method.
+func (m *MockStaffInterface) Get(ctx context.Context, id int) (*models.Staff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(*models.Staff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockStaffInterfaceMockRecorder) Get(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStaffInterface)(nil).Get), ctx, id)
+}
+
+// Insert mocks base method.
+func (m *MockStaffInterface) Insert(ctx context.Context, st *models.Staff) (*models.Staff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, st)
+	ret0, _ := ret[0].(*models.Staff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockStaffInterfaceMockRecorder) Insert(ctx, st interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockStaffInterface)(nil).Insert), ctx, st)
+}
+
+// List mocks base method.
+func (m *MockStaffInterface) List(ctx context.Context, profession models.Profession) ([]*models.Staff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, profession)
+	ret0, _ := ret[0].([]*models.Staff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockStaffInterfaceMockRecorder) List(ctx, profession interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockStaffInterface)(nil).List), ctx, profession)
+}
+
+// Patients mocks base method.
+func (m *MockStaffInterface) Patients(ctx context.Context, staffID int) ([]*models.CareTeamMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patients", ctx, staffID)
+	ret0, _ := ret[0].([]*models.CareTeamMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Patients indicates an expected call of Patients.
+func (mr *MockStaffInterfaceMockRecorder) Patients(ctx, staffID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patients", reflect.TypeOf((*MockStaffInterface)(nil).Patients), ctx, staffID)
+}
+
+// Team mocks base method.
+func (m *MockStaffInterface) Team(ctx context.Context, patientID int) ([]*models.CareTeamMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Team", ctx, patientID)
+	ret0, _ := ret[0].([]*models.CareTeamMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Team indicates an expected call of Team.
+func (mr *MockStaffInterfaceMockRecorder) Team(ctx, patientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Team", reflect.TypeOf((*MockStaffInterface)(nil).Team), ctx, patientID)
+}
+
+// Unassign mocks base method.
+func (m *MockStaffInterface) Unassign(ctx context.Context, patientID, staffID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unassign", ctx, patientID, staffID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unassign indicates an expected call of Unassign.
+func (mr *MockStaffInterfaceMockRecorder) Unassign(ctx, patientID, staffID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unassign", reflect.TypeOf((*MockStaffInterface)(nil).Unassign), ctx, patientID, staffID)
+}
+
+// Update mocks base method.
+func (m *MockStaffInterface) Update(ctx context.Context, st *models.Staff) (*models.Staff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, st)
+	ret0, _ := ret[0].(*models.Staff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStaffInterfaceMockRecorder) Update(ctx, st interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStaffInterface)(nil).Update), ctx, st)
+}
+
+// MockAppointmentInterface is a mock of AppointmentInterface interface.
+type MockAppointmentInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockAppointmentInterfaceMockRecorder
+}
+
+// MockAppointmentInterfaceMockRecorder is the mock recorder for MockAppointmentInterface.
+type MockAppointmentInterfaceMockRecorder struct {
+	mock *MockAppointmentInterface
+}
+
+// NewMockAppointmentInterface creates a new mock instance.
+func NewMockAppointmentInterface(ctrl *gomock.Controller) *MockAppointmentInterface {
+	mock := &MockAppointmentInterface{ctrl: ctrl}
+	mock.recorder = &MockAppointmentInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAppointmentInterface) EXPECT() *MockAppointmentInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Availability mocks base method.
+func (m *MockAppointmentInterface) Availability(ctx context.Context, staffID int, from, to time.Time, length time.Duration) ([]*models.Slot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Availability", ctx, staffID, from, to, length)
+	ret0, _ := ret[0].([]*models.Slot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Availability indicates an expected call of Availability.
+func (mr *MockAppointmentInterfaceMockRecorder) Availability(ctx, staffID, from, to, length interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Availability", reflect.TypeOf((*MockAppointmentInterface)(nil).Availability), ctx, staffID, from, to, length)
+}
+
+// Book mocks base method.
+func (m *MockAppointmentInterface) Book(ctx context.Context, a *models.Appointment) (*models.Appointment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Book", ctx, a)
+	ret0, _ := ret[0].(*models.Appointment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Book indicates an expected call of Book.
+func (mr *MockAppointmentInterfaceMockRecorder) Book(ctx, a interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Book", reflect.TypeOf((*MockAppointmentInterface)(nil).Book), ctx, a)
+}
+
+// Cancel mocks base method.
+func (m *MockAppointmentInterface) Cancel(ctx context.Context, id, version int) (*models.Appointment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cancel", ctx, id, version)
+	ret0, _ := ret[0].(*models.Appointment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Cancel indicates an expected call of Cancel.
+func (mr *MockAppointmentInterfaceMockRecorder) Cancel(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cancel", reflect.TypeOf((*MockAppointmentInterface)(nil).Cancel), ctx, id, version)
+}
+
+// Get mocks base method.
+func (m *MockAppointmentInterface) Get(ctx context.Context, id int) (*models.Appointment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(*models.Appointment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockAppointmentInterfaceMockRecorder) Get(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockAppointmentInterface)(nil).Get), ctx, id)
+}
+
+// List mocks base method.
+func (m *MockAppointmentInterface) List(ctx context.Context, f models.AppointmentFilter) ([]*models.Appointment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, f)
+	ret0, _ := ret[0].([]*models.Appointment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockAppointmentInterfaceMockRecorder) List(ctx, f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAppointmentInterface)(nil).List), ctx, f)
+}
+
+// Reschedule mocks base method.
+func (m *MockAppointmentInterface) Reschedule(ctx context.Context, a *models.Appointment) (*models.Appointment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reschedule", ctx, a)
+	ret0, _ := ret[0].(*models.Appointment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reschedule indicates an expected call of Reschedule.
+func (mr *MockAppointmentInterfaceMockRecorder) Reschedule(ctx, a interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reschedule", reflect.TypeOf((*MockAppointmentInterface)(nil).Reschedule), ctx, a)
+}
@@ -0,0 +1,185 @@
+package staff
+
+import (
+	"context"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service/patient"
+	"github.com/aakanksha/ppms/internal/stores"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxNameLength      = 100
+	maxSpecialtyLength = 100
+	maxEmailLength     = 254
+)
+
+var professions = map[models.Profession]bool{models.Doctor: true, models.Nurse: true}
+
+var careRoles = map[models.CareRole]bool{models.Attending: true, models.Consulting: true, models.PrimaryNurse: true}
+
+type Svc struct {
+	stores   stores.StaffInterface
+	timeouts patient.Timeouts
+}
+
+func New(stores stores.StaffInterface) *Svc {
+	return &Svc{stores: stores}
+}
+
+// WithTimeouts applies the read and write deadlines of the patient service to
+// staff and care team calls.
+func (ss *Svc) WithTimeouts(t patient.Timeouts) *Svc {
+	ss.timeouts = t
+	return ss
+}
+
+// List returns the staff by name, only doctors or only nurses when profession
+// is given.
+func (ss *Svc) List(ctx context.Context, profession models.Profession) ([]*models.Staff, error) {
+	if profession != "" && !professions[profession] {
+		return nil, perrors.NewValidation("profession", "must be doctor or nurse")
+	}
+	ctx, cancel := ss.timeouts.With(ctx, ss.timeouts.Read)
+	defer cancel()
+	return ss.stores.List(ctx, profession)
+}
+
+func (ss *Svc) Get(ctx context.Context, id int) (*models.Staff, error) {
+	if id <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := ss.timeouts.With(ctx, ss.timeouts.Read)
+	defer cancel()
+	return ss.stores.Get(ctx, id)
+}
+
+func (ss *Svc) Insert(ctx context.Context, st *models.Staff) (*models.Staff, error) {
+	if err := validateStaff(st); err != nil {
+		return nil, err
+	}
+	ctx, cancel := ss.timeouts.With(ctx, ss.timeouts.Write)
+	defer cancel()
+	return ss.stores.Insert(ctx, st)
+}
+
+// Update overwrites a staff member, conditionally on st.Version when it is
+// set.
+func (ss *Svc) Update(ctx context.Context, st *models.Staff) (*models.Staff, error) {
+	if st.ID <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	if err := validateStaff(st); err != nil {
+		return nil, err
+	}
+	ctx, cancel := ss.timeouts.With(ctx, ss.timeouts.Write)
+	defer cancel()
+	return ss.stores.Update(ctx, st)
+}
+
+// Delete removes a staff member and takes them off every care team,
+// conditionally on version when it is set.
+func (ss *Svc) Delete(ctx context.Context, id, version int) error {
+	if id <= 0 {
+		return perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := ss.timeouts.With(ctx, ss.timeouts.Write)
+	defer cancel()
+	return ss.stores.Delete(ctx, id, version)
+}
+
+// validateStaff trims and normalises st and reports every invalid field.
+func validateStaff(st *models.Staff) error {
+	verr := &perrors.Validation{}
+	st.Name = strings.TrimSpace(st.Name)
+	if st.Name == "" {
+		verr.Add("name", "must not be empty")
+	} else if utf8.RuneCountInString(st.Name) > maxNameLength {
+		verr.Add("name", "must be at most 100 characters")
+	}
+	if !professions[st.Profession] {
+		verr.Add("profession", "must be doctor or nurse")
+	}
+	st.Specialty = strings.TrimSpace(st.Specialty)
+	if utf8.RuneCountInString(st.Specialty) > maxSpecialtyLength {
+		verr.Add("specialty", "must be at most 100 characters")
+	}
+	if st.Phone != "" {
+		if phone, ok := patient.NormalisePhone(st.Phone); ok {
+			st.Phone = phone
+		} else {
+			verr.Add("phone", "must be a valid phone number in E.164 format")
+		}
+	}
+	st.Email = strings.TrimSpace(st.Email)
+	if st.Email != "" {
+		addr, err := mail.ParseAddress(st.Email)
+		if err != nil || addr.Address != st.Email || len(st.Email) > maxEmailLength {
+			verr.Add("email", "must be a valid email address")
+		}
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// Team returns the care team of a patient.
+func (ss *Svc) Team(ctx context.Context, patientID int) ([]*models.CareTeamMember, error) {
+	if patientID <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := ss.timeouts.With(ctx, ss.timeouts.Read)
+	defer cancel()
+	return ss.stores.Team(ctx, patientID)
+}
+
+// Patients returns the patients whose care team a staff member is on.
+func (ss *Svc) Patients(ctx context.Context, staffID int) ([]*models.CareTeamMember, error) {
+	if staffID <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := ss.timeouts.With(ctx, ss.timeouts.Read)
+	defer cancel()
+	return ss.stores.Patients(ctx, staffID)
+}
+
+// Assign puts a staff member on the care team of a patient in a role, or
+// changes their role.
+func (ss *Svc) Assign(ctx context.Context, m *models.CareTeamMember) (*models.CareTeamMember, error) {
+	verr := &perrors.Validation{}
+	if m.PatientID <= 0 {
+		verr.Add("id", "must be a positive integer")
+	}
+	if m.StaffID <= 0 {
+		verr.Add("staffId", "must be a positive integer")
+	}
+	if !careRoles[m.Role] {
+		verr.Add("role", "must be attending, consulting or primary-nurse")
+	}
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	ctx, cancel := ss.timeouts.With(ctx, ss.timeouts.Write)
+	defer cancel()
+	return ss.stores.Assign(ctx, m)
+}
+
+func (ss *Svc) Unassign(ctx context.Context, patientID, staffID int) error {
+	verr := &perrors.Validation{}
+	if patientID <= 0 {
+		verr.Add("id", "must be a positive integer")
+	}
+	if staffID <= 0 {
+		verr.Add("staffId", "must be a positive integer")
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	ctx, cancel := ss.timeouts.With(ctx, ss.timeouts.Write)
+	defer cancel()
+	return ss.stores.Unassign(ctx, patientID, staffID)
+}
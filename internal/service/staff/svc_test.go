@@ -0,0 +1,76 @@
+package staff
+
+import (
+	"context"
+	"errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores"
+	"github.com/golang/mock/gomock"
+	"strings"
+	"testing"
+)
+
+func TestInsert(t *testing.T) {
+	tests := []struct {
+		desc        string
+		input       *models.Staff
+		stored      *models.Staff
+		expectError error
+	}{
+		{
+			desc:   "normalised doctor",
+			input:  &models.Staff{Name: " Dr. Rao ", Profession: models.Doctor, Specialty: " cardiology", Phone: "091726 81679", Email: " rao@example.org "},
+			stored: &models.Staff{Name: "Dr. Rao", Profession: models.Doctor, Specialty: "cardiology", Phone: "+919172681679", Email: "rao@example.org"},
+		},
+		{
+			desc:   "nurse without contact details",
+			input:  &models.Staff{Name: "Meera", Profession: models.Nurse},
+			stored: &models.Staff{Name: "Meera", Profession: models.Nurse},
+		},
+		{
+			desc:        "every field invalid",
+			input:       &models.Staff{Name: strings.Repeat("x", 101), Profession: "surgeon", Specialty: strings.Repeat("x", 101), Phone: "12", Email: "Rao <rao@example.org>"},
+			expectError: errors.New("invalid name, profession, specialty, phone, email"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockStore := stores.NewMockStaffInterface(mockCtrl)
+			if test.stored != nil {
+				mockStore.EXPECT().Insert(gomock.Any(), test.stored).Return(test.stored, nil)
+			}
+
+			_, err := New(mockStore).Insert(context.TODO(), test.input)
+			if (err == nil) != (test.expectError == nil) || (err != nil && err.Error() != test.expectError.Error()) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestCareTeam(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockStore := stores.NewMockStaffInterface(mockCtrl)
+	svc := New(mockStore)
+
+	m := &models.CareTeamMember{PatientID: 1, StaffID: 3, Role: models.Attending}
+	mockStore.EXPECT().Assign(gomock.Any(), m).Return(m, nil)
+	if _, err := svc.Assign(context.TODO(), m); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+	_, err := svc.Assign(context.TODO(), &models.CareTeamMember{Role: "surgeon"})
+	if err == nil || err.Error() != "invalid id, staffId, role" {
+		t.Errorf("expected error :invalid id, staffId, role, got :%v ", err)
+	}
+	if _, err := svc.List(context.TODO(), "porter"); err == nil || err.Error() != "invalid profession" {
+		t.Errorf("expected error :invalid profession, got :%v ", err)
+	}
+	mockStore.EXPECT().Patients(gomock.Any(), 3).Return([]*models.CareTeamMember{m}, nil)
+	if _, err := svc.Patients(context.TODO(), 3); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+}
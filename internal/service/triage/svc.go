@@ -0,0 +1,182 @@
+package triage
+
+import (
+	"context"
+	"errors"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service/patient"
+	"github.com/aakanksha/ppms/internal/stores"
+	"sort"
+	"time"
+)
+
+// callAttempts bounds how often CallNext moves on to the next visit when a
+// concurrent call took the one it picked.
+const callAttempts = 3
+
+type Svc struct {
+	stores   stores.TriageInterface
+	timeouts patient.Timeouts
+	aging    time.Duration
+	now      func() time.Time
+}
+
+func New(stores stores.TriageInterface) *Svc {
+	return &Svc{stores: stores, now: time.Now}
+}
+
+// WithTimeouts applies the read and write deadlines of the patient service to
+// triage calls.
+func (ts *Svc) WithTimeouts(t patient.Timeouts) *Svc {
+	ts.timeouts = t
+	return ts
+}
+
+// WithAging raises the priority of a waiting visit by one level for every
+// aging it has waited, so that low levels are not starved. 0 disables aging.
+func (ts *Svc) WithAging(aging time.Duration) *Svc {
+	ts.aging = aging
+	return ts
+}
+
+// Enqueue puts a patient in the queue at the given level.
+func (ts *Svc) Enqueue(ctx context.Context, t *models.Triage) (*models.Triage, error) {
+	verr := &perrors.Validation{}
+	if t.PatientID <= 0 {
+		verr.Add("patientId", "must be a positive integer")
+	}
+	if msg := checkLevel(t.Level); msg != "" {
+		verr.Add("level", msg)
+	}
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	ctx, cancel := ts.timeouts.With(ctx, ts.timeouts.Write)
+	defer cancel()
+	created, err := ts.stores.Insert(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	created.Priority = ts.priority(created, ts.now())
+	return created, nil
+}
+
+// Queue returns the waiting visits, the next to be called first.
+func (ts *Svc) Queue(ctx context.Context) ([]*models.Triage, error) {
+	ctx, cancel := ts.timeouts.With(ctx, ts.timeouts.Read)
+	defer cancel()
+	return ts.queue(ctx)
+}
+
+func (ts *Svc) queue(ctx context.Context) ([]*models.Triage, error) {
+	waiting, err := ts.stores.Waiting(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ts.order(waiting, ts.now())
+	return waiting, nil
+}
+
+// priority is the level of t raised by one for every aging waited, but never
+// above 1.
+func (ts *Svc) priority(t *models.Triage, now time.Time) int {
+	p := t.Level
+	if ts.aging > 0 {
+		p -= int(now.Sub(t.ArrivedAt) / ts.aging)
+	}
+	if p < 1 {
+		p = 1
+	}
+	return p
+}
+
+// order sorts visits by priority, then by level so that aging never puts a
+// visit ahead of a more urgent one of the same priority, then by arrival.
+func (ts *Svc) order(visits []*models.Triage, now time.Time) {
+	for _, t := range visits {
+		t.Priority = ts.priority(t, now)
+	}
+	sort.SliceStable(visits, func(i, j int) bool {
+		a, b := visits[i], visits[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if a.Level != b.Level {
+			return a.Level < b.Level
+		}
+		return a.ArrivedAt.Before(b.ArrivedAt)
+	})
+}
+
+// Peek returns the visit CallNext would call, without calling it.
+func (ts *Svc) Peek(ctx context.Context) (*models.Triage, error) {
+	ctx, cancel := ts.timeouts.With(ctx, ts.timeouts.Read)
+	defer cancel()
+	waiting, err := ts.queue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(waiting) == 0 {
+		return nil, &perrors.NotFound{Entity: "waiting patient"}
+	}
+	return waiting[0], nil
+}
+
+// CallNext takes the first visit off the queue. When a concurrent call takes
+// it first, CallNext reads the queue again and calls the new first.
+func (ts *Svc) CallNext(ctx context.Context) (*models.Triage, error) {
+	ctx, cancel := ts.timeouts.With(ctx, ts.timeouts.Write)
+	defer cancel()
+	var err error
+	for i := 0; i < callAttempts; i++ {
+		var waiting []*models.Triage
+		if waiting, err = ts.queue(ctx); err != nil {
+			return nil, err
+		}
+		if len(waiting) == 0 {
+			return nil, &perrors.NotFound{Entity: "waiting patient"}
+		}
+		var called *models.Triage
+		called, err = ts.stores.Call(ctx, waiting[0].ID)
+		if err == nil {
+			called.Priority = waiting[0].Priority
+			return called, nil
+		}
+		var conflict *perrors.Conflict
+		var notFound *perrors.NotFound
+		if !errors.As(err, &conflict) && !errors.As(err, &notFound) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// Retriage changes the level of a waiting visit.
+func (ts *Svc) Retriage(ctx context.Context, id, level int) (*models.Triage, error) {
+	verr := &perrors.Validation{}
+	if id <= 0 {
+		verr.Add("id", "must be a positive integer")
+	}
+	if msg := checkLevel(level); msg != "" {
+		verr.Add("level", msg)
+	}
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	ctx, cancel := ts.timeouts.With(ctx, ts.timeouts.Write)
+	defer cancel()
+	updated, err := ts.stores.Retriage(ctx, id, level)
+	if err != nil {
+		return nil, err
+	}
+	updated.Priority = ts.priority(updated, ts.now())
+	return updated, nil
+}
+
+func checkLevel(level int) string {
+	if level < 1 || level > 5 {
+		return "must be an ESI level from 1 to 5"
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+package triage
+
+import (
+	"context"
+	"errors"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores"
+	"github.com/golang/mock/gomock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var noon = time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func visit(id, level int, waited time.Duration) *models.Triage {
+	return &models.Triage{ID: id, PatientID: id, Level: level, ArrivedAt: noon.Add(-waited), TriagedAt: noon.Add(-waited)}
+}
+
+func ids(visits []*models.Triage) []int {
+	ids := make([]int, len(visits))
+	for i, t := range visits {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+func TestQueue(t *testing.T) {
+	tests := []struct {
+		desc     string
+		aging    time.Duration
+		waiting  []*models.Triage
+		expected []int
+	}{
+		{
+			desc:     "by level then arrival",
+			waiting:  []*models.Triage{visit(1, 3, 10*time.Minute), visit(2, 1, time.Minute), visit(3, 3, 20*time.Minute), visit(4, 5, 3*time.Hour)},
+			expected: []int{2, 3, 1, 4},
+		},
+		{
+			desc:     "aging lifts a long wait",
+			aging:    30 * time.Minute,
+			waiting:  []*models.Triage{visit(1, 2, 5*time.Minute), visit(2, 4, 70*time.Minute), visit(3, 5, 3*time.Hour)},
+			expected: []int{3, 1, 2},
+		},
+		{
+			desc:     "aging never overtakes a more urgent level",
+			aging:    30 * time.Minute,
+			waiting:  []*models.Triage{visit(1, 1, time.Minute), visit(2, 5, 5*time.Hour), visit(3, 2, 40*time.Minute)},
+			expected: []int{1, 3, 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockStore := stores.NewMockTriageInterface(mockCtrl)
+			mockStore.EXPECT().Waiting(gomock.Any()).Return(test.waiting, nil)
+			svc := New(mockStore).WithAging(test.aging)
+			svc.now = func() time.Time { return noon }
+
+			queue, err := svc.Queue(context.TODO())
+			if err != nil || !reflect.DeepEqual(ids(queue), test.expected) {
+				t.Errorf("Expected: %v, Got: %v (%v)", test.expected, ids(queue), err)
+			}
+		})
+	}
+}
+
+func TestPriority(t *testing.T) {
+	svc := New(nil).WithAging(30 * time.Minute)
+	tests := []struct {
+		visit    *models.Triage
+		expected int
+	}{
+		{visit(1, 4, 29*time.Minute), 4},
+		{visit(2, 4, 30*time.Minute), 3},
+		{visit(3, 4, 95*time.Minute), 1},
+		{visit(4, 5, 10*time.Hour), 1},
+	}
+	for _, test := range tests {
+		if got := svc.priority(test.visit, noon); got != test.expected {
+			t.Errorf("Expected: %v, Got: %v", test.expected, got)
+		}
+	}
+}
+
+func TestCallNext(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockStore := stores.NewMockTriageInterface(mockCtrl)
+	svc := New(mockStore)
+	svc.now = func() time.Time { return noon }
+	called := noon
+
+	gomock.InOrder(
+		mockStore.EXPECT().Waiting(gomock.Any()).Return([]*models.Triage{visit(1, 2, time.Minute), visit(2, 3, time.Minute)}, nil),
+		mockStore.EXPECT().Call(gomock.Any(), 1).Return(nil, &perrors.Conflict{Entity: "triage", Reason: "1 has already been called"}),
+		mockStore.EXPECT().Waiting(gomock.Any()).Return([]*models.Triage{visit(2, 3, time.Minute)}, nil),
+		mockStore.EXPECT().Call(gomock.Any(), 2).Return(&models.Triage{ID: 2, Level: 3, CalledAt: &called}, nil),
+		mockStore.EXPECT().Waiting(gomock.Any()).Return([]*models.Triage{}, nil),
+	)
+
+	next, err := svc.CallNext(context.TODO())
+	if err != nil || next.ID != 2 || next.Priority != 3 {
+		t.Errorf("Expected: visit 2, Got: %+v (%v)", next, err)
+	}
+	_, err = svc.CallNext(context.TODO())
+	if !reflect.DeepEqual(err, &perrors.NotFound{Entity: "waiting patient"}) {
+		t.Errorf("expected error :waiting patient not found, got :%v ", err)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	svc := New(stores.NewMockTriageInterface(mockCtrl))
+
+	tests := []struct {
+		desc        string
+		call        func() error
+		expectError error
+	}{
+		{
+			desc: "enqueue without patient or level",
+			call: func() error {
+				_, err := svc.Enqueue(context.TODO(), &models.Triage{})
+				return err
+			},
+			expectError: errors.New("invalid patientId, level"),
+		},
+		{
+			desc: "retriage to level 6",
+			call: func() error {
+				_, err := svc.Retriage(context.TODO(), 1, 6)
+				return err
+			},
+			expectError: errors.New("invalid level"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if err := test.call(); err == nil || err.Error() != test.expectError.Error() {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package ward
+
+import (
+	"context"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/service/patient"
+	"github.com/aakanksha/ppms/internal/stores"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxNameLength  = 50
+	maxLabelLength = 20
+	// maxBeds caps the beds created with one ward.
+	maxBeds = 200
+)
+
+type Svc struct {
+	stores   stores.WardInterface
+	timeouts patient.Timeouts
+}
+
+func New(stores stores.WardInterface) *Svc {
+	return &Svc{stores: stores}
+}
+
+// WithTimeouts applies the read and write deadlines of the patient service to
+// ward and bed calls.
+func (ws *Svc) WithTimeouts(t patient.Timeouts) *Svc {
+	ws.timeouts = t
+	return ws
+}
+
+// CreateWard creates a ward with the beds listed in w.Beds, of which only the
+// labels are used.
+func (ws *Svc) CreateWard(ctx context.Context, w *models.Ward) (*models.Ward, error) {
+	verr := &perrors.Validation{}
+	w.Name = strings.TrimSpace(w.Name)
+	if w.Name == "" {
+		verr.Add("name", "must not be empty")
+	} else if utf8.RuneCountInString(w.Name) > maxNameLength {
+		verr.Add("name", "must be at most 50 characters")
+	}
+	if len(w.Beds) > maxBeds {
+		verr.Add("beds", "must hold at most 200 beds")
+	}
+	seen := map[string]bool{}
+	for i, b := range w.Beds {
+		field := "beds[" + strconv.Itoa(i) + "].label"
+		if b == nil {
+			verr.Add(field, "must not be empty")
+			continue
+		}
+		if msg := checkLabel(b); msg != "" {
+			verr.Add(field, msg)
+		} else if seen[b.Label] {
+			verr.Add(field, "appears more than once")
+		}
+		seen[b.Label] = true
+	}
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	ctx, cancel := ws.timeouts.With(ctx, ws.timeouts.Write)
+	defer cancel()
+	return ws.stores.CreateWard(ctx, w)
+}
+
+// checkLabel trims the label of b and describes what is wrong with it, if
+// anything.
+func checkLabel(b *models.Bed) string {
+	b.Label = strings.TrimSpace(b.Label)
+	switch {
+	case b.Label == "":
+		return "must not be empty"
+	case utf8.RuneCountInString(b.Label) > maxLabelLength:
+		return "must be at most 20 characters"
+	}
+	return ""
+}
+
+func (ws *Svc) ListWards(ctx context.Context) ([]*models.Ward, error) {
+	ctx, cancel := ws.timeouts.With(ctx, ws.timeouts.Read)
+	defer cancel()
+	return ws.stores.ListWards(ctx)
+}
+
+func (ws *Svc) GetWard(ctx context.Context, id int) (*models.Ward, error) {
+	if id <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := ws.timeouts.With(ctx, ws.timeouts.Read)
+	defer cancel()
+	return ws.stores.GetWard(ctx, id)
+}
+
+func (ws *Svc) AddBed(ctx context.Context, b *models.Bed) (*models.Bed, error) {
+	verr := &perrors.Validation{}
+	if b.WardID <= 0 {
+		verr.Add("id", "must be a positive integer")
+	}
+	if msg := checkLabel(b); msg != "" {
+		verr.Add("label", msg)
+	}
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	ctx, cancel := ws.timeouts.With(ctx, ws.timeouts.Write)
+	defer cancel()
+	return ws.stores.AddBed(ctx, b)
+}
+
+func (ws *Svc) Occupancy(ctx context.Context, wardID int) (*models.Occupancy, error) {
+	if wardID <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := ws.timeouts.With(ctx, ws.timeouts.Read)
+	defer cancel()
+	return ws.stores.Occupancy(ctx, wardID)
+}
+
+func (ws *Svc) CurrentBed(ctx context.Context, patientID int) (*models.BedAssignment, error) {
+	if patientID <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := ws.timeouts.With(ctx, ws.timeouts.Read)
+	defer cancel()
+	return ws.stores.CurrentBed(ctx, patientID)
+}
+
+// Assign puts an admitted patient without a bed into a free bed.
+func (ws *Svc) Assign(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error) {
+	if err := validIds(patientID, bedID); err != nil {
+		return nil, err
+	}
+	ctx, cancel := ws.timeouts.With(ctx, ws.timeouts.Write)
+	defer cancel()
+	return ws.stores.Assign(ctx, patientID, bedID)
+}
+
+// Transfer moves an admitted patient to another, free, bed.
+func (ws *Svc) Transfer(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error) {
+	if err := validIds(patientID, bedID); err != nil {
+		return nil, err
+	}
+	ctx, cancel := ws.timeouts.With(ctx, ws.timeouts.Write)
+	defer cancel()
+	return ws.stores.Transfer(ctx, patientID, bedID)
+}
+
+func (ws *Svc) Release(ctx context.Context, patientID int) (*models.BedAssignment, error) {
+	if patientID <= 0 {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := ws.timeouts.With(ctx, ws.timeouts.Write)
+	defer cancel()
+	return ws.stores.Release(ctx, patientID)
+}
+
+func validIds(patientID, bedID int) error {
+	verr := &perrors.Validation{}
+	if patientID <= 0 {
+		verr.Add("id", "must be a positive integer")
+	}
+	if bedID <= 0 {
+		verr.Add("bedId", "must be a positive integer")
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
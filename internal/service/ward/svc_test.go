@@ -0,0 +1,79 @@
+package ward
+
+import (
+	"context"
+	"errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores"
+	"github.com/golang/mock/gomock"
+	"strings"
+	"testing"
+)
+
+func TestCreateWard(t *testing.T) {
+	tests := []struct {
+		desc        string
+		input       *models.Ward
+		stored      *models.Ward
+		expectError error
+	}{
+		{
+			desc:   "trimmed name and labels",
+			input:  &models.Ward{Name: " ICU ", Beds: []*models.Bed{{Label: " A1"}, {Label: "A2 "}}},
+			stored: &models.Ward{Name: "ICU", Beds: []*models.Bed{{Label: "A1"}, {Label: "A2"}}},
+		},
+		{
+			desc:   "ward without beds",
+			input:  &models.Ward{Name: "Maternity"},
+			stored: &models.Ward{Name: "Maternity"},
+		},
+		{
+			desc:        "empty name",
+			input:       &models.Ward{Name: " "},
+			expectError: errors.New("invalid name"),
+		},
+		{
+			desc:        "bad labels",
+			input:       &models.Ward{Name: "ICU", Beds: []*models.Bed{{Label: "A1"}, {Label: "A1"}, {Label: ""}, {Label: strings.Repeat("x", 21)}, nil}},
+			expectError: errors.New("invalid beds[1].label, beds[2].label, beds[3].label, beds[4].label"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockStore := stores.NewMockWardInterface(mockCtrl)
+			if test.stored != nil {
+				mockStore.EXPECT().CreateWard(gomock.Any(), test.stored).Return(test.stored, nil)
+			}
+
+			_, err := New(mockStore).CreateWard(context.TODO(), test.input)
+			if (err == nil) != (test.expectError == nil) || (err != nil && err.Error() != test.expectError.Error()) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestBedMoves(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockStore := stores.NewMockWardInterface(mockCtrl)
+	svc := New(mockStore)
+
+	mockStore.EXPECT().Assign(gomock.Any(), 1, 4).Return(&models.BedAssignment{ID: 1}, nil)
+	if _, err := svc.Assign(context.TODO(), 1, 4); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+	if _, err := svc.Transfer(context.TODO(), 0, 0); err == nil || err.Error() != "invalid id, bedId" {
+		t.Errorf("expected error :invalid id, bedId, got :%v ", err)
+	}
+	if _, err := svc.AddBed(context.TODO(), &models.Bed{WardID: 1, Label: "  "}); err == nil || err.Error() != "invalid label" {
+		t.Errorf("expected error :invalid label, got :%v ", err)
+	}
+	mockStore.EXPECT().Release(gomock.Any(), 1).Return(&models.BedAssignment{ID: 1}, nil)
+	if _, err := svc.Release(context.TODO(), 1); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+}
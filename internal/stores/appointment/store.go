@@ -0,0 +1,252 @@
+package appointment
+
+import (
+	"context"
+	"database/sql"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores/sqltx"
+	"strconv"
+	"time"
+)
+
+const columns = "a.id,a.patientid,a.staffid,a.startat,a.endat,a.status,a.version"
+
+type store struct {
+	db    *sql.DB
+	audit sqltx.Auditor
+}
+
+// New returns a store that records appointment changes in the audit trail of
+// the patient through audit.
+func New(db *sql.DB, audit sqltx.Auditor) *store {
+	return &store{db: db, audit: audit}
+}
+
+// list returns the appointments of live patients matching where, which
+// filters the appointment table a.
+func list(ctx context.Context, q sqltx.Querier, where string, args ...interface{}) ([]*models.Appointment, error) {
+	query := "select " + columns + " from appointment a join patient p on p.id = a.patientid where p.deletedat IS NULL" + where
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	defer rows.Close()
+	appointments := []*models.Appointment{}
+	for rows.Next() {
+		var a models.Appointment
+		if err := rows.Scan(&a.ID, &a.PatientID, &a.StaffID, &a.Start, &a.End, &a.Status, &a.Version); err != nil {
+			return nil, sqltx.Error(err, nil)
+		}
+		appointments = append(appointments, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	return appointments, nil
+}
+
+// exists reports NotFound unless the query finds a row.
+func exists(ctx context.Context, q sqltx.Querier, entity string, id int, query string) error {
+	var found int
+	err := q.QueryRowContext(ctx, query, id).Scan(&found)
+	if err == sql.ErrNoRows {
+		return &perrors.NotFound{Entity: entity, ID: strconv.Itoa(id)}
+	}
+	if err != nil {
+		return sqltx.Error(err, nil)
+	}
+	return nil
+}
+
+// List returns the appointments matching f, cancelled ones included, by start
+// time.
+func (s *store) List(ctx context.Context, f models.AppointmentFilter) ([]*models.Appointment, error) {
+	var where string
+	var args []interface{}
+	if f.PatientID > 0 {
+		if err := exists(ctx, s.db, "patient", f.PatientID, "select 1 from patient where deletedat IS NULL and id=?"); err != nil {
+			return nil, err
+		}
+		where += " and a.patientid=?"
+		args = append(args, f.PatientID)
+	}
+	if f.StaffID > 0 {
+		if err := exists(ctx, s.db, "staff", f.StaffID, "select 1 from staff where deletedat IS NULL and id=?"); err != nil {
+			return nil, err
+		}
+		where += " and a.staffid=?"
+		args = append(args, f.StaffID)
+	}
+	if !f.From.IsZero() {
+		where += " and a.endat > ?"
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		where += " and a.startat < ?"
+		args = append(args, f.To)
+	}
+	return list(ctx, s.db, where+" order by a.startat, a.id", args...)
+}
+
+func (s *store) Get(ctx context.Context, id int) (*models.Appointment, error) {
+	appointments, err := list(ctx, s.db, " and a.id=?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(appointments) == 0 {
+		return nil, &perrors.NotFound{Entity: "appointment", ID: strconv.Itoa(id)}
+	}
+	return appointments[0], nil
+}
+
+// Insert books an appointment. The patient and staff rows are locked so that
+// concurrent bookings of either are checked for overlaps one at a time.
+func (s *store) Insert(ctx context.Context, a *models.Appointment) (*models.Appointment, error) {
+	var booked *models.Appointment
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := lock(ctx, tx, a); err != nil {
+			return err
+		}
+		query := "insert into appointment (patientid,staffid,startat,endat,status) values (?, ?, ?, ?, ?)"
+		res, err := tx.ExecContext(ctx, query, a.PatientID, a.StaffID, a.Start, a.End, models.Booked)
+		if err != nil {
+			return sqltx.Error(err, nil)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return sqltx.Error(err, nil)
+		}
+		booked = &models.Appointment{ID: int(id), PatientID: a.PatientID, StaffID: a.StaffID, Start: a.Start, End: a.End, Status: models.Booked, Version: 1}
+		return s.audit.AppendAudit(ctx, tx, a.PatientID, "appointment", appointmentChange(nil, booked))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return booked, nil
+}
+
+// Reschedule moves a booked appointment to a.Start and a.End, provided it is
+// still at a.Version when that is set.
+func (s *store) Reschedule(ctx context.Context, a *models.Appointment) (*models.Appointment, error) {
+	var moved *models.Appointment
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := lockBooked(ctx, tx, a.ID, a.Version)
+		if err != nil {
+			return err
+		}
+		after := *before
+		after.Start, after.End, after.Version = a.Start, a.End, before.Version+1
+		if err := lock(ctx, tx, &after); err != nil {
+			return err
+		}
+		query := "update appointment SET startat=?, endat=?, version=version+1 where id=?"
+		if _, err := tx.ExecContext(ctx, query, after.Start, after.End, after.ID); err != nil {
+			return sqltx.Error(err, nil)
+		}
+		moved = &after
+		return s.audit.AppendAudit(ctx, tx, after.PatientID, "reschedule", appointmentChange(before, moved))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// Cancel frees the time of a booked appointment, provided it is still at
+// version when that is set.
+func (s *store) Cancel(ctx context.Context, id, version int) (*models.Appointment, error) {
+	var cancelled *models.Appointment
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := lockBooked(ctx, tx, id, version)
+		if err != nil {
+			return err
+		}
+		after := *before
+		after.Status, after.Version = models.Cancelled, before.Version+1
+		query := "update appointment SET status=?, version=version+1 where id=?"
+		if _, err := tx.ExecContext(ctx, query, models.Cancelled, id); err != nil {
+			return sqltx.Error(err, nil)
+		}
+		cancelled = &after
+		return s.audit.AppendAudit(ctx, tx, after.PatientID, "cancel appointment", appointmentChange(before, cancelled))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cancelled, nil
+}
+
+// lockBooked locks a booked appointment at version, when that is set.
+func lockBooked(ctx context.Context, tx *sql.Tx, id, version int) (*models.Appointment, error) {
+	appointments, err := list(ctx, tx, " and a.id=? for update", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(appointments) == 0 {
+		return nil, &perrors.NotFound{Entity: "appointment", ID: strconv.Itoa(id)}
+	}
+	a := appointments[0]
+	if version > 0 && version != a.Version {
+		return nil, &perrors.PreconditionFailed{Entity: "appointment", ID: strconv.Itoa(id)}
+	}
+	if a.Status != models.Booked {
+		return nil, &perrors.Conflict{Entity: "appointment", Reason: strconv.Itoa(id) + " is cancelled"}
+	}
+	return a, nil
+}
+
+// lock locks the patient and staff member of a, then reports a Conflict when
+// either has another booked appointment overlapping it.
+func lock(ctx context.Context, tx *sql.Tx, a *models.Appointment) error {
+	if _, err := sqltx.LockPatient(ctx, tx, a.PatientID); err != nil {
+		return err
+	}
+	if err := exists(ctx, tx, "staff", a.StaffID, "select 1 from staff where deletedat IS NULL and id=? for update"); err != nil {
+		return err
+	}
+	where := " and a.status=? and a.id<>? and a.startat < ? and a.endat > ? and (a.staffid=? or a.patientid=?) order by a.startat, a.id for update"
+	overlapping, err := list(ctx, tx, where, models.Booked, a.ID, a.End, a.Start, a.StaffID, a.PatientID)
+	if err != nil {
+		return err
+	}
+	for _, o := range overlapping {
+		if o.StaffID == a.StaffID {
+			return &perrors.Conflict{Entity: "appointment", Reason: "the staff member is already booked by appointment " + strconv.Itoa(o.ID)}
+		}
+	}
+	if len(overlapping) > 0 {
+		return &perrors.Conflict{Entity: "appointment", Reason: "the patient already has appointment " + strconv.Itoa(overlapping[0].ID) + " at that time"}
+	}
+	return nil
+}
+
+// auditFields are the appointment fields recorded in the audit trail.
+var auditFields = []string{"staffId", "start", "end", "status"}
+
+// auditValues returns the audited fields of a, or none when a is nil.
+func auditValues(a *models.Appointment) map[string]interface{} {
+	if a == nil {
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"id":      a.ID,
+		"staffId": a.StaffID,
+		"start":   a.Start.Format(time.RFC3339),
+		"end":     a.End.Format(time.RFC3339),
+		"status":  string(a.Status),
+	}
+}
+
+// appointmentChange describes an appointment change for the audit trail: the
+// appointment id and the fields that changed.
+func appointmentChange(before, after *models.Appointment) map[string]models.FieldChange {
+	b, a := auditValues(before), auditValues(after)
+	changes := map[string]models.FieldChange{"appointment.id": {Before: b["id"], After: a["id"]}}
+	for _, name := range auditFields {
+		if b[name] != a[name] {
+			changes["appointment."+name] = models.FieldChange{Before: b[name], After: a[name]}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,253 @@
+package appointment
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	selectAppointments = "select " + columns + " from appointment a join patient p on p.id = a.patientid where p.deletedat IS NULL"
+	lockAppointment    = selectAppointments + " and a.id=? for update"
+	lockPatient        = "select discharge from patient where deletedat IS NULL and id=? for update"
+	lockStaff          = "select 1 from staff where deletedat IS NULL and id=? for update"
+	selectOverlapping  = selectAppointments + " and a.status=? and a.id<>? and a.startat < ? and a.endat > ? and (a.staffid=? or a.patientid=?) order by a.startat, a.id for update"
+)
+
+var (
+	nine      = time.Date(2022, 3, 1, 9, 0, 0, 0, time.UTC)
+	halfNine  = time.Date(2022, 3, 1, 9, 30, 0, 0, time.UTC)
+	ten       = time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	halfPast  = time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)
+	oneRow    = func() *sqlmock.Rows { return sqlmock.NewRows([]string{"1"}).AddRow(1) }
+	noRow     = func() *sqlmock.Rows { return sqlmock.NewRows([]string{"1"}) }
+	nineToTen = &models.Appointment{PatientID: 1, StaffID: 3, Start: nine, End: ten}
+)
+
+type auditCall struct {
+	id        int
+	operation string
+	changes   map[string]models.FieldChange
+}
+
+// fakeAuditor records the audit entries a store appends.
+type fakeAuditor struct {
+	calls []auditCall
+}
+
+func (f *fakeAuditor) AppendAudit(ctx context.Context, tx *sql.Tx, id int, operation string, changes map[string]models.FieldChange) error {
+	f.calls = append(f.calls, auditCall{id: id, operation: operation, changes: changes})
+	return nil
+}
+
+func appointmentRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "patientid", "staffid", "startat", "endat", "status", "version"})
+}
+
+func TestList(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("select 1 from staff where deletedat IS NULL and id=?").WithArgs(3).WillReturnRows(oneRow())
+	mock.ExpectQuery(selectAppointments+" and a.staffid=? and a.endat > ? and a.startat < ? order by a.startat, a.id").WithArgs(3, nine, halfPast).
+		WillReturnRows(appointmentRows().AddRow(7, 1, 3, nine, ten, "booked", 1).AddRow(8, 2, 3, ten, halfPast, "cancelled", 2))
+	mock.ExpectQuery("select 1 from patient where deletedat IS NULL and id=?").WithArgs(9).WillReturnRows(noRow())
+
+	s := New(db, &fakeAuditor{})
+	got, err := s.List(context.TODO(), models.AppointmentFilter{StaffID: 3, From: nine, To: halfPast})
+	expected := []*models.Appointment{
+		{ID: 7, PatientID: 1, StaffID: 3, Start: nine, End: ten, Status: models.Booked, Version: 1},
+		{ID: 8, PatientID: 2, StaffID: 3, Start: ten, End: halfPast, Status: models.Cancelled, Version: 2},
+	}
+	if err != nil || !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected: %v, Got: %v (%v)", expected, got, err)
+	}
+	notFound := &perrors.NotFound{Entity: "patient", ID: "9"}
+	if _, err := s.List(context.TODO(), models.AppointmentFilter{PatientID: 9}); !reflect.DeepEqual(err, notFound) {
+		t.Errorf("expected error :%v, got :%v ", notFound, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWrites(t *testing.T) {
+	tests := []struct {
+		desc        string
+		call        func(s *store) (*models.Appointment, error)
+		mock        func(mock sqlmock.Sqlmock)
+		expected    *models.Appointment
+		operation   string
+		changes     map[string]models.FieldChange
+		expectError error
+	}{
+		{
+			desc: "book",
+			call: func(s *store) (*models.Appointment, error) { return s.Insert(context.TODO(), nineToTen) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockPatient).WithArgs(1).WillReturnRows(oneRow())
+				mock.ExpectQuery(lockStaff).WithArgs(3).WillReturnRows(oneRow())
+				mock.ExpectQuery(selectOverlapping).WithArgs(models.Booked, 0, ten, nine, 3, 1).WillReturnRows(appointmentRows())
+				mock.ExpectExec("insert into appointment (patientid,staffid,startat,endat,status) values (?, ?, ?, ?, ?)").
+					WithArgs(1, 3, nine, ten, models.Booked).WillReturnResult(sqlmock.NewResult(7, 1))
+				mock.ExpectCommit()
+			},
+			expected:  &models.Appointment{ID: 7, PatientID: 1, StaffID: 3, Start: nine, End: ten, Status: models.Booked, Version: 1},
+			operation: "appointment",
+			changes: map[string]models.FieldChange{
+				"appointment.id":      {After: 7},
+				"appointment.staffId": {After: 3},
+				"appointment.start":   {After: "2022-03-01T09:00:00Z"},
+				"appointment.end":     {After: "2022-03-01T10:00:00Z"},
+				"appointment.status":  {After: "booked"},
+			},
+		},
+		{
+			desc: "double-booked staff member",
+			call: func(s *store) (*models.Appointment, error) { return s.Insert(context.TODO(), nineToTen) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockPatient).WithArgs(1).WillReturnRows(oneRow())
+				mock.ExpectQuery(lockStaff).WithArgs(3).WillReturnRows(oneRow())
+				mock.ExpectQuery(selectOverlapping).WithArgs(models.Booked, 0, ten, nine, 3, 1).
+					WillReturnRows(appointmentRows().AddRow(4, 1, 5, halfNine, ten, "booked", 1).AddRow(5, 2, 3, halfNine, halfPast, "booked", 1))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "appointment", Reason: "the staff member is already booked by appointment 5"},
+		},
+		{
+			desc: "overlapping patient slot",
+			call: func(s *store) (*models.Appointment, error) { return s.Insert(context.TODO(), nineToTen) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockPatient).WithArgs(1).WillReturnRows(oneRow())
+				mock.ExpectQuery(lockStaff).WithArgs(3).WillReturnRows(oneRow())
+				mock.ExpectQuery(selectOverlapping).WithArgs(models.Booked, 0, ten, nine, 3, 1).
+					WillReturnRows(appointmentRows().AddRow(4, 1, 5, halfNine, ten, "booked", 1))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "appointment", Reason: "the patient already has appointment 4 at that time"},
+		},
+		{
+			desc: "unknown staff member",
+			call: func(s *store) (*models.Appointment, error) { return s.Insert(context.TODO(), nineToTen) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockPatient).WithArgs(1).WillReturnRows(oneRow())
+				mock.ExpectQuery(lockStaff).WithArgs(3).WillReturnRows(noRow())
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.NotFound{Entity: "staff", ID: "3"},
+		},
+		{
+			desc: "reschedule",
+			call: func(s *store) (*models.Appointment, error) {
+				return s.Reschedule(context.TODO(), &models.Appointment{ID: 7, Start: ten, End: halfPast, Version: 1})
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAppointment).WithArgs(7).WillReturnRows(appointmentRows().AddRow(7, 1, 3, nine, ten, "booked", 1))
+				mock.ExpectQuery(lockPatient).WithArgs(1).WillReturnRows(oneRow())
+				mock.ExpectQuery(lockStaff).WithArgs(3).WillReturnRows(oneRow())
+				mock.ExpectQuery(selectOverlapping).WithArgs(models.Booked, 7, halfPast, ten, 3, 1).WillReturnRows(appointmentRows())
+				mock.ExpectExec("update appointment SET startat=?, endat=?, version=version+1 where id=?").
+					WithArgs(ten, halfPast, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expected:  &models.Appointment{ID: 7, PatientID: 1, StaffID: 3, Start: ten, End: halfPast, Status: models.Booked, Version: 2},
+			operation: "reschedule",
+			changes: map[string]models.FieldChange{
+				"appointment.id":    {Before: 7, After: 7},
+				"appointment.start": {Before: "2022-03-01T09:00:00Z", After: "2022-03-01T10:00:00Z"},
+				"appointment.end":   {Before: "2022-03-01T10:00:00Z", After: "2022-03-01T10:30:00Z"},
+			},
+		},
+		{
+			desc: "reschedule a stale version",
+			call: func(s *store) (*models.Appointment, error) {
+				return s.Reschedule(context.TODO(), &models.Appointment{ID: 7, Start: ten, End: halfPast, Version: 1})
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAppointment).WithArgs(7).WillReturnRows(appointmentRows().AddRow(7, 1, 3, nine, ten, "booked", 2))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.PreconditionFailed{Entity: "appointment", ID: "7"},
+		},
+		{
+			desc: "cancel",
+			call: func(s *store) (*models.Appointment, error) { return s.Cancel(context.TODO(), 7, 0) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAppointment).WithArgs(7).WillReturnRows(appointmentRows().AddRow(7, 1, 3, nine, ten, "booked", 1))
+				mock.ExpectExec("update appointment SET status=?, version=version+1 where id=?").
+					WithArgs(models.Cancelled, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expected:  &models.Appointment{ID: 7, PatientID: 1, StaffID: 3, Start: nine, End: ten, Status: models.Cancelled, Version: 2},
+			operation: "cancel appointment",
+			changes: map[string]models.FieldChange{
+				"appointment.id":     {Before: 7, After: 7},
+				"appointment.status": {Before: "booked", After: "cancelled"},
+			},
+		},
+		{
+			desc: "cancel twice",
+			call: func(s *store) (*models.Appointment, error) { return s.Cancel(context.TODO(), 7, 0) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAppointment).WithArgs(7).WillReturnRows(appointmentRows().AddRow(7, 1, 3, nine, ten, "cancelled", 2))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "appointment", Reason: "7 is cancelled"},
+		},
+		{
+			desc: "cancel an unknown appointment",
+			call: func(s *store) (*models.Appointment, error) { return s.Cancel(context.TODO(), 9, 0) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAppointment).WithArgs(9).WillReturnRows(appointmentRows())
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.NotFound{Entity: "appointment", ID: "9"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			test.mock(mock)
+			auditor := &fakeAuditor{}
+
+			got, err := test.call(New(db, auditor))
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected: %v, Got: %v", test.expected, got)
+			}
+			if test.changes != nil {
+				expected := []auditCall{{id: 1, operation: test.operation, changes: test.changes}}
+				if !reflect.DeepEqual(auditor.calls, expected) {
+					t.Errorf("Expected: %v, Got: %v", expected, auditor.calls)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
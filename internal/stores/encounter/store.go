@@ -0,0 +1,373 @@
+package encounter
+
+import (
+	"context"
+	"database/sql"
+	"github.com/aakanksha/ppms/internal/encryption"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores/sqltx"
+	"strconv"
+	"time"
+)
+
+const columns = "id,patientid,admittedat,dischargedat,reason,doctor,ward,version"
+
+// bedReleaser frees the bed of a patient inside a transaction; the ward
+// store implements it.
+type bedReleaser interface {
+	ReleaseBed(ctx context.Context, tx *sql.Tx, patientID int) (*models.BedAssignment, error)
+}
+
+type store struct {
+	db    *sql.DB
+	audit sqltx.Auditor
+	keys  *encryption.Keyring
+	beds  bedReleaser
+}
+
+// New returns a store that records every encounter change in the audit trail
+// of its patient through audit.
+func New(db *sql.DB, audit sqltx.Auditor) *store {
+	return &store{db: db, audit: audit}
+}
+
+// WithKeyring encrypts the reason of encounters at rest with keys.
+func (s *store) WithKeyring(keys *encryption.Keyring) *store {
+	s.keys = keys
+	return s
+}
+
+// WithBeds releases the bed of a patient, through beds, when the patient is
+// discharged.
+func (s *store) WithBeds(beds bedReleaser) *store {
+	s.beds = beds
+	return s
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (s *store) scan(row scanner) (*models.Encounter, error) {
+	var e models.Encounter
+	var dischargedAt sql.NullTime
+	if err := row.Scan(&e.ID, &e.PatientID, &e.AdmittedAt, &dischargedAt, &e.Reason, &e.Doctor, &e.Ward, &e.Version); err != nil {
+		return nil, err
+	}
+	if dischargedAt.Valid {
+		e.DischargedAt = &dischargedAt.Time
+	}
+	var err error
+	if e.Reason, err = s.keys.DecryptFor("encounter.reason", e.ID, e.Reason); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// List returns the encounters of a live patient, the latest admission first.
+func (s *store) List(ctx context.Context, patientID int) ([]*models.Encounter, error) {
+	var exists int
+	err := s.db.QueryRowContext(ctx, "select 1 from patient where deletedat IS NULL and id=?", patientID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil, &perrors.NotFound{Entity: "patient", ID: strconv.Itoa(patientID)}
+	}
+	if err != nil {
+		return nil, dbError(err)
+	}
+	rows, err := s.db.QueryContext(ctx, "select "+columns+" from encounter where patientid=? order by admittedat desc, id desc", patientID)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+	encounters := []*models.Encounter{}
+	for rows.Next() {
+		e, err := s.scan(rows)
+		if err != nil {
+			return nil, dbError(err)
+		}
+		encounters = append(encounters, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+	return encounters, nil
+}
+
+func (s *store) Get(ctx context.Context, patientID, id int) (*models.Encounter, error) {
+	return s.get(ctx, s.db, patientID, id, false)
+}
+
+// get reads an encounter of a live patient; forUpdate locks the row until the
+// surrounding transaction ends.
+func (s *store) get(ctx context.Context, q sqltx.Querier, patientID, id int, forUpdate bool) (*models.Encounter, error) {
+	query := "select e.id,e.patientid,e.admittedat,e.dischargedat,e.reason,e.doctor,e.ward,e.version from encounter e " +
+		"join patient p on p.id = e.patientid where p.deletedat IS NULL and e.patientid=? and e.id=?"
+	if forUpdate {
+		query += " for update"
+	}
+	e, err := s.scan(q.QueryRowContext(ctx, query, patientID, id))
+	if err == sql.ErrNoRows {
+		return nil, &perrors.NotFound{Entity: "encounter", ID: strconv.Itoa(id)}
+	}
+	if err != nil {
+		return nil, dbError(err)
+	}
+	return e, nil
+}
+
+// checkOverlap rejects e if its stay overlaps another encounter of the same
+// patient. An open encounter lasts until now and beyond, so this also keeps a
+// patient from being admitted twice.
+func checkOverlap(ctx context.Context, tx *sql.Tx, e *models.Encounter) error {
+	query := "select id from encounter where patientid=? and id<>? and (dischargedat IS NULL or dischargedat > ?)"
+	args := []interface{}{e.PatientID, e.ID, e.AdmittedAt}
+	if e.DischargedAt != nil {
+		query += " and admittedat < ?"
+		args = append(args, *e.DischargedAt)
+	}
+	query += " limit 1"
+	var other int
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&other)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return dbError(err)
+	}
+	return &perrors.Conflict{Entity: "encounter", Reason: "overlaps encounter " + strconv.Itoa(other)}
+}
+
+// syncDischarge keeps the patient's discharge flag in step with the
+// encounters of the patient, of which e was just written: the patient is
+// discharged when none of them is open. A patient discharged this way has the
+// bed freed. It reports the changes to audit, if any.
+func (s *store) syncDischarge(ctx context.Context, tx *sql.Tx, e *models.Encounter, was bool, changes map[string]models.FieldChange) error {
+	var discharge bool
+	query := "select not exists (select 1 from encounter where patientid=? and dischargedat IS NULL)"
+	if err := tx.QueryRowContext(ctx, query, e.PatientID).Scan(&discharge); err != nil {
+		return dbError(err)
+	}
+	if discharge == was {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, "update patient SET discharge=?, udatedat=?, version=version+1 where id=?", discharge, time.Now(), e.PatientID)
+	if err != nil {
+		return dbError(err)
+	}
+	changes["discharge"] = models.FieldChange{Before: was, After: discharge}
+	if !discharge || s.beds == nil {
+		return nil
+	}
+	released, err := s.beds.ReleaseBed(ctx, tx, e.PatientID)
+	if err != nil {
+		return err
+	}
+	if released != nil {
+		changes["bed"] = models.FieldChange{Before: released.BedLocation}
+	}
+	return nil
+}
+
+// Insert admits a patient. The patient is no longer discharged if the new
+// encounter is open.
+func (s *store) Insert(ctx context.Context, e *models.Encounter) (*models.Encounter, error) {
+	var created *models.Encounter
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		discharge, err := sqltx.LockPatient(ctx, tx, e.PatientID)
+		if err != nil {
+			return err
+		}
+		if err := checkOverlap(ctx, tx, e); err != nil {
+			return err
+		}
+		// An encrypted reason is bound to its row, so with a keyring it is
+		// written once the id is known.
+		var reason string
+		if s.keys == nil {
+			if reason, err = s.keys.EncryptFor("encounter.reason", 0, e.Reason); err != nil {
+				return dbError(err)
+			}
+		}
+		query := "insert into encounter (patientid,admittedat,dischargedat,reason,doctor,ward) values (?, ?, ?, ?, ?, ?)"
+		res, err := tx.ExecContext(ctx, query, e.PatientID, e.AdmittedAt, nullTime(e.DischargedAt), reason, e.Doctor, e.Ward)
+		if err != nil {
+			return dbError(err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return dbError(err)
+		}
+		if s.keys != nil {
+			if reason, err = s.keys.EncryptFor("encounter.reason", int(id), e.Reason); err != nil {
+				return dbError(err)
+			}
+			if _, err := tx.ExecContext(ctx, "update encounter SET reason=? where id=?", reason, id); err != nil {
+				return dbError(err)
+			}
+		}
+		if created, err = s.get(ctx, tx, e.PatientID, int(id), false); err != nil {
+			return err
+		}
+		changes := diffEncounters(nil, created)
+		if err := s.syncDischarge(ctx, tx, created, discharge, changes); err != nil {
+			return err
+		}
+		return s.audit.AppendAudit(ctx, tx, e.PatientID, "admit", changes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Update overwrites an encounter, provided it is still at e.Version when that
+// is non-zero. Closing the patient's open encounter discharges the patient.
+func (s *store) Update(ctx context.Context, e *models.Encounter) (*models.Encounter, error) {
+	var updated *models.Encounter
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		discharge, err := sqltx.LockPatient(ctx, tx, e.PatientID)
+		if err != nil {
+			return err
+		}
+		before, err := s.get(ctx, tx, e.PatientID, e.ID, true)
+		if err != nil {
+			return err
+		}
+		if e.Version > 0 && e.Version != before.Version {
+			return &perrors.PreconditionFailed{Entity: "encounter", ID: strconv.Itoa(e.ID)}
+		}
+		if err := checkOverlap(ctx, tx, e); err != nil {
+			return err
+		}
+		reason, err := s.keys.EncryptFor("encounter.reason", e.ID, e.Reason)
+		if err != nil {
+			return dbError(err)
+		}
+		query := "update encounter SET admittedat=?, dischargedat=?, reason=?, doctor=?, ward=?, version=version+1 where id=?"
+		if _, err := tx.ExecContext(ctx, query, e.AdmittedAt, nullTime(e.DischargedAt), reason, e.Doctor, e.Ward, e.ID); err != nil {
+			return dbError(err)
+		}
+		if updated, err = s.get(ctx, tx, e.PatientID, e.ID, false); err != nil {
+			return err
+		}
+		changes := diffEncounters(before, updated)
+		if err := s.syncDischarge(ctx, tx, updated, discharge, changes); err != nil {
+			return err
+		}
+		operation := "encounter"
+		if before.DischargedAt == nil && updated.DischargedAt != nil {
+			operation = "discharge"
+		}
+		return s.audit.AppendAudit(ctx, tx, e.PatientID, operation, changes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+const reencryptBatchSize = 500
+
+// Reencrypt brings every encounter reason under the primary key of the
+// keyring and binds it to its row. Each row is only rewritten if it has not changed since it was
+// read. It returns the number of rows rewritten.
+func (s *store) Reencrypt(ctx context.Context) (int64, error) {
+	type row struct {
+		id     int
+		reason string
+	}
+	var rewritten int64
+	lastID := 0
+	for {
+		rows, err := s.db.QueryContext(ctx, "select id,reason from encounter where id > ? order by id limit ?", lastID, reencryptBatchSize)
+		if err != nil {
+			return rewritten, dbError(err)
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.reason); err != nil {
+				rows.Close()
+				return rewritten, dbError(err)
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return rewritten, dbError(err)
+		}
+
+		for _, r := range batch {
+			if !s.keys.NeedsRotation(r.reason) {
+				continue
+			}
+			reason, err := s.keys.RotateFor("encounter.reason", r.id, r.reason)
+			if err != nil {
+				return rewritten, dbError(err)
+			}
+			res, err := s.db.ExecContext(ctx, "update encounter SET reason=? where id=? and reason=?", reason, r.id, r.reason)
+			if err != nil {
+				return rewritten, dbError(err)
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return rewritten, dbError(err)
+			}
+			rewritten += n
+		}
+		if len(batch) < reencryptBatchSize {
+			return rewritten, nil
+		}
+		lastID = batch[len(batch)-1].id
+	}
+}
+
+// diffEncounters lists the fields that differ between before and after, keyed
+// "encounter.<json name>" in the patient's audit trail. A nil before records
+// every field of a new encounter.
+func diffEncounters(before, after *models.Encounter) map[string]models.FieldChange {
+	changes := map[string]models.FieldChange{"encounter.id": {After: after.ID}}
+	if before == nil {
+		before = &models.Encounter{}
+	} else {
+		changes["encounter.id"] = models.FieldChange{Before: before.ID, After: after.ID}
+	}
+	fields := []struct {
+		name          string
+		before, after interface{}
+	}{
+		{"admittedAt", formatTime(&before.AdmittedAt), formatTime(&after.AdmittedAt)},
+		{"dischargedAt", formatTime(before.DischargedAt), formatTime(after.DischargedAt)},
+		{"reason", before.Reason, after.Reason},
+		{"doctor", before.Doctor, after.Doctor},
+		{"ward", before.Ward, after.Ward},
+	}
+	for _, f := range fields {
+		if f.before != f.after {
+			changes["encounter."+f.name] = models.FieldChange{Before: f.before, After: f.after}
+		}
+	}
+	return changes
+}
+
+// formatTime renders audited times in UTC; unset times are nil.
+func formatTime(t *time.Time) interface{} {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func nullTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+// dbError wraps err; a duplicate key means a second open encounter for the
+// same patient.
+func dbError(err error) error {
+	return sqltx.Error(err, &perrors.Conflict{Entity: "encounter", Reason: "patient already has an open encounter"})
+}
@@ -0,0 +1,435 @@
+package encounter
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aakanksha/ppms/internal/encryption"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/go-sql-driver/mysql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	selectPatient   = "select discharge from patient where deletedat IS NULL and id=? for update"
+	selectEncounter = "select e.id,e.patientid,e.admittedat,e.dischargedat,e.reason,e.doctor,e.ward,e.version from encounter e " +
+		"join patient p on p.id = e.patientid where p.deletedat IS NULL and e.patientid=? and e.id=?"
+	selectOverlap    = "select id from encounter where patientid=? and id<>? and (dischargedat IS NULL or dischargedat > ?) limit 1"
+	selectOverlapEnd = "select id from encounter where patientid=? and id<>? and (dischargedat IS NULL or dischargedat > ?) and admittedat < ? limit 1"
+	selectOpen       = "select not exists (select 1 from encounter where patientid=? and dischargedat IS NULL)"
+	updatePatient    = "update patient SET discharge=?, udatedat=?, version=version+1 where id=?"
+)
+
+var (
+	admitted   = time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	discharged = time.Date(2022, 3, 4, 9, 0, 0, 0, time.UTC)
+)
+
+type auditCall struct {
+	id        int
+	operation string
+	changes   map[string]models.FieldChange
+}
+
+// fakeAuditor records the audit entries a store appends.
+type fakeAuditor struct {
+	calls []auditCall
+}
+
+func (f *fakeAuditor) AppendAudit(ctx context.Context, tx *sql.Tx, id int, operation string, changes map[string]models.FieldChange) error {
+	f.calls = append(f.calls, auditCall{id: id, operation: operation, changes: changes})
+	return nil
+}
+
+// fakeBeds frees the bed in released for any patient.
+type fakeBeds struct {
+	released *models.BedAssignment
+}
+
+func (f *fakeBeds) ReleaseBed(ctx context.Context, tx *sql.Tx, patientID int) (*models.BedAssignment, error) {
+	return f.released, nil
+}
+
+func encounterRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "patientid", "admittedat", "dischargedat", "reason", "doctor", "ward", "version"})
+}
+
+func TestList(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("select 1 from patient where deletedat IS NULL and id=?").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	mock.ExpectQuery("select id,patientid,admittedat,dischargedat,reason,doctor,ward,version from encounter where patientid=? order by admittedat desc, id desc").WithArgs(1).
+		WillReturnRows(encounterRows().
+			AddRow(2, 1, discharged, nil, "fall", "Dr. Rao", "B2", 1).
+			AddRow(1, 1, admitted, discharged, "fever", "", "", 2))
+	mock.ExpectQuery("select 1 from patient where deletedat IS NULL and id=?").WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"1"}))
+
+	s := New(db, &fakeAuditor{})
+	encounters, err := s.List(context.TODO(), 1)
+	expected := []*models.Encounter{
+		{ID: 2, PatientID: 1, AdmittedAt: discharged, Reason: "fall", Doctor: "Dr. Rao", Ward: "B2", Version: 1},
+		{ID: 1, PatientID: 1, AdmittedAt: admitted, DischargedAt: &discharged, Reason: "fever", Version: 2},
+	}
+	if err != nil || !reflect.DeepEqual(encounters, expected) {
+		t.Errorf("Expected: %v, Got: %v (%v)", expected, encounters, err)
+	}
+
+	_, err = s.List(context.TODO(), 9)
+	if !reflect.DeepEqual(err, &perrors.NotFound{Entity: "patient", ID: "9"}) {
+		t.Errorf("expected error :patient not found, got :%v ", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	tests := []struct {
+		desc        string
+		input       *models.Encounter
+		mock        func(mock sqlmock.Sqlmock)
+		operation   string
+		changes     map[string]models.FieldChange
+		expectError error
+	}{
+		{
+			desc:  "admission undischarges the patient",
+			input: &models.Encounter{PatientID: 1, AdmittedAt: admitted, Reason: "fever", Ward: "B2"},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(true))
+				mock.ExpectQuery(selectOverlap).WithArgs(1, 0, admitted).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectExec("insert into encounter (patientid,admittedat,dischargedat,reason,doctor,ward) values (?, ?, ?, ?, ?, ?)").
+					WithArgs(1, admitted, nil, "fever", "", "B2").WillReturnResult(sqlmock.NewResult(5, 1))
+				mock.ExpectQuery(selectEncounter).WithArgs(1, 5).
+					WillReturnRows(encounterRows().AddRow(5, 1, admitted, nil, "fever", "", "B2", 1))
+				mock.ExpectQuery(selectOpen).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectExec(updatePatient).WithArgs(false, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			operation: "admit",
+			changes: map[string]models.FieldChange{
+				"encounter.id":         {After: 5},
+				"encounter.admittedAt": {After: "2022-03-01T10:00:00Z"},
+				"encounter.reason":     {Before: "", After: "fever"},
+				"encounter.ward":       {Before: "", After: "B2"},
+				"discharge":            {Before: true, After: false},
+			},
+		},
+		{
+			desc:  "past stay leaves the patient discharged",
+			input: &models.Encounter{PatientID: 1, AdmittedAt: admitted, DischargedAt: &discharged},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(true))
+				mock.ExpectQuery(selectOverlapEnd).WithArgs(1, 0, admitted, discharged).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectExec("insert into encounter (patientid,admittedat,dischargedat,reason,doctor,ward) values (?, ?, ?, ?, ?, ?)").
+					WithArgs(1, admitted, discharged, "", "", "").WillReturnResult(sqlmock.NewResult(6, 1))
+				mock.ExpectQuery(selectEncounter).WithArgs(1, 6).
+					WillReturnRows(encounterRows().AddRow(6, 1, admitted, discharged, "", "", "", 1))
+				mock.ExpectQuery(selectOpen).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(true))
+				mock.ExpectCommit()
+			},
+			operation: "admit",
+			changes: map[string]models.FieldChange{
+				"encounter.id":           {After: 6},
+				"encounter.admittedAt":   {After: "2022-03-01T10:00:00Z"},
+				"encounter.dischargedAt": {After: "2022-03-04T09:00:00Z"},
+			},
+		},
+		{
+			desc:  "past stay while admitted",
+			input: &models.Encounter{PatientID: 1, AdmittedAt: admitted, DischargedAt: &discharged},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectOverlapEnd).WithArgs(1, 0, admitted, discharged).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectExec("insert into encounter (patientid,admittedat,dischargedat,reason,doctor,ward) values (?, ?, ?, ?, ?, ?)").
+					WithArgs(1, admitted, discharged, "", "", "").WillReturnResult(sqlmock.NewResult(6, 1))
+				mock.ExpectQuery(selectEncounter).WithArgs(1, 6).
+					WillReturnRows(encounterRows().AddRow(6, 1, admitted, discharged, "", "", "", 1))
+				mock.ExpectQuery(selectOpen).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectCommit()
+			},
+			operation: "admit",
+			changes: map[string]models.FieldChange{
+				"encounter.id":           {After: 6},
+				"encounter.admittedAt":   {After: "2022-03-01T10:00:00Z"},
+				"encounter.dischargedAt": {After: "2022-03-04T09:00:00Z"},
+			},
+		},
+		{
+			desc:  "overlapping stay",
+			input: &models.Encounter{PatientID: 1, AdmittedAt: admitted},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectOverlap).WithArgs(1, 0, admitted).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "encounter", Reason: "overlaps encounter 3"},
+		},
+		{
+			desc:  "concurrent admission",
+			input: &models.Encounter{PatientID: 1, AdmittedAt: admitted},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(true))
+				mock.ExpectQuery(selectOverlap).WithArgs(1, 0, admitted).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectExec("insert into encounter (patientid,admittedat,dischargedat,reason,doctor,ward) values (?, ?, ?, ?, ?, ?)").
+					WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "encounter", Reason: "patient already has an open encounter"},
+		},
+		{
+			desc:  "unknown patient",
+			input: &models.Encounter{PatientID: 9, AdmittedAt: admitted},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(9).WillReturnRows(sqlmock.NewRows([]string{"discharge"}))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.NotFound{Entity: "patient", ID: "9"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			test.mock(mock)
+			auditor := &fakeAuditor{}
+
+			_, err = New(db, auditor).Insert(context.TODO(), test.input)
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if test.changes != nil {
+				expected := []auditCall{{id: test.input.PatientID, operation: test.operation, changes: test.changes}}
+				if !reflect.DeepEqual(auditor.calls, expected) {
+					t.Errorf("Expected: %v, Got: %v", expected, auditor.calls)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		desc        string
+		input       *models.Encounter
+		beds        *fakeBeds
+		mock        func(mock sqlmock.Sqlmock)
+		operation   string
+		changes     map[string]models.FieldChange
+		expectError error
+	}{
+		{
+			desc:  "discharge closes the open encounter",
+			input: &models.Encounter{ID: 5, PatientID: 1, AdmittedAt: admitted, DischargedAt: &discharged, Reason: "fever", Version: 1},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectEncounter+" for update").WithArgs(1, 5).
+					WillReturnRows(encounterRows().AddRow(5, 1, admitted, nil, "fever", "", "", 1))
+				mock.ExpectQuery(selectOverlapEnd).WithArgs(1, 5, admitted, discharged).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectExec("update encounter SET admittedat=?, dischargedat=?, reason=?, doctor=?, ward=?, version=version+1 where id=?").
+					WithArgs(admitted, discharged, "fever", "", "", 5).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(selectEncounter).WithArgs(1, 5).
+					WillReturnRows(encounterRows().AddRow(5, 1, admitted, discharged, "fever", "", "", 2))
+				mock.ExpectQuery(selectOpen).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(true))
+				mock.ExpectExec(updatePatient).WithArgs(true, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			operation: "discharge",
+			changes: map[string]models.FieldChange{
+				"encounter.id":           {Before: 5, After: 5},
+				"encounter.dischargedAt": {After: "2022-03-04T09:00:00Z"},
+				"discharge":              {Before: false, After: true},
+			},
+		},
+		{
+			desc:  "discharge frees the bed",
+			input: &models.Encounter{ID: 5, PatientID: 1, AdmittedAt: admitted, DischargedAt: &discharged, Version: 1},
+			beds:  &fakeBeds{released: &models.BedAssignment{ID: 3, PatientID: 1, BedLocation: models.BedLocation{BedID: 4, WardID: 2, Ward: "ICU", Label: "A1"}}},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectEncounter+" for update").WithArgs(1, 5).
+					WillReturnRows(encounterRows().AddRow(5, 1, admitted, nil, "", "", "", 1))
+				mock.ExpectQuery(selectOverlapEnd).WithArgs(1, 5, admitted, discharged).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectExec("update encounter SET admittedat=?, dischargedat=?, reason=?, doctor=?, ward=?, version=version+1 where id=?").
+					WithArgs(admitted, discharged, "", "", "", 5).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(selectEncounter).WithArgs(1, 5).
+					WillReturnRows(encounterRows().AddRow(5, 1, admitted, discharged, "", "", "", 2))
+				mock.ExpectQuery(selectOpen).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(true))
+				mock.ExpectExec(updatePatient).WithArgs(true, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			operation: "discharge",
+			changes: map[string]models.FieldChange{
+				"encounter.id":           {Before: 5, After: 5},
+				"encounter.dischargedAt": {After: "2022-03-04T09:00:00Z"},
+				"discharge":              {Before: false, After: true},
+				"bed":                    {Before: models.BedLocation{BedID: 4, WardID: 2, Ward: "ICU", Label: "A1"}},
+			},
+		},
+		{
+			desc:  "edit closed encounter while admitted",
+			input: &models.Encounter{ID: 4, PatientID: 1, AdmittedAt: admitted, DischargedAt: &discharged, Reason: "fall", Version: 1},
+			beds:  &fakeBeds{released: &models.BedAssignment{ID: 3, PatientID: 1, BedLocation: models.BedLocation{BedID: 4, WardID: 2, Ward: "ICU", Label: "A1"}}},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectEncounter+" for update").WithArgs(1, 4).
+					WillReturnRows(encounterRows().AddRow(4, 1, admitted, discharged, "fever", "", "", 1))
+				mock.ExpectQuery(selectOverlapEnd).WithArgs(1, 4, admitted, discharged).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectExec("update encounter SET admittedat=?, dischargedat=?, reason=?, doctor=?, ward=?, version=version+1 where id=?").
+					WithArgs(admitted, discharged, "fall", "", "", 4).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(selectEncounter).WithArgs(1, 4).
+					WillReturnRows(encounterRows().AddRow(4, 1, admitted, discharged, "fall", "", "", 2))
+				mock.ExpectQuery(selectOpen).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectCommit()
+			},
+			operation: "encounter",
+			changes: map[string]models.FieldChange{
+				"encounter.id":     {Before: 4, After: 4},
+				"encounter.reason": {Before: "fever", After: "fall"},
+			},
+		},
+		{
+			desc:  "stale version",
+			input: &models.Encounter{ID: 5, PatientID: 1, AdmittedAt: admitted, Version: 1},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectEncounter+" for update").WithArgs(1, 5).
+					WillReturnRows(encounterRows().AddRow(5, 1, admitted, nil, "fever", "", "", 2))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.PreconditionFailed{Entity: "encounter", ID: "5"},
+		},
+		{
+			desc:  "encounter of another patient",
+			input: &models.Encounter{ID: 5, PatientID: 2, AdmittedAt: admitted},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(true))
+				mock.ExpectQuery(selectEncounter+" for update").WithArgs(2, 5).WillReturnRows(encounterRows())
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.NotFound{Entity: "encounter", ID: "5"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			test.mock(mock)
+			auditor := &fakeAuditor{}
+			s := New(db, auditor)
+			if test.beds != nil {
+				s.WithBeds(test.beds)
+			}
+
+			_, err = s.Update(context.TODO(), test.input)
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if test.changes != nil {
+				expected := []auditCall{{id: test.input.PatientID, operation: test.operation, changes: test.changes}}
+				if !reflect.DeepEqual(auditor.calls, expected) {
+					t.Errorf("Expected: %v, Got: %v", expected, auditor.calls)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// reasonArg matches a reason sealed for encounter id.
+type reasonArg struct {
+	keys *encryption.Keyring
+	id   int
+}
+
+func (a reasonArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	reason, err := a.keys.DecryptFor("encounter.reason", a.id, s)
+	return err == nil && reason == "fever" && s != "fever"
+}
+
+func TestEncryptedReason(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	keys, err := encryption.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{9}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := keys.EncryptFor("encounter.reason", 5, "fever")
+	copied, _ := keys.EncryptFor("encounter.reason", 4, "fever")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+	mock.ExpectQuery(selectOverlapEnd).WithArgs(1, 0, admitted, discharged).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec("insert into encounter (patientid,admittedat,dischargedat,reason,doctor,ward) values (?, ?, ?, ?, ?, ?)").
+		WithArgs(1, admitted, discharged, "", "", "").WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectExec("update encounter SET reason=? where id=?").WithArgs(reasonArg{keys, 5}, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(selectEncounter).WithArgs(1, 5).
+		WillReturnRows(encounterRows().AddRow(5, 1, admitted, discharged, sealed, "", "", 1))
+	mock.ExpectQuery(selectOpen).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+	mock.ExpectCommit()
+	mock.ExpectQuery(selectEncounter).WithArgs(1, 5).
+		WillReturnRows(encounterRows().AddRow(5, 1, admitted, discharged, copied, "", "", 1))
+
+	s := New(db, &fakeAuditor{}).WithKeyring(keys)
+	created, err := s.Insert(context.TODO(), &models.Encounter{PatientID: 1, AdmittedAt: admitted, DischargedAt: &discharged, Reason: "fever"})
+	if err != nil || created.Reason != "fever" {
+		t.Errorf("unexpected result: %+v, %v", created, err)
+	}
+	if _, err := s.Get(context.TODO(), 1, 5); err == nil {
+		t.Error("expected error for a reason copied from another encounter")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDBError(t *testing.T) {
+	err := dbError(errors.New("connection refused"))
+	var internal *perrors.Internal
+	if !errors.As(err, &internal) {
+		t.Errorf("expected internal error, got :%v", err)
+	}
+}
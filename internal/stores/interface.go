@@ -0,0 +1,77 @@
+package stores
+
+import (
+	"context"
+	"github.com/aakanksha/ppms/internal/models"
+	"time"
+)
+
+//go:generate mockgen -source=interface.go -destination=mock_interface.go -package=stores
+
+type StoreInterface interface {
+	Insert(ctx context.Context, pt *models.Patient) (*models.Patient, error)
+	GetByID(ctx context.Context, id int) (*models.Patient, error)
+	GetAll(ctx context.Context) ([]*models.Patient, error)
+	Each(ctx context.Context, fn func(*models.Patient) error) error
+	List(ctx context.Context, opts models.ListOptions) (*models.PatientPage, error)
+	Search(ctx context.Context, query string, opts models.ListOptions) (*models.PatientPage, error)
+	Update(ctx context.Context, pt *models.Patient, id int) (*models.Patient, error)
+	Patch(ctx context.Context, id int, version int, columns map[string]interface{}) (*models.Patient, error)
+	Delete(ctx context.Context, id int, version int) error
+	Restore(ctx context.Context, id int) (*models.Patient, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	History(ctx context.Context, id int) ([]*models.AuditEntry, error)
+	BulkInsert(ctx context.Context, pts []*models.Patient) ([]*models.Patient, error)
+	BulkUpdate(ctx context.Context, pts []*models.Patient, mode models.BulkMode) ([]models.BulkResult, error)
+	BulkDelete(ctx context.Context, refs []models.PatientRef, mode models.BulkMode) ([]models.BulkResult, error)
+}
+
+// EncounterInterface stores the admissions of patients.
+type EncounterInterface interface {
+	List(ctx context.Context, patientID int) ([]*models.Encounter, error)
+	Get(ctx context.Context, patientID, id int) (*models.Encounter, error)
+	Insert(ctx context.Context, e *models.Encounter) (*models.Encounter, error)
+	Update(ctx context.Context, e *models.Encounter) (*models.Encounter, error)
+}
+
+// WardInterface stores wards, their beds and which patient is in which bed.
+type WardInterface interface {
+	CreateWard(ctx context.Context, w *models.Ward) (*models.Ward, error)
+	ListWards(ctx context.Context) ([]*models.Ward, error)
+	GetWard(ctx context.Context, id int) (*models.Ward, error)
+	AddBed(ctx context.Context, b *models.Bed) (*models.Bed, error)
+	Occupancy(ctx context.Context, wardID int) (*models.Occupancy, error)
+	CurrentBed(ctx context.Context, patientID int) (*models.BedAssignment, error)
+	Assign(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error)
+	Transfer(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error)
+	Release(ctx context.Context, patientID int) (*models.BedAssignment, error)
+}
+
+// TriageInterface stores the visits of patients waiting to be seen.
+type TriageInterface interface {
+	Waiting(ctx context.Context) ([]*models.Triage, error)
+	Insert(ctx context.Context, t *models.Triage) (*models.Triage, error)
+	Retriage(ctx context.Context, id, level int) (*models.Triage, error)
+	Call(ctx context.Context, id int) (*models.Triage, error)
+}
+
+// StaffInterface stores doctors and nurses and the care teams they are on.
+type StaffInterface interface {
+	List(ctx context.Context, profession models.Profession) ([]*models.Staff, error)
+	Get(ctx context.Context, id int) (*models.Staff, error)
+	Insert(ctx context.Context, st *models.Staff) (*models.Staff, error)
+	Update(ctx context.Context, st *models.Staff) (*models.Staff, error)
+	Delete(ctx context.Context, id, version int) error
+	Team(ctx context.Context, patientID int) ([]*models.CareTeamMember, error)
+	Patients(ctx context.Context, staffID int) ([]*models.CareTeamMember, error)
+	Assign(ctx context.Context, m *models.CareTeamMember) (*models.CareTeamMember, error)
+	Unassign(ctx context.Context, patientID, staffID int) error
+}
+
+type AppointmentInterface interface {
+	List(ctx context.Context, f models.AppointmentFilter) ([]*models.Appointment, error)
+	Get(ctx context.Context, id int) (*models.Appointment, error)
+	Insert(ctx context.Context, a *models.Appointment) (*models.Appointment, error)
+	Reschedule(ctx context.Context, a *models.Appointment) (*models.Appointment, error)
+	Cancel(ctx context.Context, id, version int) (*models.Appointment, error)
+}
@@ -0,0 +1,838 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: interface.go
+
+// Package stores is a generated GoMock package.
+package stores
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	models "github.com/aakanksha/ppms/internal/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockStoreInterface is a mock of StoreInterface interface.
+type MockStoreInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreInterfaceMockRecorder
+}
+
+// MockStoreInterfaceMockRecorder is the mock recorder for MockStoreInterface.
+type MockStoreInterfaceMockRecorder struct {
+	mock *MockStoreInterface
+}
+
+// NewMockStoreInterface creates a new mock instance.
+func NewMockStoreInterface(ctrl *gomock.Controller) *MockStoreInterface {
+	mock := &MockStoreInterface{ctrl: ctrl}
+	mock.recorder = &MockStoreInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStoreInterface) EXPECT() *MockStoreInterfaceMockRecorder {
+	return m.recorder
+}
+
+// BulkDelete mocks base method.
+func (m *MockStoreInterface) BulkDelete(ctx context.Context, refs []models.PatientRef, mode models.BulkMode) ([]models.BulkResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkDelete", ctx, refs, mode)
+	ret0, _ := ret[0].([]models.BulkResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkDelete indicates an expected call of BulkDelete.
+func (mr *MockStoreInterfaceMockRecorder) BulkDelete(ctx, refs, mode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkDelete", reflect.TypeOf((*MockStoreInterface)(nil).BulkDelete), ctx, refs, mode)
+}
+
+// BulkInsert mocks base method.
+func (m *MockStoreInterface) BulkInsert(ctx context.Context, pts []*models.Patient) ([]*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkInsert", ctx, pts)
+	ret0, _ := ret[0].([]*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkInsert indicates an expected call of BulkInsert.
+func (mr *MockStoreInterfaceMockRecorder) BulkInsert(ctx, pts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkInsert", reflect.TypeOf((*MockStoreInterface)(nil).BulkInsert), ctx, pts)
+}
+
+// BulkUpdate mocks base method.
+func (m *MockStoreInterface) BulkUpdate(ctx context.Context, pts []*models.Patient, mode models.BulkMode) ([]models.BulkResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkUpdate", ctx, pts, mode)
+	ret0, _ := ret[0].([]models.BulkResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkUpdate indicates an expected call of BulkUpdate.
+func (mr *MockStoreInterfaceMockRecorder) BulkUpdate(ctx, pts, mode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkUpdate", reflect.TypeOf((*MockStoreInterface)(nil).BulkUpdate), ctx, pts, mode)
+}
+
+// Delete mocks base method.
+func (m *MockStoreInterface) Delete(ctx context.Context, id, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockStoreInterfaceMockRecorder) Delete(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStoreInterface)(nil).Delete), ctx, id, version)
+}
+
+// Each mocks base method.
+func (m *MockStoreInterface) Each(ctx context.Context, fn func(*models.Patient) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Each", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Each indicates an expected call of Each.
+func (mr *MockStoreInterfaceMockRecorder) Each(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Each", reflect.TypeOf((*MockStoreInterface)(nil).Each), ctx, fn)
+}
+
+// GetAll mocks base method.
+func (m *MockStoreInterface) GetAll(ctx context.Context) ([]*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret0, _ := ret[0].([]*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockStoreInterfaceMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockStoreInterface)(nil).GetAll), ctx)
+}
+
+// GetByID mocks base method.
+func (m *MockStoreInterface) GetByID(ctx context.Context, id int) (*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
+	ret0, _ := ret[0].(*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockStoreInterfaceMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockStoreInterface)(nil).GetByID), ctx, id)
+}
+
+// History mocks base method.
+func (m *MockStoreInterface) History(ctx context.Context, id int) ([]*models.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", ctx, id)
+	ret0, _ := ret[0].([]*models.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// History indicates an expected call of History.
+func (mr *MockStoreInterfaceMockRecorder) History(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockStoreInterface)(nil).History), ctx, id)
+}
+
+// Insert mocks base method.
+func (m *MockStoreInterface) Insert(ctx context.Context, pt *models.Patient) (*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, pt)
+	ret0, _ := ret[0].(*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockStoreInterfaceMockRecorder) Insert(ctx, pt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockStoreInterface)(nil).Insert), ctx, pt)
+}
+
+// List mocks base method.
+func (m *MockStoreInterface) List(ctx context.Context, opts models.ListOptions) (*models.PatientPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, opts)
+	ret0, _ := ret[0].(*models.PatientPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockStoreInterfaceMockRecorder) List(ctx, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockStoreInterface)(nil).List), ctx, opts)
+}
+
+// Patch mocks base method.
+func (m *MockStoreInterface) Patch(ctx context.Context, id, version int, columns map[string]interface{}) (*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", ctx, id, version, columns)
+	ret0, _ := ret[0].(*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Patch indicates an expected call of Patch.
+func (mr *MockStoreInterfaceMockRecorder) Patch(ctx, id, version, columns interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*MockStoreInterface)(nil).Patch), ctx, id, version, columns)
+}
+
+// Purge mocks base method.
+func (m *MockStoreInterface) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, deletedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockStoreInterfaceMockRecorder) Purge(ctx, deletedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockStoreInterface)(nil).Purge), ctx, deletedBefore)
+}
+
+// Restore mocks base method.
+func (m *MockStoreInterface) Restore(ctx context.Context, id int) (*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, id)
+	ret0, _ := ret[0].(*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockStoreInterfaceMockRecorder) Restore(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockStoreInterface)(nil).Restore), ctx, id)
+}
+
+// Search mocks base method.
+func (m *MockStoreInterface) Search(ctx context.Context, query string, opts models.ListOptions) (*models.PatientPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, query, opts)
+	ret0, _ := ret[0].(*models.PatientPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockStoreInterfaceMockRecorder) Search(ctx, query, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockStoreInterface)(nil).Search), ctx, query, opts)
+}
+
+// Update mocks base method.
+func (m *MockStoreInterface) Update(ctx context.Context, pt *models.Patient, id int) (*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, pt, id)
+	ret0, _ := ret[0].(*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStoreInterfaceMockRecorder) Update(ctx, pt, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStoreInterface)(nil).Update), ctx, pt, id)
+}
+
+// MockEncounterInterface is a mock of EncounterInterface interface.
+type MockEncounterInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockEncounterInterfaceMockRecorder
+}
+
+// MockEncounterInterfaceMockRecorder is the mock recorder for MockEncounterInterface.
+type MockEncounterInterfaceMockRecorder struct {
+	mock *MockEncounterInterface
+}
+
+// NewMockEncounterInterface creates a new mock instance.
+func NewMockEncounterInterface(ctrl *gomock.Controller) *MockEncounterInterface {
+	mock := &MockEncounterInterface{ctrl: ctrl}
+	mock.recorder = &MockEncounterInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEncounterInterface) EXPECT() *MockEncounterInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockEncounterInterface) Get(ctx context.Context, patientID, id int) (*models.Encounter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, patientID, id)
+	ret0, _ := ret[0].(*models.Encounter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockEncounterInterfaceMockRecorder) Get(ctx, patientID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockEncounterInterface)(nil).Get), ctx, patientID, id)
+}
+
+// Insert mocks base method.
+func (m *MockEncounterInterface) Insert(ctx context.Context, e *models.Encounter) (*models.Encounter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, e)
+	ret0, _ := ret[0].(*models.Encounter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockEncounterInterfaceMockRecorder) Insert(ctx, e interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockEncounterInterface)(nil).Insert), ctx, e)
+}
+
+// List mocks base method.
+func (m *MockEncounterInterface) List(ctx context.Context, patientID int) ([]*models.Encounter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, patientID)
+	ret0, _ := ret[0].([]*models.Encounter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockEncounterInterfaceMockRecorder) List(ctx, patientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockEncounterInterface)(nil).List), ctx, patientID)
+}
+
+// Update mocks base method.
+func (m *MockEncounterInterface) Update(ctx context.Context, e *models.Encounter) (*models.Encounter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, e)
+	ret0, _ := ret[0].(*models.Encounter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockEncounterInterfaceMockRecorder) Update(ctx, e interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockEncounterInterface)(nil).Update), ctx, e)
+}
+
+// MockWardInterface is a mock of WardInterface interface.
+type MockWardInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockWardInterfaceMockRecorder
+}
+
+// MockWardInterfaceMockRecorder is the mock recorder for MockWardInterface.
+type MockWardInterfaceMockRecorder struct {
+	mock *MockWardInterface
+}
+
+// NewMockWardInterface creates a new mock instance.
+func NewMockWardInterface(ctrl *gomock.Controller) *MockWardInterface {
+	mock := &MockWardInterface{ctrl: ctrl}
+	mock.recorder = &MockWardInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWardInterface) EXPECT() *MockWardInterfaceMockRecorder {
+	return m.recorder
+}
+
+// AddBed mocks base method.
+func (m *MockWardInterface) AddBed(ctx context.Context, b *models.Bed) (*models.Bed, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddBed", ctx, b)
+	ret0, _ := ret[0].(*models.Bed)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddBed indicates an expected call of AddBed.
+func (mr *MockWardInterfaceMockRecorder) AddBed(ctx, b interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBed", reflect.TypeOf((*MockWardInterface)(nil).AddBed), ctx, b)
+}
+
+// Assign mocks base method.
+func (m *MockWardInterface) Assign(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Assign", ctx, patientID, bedID)
+	ret0, _ := ret[0].(*models.BedAssignment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Assign indicates an expected call of Assign.
+func (mr *MockWardInterfaceMockRecorder) Assign(ctx, patientID, bedID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Assign", reflect.TypeOf((*MockWardInterface)(nil).Assign), ctx, patientID, bedID)
+}
+
+// CreateWard mocks base method.
+func (m *MockWardInterface) CreateWard(ctx context.Context, w *models.Ward) (*models.Ward, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWard", ctx, w)
+	ret0, _ := ret[0].(*models.Ward)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWard indicates an expected call of CreateWard.
+func (mr *MockWardInterfaceMockRecorder) CreateWard(ctx, w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWard", reflect.TypeOf((*MockWardInterface)(nil).CreateWard), ctx, w)
+}
+
+// CurrentBed mocks base method.
+func (m *MockWardInterface) CurrentBed(ctx context.Context, patientID int) (*models.BedAssignment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CurrentBed", ctx, patientID)
+	ret0, _ := ret[0].(*models.BedAssignment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CurrentBed indicates an expected call of CurrentBed.
+func (mr *MockWardInterfaceMockRecorder) CurrentBed(ctx, patientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CurrentBed", reflect.TypeOf((*MockWardInterface)(nil).CurrentBed), ctx, patientID)
+}
+
+// GetWard mocks base method.
+func (m *MockWardInterface) GetWard(ctx context.Context, id int) (*models.Ward, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWard", ctx, id)
+	ret0, _ := ret[0].(*models.Ward)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWard indicates an expected call of GetWard.
+func (mr *MockWardInterfaceMockRecorder) GetWard(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWard", reflect.TypeOf((*MockWardInterface)(nil).GetWard), ctx, id)
+}
+
+// ListWards mocks base method.
+func (m *MockWardInterface) ListWards(ctx context.Context) ([]*models.Ward, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWards", ctx)
+	ret0, _ := ret[0].([]*models.Ward)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWards indicates an expected call of ListWards.
+func (mr *MockWardInterfaceMockRecorder) ListWards(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWards", reflect.TypeOf((*MockWardInterface)(nil).ListWards), ctx)
+}
+
+// Occupancy mocks base method.
+func (m *MockWardInterface) Occupancy(ctx context.Context, wardID int) (*models.Occupancy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Occupancy", ctx, wardID)
+	ret0, _ := ret[0].(*models.Occupancy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Occupancy indicates an expected call of Occupancy.
+func (mr *MockWardInterfaceMockRecorder) Occupancy(ctx, wardID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Occupancy", reflect.TypeOf((*MockWardInterface)(nil).Occupancy), ctx, wardID)
+}
+
+// Release mocks base method.
+func (m *MockWardInterface) Release(ctx context.Context, patientID int) (*models.BedAssignment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, patientID)
+	ret0, _ := ret[0].(*models.BedAssignment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockWardInterfaceMockRecorder) Release(ctx, patientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockWardInterface)(nil).Release), ctx, patientID)
+}
+
+// Transfer mocks base method.
+func (m *MockWardInterface) Transfer(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", ctx, patientID, bedID)
+	ret0, _ := ret[0].(*models.BedAssignment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockWardInterfaceMockRecorder) Transfer(ctx, patientID, bedID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockWardInterface)(nil).Transfer), ctx, patientID, bedID)
+}
+
+// MockTriageInterface is a mock of TriageInterface interface.
+type MockTriageInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockTriageInterfaceMockRecorder
+}
+
+// MockTriageInterfaceMockRecorder is the mock recorder for MockTriageInterface.
+type MockTriageInterfaceMockRecorder struct {
+	mock *MockTriageInterface
+}
+
+// NewMockTriageInterface creates a new mock instance.
+func NewMockTriageInterface(ctrl *gomock.Controller) *MockTriageInterface {
+	mock := &MockTriageInterface{ctrl: ctrl}
+	mock.recorder = &MockTriageInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTriageInterface) EXPECT() *MockTriageInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Call mocks base method.
+func (m *MockTriageInterface) Call(ctx context.Context, id int) (*models.Triage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Call", ctx, id)
+	ret0, _ := ret[0].(*models.Triage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Call indicates an expected call of Call.
+func (mr *MockTriageInterfaceMockRecorder) Call(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Call", reflect.TypeOf((*MockTriageInterface)(nil).Call), ctx, id)
+}
+
+// Insert mocks base method.
+func (m *MockTriageInterface) Insert(ctx context.Context, t *models.Triage) (*models.Triage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, t)
+	ret0, _ := ret[0].(*models.Triage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockTriageInterfaceMockRecorder) Insert(ctx, t interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockTriageInterface)(nil).Insert), ctx, t)
+}
+
+// Retriage mocks base method.
+func (m *MockTriageInterface) Retriage(ctx context.Context, id, level int) (*models.Triage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Retriage", ctx, id, level)
+	ret0, _ := ret[0].(*models.Triage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Retriage indicates an expected call of Retriage.
+func (mr *MockTriageInterfaceMockRecorder) Retriage(ctx, id, level interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Retriage", reflect.TypeOf((*MockTriageInterface)(nil).Retriage), ctx, id, level)
+}
+
+// Waiting mocks base method.
+func (m *MockTriageInterface) Waiting(ctx context.Context) ([]*models.Triage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Waiting", ctx)
+	ret0, _ := ret[0].([]*models.Triage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Waiting indicates an expected call of Waiting.
+func (mr *MockTriageInterfaceMockRecorder) Waiting(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Waiting", reflect.TypeOf((*MockTriageInterface)(nil).Waiting), ctx)
+}
+
+// MockStaffInterface is a mock of StaffInterface interface.
+type MockStaffInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockStaffInterfaceMockRecorder
+}
+
+// MockStaffInterfaceMockRecorder is the mock recorder for MockStaffInterface.
+type MockStaffInterfaceMockRecorder struct {
+	mock *MockStaffInterface
+}
+
+// NewMockStaffInterface creates a new mock instance.
+func NewMockStaffInterface(ctrl *gomock.Controller) *MockStaffInterface {
+	mock := &MockStaffInterface{ctrl: ctrl}
+	mock.recorder = &MockStaffInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStaffInterface) EXPECT() *MockStaffInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Assign mocks base method.
+func (m_2 *MockStaffInterface) Assign(ctx context.Context, m *models.CareTeamMember) (*models.CareTeamMember, error) {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "Assign", ctx, m)
+	ret0, _ := ret[0].(*models.CareTeamMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Assign indicates an expected call of Assign.
+func (mr *MockStaffInterfaceMockRecorder) Assign(ctx, m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Assign", reflect.TypeOf((*MockStaffInterface)(nil).Assign), ctx, m)
+}
+
+// Delete mocks base method.
+func (m *MockStaffInterface) Delete(ctx context.Context, id, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockStaffInterfaceMockRecorder) Delete(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStaffInterface)(nil).Delete), ctx, id, version)
+}
+
+// Get mocks base method.
+func (m *MockStaffInterface) Get(ctx context.Context, id int) (*models.Staff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(*models.Staff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockStaffInterfaceMockRecorder) Get(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStaffInterface)(nil).Get), ctx, id)
+}
+
+// Insert mocks base method.
+func (m *MockStaffInterface) Insert(ctx context.Context, st *models.Staff) (*models.Staff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, st)
+	ret0, _ := ret[0].(*models.Staff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockStaffInterfaceMockRecorder) Insert(ctx, st interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockStaffInterface)(nil).Insert), ctx, st)
+}
+
+// List mocks base method.
+func (m *MockStaffInterface) List(ctx context.Context, profession models.Profession) ([]*models.Staff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, profession)
+	ret0, _ := ret[0].([]*models.Staff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockStaffInterfaceMockRecorder) List(ctx, profession interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockStaffInterface)(nil).List), ctx, profession)
+}
+
+// Patients mocks base method.
+func (m *MockStaffInterface) Patients(ctx context.Context, staffID int) ([]*models.CareTeamMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patients", ctx, staffID)
+	ret0, _ := ret[0].([]*models.CareTeamMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Patients indicates an expected call of Patients.
+func (mr *MockStaffInterfaceMockRecorder) Patients(ctx, staffID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patients", reflect.TypeOf((*MockStaffInterface)(nil).Patients), ctx, staffID)
+}
+
+// Team mocks base method.
+func (m *MockStaffInterface) Team(ctx context.Context, patientID int) ([]*models.CareTeamMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Team", ctx, patientID)
+	ret0, _ := ret[0].([]*models.CareTeamMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Team indicates an expected call of Team.
+func (mr *MockStaffInterfaceMockRecorder) Team(ctx, patientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Team", reflect.TypeOf((*MockStaffInterface)(nil).Team), ctx, patientID)
+}
+
+// Unassign mocks base method.
+func (m *MockStaffInterface) Unassign(ctx context.Context, patientID, staffID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unassign", ctx, patientID, staffID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unassign indicates an expected call of Unassign.
+func (mr *MockStaffInterfaceMockRecorder) Unassign(ctx, patientID, staffID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unassign", reflect.TypeOf((*MockStaffInterface)(nil).Unassign), ctx, patientID, staffID)
+}
+
+// Update mocks base method.
+func (m *MockStaffInterface) Update(ctx context.Context, st *models.Staff) (*models.Staff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, st)
+	ret0, _ := ret[0].(*models.Staff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStaffInterfaceMockRecorder) Update(ctx, st interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStaffInterface)(nil).Update), ctx, st)
+}
+
+// MockAppointmentInterface is a mock of AppointmentInterface interface.
+type MockAppointmentInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockAppointmentInterfaceMockRecorder
+}
+
+// MockAppointmentInterfaceMockRecorder is the mock recorder for MockAppointmentInterface.
+type MockAppointmentInterfaceMockRecorder struct {
+	mock *MockAppointmentInterface
+}
+
+// NewMockAppointmentInterface creates a new mock instance.
+func NewMockAppointmentInterface(ctrl *gomock.Controller) *MockAppointmentInterface {
+	mock := &MockAppointmentInterface{ctrl: ctrl}
+	mock.recorder = &MockAppointmentInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAppointmentInterface) EXPECT() *MockAppointmentInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Cancel mocks base method.
+func (m *MockAppointmentInterface) Cancel(ctx context.Context, id, version int) (*models.Appointment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cancel", ctx, id, version)
+	ret0, _ := ret[0].(*models.Appointment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Cancel indicates an expected call of Cancel.
+func (mr *MockAppointmentInterfaceMockRecorder) Cancel(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cancel", reflect.TypeOf((*MockAppointmentInterface)(nil).Cancel), ctx, id, version)
+}
+
+// Get mocks base method.
+func (m *MockAppointmentInterface) Get(ctx context.Context, id int) (*models.Appointment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(*models.Appointment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockAppointmentInterfaceMockRecorder) Get(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockAppointmentInterface)(nil).Get), ctx, id)
+}
+
+// Insert mocks base method.
+func (m *MockAppointmentInterface) Insert(ctx context.Context, a *models.Appointment) (*models.Appointment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, a)
+	ret0, _ := ret[0].(*models.Appointment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockAppointmentInterfaceMockRecorder) Insert(ctx, a interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockAppointmentInterface)(nil).Insert), ctx, a)
+}
+
+// List mocks base method.
+func (m *MockAppointmentInterface) List(ctx context.Context, f models.AppointmentFilter) ([]*models.Appointment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, f)
+	ret0, _ := ret[0].([]*models.Appointment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockAppointmentInterfaceMockRecorder) List(ctx, f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAppointmentInterface)(nil).List), ctx, f)
+}
+
+// Reschedule mocks base method.
+func (m *MockAppointmentInterface) Reschedule(ctx context.Context, a *models.Appointment) (*models.Appointment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reschedule", ctx, a)
+	ret0, _ := ret[0].(*models.Appointment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reschedule indicates an expected call of Reschedule.
+func (mr *MockAppointmentInterfaceMockRecorder) Reschedule(ctx, a interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reschedule", reflect.TypeOf((*MockAppointmentInterface)(nil).Reschedule), ctx, a)
+}
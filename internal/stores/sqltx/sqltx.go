@@ -0,0 +1,75 @@
+// Package sqltx holds the transaction, audit and error plumbing shared by the
+// stores that keep their rows next to the patient table.
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/go-sql-driver/mysql"
+	"strconv"
+	"time"
+)
+
+// errDuplicateEntry is the MySQL error number of a duplicate key.
+const errDuplicateEntry = 1062
+
+// Querier is satisfied by both *sql.DB and *sql.Tx.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Auditor appends entries to the audit trail of a patient inside a
+// transaction; the patient store implements it.
+type Auditor interface {
+	AppendAudit(ctx context.Context, tx *sql.Tx, id int, operation string, changes map[string]models.FieldChange) error
+}
+
+// InTx runs fn in a transaction on db, committing only if it succeeds.
+func InTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Error(err, nil)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return Error(err, nil)
+	}
+	return nil
+}
+
+// LockPatient locks the row of a live patient until tx ends, serialising the
+// writes made on the patient's behalf, and returns its discharge flag.
+func LockPatient(ctx context.Context, tx *sql.Tx, id int) (bool, error) {
+	var discharge bool
+	err := tx.QueryRowContext(ctx, "select discharge from patient where deletedat IS NULL and id=? for update", id).Scan(&discharge)
+	if err == sql.ErrNoRows {
+		return false, &perrors.NotFound{Entity: "patient", ID: strconv.Itoa(id)}
+	}
+	if err != nil {
+		return false, Error(err, nil)
+	}
+	return discharge, nil
+}
+
+// Error wraps err as an internal error; a duplicate key becomes conflict when
+// it is given.
+func Error(err error, conflict *perrors.Conflict) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry && conflict != nil {
+		return conflict
+	}
+	return &perrors.Internal{Err: err}
+}
+
+// Now is the time recorded for writes, to the second as it is stored. Tests
+// replace it.
+var Now = func() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
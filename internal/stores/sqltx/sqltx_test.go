@@ -0,0 +1,80 @@
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/go-sql-driver/mysql"
+	"reflect"
+	"testing"
+)
+
+func TestInTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	failed := errors.New("failed")
+	if err := InTx(context.TODO(), db, func(tx *sql.Tx) error { return failed }); err != failed {
+		t.Errorf("expected error :%v, got :%v ", failed, err)
+	}
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(errors.New("connection lost"))
+	err = InTx(context.TODO(), db, func(tx *sql.Tx) error { return nil })
+	var internal *perrors.Internal
+	if !errors.As(err, &internal) {
+		t.Errorf("expected internal error, got :%v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLockPatient(t *testing.T) {
+	const lock = "select discharge from patient where deletedat IS NULL and id=? for update"
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lock).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(true))
+	mock.ExpectQuery(lock).WithArgs(2).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	err = InTx(context.TODO(), db, func(tx *sql.Tx) error {
+		if discharge, err := LockPatient(context.TODO(), tx, 1); err != nil || !discharge {
+			t.Errorf("Expected: true, Got: %v (%v)", discharge, err)
+		}
+		_, err := LockPatient(context.TODO(), tx, 2)
+		return err
+	})
+	expected := &perrors.NotFound{Entity: "patient", ID: "2"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("expected error :%v, got :%v ", expected, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestError(t *testing.T) {
+	conflict := &perrors.Conflict{Entity: "care team", Reason: "the attending role is taken"}
+	if err := Error(&mysql.MySQLError{Number: 1062}, conflict); err != conflict {
+		t.Errorf("expected error :%v, got :%v ", conflict, err)
+	}
+	err := Error(&mysql.MySQLError{Number: 1062}, nil)
+	var internal *perrors.Internal
+	if !errors.As(err, &internal) {
+		t.Errorf("expected internal error, got :%v", err)
+	}
+	if err := Error(errors.New("connection refused"), conflict); !errors.As(err, &internal) {
+		t.Errorf("expected internal error, got :%v", err)
+	}
+}
@@ -0,0 +1,311 @@
+package staff
+
+import (
+	"context"
+	"database/sql"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores/sqltx"
+	"strconv"
+)
+
+const columns = "id,name,profession,specialty,phone,email,createdat,updatedat,version"
+
+const memberColumns = "c.patientid,c.staffid,c.role,s.name,s.profession,c.assignedat"
+
+// roleProfession is the profession each care team role needs.
+var roleProfession = map[models.CareRole]models.Profession{
+	models.Attending:    models.Doctor,
+	models.Consulting:   models.Doctor,
+	models.PrimaryNurse: models.Nurse,
+}
+
+type store struct {
+	db    *sql.DB
+	audit sqltx.Auditor
+}
+
+// New returns a store that records care team changes in the audit trail of
+// the patient through audit.
+func New(db *sql.DB, audit sqltx.Auditor) *store {
+	return &store{db: db, audit: audit}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanStaff(row scanner) (*models.Staff, error) {
+	var st models.Staff
+	err := row.Scan(&st.ID, &st.Name, &st.Profession, &st.Specialty, &st.Phone, &st.Email, &st.CreatedAt, &st.UpdatedAt, &st.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func getStaff(ctx context.Context, q sqltx.Querier, id int, lock bool) (*models.Staff, error) {
+	query := "select " + columns + " from staff where deletedat IS NULL and id=?"
+	if lock {
+		query += " for update"
+	}
+	st, err := scanStaff(q.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, &perrors.NotFound{Entity: "staff", ID: strconv.Itoa(id)}
+	}
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	return st, nil
+}
+
+// List returns the staff by name, only those of profession unless it is
+// empty.
+func (s *store) List(ctx context.Context, profession models.Profession) ([]*models.Staff, error) {
+	query := "select " + columns + " from staff where deletedat IS NULL"
+	var args []interface{}
+	if profession != "" {
+		query += " and profession=?"
+		args = append(args, profession)
+	}
+	rows, err := s.db.QueryContext(ctx, query+" order by name, id", args...)
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	defer rows.Close()
+	staff := []*models.Staff{}
+	for rows.Next() {
+		st, err := scanStaff(rows)
+		if err != nil {
+			return nil, sqltx.Error(err, nil)
+		}
+		staff = append(staff, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	return staff, nil
+}
+
+func (s *store) Get(ctx context.Context, id int) (*models.Staff, error) {
+	return getStaff(ctx, s.db, id, false)
+}
+
+func (s *store) Insert(ctx context.Context, st *models.Staff) (*models.Staff, error) {
+	at := sqltx.Now()
+	query := "insert into staff (name,profession,specialty,phone,email,createdat,updatedat) values (?, ?, ?, ?, ?, ?, ?)"
+	res, err := s.db.ExecContext(ctx, query, st.Name, st.Profession, st.Specialty, st.Phone, st.Email, at, at)
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	return s.Get(ctx, int(id))
+}
+
+// Update overwrites a staff member, provided it is still at st.Version when
+// that is set. The profession of a care team member cannot change, as their
+// roles depend on it.
+func (s *store) Update(ctx context.Context, st *models.Staff) (*models.Staff, error) {
+	var updated *models.Staff
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := getStaff(ctx, tx, st.ID, true)
+		if err != nil {
+			return err
+		}
+		if st.Version > 0 && st.Version != before.Version {
+			return &perrors.PreconditionFailed{Entity: "staff", ID: strconv.Itoa(st.ID)}
+		}
+		if st.Profession != before.Profession {
+			var teams int
+			if err := tx.QueryRowContext(ctx, "select count(*) from careteam where staffid=?", st.ID).Scan(&teams); err != nil {
+				return sqltx.Error(err, nil)
+			}
+			if teams > 0 {
+				return &perrors.Conflict{Entity: "staff", Reason: "cannot change the profession of a care team member"}
+			}
+		}
+		query := "update staff SET name=?, profession=?, specialty=?, phone=?, email=?, updatedat=?, version=version+1 where id=?"
+		if _, err := tx.ExecContext(ctx, query, st.Name, st.Profession, st.Specialty, st.Phone, st.Email, sqltx.Now(), st.ID); err != nil {
+			return sqltx.Error(err, nil)
+		}
+		updated, err = getStaff(ctx, tx, st.ID, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Delete marks a staff member as deleted, provided it is still at version
+// when that is set, and takes them off every care team. It refuses while the
+// staff member has booked appointments that have not ended.
+func (s *store) Delete(ctx context.Context, id, version int) error {
+	return sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := getStaff(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		if version > 0 && version != before.Version {
+			return &perrors.PreconditionFailed{Entity: "staff", ID: strconv.Itoa(id)}
+		}
+		// The staff row is locked, so no appointment can be booked with them
+		// until this commits.
+		var booked int
+		query := "select count(*) from appointment where staffid=? and status=? and endat > ?"
+		if err := tx.QueryRowContext(ctx, query, id, models.Booked, sqltx.Now()).Scan(&booked); err != nil {
+			return sqltx.Error(err, nil)
+		}
+		if booked > 0 {
+			return &perrors.Conflict{Entity: "staff", Reason: strconv.Itoa(booked) + " booked appointments have not ended yet; cancel them first"}
+		}
+		members, err := list(ctx, tx, "c.staffid=? for update", id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "delete from careteam where staffid=?", id); err != nil {
+			return sqltx.Error(err, nil)
+		}
+		for _, m := range members {
+			if err := s.audit.AppendAudit(ctx, tx, m.PatientID, "care team", teamChange(m, nil)); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "update staff SET deletedat=?, version=version+1 where id=?", sqltx.Now(), id); err != nil {
+			return sqltx.Error(err, nil)
+		}
+		return nil
+	})
+}
+
+// list returns the care team assignments matching where, which filters the
+// careteam table c.
+func list(ctx context.Context, q sqltx.Querier, where string, args ...interface{}) ([]*models.CareTeamMember, error) {
+	query := "select " + memberColumns + " from careteam c join staff s on s.id = c.staffid " +
+		"join patient p on p.id = c.patientid where p.deletedat IS NULL and s.deletedat IS NULL and " + where
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	defer rows.Close()
+	members := []*models.CareTeamMember{}
+	for rows.Next() {
+		var m models.CareTeamMember
+		if err := rows.Scan(&m.PatientID, &m.StaffID, &m.Role, &m.Name, &m.Profession, &m.AssignedAt); err != nil {
+			return nil, sqltx.Error(err, nil)
+		}
+		members = append(members, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	return members, nil
+}
+
+// Team returns the care team of a live patient, by role and then name.
+func (s *store) Team(ctx context.Context, patientID int) ([]*models.CareTeamMember, error) {
+	var exists int
+	err := s.db.QueryRowContext(ctx, "select 1 from patient where deletedat IS NULL and id=?", patientID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil, &perrors.NotFound{Entity: "patient", ID: strconv.Itoa(patientID)}
+	}
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	return list(ctx, s.db, "c.patientid=? order by c.role, s.name", patientID)
+}
+
+// Patients returns the care team assignments of a staff member, by patient.
+func (s *store) Patients(ctx context.Context, staffID int) ([]*models.CareTeamMember, error) {
+	if _, err := s.Get(ctx, staffID); err != nil {
+		return nil, err
+	}
+	return list(ctx, s.db, "c.staffid=? order by c.patientid", staffID)
+}
+
+// Assign puts a staff member on the care team of a patient, or changes their
+// role on it. The role must suit the staff member's profession.
+func (s *store) Assign(ctx context.Context, m *models.CareTeamMember) (*models.CareTeamMember, error) {
+	var assigned *models.CareTeamMember
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := sqltx.LockPatient(ctx, tx, m.PatientID); err != nil {
+			return err
+		}
+		st, err := getStaff(ctx, tx, m.StaffID, true)
+		if err != nil {
+			return err
+		}
+		if roleProfession[m.Role] != st.Profession {
+			return perrors.NewValidation("role", string(m.Role)+" must be a "+string(roleProfession[m.Role]))
+		}
+		before, err := member(ctx, tx, m.PatientID, m.StaffID)
+		if err != nil {
+			return err
+		}
+		if before != nil && before.Role == m.Role {
+			assigned = before
+			return nil
+		}
+		conflict := &perrors.Conflict{Entity: "care team", Reason: "the " + string(m.Role) + " role is taken"}
+		at := sqltx.Now()
+		if before == nil {
+			query := "insert into careteam (patientid,staffid,role,assignedat) values (?, ?, ?, ?)"
+			_, err = tx.ExecContext(ctx, query, m.PatientID, m.StaffID, m.Role, at)
+		} else {
+			_, err = tx.ExecContext(ctx, "update careteam SET role=?, assignedat=? where patientid=? and staffid=?", m.Role, at, m.PatientID, m.StaffID)
+		}
+		if err != nil {
+			return sqltx.Error(err, conflict)
+		}
+		assigned = &models.CareTeamMember{PatientID: m.PatientID, StaffID: m.StaffID, Role: m.Role, Name: st.Name, Profession: st.Profession, AssignedAt: at}
+		return s.audit.AppendAudit(ctx, tx, m.PatientID, "care team", teamChange(before, assigned))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return assigned, nil
+}
+
+// Unassign takes a staff member off the care team of a patient.
+func (s *store) Unassign(ctx context.Context, patientID, staffID int) error {
+	return sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := sqltx.LockPatient(ctx, tx, patientID); err != nil {
+			return err
+		}
+		before, err := member(ctx, tx, patientID, staffID)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return &perrors.NotFound{Entity: "care team member", ID: strconv.Itoa(staffID)}
+		}
+		if _, err := tx.ExecContext(ctx, "delete from careteam where patientid=? and staffid=?", patientID, staffID); err != nil {
+			return sqltx.Error(err, nil)
+		}
+		return s.audit.AppendAudit(ctx, tx, patientID, "care team", teamChange(before, nil))
+	})
+}
+
+// member returns the assignment of a staff member to a patient, or nil.
+func member(ctx context.Context, tx *sql.Tx, patientID, staffID int) (*models.CareTeamMember, error) {
+	members, err := list(ctx, tx, "c.patientid=? and c.staffid=? for update", patientID, staffID)
+	if err != nil || len(members) == 0 {
+		return nil, err
+	}
+	return members[0], nil
+}
+
+// teamChange describes a care team change for the audit trail.
+func teamChange(before, after *models.CareTeamMember) map[string]models.FieldChange {
+	var staff, role models.FieldChange
+	if before != nil {
+		staff.Before, role.Before = before.StaffID, string(before.Role)
+	}
+	if after != nil {
+		staff.After, role.After = after.StaffID, string(after.Role)
+	}
+	return map[string]models.FieldChange{"careTeam.staffId": staff, "careTeam.role": role}
+}
@@ -0,0 +1,324 @@
+package staff
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores/sqltx"
+	"github.com/go-sql-driver/mysql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	selectStaff     = "select " + columns + " from staff where deletedat IS NULL and id=?"
+	selectMembers   = "select " + memberColumns + " from careteam c join staff s on s.id = c.staffid join patient p on p.id = c.patientid where p.deletedat IS NULL and s.deletedat IS NULL and "
+	lockPatientStmt = "select discharge from patient where deletedat IS NULL and id=? for update"
+	countBooked     = "select count(*) from appointment where staffid=? and status=? and endat > ?"
+)
+
+var (
+	hired    = time.Date(2022, 1, 3, 9, 0, 0, 0, time.UTC)
+	assigned = time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+)
+
+type auditCall struct {
+	id        int
+	operation string
+	changes   map[string]models.FieldChange
+}
+
+// fakeAuditor records the audit entries a store appends.
+type fakeAuditor struct {
+	calls []auditCall
+}
+
+func (f *fakeAuditor) AppendAudit(ctx context.Context, tx *sql.Tx, id int, operation string, changes map[string]models.FieldChange) error {
+	f.calls = append(f.calls, auditCall{id: id, operation: operation, changes: changes})
+	return nil
+}
+
+func staffRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "profession", "specialty", "phone", "email", "createdat", "updatedat", "version"})
+}
+
+func memberRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"patientid", "staffid", "role", "name", "profession", "assignedat"})
+}
+
+func TestList(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("select " + columns + " from staff where deletedat IS NULL and profession=? order by name, id").WithArgs(models.Nurse).
+		WillReturnRows(staffRows().AddRow(4, "Meera", "nurse", "", "", "", hired, hired, 1))
+	mock.ExpectQuery(selectStaff).WithArgs(4).WillReturnRows(staffRows().AddRow(4, "Meera", "nurse", "", "", "", hired, hired, 1))
+	mock.ExpectQuery(selectMembers + "c.staffid=? order by c.patientid").WithArgs(4).
+		WillReturnRows(memberRows().AddRow(1, 4, "primary-nurse", "Meera", "nurse", assigned))
+
+	s := New(db, &fakeAuditor{})
+	staff, err := s.List(context.TODO(), models.Nurse)
+	expected := []*models.Staff{{ID: 4, Name: "Meera", Profession: models.Nurse, CreatedAt: hired, UpdatedAt: hired, Version: 1}}
+	if err != nil || !reflect.DeepEqual(staff, expected) {
+		t.Errorf("Expected: %v, Got: %v (%v)", expected, staff, err)
+	}
+	patients, err := s.Patients(context.TODO(), 4)
+	members := []*models.CareTeamMember{{PatientID: 1, StaffID: 4, Role: models.PrimaryNurse, Name: "Meera", Profession: models.Nurse, AssignedAt: assigned}}
+	if err != nil || !reflect.DeepEqual(patients, members) {
+		t.Errorf("Expected: %v, Got: %v (%v)", members, patients, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		desc        string
+		input       *models.Staff
+		mock        func(mock sqlmock.Sqlmock)
+		expectError error
+	}{
+		{
+			desc:  "stale version",
+			input: &models.Staff{ID: 3, Name: "Dr. Rao", Profession: models.Doctor, Version: 1},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectStaff + " for update").WithArgs(3).WillReturnRows(staffRows().AddRow(3, "Dr. Rao", "doctor", "", "", "", hired, hired, 2))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.PreconditionFailed{Entity: "staff", ID: "3"},
+		},
+		{
+			desc:  "profession of a care team member",
+			input: &models.Staff{ID: 3, Name: "Dr. Rao", Profession: models.Nurse},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectStaff + " for update").WithArgs(3).WillReturnRows(staffRows().AddRow(3, "Dr. Rao", "doctor", "", "", "", hired, hired, 2))
+				mock.ExpectQuery("select count(*) from careteam where staffid=?").WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "staff", Reason: "cannot change the profession of a care team member"},
+		},
+		{
+			desc:  "unknown staff",
+			input: &models.Staff{ID: 9, Name: "Dr. Rao", Profession: models.Doctor},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectStaff + " for update").WithArgs(9).WillReturnRows(staffRows())
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.NotFound{Entity: "staff", ID: "9"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			test.mock(mock)
+
+			_, err = New(db, &fakeAuditor{}).Update(context.TODO(), test.input)
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	defer func(saved func() time.Time) { sqltx.Now = saved }(sqltx.Now)
+	sqltx.Now = func() time.Time { return assigned }
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectStaff + " for update").WithArgs(3).WillReturnRows(staffRows().AddRow(3, "Dr. Rao", "doctor", "", "", "", hired, hired, 2))
+	mock.ExpectQuery(countBooked).WithArgs(3, models.Booked, assigned).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(selectMembers + "c.staffid=? for update").WithArgs(3).
+		WillReturnRows(memberRows().AddRow(1, 3, "attending", "Dr. Rao", "doctor", hired).AddRow(2, 3, "consulting", "Dr. Rao", "doctor", hired))
+	mock.ExpectExec("delete from careteam where staffid=?").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("update staff SET deletedat=?, version=version+1 where id=?").WithArgs(assigned, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	auditor := &fakeAuditor{}
+	if err := New(db, auditor).Delete(context.TODO(), 3, 2); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+	expected := []auditCall{
+		{id: 1, operation: "care team", changes: map[string]models.FieldChange{"careTeam.staffId": {Before: 3}, "careTeam.role": {Before: "attending"}}},
+		{id: 2, operation: "care team", changes: map[string]models.FieldChange{"careTeam.staffId": {Before: 3}, "careTeam.role": {Before: "consulting"}}},
+	}
+	if !reflect.DeepEqual(auditor.calls, expected) {
+		t.Errorf("Expected: %v, Got: %v", expected, auditor.calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeleteBooked(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	defer func(saved func() time.Time) { sqltx.Now = saved }(sqltx.Now)
+	sqltx.Now = func() time.Time { return assigned }
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectStaff + " for update").WithArgs(3).WillReturnRows(staffRows().AddRow(3, "Dr. Rao", "doctor", "", "", "", hired, hired, 2))
+	mock.ExpectQuery(countBooked).WithArgs(3, models.Booked, assigned).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+
+	auditor := &fakeAuditor{}
+	err = New(db, auditor).Delete(context.TODO(), 3, 0)
+	expectError := &perrors.Conflict{Entity: "staff", Reason: "2 booked appointments have not ended yet; cancel them first"}
+	if !reflect.DeepEqual(err, expectError) {
+		t.Errorf("expected error :%v, got :%v ", expectError, err)
+	}
+	if len(auditor.calls) != 0 {
+		t.Errorf("Expected no audit, Got: %v", auditor.calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	rao := func() *sqlmock.Rows { return staffRows().AddRow(3, "Dr. Rao", "doctor", "", "", "", hired, hired, 2) }
+	tests := []struct {
+		desc        string
+		role        models.CareRole
+		mock        func(mock sqlmock.Sqlmock)
+		expected    *models.CareTeamMember
+		changes     map[string]models.FieldChange
+		expectError error
+	}{
+		{
+			desc: "new attending",
+			role: models.Attending,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectMembers+"c.patientid=? and c.staffid=? for update").WithArgs(1, 3).WillReturnRows(memberRows())
+				mock.ExpectExec("insert into careteam (patientid,staffid,role,assignedat) values (?, ?, ?, ?)").
+					WithArgs(1, 3, models.Attending, assigned).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expected: &models.CareTeamMember{PatientID: 1, StaffID: 3, Role: models.Attending, Name: "Dr. Rao", Profession: models.Doctor, AssignedAt: assigned},
+			changes:  map[string]models.FieldChange{"careTeam.staffId": {After: 3}, "careTeam.role": {After: "attending"}},
+		},
+		{
+			desc: "consulting becomes attending",
+			role: models.Attending,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectMembers+"c.patientid=? and c.staffid=? for update").WithArgs(1, 3).
+					WillReturnRows(memberRows().AddRow(1, 3, "consulting", "Dr. Rao", "doctor", hired))
+				mock.ExpectExec("update careteam SET role=?, assignedat=? where patientid=? and staffid=?").
+					WithArgs(models.Attending, assigned, 1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expected: &models.CareTeamMember{PatientID: 1, StaffID: 3, Role: models.Attending, Name: "Dr. Rao", Profession: models.Doctor, AssignedAt: assigned},
+			changes:  map[string]models.FieldChange{"careTeam.staffId": {Before: 3, After: 3}, "careTeam.role": {Before: "consulting", After: "attending"}},
+		},
+		{
+			desc: "same role again",
+			role: models.Consulting,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectMembers+"c.patientid=? and c.staffid=? for update").WithArgs(1, 3).
+					WillReturnRows(memberRows().AddRow(1, 3, "consulting", "Dr. Rao", "doctor", hired))
+				mock.ExpectCommit()
+			},
+			expected: &models.CareTeamMember{PatientID: 1, StaffID: 3, Role: models.Consulting, Name: "Dr. Rao", Profession: models.Doctor, AssignedAt: hired},
+		},
+		{
+			desc: "taken role",
+			role: models.Attending,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectMembers+"c.patientid=? and c.staffid=? for update").WithArgs(1, 3).WillReturnRows(memberRows())
+				mock.ExpectExec("insert into careteam (patientid,staffid,role,assignedat) values (?, ?, ?, ?)").
+					WithArgs(1, 3, models.Attending, assigned).WillReturnError(&mysql.MySQLError{Number: 1062})
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "care team", Reason: "the attending role is taken"},
+		},
+		{
+			desc: "doctor as primary nurse",
+			role: models.PrimaryNurse,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectRollback()
+			},
+			expectError: perrors.NewValidation("role", "primary-nurse must be a nurse"),
+		},
+	}
+
+	defer func(saved func() time.Time) { sqltx.Now = saved }(sqltx.Now)
+	sqltx.Now = func() time.Time { return assigned }
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			mock.ExpectBegin()
+			mock.ExpectQuery(lockPatientStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+			mock.ExpectQuery(selectStaff + " for update").WithArgs(3).WillReturnRows(rao())
+			test.mock(mock)
+			auditor := &fakeAuditor{}
+
+			got, err := New(db, auditor).Assign(context.TODO(), &models.CareTeamMember{PatientID: 1, StaffID: 3, Role: test.role})
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected: %v, Got: %v", test.expected, got)
+			}
+			var expected []auditCall
+			if test.changes != nil {
+				expected = []auditCall{{id: 1, operation: "care team", changes: test.changes}}
+			}
+			if !reflect.DeepEqual(auditor.calls, expected) {
+				t.Errorf("Expected: %v, Got: %v", expected, auditor.calls)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestUnassign(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockPatientStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	mock.ExpectQuery(selectMembers+"c.patientid=? and c.staffid=? for update").WithArgs(1, 5).WillReturnRows(memberRows())
+	mock.ExpectRollback()
+
+	expected := &perrors.NotFound{Entity: "care team member", ID: "5"}
+	if err := New(db, &fakeAuditor{}).Unassign(context.TODO(), 1, 5); !reflect.DeepEqual(err, expected) {
+		t.Errorf("expected error :%v, got :%v ", expected, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
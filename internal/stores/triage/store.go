@@ -0,0 +1,165 @@
+package triage
+
+import (
+	"context"
+	"database/sql"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores/sqltx"
+	"strconv"
+	"time"
+)
+
+const columns = "t.id,t.patientid,t.level,t.arrivedat,t.triagedat,t.calledat"
+
+type store struct {
+	db    *sql.DB
+	audit sqltx.Auditor
+}
+
+// New returns a store that records triage in the audit trail of the patient
+// through audit.
+func New(db *sql.DB, audit sqltx.Auditor) *store {
+	return &store{db: db, audit: audit}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scan(row scanner) (*models.Triage, error) {
+	var t models.Triage
+	var calledAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.PatientID, &t.Level, &t.ArrivedAt, &t.TriagedAt, &calledAt); err != nil {
+		return nil, err
+	}
+	if calledAt.Valid {
+		t.CalledAt = &calledAt.Time
+	}
+	return &t, nil
+}
+
+// Waiting returns the visits of live patients that have not been called, by
+// level and then arrival.
+func (s *store) Waiting(ctx context.Context) ([]*models.Triage, error) {
+	query := "select " + columns + " from triage t join patient p on p.id = t.patientid " +
+		"where p.deletedat IS NULL and t.calledat IS NULL order by t.level, t.arrivedat, t.id"
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+	waiting := []*models.Triage{}
+	for rows.Next() {
+		t, err := scan(rows)
+		if err != nil {
+			return nil, dbError(err)
+		}
+		waiting = append(waiting, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+	return waiting, nil
+}
+
+// Insert puts a live patient in the queue, arrived and triaged now.
+func (s *store) Insert(ctx context.Context, t *models.Triage) (*models.Triage, error) {
+	var created *models.Triage
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := sqltx.LockPatient(ctx, tx, t.PatientID); err != nil {
+			return err
+		}
+		at := sqltx.Now()
+		res, err := tx.ExecContext(ctx, "insert into triage (patientid,level,arrivedat,triagedat) values (?, ?, ?, ?)", t.PatientID, t.Level, at, at)
+		if err != nil {
+			return dbError(err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return dbError(err)
+		}
+		created = &models.Triage{ID: int(id), PatientID: t.PatientID, Level: t.Level, ArrivedAt: at, TriagedAt: at}
+		return s.audit.AppendAudit(ctx, tx, t.PatientID, "triage", map[string]models.FieldChange{
+			"triage.id":    {After: created.ID},
+			"triage.level": {After: created.Level},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// lockWaiting locks a visit that is still waiting.
+func lockWaiting(ctx context.Context, tx *sql.Tx, id int) (*models.Triage, error) {
+	t, err := scan(tx.QueryRowContext(ctx, "select "+columns+" from triage t where t.id=? for update", id))
+	if err == sql.ErrNoRows {
+		return nil, &perrors.NotFound{Entity: "triage", ID: strconv.Itoa(id)}
+	}
+	if err != nil {
+		return nil, dbError(err)
+	}
+	if t.CalledAt != nil {
+		return nil, &perrors.Conflict{Entity: "triage", Reason: strconv.Itoa(id) + " has already been called"}
+	}
+	return t, nil
+}
+
+// Retriage changes the level of a waiting visit. It keeps its place by
+// arrival.
+func (s *store) Retriage(ctx context.Context, id, level int) (*models.Triage, error) {
+	var updated *models.Triage
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := lockWaiting(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		at := sqltx.Now()
+		if _, err := tx.ExecContext(ctx, "update triage SET level=?, triagedat=? where id=?", level, at, id); err != nil {
+			return dbError(err)
+		}
+		after := *before
+		after.Level, after.TriagedAt = level, at
+		updated = &after
+		return s.audit.AppendAudit(ctx, tx, before.PatientID, "retriage", map[string]models.FieldChange{
+			"triage.id":    {Before: id, After: id},
+			"triage.level": {Before: before.Level, After: level},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Call takes a waiting visit off the queue. Of concurrent calls for the same
+// visit only the first succeeds; the others get a Conflict.
+func (s *store) Call(ctx context.Context, id int) (*models.Triage, error) {
+	var called *models.Triage
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		t, err := lockWaiting(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		at := sqltx.Now()
+		if _, err := tx.ExecContext(ctx, "update triage SET calledat=? where id=?", at, id); err != nil {
+			return dbError(err)
+		}
+		t.CalledAt = &at
+		called = t
+		return s.audit.AppendAudit(ctx, tx, t.PatientID, "call", map[string]models.FieldChange{
+			"triage.id":       {Before: id, After: id},
+			"triage.calledAt": {After: at.Format(time.RFC3339)},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return called, nil
+}
+
+// dbError wraps err; a duplicate key means the patient is already waiting.
+func dbError(err error) error {
+	return sqltx.Error(err, &perrors.Conflict{Entity: "triage", Reason: "patient is already waiting"})
+}
@@ -0,0 +1,209 @@
+package triage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores/sqltx"
+	"github.com/go-sql-driver/mysql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const selectVisit = "select t.id,t.patientid,t.level,t.arrivedat,t.triagedat,t.calledat from triage t where t.id=? for update"
+
+var (
+	arrived = time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	later   = time.Date(2022, 3, 1, 10, 40, 0, 0, time.UTC)
+)
+
+type auditCall struct {
+	id        int
+	operation string
+	changes   map[string]models.FieldChange
+}
+
+// fakeAuditor records the audit entries a store appends.
+type fakeAuditor struct {
+	calls []auditCall
+}
+
+func (f *fakeAuditor) AppendAudit(ctx context.Context, tx *sql.Tx, id int, operation string, changes map[string]models.FieldChange) error {
+	f.calls = append(f.calls, auditCall{id: id, operation: operation, changes: changes})
+	return nil
+}
+
+func triageRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "patientid", "level", "arrivedat", "triagedat", "calledat"})
+}
+
+func TestWaiting(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("select t.id,t.patientid,t.level,t.arrivedat,t.triagedat,t.calledat from triage t join patient p on p.id = t.patientid " +
+		"where p.deletedat IS NULL and t.calledat IS NULL order by t.level, t.arrivedat, t.id").
+		WillReturnRows(triageRows().AddRow(2, 7, 1, later, later, nil).AddRow(1, 4, 3, arrived, arrived, nil))
+
+	waiting, err := New(db, &fakeAuditor{}).Waiting(context.TODO())
+	expected := []*models.Triage{
+		{ID: 2, PatientID: 7, Level: 1, ArrivedAt: later, TriagedAt: later},
+		{ID: 1, PatientID: 4, Level: 3, ArrivedAt: arrived, TriagedAt: arrived},
+	}
+	if err != nil || !reflect.DeepEqual(waiting, expected) {
+		t.Errorf("Expected: %v, Got: %v (%v)", expected, waiting, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWrites(t *testing.T) {
+	tests := []struct {
+		desc        string
+		call        func(s *store) (*models.Triage, error)
+		mock        func(mock sqlmock.Sqlmock)
+		expected    *models.Triage
+		operation   string
+		changes     map[string]models.FieldChange
+		expectError error
+	}{
+		{
+			desc: "enqueue",
+			call: func(s *store) (*models.Triage, error) {
+				return s.Insert(context.TODO(), &models.Triage{PatientID: 4, Level: 3})
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("select discharge from patient where deletedat IS NULL and id=? for update").WithArgs(4).
+					WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(true))
+				mock.ExpectExec("insert into triage (patientid,level,arrivedat,triagedat) values (?, ?, ?, ?)").
+					WithArgs(4, 3, later, later).WillReturnResult(sqlmock.NewResult(5, 1))
+				mock.ExpectCommit()
+			},
+			expected:  &models.Triage{ID: 5, PatientID: 4, Level: 3, ArrivedAt: later, TriagedAt: later},
+			operation: "triage",
+			changes:   map[string]models.FieldChange{"triage.id": {After: 5}, "triage.level": {After: 3}},
+		},
+		{
+			desc: "enqueue a waiting patient",
+			call: func(s *store) (*models.Triage, error) {
+				return s.Insert(context.TODO(), &models.Triage{PatientID: 4, Level: 3})
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("select discharge from patient where deletedat IS NULL and id=? for update").WithArgs(4).
+					WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(true))
+				mock.ExpectExec("insert into triage (patientid,level,arrivedat,triagedat) values (?, ?, ?, ?)").
+					WithArgs(4, 3, later, later).WillReturnError(&mysql.MySQLError{Number: 1062})
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "triage", Reason: "patient is already waiting"},
+		},
+		{
+			desc: "enqueue an unknown patient",
+			call: func(s *store) (*models.Triage, error) {
+				return s.Insert(context.TODO(), &models.Triage{PatientID: 9, Level: 3})
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("select discharge from patient where deletedat IS NULL and id=? for update").WithArgs(9).
+					WillReturnRows(sqlmock.NewRows([]string{"discharge"}))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.NotFound{Entity: "patient", ID: "9"},
+		},
+		{
+			desc: "retriage",
+			call: func(s *store) (*models.Triage, error) { return s.Retriage(context.TODO(), 5, 2) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectVisit).WithArgs(5).WillReturnRows(triageRows().AddRow(5, 4, 3, arrived, arrived, nil))
+				mock.ExpectExec("update triage SET level=?, triagedat=? where id=?").WithArgs(2, later, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expected:  &models.Triage{ID: 5, PatientID: 4, Level: 2, ArrivedAt: arrived, TriagedAt: later},
+			operation: "retriage",
+			changes:   map[string]models.FieldChange{"triage.id": {Before: 5, After: 5}, "triage.level": {Before: 3, After: 2}},
+		},
+		{
+			desc: "call",
+			call: func(s *store) (*models.Triage, error) { return s.Call(context.TODO(), 5) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectVisit).WithArgs(5).WillReturnRows(triageRows().AddRow(5, 4, 3, arrived, arrived, nil))
+				mock.ExpectExec("update triage SET calledat=? where id=?").WithArgs(later, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expected:  &models.Triage{ID: 5, PatientID: 4, Level: 3, ArrivedAt: arrived, TriagedAt: arrived, CalledAt: &later},
+			operation: "call",
+			changes:   map[string]models.FieldChange{"triage.id": {Before: 5, After: 5}, "triage.calledAt": {After: "2022-03-01T10:40:00Z"}},
+		},
+		{
+			desc: "call twice",
+			call: func(s *store) (*models.Triage, error) { return s.Call(context.TODO(), 5) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectVisit).WithArgs(5).WillReturnRows(triageRows().AddRow(5, 4, 3, arrived, arrived, later))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "triage", Reason: "5 has already been called"},
+		},
+		{
+			desc: "retriage an unknown visit",
+			call: func(s *store) (*models.Triage, error) { return s.Retriage(context.TODO(), 9, 2) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectVisit).WithArgs(9).WillReturnRows(triageRows())
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.NotFound{Entity: "triage", ID: "9"},
+		},
+	}
+
+	defer func(saved func() time.Time) { sqltx.Now = saved }(sqltx.Now)
+	sqltx.Now = func() time.Time { return later }
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			test.mock(mock)
+			auditor := &fakeAuditor{}
+
+			got, err := test.call(New(db, auditor))
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected: %v, Got: %v", test.expected, got)
+			}
+			if test.changes != nil {
+				expected := []auditCall{{id: 4, operation: test.operation, changes: test.changes}}
+				if !reflect.DeepEqual(auditor.calls, expected) {
+					t.Errorf("Expected: %v, Got: %v", expected, auditor.calls)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDBError(t *testing.T) {
+	err := dbError(errors.New("connection refused"))
+	var internal *perrors.Internal
+	if !errors.As(err, &internal) {
+		t.Errorf("expected internal error, got :%v", err)
+	}
+}
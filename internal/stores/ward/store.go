@@ -0,0 +1,382 @@
+package ward
+
+import (
+	"context"
+	"database/sql"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores/sqltx"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type store struct {
+	db    *sql.DB
+	audit sqltx.Auditor
+}
+
+// New returns a store that records bed moves in the audit trail of the
+// patient through audit.
+func New(db *sql.DB, audit sqltx.Auditor) *store {
+	return &store{db: db, audit: audit}
+}
+
+// CreateWard creates a ward together with its beds.
+func (s *store) CreateWard(ctx context.Context, w *models.Ward) (*models.Ward, error) {
+	var created *models.Ward
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "insert into ward (name) values (?)", w.Name)
+		if err != nil {
+			return sqltx.Error(err, &perrors.Conflict{Entity: "ward", Reason: "a ward named " + w.Name + " exists"})
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return sqltx.Error(err, nil)
+		}
+		if len(w.Beds) > 0 {
+			values := make([]string, len(w.Beds))
+			args := make([]interface{}, 0, 2*len(w.Beds))
+			for i, b := range w.Beds {
+				values[i] = "(?, ?)"
+				args = append(args, id, b.Label)
+			}
+			if _, err := tx.ExecContext(ctx, "insert into bed (wardid,label) values "+strings.Join(values, ", "), args...); err != nil {
+				return sqltx.Error(err, &perrors.Conflict{Entity: "ward", Reason: "bed labels must be unique within a ward"})
+			}
+		}
+		created, err = s.getWard(ctx, tx, int(id))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ListWards returns every ward, by name, without its beds.
+func (s *store) ListWards(ctx context.Context) ([]*models.Ward, error) {
+	rows, err := s.db.QueryContext(ctx, "select id,name,createdat from ward order by name")
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	defer rows.Close()
+	wards := []*models.Ward{}
+	for rows.Next() {
+		var w models.Ward
+		if err := rows.Scan(&w.ID, &w.Name, &w.CreatedAt); err != nil {
+			return nil, sqltx.Error(err, nil)
+		}
+		wards = append(wards, &w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	return wards, nil
+}
+
+func (s *store) GetWard(ctx context.Context, id int) (*models.Ward, error) {
+	return s.getWard(ctx, s.db, id)
+}
+
+// getWard reads a ward with its beds in label order.
+func (s *store) getWard(ctx context.Context, q sqltx.Querier, id int) (*models.Ward, error) {
+	var w models.Ward
+	err := q.QueryRowContext(ctx, "select id,name,createdat from ward where id=?", id).Scan(&w.ID, &w.Name, &w.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, &perrors.NotFound{Entity: "ward", ID: strconv.Itoa(id)}
+	}
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	rows, err := q.QueryContext(ctx, "select id,wardid,label from bed where wardid=? order by label", id)
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	defer rows.Close()
+	w.Beds = []*models.Bed{}
+	for rows.Next() {
+		var b models.Bed
+		if err := rows.Scan(&b.ID, &b.WardID, &b.Label); err != nil {
+			return nil, sqltx.Error(err, nil)
+		}
+		w.Beds = append(w.Beds, &b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	return &w, nil
+}
+
+// AddBed adds a bed to an existing ward.
+func (s *store) AddBed(ctx context.Context, b *models.Bed) (*models.Bed, error) {
+	var exists int
+	err := s.db.QueryRowContext(ctx, "select 1 from ward where id=?", b.WardID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil, &perrors.NotFound{Entity: "ward", ID: strconv.Itoa(b.WardID)}
+	}
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	res, err := s.db.ExecContext(ctx, "insert into bed (wardid,label) values (?, ?)", b.WardID, b.Label)
+	if err != nil {
+		return nil, sqltx.Error(err, &perrors.Conflict{Entity: "bed", Reason: b.Label + " exists in this ward"})
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	return &models.Bed{ID: int(id), WardID: b.WardID, Label: b.Label}, nil
+}
+
+// Occupancy lists every bed of a ward with the live patient currently in it.
+func (s *store) Occupancy(ctx context.Context, wardID int) (*models.Occupancy, error) {
+	o := &models.Occupancy{WardID: wardID, Status: []models.BedStatus{}}
+	err := s.db.QueryRowContext(ctx, "select name from ward where id=?", wardID).Scan(&o.Ward)
+	if err == sql.ErrNoRows {
+		return nil, &perrors.NotFound{Entity: "ward", ID: strconv.Itoa(wardID)}
+	}
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	query := "select b.id,b.label,a.patientid,a.assignedat from bed b " +
+		"left join (bedassignment a join patient p on p.id = a.patientid and p.deletedat IS NULL) " +
+		"on a.bedid = b.id and a.releasedat IS NULL where b.wardid=? order by b.label"
+	rows, err := s.db.QueryContext(ctx, query, wardID)
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status models.BedStatus
+		var patientID sql.NullInt64
+		var assignedAt sql.NullTime
+		if err := rows.Scan(&status.ID, &status.Label, &patientID, &assignedAt); err != nil {
+			return nil, sqltx.Error(err, nil)
+		}
+		if patientID.Valid {
+			status.PatientID = int(patientID.Int64)
+			status.AssignedAt = &assignedAt.Time
+			o.Occupied++
+		}
+		o.Status = append(o.Status, status)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	o.Beds = len(o.Status)
+	o.Free = o.Beds - o.Occupied
+	if o.Beds > 0 {
+		o.Rate = float64(o.Occupied) / float64(o.Beds)
+	}
+	return o, nil
+}
+
+const assignmentColumns = "a.id,a.patientid,a.bedid,b.wardid,w.name,b.label,a.assignedat,a.releasedat"
+
+const assignmentTables = "bedassignment a join bed b on b.id = a.bedid join ward w on w.id = b.wardid"
+
+func scanAssignment(row *sql.Row) (*models.BedAssignment, error) {
+	var a models.BedAssignment
+	var releasedAt sql.NullTime
+	err := row.Scan(&a.ID, &a.PatientID, &a.BedID, &a.WardID, &a.Ward, &a.Label, &a.AssignedAt, &releasedAt)
+	if err != nil {
+		return nil, err
+	}
+	if releasedAt.Valid {
+		a.ReleasedAt = &releasedAt.Time
+	}
+	return &a, nil
+}
+
+// CurrentBed returns the bed a live patient is in.
+func (s *store) CurrentBed(ctx context.Context, patientID int) (*models.BedAssignment, error) {
+	query := "select " + assignmentColumns + " from " + assignmentTables +
+		" join patient p on p.id = a.patientid where p.deletedat IS NULL and a.patientid=? and a.releasedat IS NULL"
+	a, err := scanAssignment(s.db.QueryRowContext(ctx, query, patientID))
+	if err == sql.ErrNoRows {
+		return nil, &perrors.NotFound{Entity: "bed assignment", ID: strconv.Itoa(patientID)}
+	}
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	return a, nil
+}
+
+// current locks and returns the open assignment of a patient, or nil.
+func current(ctx context.Context, tx *sql.Tx, patientID int) (*models.BedAssignment, error) {
+	query := "select " + assignmentColumns + " from " + assignmentTables + " where a.patientid=? and a.releasedat IS NULL for update"
+	a, err := scanAssignment(tx.QueryRowContext(ctx, query, patientID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	return a, nil
+}
+
+// lockAdmitted locks a live patient row, serialising every bed move of that
+// patient, and requires the patient to have an open encounter.
+func lockAdmitted(ctx context.Context, tx *sql.Tx, patientID int) error {
+	discharge, err := sqltx.LockPatient(ctx, tx, patientID)
+	if err == nil && discharge {
+		return &perrors.Conflict{Entity: "patient", Reason: "is not admitted"}
+	}
+	return err
+}
+
+// lockFreeBed locks a bed row, serialising every assignment to it, and
+// requires it to be free.
+func lockFreeBed(ctx context.Context, tx *sql.Tx, bedID int) (models.BedLocation, error) {
+	loc := models.BedLocation{BedID: bedID}
+	query := "select b.wardid,w.name,b.label from bed b join ward w on w.id = b.wardid where b.id=? for update"
+	err := tx.QueryRowContext(ctx, query, bedID).Scan(&loc.WardID, &loc.Ward, &loc.Label)
+	if err == sql.ErrNoRows {
+		return loc, &perrors.NotFound{Entity: "bed", ID: strconv.Itoa(bedID)}
+	}
+	if err != nil {
+		return loc, sqltx.Error(err, nil)
+	}
+	var occupant int
+	err = tx.QueryRowContext(ctx, "select patientid from bedassignment where bedid=? and releasedat IS NULL", bedID).Scan(&occupant)
+	if err == nil {
+		return loc, &perrors.Conflict{Entity: "bed", Reason: loc.Ward + " " + loc.Label + " is occupied"}
+	}
+	if err != sql.ErrNoRows {
+		return loc, sqltx.Error(err, nil)
+	}
+	return loc, nil
+}
+
+func (s *store) insertAssignment(ctx context.Context, tx *sql.Tx, patientID int, loc models.BedLocation, now time.Time) (*models.BedAssignment, error) {
+	res, err := tx.ExecContext(ctx, "insert into bedassignment (bedid,patientid,assignedat) values (?, ?, ?)", loc.BedID, patientID, now)
+	if err != nil {
+		return nil, sqltx.Error(err, &perrors.Conflict{Entity: "bed", Reason: loc.Ward + " " + loc.Label + " is occupied"})
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, sqltx.Error(err, nil)
+	}
+	return &models.BedAssignment{ID: int(id), PatientID: patientID, BedLocation: loc, AssignedAt: now}, nil
+}
+
+func release(ctx context.Context, tx *sql.Tx, a *models.BedAssignment, now time.Time) error {
+	if _, err := tx.ExecContext(ctx, "update bedassignment SET releasedat=? where id=?", now, a.ID); err != nil {
+		return sqltx.Error(err, nil)
+	}
+	a.ReleasedAt = &now
+	return nil
+}
+
+func bedChange(before, after *models.BedAssignment) map[string]models.FieldChange {
+	var change models.FieldChange
+	if before != nil {
+		change.Before = before.BedLocation
+	}
+	if after != nil {
+		change.After = after.BedLocation
+	}
+	return map[string]models.FieldChange{"bed": change}
+}
+
+// Assign puts an admitted patient who has no bed into a free one.
+func (s *store) Assign(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error) {
+	var assigned *models.BedAssignment
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := lockAdmitted(ctx, tx, patientID); err != nil {
+			return err
+		}
+		existing, err := current(ctx, tx, patientID)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return &perrors.Conflict{Entity: "patient", Reason: "is already in " + existing.Ward + " " + existing.Label + "; transfer instead"}
+		}
+		loc, err := lockFreeBed(ctx, tx, bedID)
+		if err != nil {
+			return err
+		}
+		if assigned, err = s.insertAssignment(ctx, tx, patientID, loc, sqltx.Now()); err != nil {
+			return err
+		}
+		return s.audit.AppendAudit(ctx, tx, patientID, "assign bed", bedChange(nil, assigned))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return assigned, nil
+}
+
+// Transfer moves an admitted patient from their bed to a free one.
+func (s *store) Transfer(ctx context.Context, patientID, bedID int) (*models.BedAssignment, error) {
+	var assigned *models.BedAssignment
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := lockAdmitted(ctx, tx, patientID); err != nil {
+			return err
+		}
+		existing, err := current(ctx, tx, patientID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return &perrors.Conflict{Entity: "patient", Reason: "has no bed; assign one instead"}
+		}
+		if existing.BedID == bedID {
+			return &perrors.Conflict{Entity: "patient", Reason: "is already in " + existing.Ward + " " + existing.Label}
+		}
+		loc, err := lockFreeBed(ctx, tx, bedID)
+		if err != nil {
+			return err
+		}
+		at := sqltx.Now()
+		if err := release(ctx, tx, existing, at); err != nil {
+			return err
+		}
+		if assigned, err = s.insertAssignment(ctx, tx, patientID, loc, at); err != nil {
+			return err
+		}
+		return s.audit.AppendAudit(ctx, tx, patientID, "transfer", bedChange(existing, assigned))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return assigned, nil
+}
+
+// Release frees the bed of a patient and returns the ended assignment.
+func (s *store) Release(ctx context.Context, patientID int) (*models.BedAssignment, error) {
+	var released *models.BedAssignment
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := sqltx.LockPatient(ctx, tx, patientID); err != nil {
+			return err
+		}
+		var err error
+		if released, err = s.ReleaseBed(ctx, tx, patientID); err != nil {
+			return err
+		}
+		if released == nil {
+			return &perrors.NotFound{Entity: "bed assignment", ID: strconv.Itoa(patientID)}
+		}
+		return s.audit.AppendAudit(ctx, tx, patientID, "release bed", bedChange(released, nil))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return released, nil
+}
+
+// ReleaseBed frees the bed of a patient in tx, if the patient has one, and
+// returns the ended assignment. The encounter store calls it when a patient
+// is discharged; the caller records the change in the audit trail.
+func (s *store) ReleaseBed(ctx context.Context, tx *sql.Tx, patientID int) (*models.BedAssignment, error) {
+	existing, err := current(ctx, tx, patientID)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	if err := release(ctx, tx, existing, sqltx.Now()); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
@@ -0,0 +1,292 @@
+package ward
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores/sqltx"
+	"github.com/go-sql-driver/mysql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	selectPatient = "select discharge from patient where deletedat IS NULL and id=? for update"
+	selectCurrent = "select a.id,a.patientid,a.bedid,b.wardid,w.name,b.label,a.assignedat,a.releasedat from " +
+		"bedassignment a join bed b on b.id = a.bedid join ward w on w.id = b.wardid where a.patientid=? and a.releasedat IS NULL for update"
+	selectBed      = "select b.wardid,w.name,b.label from bed b join ward w on w.id = b.wardid where b.id=? for update"
+	selectOccupant = "select patientid from bedassignment where bedid=? and releasedat IS NULL"
+	insertAssign   = "insert into bedassignment (bedid,patientid,assignedat) values (?, ?, ?)"
+	updateRelease  = "update bedassignment SET releasedat=? where id=?"
+)
+
+var (
+	created  = time.Date(2022, 3, 1, 8, 0, 0, 0, time.UTC)
+	assigned = time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	moved    = time.Date(2022, 3, 2, 12, 0, 0, 0, time.UTC)
+)
+
+type auditCall struct {
+	id        int
+	operation string
+	changes   map[string]models.FieldChange
+}
+
+// fakeAuditor records the audit entries a store appends.
+type fakeAuditor struct {
+	calls []auditCall
+}
+
+func (f *fakeAuditor) AppendAudit(ctx context.Context, tx *sql.Tx, id int, operation string, changes map[string]models.FieldChange) error {
+	f.calls = append(f.calls, auditCall{id: id, operation: operation, changes: changes})
+	return nil
+}
+
+func assignmentRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "patientid", "bedid", "wardid", "name", "label", "assignedat", "releasedat"})
+}
+
+func bedRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"wardid", "name", "label"})
+}
+
+func TestCreateWard(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("insert into ward (name) values (?)").WithArgs("ICU").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("insert into bed (wardid,label) values (?, ?), (?, ?)").WithArgs(2, "A1", 2, "A2").WillReturnResult(sqlmock.NewResult(4, 2))
+	mock.ExpectQuery("select id,name,createdat from ward where id=?").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "createdat"}).AddRow(2, "ICU", created))
+	mock.ExpectQuery("select id,wardid,label from bed where wardid=? order by label").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "wardid", "label"}).AddRow(4, 2, "A1").AddRow(5, 2, "A2"))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("insert into ward (name) values (?)").WithArgs("ICU").WillReturnError(&mysql.MySQLError{Number: 1062})
+	mock.ExpectRollback()
+
+	s := New(db, &fakeAuditor{})
+	ward, err := s.CreateWard(context.TODO(), &models.Ward{Name: "ICU", Beds: []*models.Bed{{Label: "A1"}, {Label: "A2"}}})
+	expected := &models.Ward{ID: 2, Name: "ICU", CreatedAt: created, Beds: []*models.Bed{{ID: 4, WardID: 2, Label: "A1"}, {ID: 5, WardID: 2, Label: "A2"}}}
+	if err != nil || !reflect.DeepEqual(ward, expected) {
+		t.Errorf("Expected: %v, Got: %v (%v)", expected, ward, err)
+	}
+
+	_, err = s.CreateWard(context.TODO(), &models.Ward{Name: "ICU"})
+	if !reflect.DeepEqual(err, &perrors.Conflict{Entity: "ward", Reason: "a ward named ICU exists"}) {
+		t.Errorf("expected error :ward conflict, got :%v ", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOccupancy(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("select name from ward where id=?").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("ICU"))
+	mock.ExpectQuery("select b.id,b.label,a.patientid,a.assignedat from bed b " +
+		"left join (bedassignment a join patient p on p.id = a.patientid and p.deletedat IS NULL) " +
+		"on a.bedid = b.id and a.releasedat IS NULL where b.wardid=? order by b.label").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "label", "patientid", "assignedat"}).
+			AddRow(4, "A1", 1, assigned).
+			AddRow(5, "A2", nil, nil).
+			AddRow(6, "A3", nil, nil).
+			AddRow(7, "A4", 3, moved))
+	mock.ExpectQuery("select name from ward where id=?").WithArgs(9).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+	s := New(db, &fakeAuditor{})
+	o, err := s.Occupancy(context.TODO(), 2)
+	expected := &models.Occupancy{WardID: 2, Ward: "ICU", Beds: 4, Occupied: 2, Free: 2, Rate: 0.5, Status: []models.BedStatus{
+		{ID: 4, Label: "A1", PatientID: 1, AssignedAt: &assigned},
+		{ID: 5, Label: "A2"},
+		{ID: 6, Label: "A3"},
+		{ID: 7, Label: "A4", PatientID: 3, AssignedAt: &moved},
+	}}
+	if err != nil || !reflect.DeepEqual(o, expected) {
+		t.Errorf("Expected: %v, Got: %v (%v)", expected, o, err)
+	}
+
+	_, err = s.Occupancy(context.TODO(), 9)
+	if !reflect.DeepEqual(err, &perrors.NotFound{Entity: "ward", ID: "9"}) {
+		t.Errorf("expected error :ward not found, got :%v ", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMoves(t *testing.T) {
+	a1 := models.BedLocation{BedID: 4, WardID: 2, Ward: "ICU", Label: "A1"}
+	b1 := models.BedLocation{BedID: 8, WardID: 3, Ward: "Recovery", Label: "B1"}
+
+	tests := []struct {
+		desc        string
+		call        func(s *store) (*models.BedAssignment, error)
+		mock        func(mock sqlmock.Sqlmock)
+		expected    *models.BedAssignment
+		operation   string
+		changes     map[string]models.FieldChange
+		expectError error
+	}{
+		{
+			desc: "assign a free bed",
+			call: func(s *store) (*models.BedAssignment, error) { return s.Assign(context.TODO(), 1, 4) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectCurrent).WithArgs(1).WillReturnRows(assignmentRows())
+				mock.ExpectQuery(selectBed).WithArgs(4).WillReturnRows(bedRows().AddRow(2, "ICU", "A1"))
+				mock.ExpectQuery(selectOccupant).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"patientid"}))
+				mock.ExpectExec(insertAssign).WithArgs(4, 1, moved).WillReturnResult(sqlmock.NewResult(3, 1))
+				mock.ExpectCommit()
+			},
+			expected:  &models.BedAssignment{ID: 3, PatientID: 1, BedLocation: a1, AssignedAt: moved},
+			operation: "assign bed",
+			changes:   map[string]models.FieldChange{"bed": {After: a1}},
+		},
+		{
+			desc: "assign an occupied bed",
+			call: func(s *store) (*models.BedAssignment, error) { return s.Assign(context.TODO(), 1, 4) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectCurrent).WithArgs(1).WillReturnRows(assignmentRows())
+				mock.ExpectQuery(selectBed).WithArgs(4).WillReturnRows(bedRows().AddRow(2, "ICU", "A1"))
+				mock.ExpectQuery(selectOccupant).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"patientid"}).AddRow(7))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "bed", Reason: "ICU A1 is occupied"},
+		},
+		{
+			desc: "assign loses a race for the bed",
+			call: func(s *store) (*models.BedAssignment, error) { return s.Assign(context.TODO(), 1, 4) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectCurrent).WithArgs(1).WillReturnRows(assignmentRows())
+				mock.ExpectQuery(selectBed).WithArgs(4).WillReturnRows(bedRows().AddRow(2, "ICU", "A1"))
+				mock.ExpectQuery(selectOccupant).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"patientid"}))
+				mock.ExpectExec(insertAssign).WithArgs(4, 1, moved).WillReturnError(&mysql.MySQLError{Number: 1062})
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "bed", Reason: "ICU A1 is occupied"},
+		},
+		{
+			desc: "assign a discharged patient",
+			call: func(s *store) (*models.BedAssignment, error) { return s.Assign(context.TODO(), 1, 4) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(true))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "patient", Reason: "is not admitted"},
+		},
+		{
+			desc: "assign a patient who has a bed",
+			call: func(s *store) (*models.BedAssignment, error) { return s.Assign(context.TODO(), 1, 8) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectCurrent).WithArgs(1).WillReturnRows(assignmentRows().AddRow(3, 1, 4, 2, "ICU", "A1", assigned, nil))
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "patient", Reason: "is already in ICU A1; transfer instead"},
+		},
+		{
+			desc: "transfer",
+			call: func(s *store) (*models.BedAssignment, error) { return s.Transfer(context.TODO(), 1, 8) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectCurrent).WithArgs(1).WillReturnRows(assignmentRows().AddRow(3, 1, 4, 2, "ICU", "A1", assigned, nil))
+				mock.ExpectQuery(selectBed).WithArgs(8).WillReturnRows(bedRows().AddRow(3, "Recovery", "B1"))
+				mock.ExpectQuery(selectOccupant).WithArgs(8).WillReturnRows(sqlmock.NewRows([]string{"patientid"}))
+				mock.ExpectExec(updateRelease).WithArgs(moved, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertAssign).WithArgs(8, 1, moved).WillReturnResult(sqlmock.NewResult(6, 1))
+				mock.ExpectCommit()
+			},
+			expected:  &models.BedAssignment{ID: 6, PatientID: 1, BedLocation: b1, AssignedAt: moved},
+			operation: "transfer",
+			changes:   map[string]models.FieldChange{"bed": {Before: a1, After: b1}},
+		},
+		{
+			desc: "transfer without a bed",
+			call: func(s *store) (*models.BedAssignment, error) { return s.Transfer(context.TODO(), 1, 8) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectCurrent).WithArgs(1).WillReturnRows(assignmentRows())
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.Conflict{Entity: "patient", Reason: "has no bed; assign one instead"},
+		},
+		{
+			desc: "release",
+			call: func(s *store) (*models.BedAssignment, error) { return s.Release(context.TODO(), 1) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(true))
+				mock.ExpectQuery(selectCurrent).WithArgs(1).WillReturnRows(assignmentRows().AddRow(3, 1, 4, 2, "ICU", "A1", assigned, nil))
+				mock.ExpectExec(updateRelease).WithArgs(moved, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expected:  &models.BedAssignment{ID: 3, PatientID: 1, BedLocation: a1, AssignedAt: assigned, ReleasedAt: &moved},
+			operation: "release bed",
+			changes:   map[string]models.FieldChange{"bed": {Before: a1}},
+		},
+		{
+			desc: "release without a bed",
+			call: func(s *store) (*models.BedAssignment, error) { return s.Release(context.TODO(), 1) },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectPatient).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"discharge"}).AddRow(false))
+				mock.ExpectQuery(selectCurrent).WithArgs(1).WillReturnRows(assignmentRows())
+				mock.ExpectRollback()
+			},
+			expectError: &perrors.NotFound{Entity: "bed assignment", ID: "1"},
+		},
+	}
+
+	defer func(saved func() time.Time) { sqltx.Now = saved }(sqltx.Now)
+	sqltx.Now = func() time.Time { return moved }
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			test.mock(mock)
+			auditor := &fakeAuditor{}
+
+			a, err := test.call(New(db, auditor))
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if !reflect.DeepEqual(a, test.expected) {
+				t.Errorf("Expected: %v, Got: %v", test.expected, a)
+			}
+			if test.changes != nil {
+				expected := []auditCall{{id: 1, operation: test.operation, changes: test.changes}}
+				if !reflect.DeepEqual(auditor.calls, expected) {
+					t.Errorf("Expected: %v, Got: %v", expected, auditor.calls)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
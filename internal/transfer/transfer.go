@@ -0,0 +1,299 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a file format of the patient registry.
+type Format string
+
+const (
+	CSV    Format = "csv"
+	NDJSON Format = "ndjson"
+)
+
+// ParseFormat accepts csv, ndjson and its alias jsonl.
+func ParseFormat(s string) (Format, bool) {
+	switch strings.ToLower(s) {
+	case "csv":
+		return CSV, true
+	case "ndjson", "jsonl":
+		return NDJSON, true
+	}
+	return "", false
+}
+
+func (f Format) ContentType() string {
+	if f == CSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+var csvHeader = []string{"id", "name", "phone", "discharge", "bloodGroup", "description", "createdAt", "updatedAt", "version"}
+
+// Writer encodes patients one at a time. Close must be called once all
+// patients are written.
+type Writer interface {
+	Write(pt *models.Patient) error
+	Close() error
+}
+
+func NewWriter(f Format, w io.Writer) Writer {
+	if f == CSV {
+		return &csvWriter{w: csv.NewWriter(w)}
+	}
+	return &ndjsonWriter{w: bufio.NewWriter(w)}
+}
+
+type csvWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (c *csvWriter) header() error {
+	if c.wroteHeader {
+		return nil
+	}
+	c.wroteHeader = true
+	return c.w.Write(csvHeader)
+}
+
+func (c *csvWriter) Write(pt *models.Patient) error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	return c.w.Write([]string{
+		strconv.Itoa(pt.Id),
+		escapeCell(pt.Name),
+		pt.Phone,
+		strconv.FormatBool(pt.Discharge),
+		pt.BloodGroup,
+		escapeCell(pt.Description),
+		pt.CreatedAt.UTC().Format(time.RFC3339),
+		pt.UpdatedAt.UTC().Format(time.RFC3339),
+		strconv.Itoa(pt.Version),
+	})
+}
+
+// Close writes the header even when there were no patients.
+func (c *csvWriter) Close() error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	w *bufio.Writer
+}
+
+func (n *ndjsonWriter) Write(pt *models.Patient) error {
+	b, err := json.Marshal(pt)
+	if err != nil {
+		return err
+	}
+	n.w.Write(b)
+	return n.w.WriteByte('\n')
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
+
+// Spreadsheets evaluate cells starting with these characters as formulas.
+// Free-text cells starting with one are prefixed with a quote on export, which
+// the CSV reader strips again.
+const formulaPrefixes = "=+-@\t\r"
+
+func escapeCell(s string) string {
+	if s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func unescapeCell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// Row is one patient read from a file. Err reports a problem with this row
+// only; Patient is nil when it is set.
+type Row struct {
+	Line    int
+	Patient *models.Patient
+	Err     error
+}
+
+// Reader decodes patients one row at a time. Next returns io.EOF after the
+// last row; any other error means the file as a whole cannot be read.
+type Reader interface {
+	Next() (*Row, error)
+}
+
+func NewReader(f Format, r io.Reader) Reader {
+	if f == CSV {
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		return &csvReader{r: cr}
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	return &ndjsonReader{s: s}
+}
+
+// csvColumns maps the columns the CSV reader accepts to whether it imports
+// them; the rest are written by export and ignored on import.
+var csvColumns = map[string]bool{
+	"name":        true,
+	"phone":       true,
+	"discharge":   true,
+	"bloodgroup":  true,
+	"description": true,
+	"id":          false,
+	"createdat":   false,
+	"updatedat":   false,
+	"version":     false,
+}
+
+type csvReader struct {
+	r       *csv.Reader
+	columns []string
+}
+
+func (c *csvReader) readHeader() error {
+	header, err := c.r.Read()
+	if err == io.EOF {
+		return perrors.NewValidation("header", "file is empty")
+	}
+	if err != nil {
+		return perrors.NewValidation("header", err.Error())
+	}
+	verr := &perrors.Validation{}
+	seen := map[string]bool{}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := csvColumns[name]; !ok {
+			verr.Add("header", fmt.Sprintf("unknown column %q", name))
+		} else if seen[name] {
+			verr.Add("header", fmt.Sprintf("duplicate column %q", name))
+		}
+		seen[name] = true
+		c.columns = append(c.columns, name)
+	}
+	if !seen["name"] {
+		verr.Add("header", `missing column "name"`)
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func (c *csvReader) Next() (*Row, error) {
+	if c.columns == nil {
+		if err := c.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+	record, err := c.r.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		return &Row{Line: perr.StartLine, Err: perrors.NewValidation("row", perr.Err.Error())}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	line, _ := c.r.FieldPos(0)
+	if len(record) != len(c.columns) {
+		return &Row{Line: line, Err: perrors.NewValidation("row", fmt.Sprintf("has %d fields, the header %d", len(record), len(c.columns)))}, nil
+	}
+	// Like a single create, a row leaves the patient discharged unless it
+	// says otherwise.
+	pt := models.Patient{Discharge: true}
+	for i, value := range record {
+		switch c.columns[i] {
+		case "name":
+			pt.Name = unescapeCell(value)
+		case "phone":
+			pt.Phone = value
+		case "bloodgroup":
+			pt.BloodGroup = value
+		case "description":
+			pt.Description = unescapeCell(value)
+		case "discharge":
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			discharge, err := strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				return &Row{Line: line, Err: perrors.NewValidation("discharge", "must be true or false")}, nil
+			}
+			pt.Discharge = discharge
+		}
+	}
+	return &Row{Line: line, Patient: &pt}, nil
+}
+
+const maxLineBytes = 1 << 20
+
+type ndjsonReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+// Next decodes the next non-blank line. Fields export writes but import does
+// not take, such as id and version, are ignored; unknown fields are an error.
+func (n *ndjsonReader) Next() (*Row, error) {
+	for n.s.Scan() {
+		n.line++
+		b := bytes.TrimSpace(n.s.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		pt := models.Patient{Discharge: true}
+		if err := dec.Decode(&pt); err != nil {
+			return &Row{Line: n.line, Err: perrors.NewValidation("row", err.Error())}, nil
+		}
+		if dec.More() {
+			return &Row{Line: n.line, Err: perrors.NewValidation("row", "holds more than one JSON value")}, nil
+		}
+		return &Row{Line: n.line, Patient: &models.Patient{
+			Name:        pt.Name,
+			Phone:       pt.Phone,
+			Discharge:   pt.Discharge,
+			BloodGroup:  pt.BloodGroup,
+			Description: pt.Description,
+		}}, nil
+	}
+	if err := n.s.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, perrors.NewValidation("row", fmt.Sprintf("line %d is longer than %d bytes", n.line+1, maxLineBytes))
+		}
+		return nil, err
+	}
+	return nil, io.EOF
+}
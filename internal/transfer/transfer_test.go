@@ -0,0 +1,176 @@
+package transfer
+
+import (
+	"bytes"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		desc     string
+		input    string
+		expected Format
+		ok       bool
+	}{
+		{desc: "csv", input: "CSV", expected: CSV, ok: true},
+		{desc: "ndjson", input: "ndjson", expected: NDJSON, ok: true},
+		{desc: "jsonl alias", input: "jsonl", expected: NDJSON, ok: true},
+		{desc: "unknown", input: "xlsx"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, ok := ParseFormat(test.input)
+			if got != test.expected || ok != test.ok {
+				t.Errorf("Expected: %v %v, Got: %v %v", test.expected, test.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	created := time.Date(2022, 2, 22, 13, 23, 22, 0, time.UTC)
+	pt := &models.Patient{Id: 7, Name: "=HYPERLINK(\"x\")", Phone: "+919172681679", Discharge: true, CreatedAt: created, UpdatedAt: created, BloodGroup: "A+", Description: "fever, cough", Version: 2}
+
+	tests := []struct {
+		desc     string
+		format   Format
+		patients []*models.Patient
+		expected string
+	}{
+		{
+			desc:     "csv escapes formulas and quotes commas",
+			format:   CSV,
+			patients: []*models.Patient{pt},
+			expected: "id,name,phone,discharge,bloodGroup,description,createdAt,updatedAt,version\n" +
+				"7,\"'=HYPERLINK(\"\"x\"\")\",+919172681679,true,A+,\"fever, cough\",2022-02-22T13:23:22Z,2022-02-22T13:23:22Z,2\n",
+		},
+		{
+			desc:     "csv without patients has a header",
+			format:   CSV,
+			expected: "id,name,phone,discharge,bloodGroup,description,createdAt,updatedAt,version\n",
+		},
+		{
+			desc:     "ndjson",
+			format:   NDJSON,
+			patients: []*models.Patient{pt},
+			expected: `{"id":7,"name":"=HYPERLINK(\"x\")","phone":"+919172681679","discharge":true,"createdAt":"2022-02-22T13:23:22Z","updatedAt":"2022-02-22T13:23:22Z","bloodGroup":"A+","description":"fever, cough","version":2}` + "\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var b bytes.Buffer
+			w := NewWriter(test.format, &b)
+			for _, pt := range test.patients {
+				if err := w.Write(pt); err != nil {
+					t.Fatalf("expected error :%v, got :%v ", nil, err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("expected error :%v, got :%v ", nil, err)
+			}
+			if b.String() != test.expected {
+				t.Errorf("Expected: %q, Got: %q", test.expected, b.String())
+			}
+		})
+	}
+}
+
+// readAll collects the rows of r up to io.EOF or the first error.
+func readAll(r Reader) ([]*Row, error) {
+	var rows []*Row
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		desc        string
+		format      Format
+		input       string
+		expected    []*Row
+		expectError error
+	}{
+		{
+			desc:   "csv in any column order, export columns ignored",
+			format: CSV,
+			input:  "\ufeffName,id,discharge,phone\n'=1+1,9,TRUE,9172681679\n\"multi\nline\",,,\n",
+			expected: []*Row{
+				{Line: 2, Patient: &models.Patient{Name: "=1+1", Discharge: true, Phone: "9172681679"}},
+				{Line: 3, Patient: &models.Patient{Name: "multi\nline", Discharge: true}},
+			},
+		},
+		{
+			desc:   "csv line errors",
+			format: CSV,
+			input:  "name,discharge\nRam,maybe\nSita\nGita,false\n",
+			expected: []*Row{
+				{Line: 2, Err: perrors.NewValidation("discharge", "must be true or false")},
+				{Line: 3, Err: perrors.NewValidation("row", "has 1 fields, the header 2")},
+				{Line: 4, Patient: &models.Patient{Name: "Gita"}},
+			},
+		},
+		{
+			desc:        "csv header without name",
+			format:      CSV,
+			input:       "phone,ward\n123,4\n",
+			expectError: &perrors.Validation{Fields: []perrors.FieldError{{Field: "header", Message: `unknown column "ward"`}, {Field: "header", Message: `missing column "name"`}}},
+		},
+		{
+			desc:        "empty csv",
+			format:      CSV,
+			expectError: perrors.NewValidation("header", "file is empty"),
+		},
+		{
+			desc:   "ndjson skips blank lines and ignores export fields",
+			format: NDJSON,
+			input:  "{\"id\":4,\"version\":2,\"name\":\"Ram\",\"bloodGroup\":\"A+\"}\n\n{\"name\":\"Sita\",\"ward\":1}\n{\"name\":\n",
+			expected: []*Row{
+				{Line: 1, Patient: &models.Patient{Name: "Ram", BloodGroup: "A+", Discharge: true}},
+				{Line: 3, Err: perrors.NewValidation("row", `json: unknown field "ward"`)},
+				{Line: 4, Err: perrors.NewValidation("row", "unexpected EOF")},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			rows, err := readAll(NewReader(test.format, strings.NewReader(test.input)))
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if !reflect.DeepEqual(rows, test.expected) {
+				t.Errorf("Expected: %+v, Got: %+v", test.expected, rows)
+			}
+		})
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	pt := &models.Patient{Id: 1, Name: "-Ram", Phone: "+919172681679", BloodGroup: "O-", Description: "@home\nrest"}
+	var b bytes.Buffer
+	w := NewWriter(CSV, &b)
+	w.Write(pt)
+	w.Close()
+
+	rows, err := readAll(NewReader(CSV, &b))
+	if err != nil {
+		t.Fatalf("expected error :%v, got :%v ", nil, err)
+	}
+	expected := []*Row{{Line: 2, Patient: &models.Patient{Name: "-Ram", Phone: "+919172681679", BloodGroup: "O-", Description: "@home\nrest"}}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected: %+v, Got: %+v", expected, rows)
+	}
+}
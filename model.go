@@ -3,14 +3,252 @@ package models
 import "time"
 
 type Patient struct {
-	Id          int       `json:"id"`
-	Name        string    `json:"name"`
-	Phone       string    `json:"phone"`
-	Discharge   bool      `json:"discharge"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	DeletedAt   time.Time `json:"-"`
-	BloodGroup  string    `json:"bloodGroup"`
-	Description string    `json:"description"`
+	Id          int        `json:"id"`
+	Name        string     `json:"name"`
+	Phone       string     `json:"phone"`
+	Discharge   bool       `json:"discharge"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
+	BloodGroup  string     `json:"bloodGroup"`
+	Description string     `json:"description"`
+	Version     int        `json:"version"`
 }
 
+type ListOptions struct {
+	Limit      int
+	Offset     int
+	Cursor     string
+	Sort       string
+	Order      string
+	Discharge  *bool
+	BloodGroup string
+	// Phone matches patients by exact, normalised phone number.
+	Phone string
+	// Name matches patients whose name, or a word of it, starts with Name,
+	// ignoring case.
+	Name    string
+	Deleted bool
+	// NameOnly keeps a search from matching descriptions, for callers who may
+	// not read them.
+	NameOnly bool
+}
+
+type PatientPage struct {
+	Patients   []*Patient
+	Total      int
+	NextCursor string
+}
+
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type AuditEntry struct {
+	ID        int64                  `json:"id"`
+	PatientID int                    `json:"patientId"`
+	Actor     string                 `json:"actor"`
+	Operation string                 `json:"operation"`
+	Changes   map[string]FieldChange `json:"changes"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
+
+// DischargeDerived explains why a patient write may not change Discharge.
+const DischargeDerived = "is derived from encounters; admit or discharge the patient instead"
+
+// BulkMode chooses what a bulk write does when some of its items fail.
+type BulkMode string
+
+const (
+	// BulkAtomic writes every item or, if any fails, none.
+	BulkAtomic BulkMode = "atomic"
+	// BulkPartial writes the items that succeed and reports the others.
+	BulkPartial BulkMode = "partial"
+)
+
+// BulkResult is the outcome of one item of a bulk write, in request order.
+// Patient is the written patient; it is nil for deletes and failed items.
+type BulkResult struct {
+	Patient *Patient
+	Err     error
+}
+
+// PatientRef names a patient to delete; a non-zero Version makes the delete
+// conditional on it.
+type PatientRef struct {
+	ID      int `json:"id"`
+	Version int `json:"version,omitempty"`
+}
+
+// LineError is a problem with one line of an imported file.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+// ImportReport summarises an import. Imported stays zero on a dry run and
+// whenever a line has errors.
+type ImportReport struct {
+	Rows     int
+	Imported int
+	DryRun   bool
+	Errors   []LineError
+}
+
+// Encounter is one admission of a patient. It is open until DischargedAt is
+// set, and a patient has at most one open encounter.
+type Encounter struct {
+	ID           int        `json:"id"`
+	PatientID    int        `json:"patientId"`
+	AdmittedAt   time.Time  `json:"admittedAt"`
+	DischargedAt *time.Time `json:"dischargedAt,omitempty"`
+	Reason       string     `json:"reason"`
+	Doctor       string     `json:"doctor"`
+	Ward         string     `json:"ward"`
+	Version      int        `json:"version"`
+}
+
+// Ward groups the beds of one part of the hospital.
+type Ward struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	Beds      []*Bed    `json:"beds,omitempty"`
+}
+
+type Bed struct {
+	ID     int    `json:"id"`
+	WardID int    `json:"wardId"`
+	Label  string `json:"label"`
+}
+
+// BedLocation names a bed together with its ward.
+type BedLocation struct {
+	BedID  int    `json:"bedId"`
+	WardID int    `json:"wardId"`
+	Ward   string `json:"ward"`
+	Label  string `json:"label"`
+}
+
+// BedAssignment is a stay of a patient in one bed. It is current until
+// ReleasedAt is set; a bed holds one patient, and a patient one bed, at a time.
+type BedAssignment struct {
+	ID        int `json:"id"`
+	PatientID int `json:"patientId"`
+	BedLocation
+	AssignedAt time.Time  `json:"assignedAt"`
+	ReleasedAt *time.Time `json:"releasedAt,omitempty"`
+}
+
+// BedStatus is a bed of an occupancy report, with its patient if occupied.
+type BedStatus struct {
+	ID         int        `json:"id"`
+	Label      string     `json:"label"`
+	PatientID  int        `json:"patientId,omitempty"`
+	AssignedAt *time.Time `json:"assignedAt,omitempty"`
+}
+
+// Occupancy reports how many beds of a ward are in use. Rate is Occupied
+// over Beds, and 0 for a ward without beds.
+type Occupancy struct {
+	WardID   int         `json:"wardId"`
+	Ward     string      `json:"ward"`
+	Beds     int         `json:"beds"`
+	Occupied int         `json:"occupied"`
+	Free     int         `json:"free"`
+	Rate     float64     `json:"rate"`
+	Status   []BedStatus `json:"status"`
+}
+
+// Triage is a visit of a patient waiting to be seen, with its ESI level from
+// 1, the most urgent, to 5. The visit waits until CalledAt is set.
+type Triage struct {
+	ID        int        `json:"id"`
+	PatientID int        `json:"patientId"`
+	Level     int        `json:"level"`
+	ArrivedAt time.Time  `json:"arrivedAt"`
+	TriagedAt time.Time  `json:"triagedAt"`
+	CalledAt  *time.Time `json:"calledAt,omitempty"`
+	// Priority is Level raised by the time waited, computed when the queue
+	// is read.
+	Priority int `json:"priority,omitempty"`
+}
+
+// Profession is what a staff member does, and decides their care team roles.
+type Profession string
+
+const (
+	Doctor Profession = "doctor"
+	Nurse  Profession = "nurse"
+)
+
+// Staff is a doctor or nurse of the hospital.
+type Staff struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Profession Profession `json:"profession"`
+	Specialty  string     `json:"specialty,omitempty"`
+	Phone      string     `json:"phone,omitempty"`
+	Email      string     `json:"email,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	Version    int        `json:"version"`
+}
+
+// CareRole is the part a staff member plays in the care team of a patient. A
+// patient has at most one attending doctor and one primary nurse, and any
+// number of consulting doctors.
+type CareRole string
+
+const (
+	Attending    CareRole = "attending"
+	Consulting   CareRole = "consulting"
+	PrimaryNurse CareRole = "primary-nurse"
+)
+
+// CareTeamMember assigns a staff member to the care team of a patient. Name
+// and Profession describe the staff member.
+type CareTeamMember struct {
+	PatientID  int        `json:"patientId"`
+	StaffID    int        `json:"staffId"`
+	Role       CareRole   `json:"role"`
+	Name       string     `json:"name"`
+	Profession Profession `json:"profession"`
+	AssignedAt time.Time  `json:"assignedAt"`
+}
+
+// AppointmentStatus is whether an appointment still holds its time.
+type AppointmentStatus string
+
+const (
+	Booked    AppointmentStatus = "booked"
+	Cancelled AppointmentStatus = "cancelled"
+)
+
+// Appointment books a patient with a staff member from Start to End. A
+// cancelled appointment keeps its times but no longer holds them.
+type Appointment struct {
+	ID        int               `json:"id"`
+	PatientID int               `json:"patientId"`
+	StaffID   int               `json:"staffId"`
+	Start     time.Time         `json:"start"`
+	End       time.Time         `json:"end"`
+	Status    AppointmentStatus `json:"status"`
+	Version   int               `json:"version"`
+}
+
+// AppointmentFilter selects the appointments of a patient or a staff member
+// that overlap From to To. Zero fields do not filter.
+type AppointmentFilter struct {
+	PatientID int
+	StaffID   int
+	From      time.Time
+	To        time.Time
+}
+
+// Slot is a free stretch of a staff member's working hours.
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
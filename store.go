@@ -1,77 +1,1268 @@
 package patient
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aakanksha/ppms/internal/audit"
+	"github.com/aakanksha/ppms/internal/encryption"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/logging"
 	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/stores/sqltx"
+	"github.com/go-sql-driver/mysql"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
+var sortColumns = map[string]string{
+	"":          "id",
+	"id":        "id",
+	"name":      "name",
+	"createdAt": "createdat",
+	"updatedAt": "udatedat",
+}
+
+type cursor struct {
+	ID    int    `json:"id"`
+	Value string `json:"v,omitempty"`
+}
+
+// errNoFullTextIndex is the MySQL error number of a search without a FULLTEXT
+// index matching its column list.
+const errNoFullTextIndex = 1191
+
+const purgeBatchSize = 1000
+
+// bedReleaser frees the bed of a patient inside a transaction; the ward
+// store implements it.
+type bedReleaser interface {
+	ReleaseBed(ctx context.Context, tx *sql.Tx, patientID int) (*models.BedAssignment, error)
+}
+
 type store struct {
-	db *sql.DB
+	db   *sql.DB
+	keys *encryption.Keyring
+	beds bedReleaser
+	// noFullText and noNameFullText are set once a search finds the FULLTEXT
+	// index on name and description, or on name alone, missing.
+	noFullText     int32
+	noNameFullText int32
 }
 
 func New(db *sql.DB) *store {
 	return &store{db: db}
 }
-func (s *store) Insert(pt *models.Patient) (*models.Patient, error) {
-	query := "insert into patient (name,phone,discharge,bloodgroup,description) values (?, ?, ?, ?, ?)"
-	res, err := s.db.Exec(query, pt.Name, pt.Phone, pt.Discharge, pt.BloodGroup, pt.Description)
+
+// WithBeds frees the bed of a patient, through beds, when the patient is
+// deleted.
+func (s *store) WithBeds(beds bedReleaser) *store {
+	s.beds = beds
+	return s
+}
+
+// WithKeyring encrypts phone and description at rest with keys. Rows written
+// in plaintext before stay readable.
+func (s *store) WithKeyring(keys *encryption.Keyring) *store {
+	s.keys = keys
+	return s
+}
+
+// sensitiveFields are encrypted at rest, in the patient table and in audit
+// entries alike. Encounter reasons are recorded in the patient's audit trail.
+var sensitiveFields = map[string]bool{"phone": true, "description": true, "encounter.reason": true}
+
+func (s *store) phoneIndex(phone string) interface{} {
+	if phone == "" {
+		return nil
+	}
+	return s.keys.BlindIndex(phone)
+}
+
+// decrypt replaces the stored form of pt's sensitive fields with plaintext.
+func (s *store) decrypt(pt *models.Patient) error {
+	var err error
+	if pt.Phone, err = s.keys.DecryptFor("phone", pt.Id, pt.Phone); err != nil {
+		return dbError(err)
+	}
+	if pt.Description, err = s.keys.DecryptFor("description", pt.Id, pt.Description); err != nil {
+		return dbError(err)
+	}
+	return nil
+}
+
+// encrypt returns the stored form of pt's phone and description in row id.
+func (s *store) encrypt(id int, pt *models.Patient) (string, string, error) {
+	phone, err := s.keys.EncryptFor("phone", id, pt.Phone)
 	if err != nil {
-		return nil, err
+		return "", "", dbError(err)
+	}
+	description, err := s.keys.EncryptFor("description", id, pt.Description)
+	if err != nil {
+		return "", "", dbError(err)
+	}
+	return phone, description, nil
+}
+
+// sealNew encrypts the phone and description of patients just inserted as
+// ids. Encrypted values are bound to their row, so with a keyring new rows
+// are inserted without them and sealed once their ids are known.
+func (s *store) sealNew(ctx context.Context, tx *sql.Tx, ids []int, pts []*models.Patient) error {
+	if s.keys == nil {
+		return nil
+	}
+	var phones, descriptions []interface{}
+	for i, pt := range pts {
+		phone, description, err := s.encrypt(ids[i], pt)
+		if err != nil {
+			return err
+		}
+		phones = append(phones, ids[i], phone)
+		descriptions = append(descriptions, ids[i], description)
+	}
+	cases := " = CASE id" + strings.Repeat(" WHEN ? THEN ?", len(ids)) + " END"
+	query := "update patient SET phone" + cases + ", description" + cases + " where id IN (" + placeholders(len(ids)) + ")"
+	args := append(append(phones, descriptions...), intArgs(ids)...)
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return dbError(err)
+	}
+	return nil
+}
+
+// newFields returns the phone and description to insert a new patient with:
+// plaintext stored as is without a keyring, and nothing yet with one.
+func (s *store) newFields(pt *models.Patient) (string, string, error) {
+	if s.keys != nil {
+		return "", "", nil
 	}
-	lastinserted, err := res.LastInsertId()
+	return s.encrypt(0, pt)
+}
+
+func (s *store) Insert(ctx context.Context, pt *models.Patient) (*models.Patient, error) {
+	var created *models.Patient
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		phone, description, err := s.newFields(pt)
+		if err != nil {
+			return err
+		}
+		query := "insert into patient (name,phone,phoneindex,discharge,bloodgroup,description) values (?, ?, ?, ?, ?, ?)"
+		res, err := tx.ExecContext(ctx, query, pt.Name, phone, s.phoneIndex(pt.Phone), pt.Discharge, pt.BloodGroup, description)
+		if err != nil {
+			return dbError(err)
+		}
+		lastinserted, err := res.LastInsertId()
+		if err != nil {
+			return dbError(err)
+		}
+		if err := s.sealNew(ctx, tx, []int{int(lastinserted)}, []*models.Patient{pt}); err != nil {
+			return err
+		}
+		created, err = s.getByID(ctx, tx, int(lastinserted), false)
+		if err != nil {
+			return err
+		}
+		return s.writeAudit(ctx, tx, created.Id, "create", diffPatients(nil, created))
+	})
 	if err != nil {
 		return nil, err
 	}
-	return s.GetByID(int(lastinserted))
+	return created, nil
 }
 
-func (s *store) GetByID(gid int) (*models.Patient, error) {
+func (s *store) GetByID(ctx context.Context, gid int) (*models.Patient, error) {
+	return s.getByID(ctx, s.db, gid, false)
+}
+
+// getByID reads a live patient; forUpdate locks the row until the surrounding
+// transaction ends.
+func (s *store) getByID(ctx context.Context, q sqltx.Querier, gid int, forUpdate bool) (*models.Patient, error) {
 	var pt models.Patient
-	query := "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description from patient where deletedat IS NULL and id=?"
-	row := s.db.QueryRow(query, gid)
-	err := row.Scan(&pt.Id, &pt.Name, &pt.Phone, &pt.Discharge, &pt.CreatedAt, &pt.UpdatedAt, &pt.BloodGroup, &pt.Description)
+	query := "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id=?"
+	if forUpdate {
+		query += " for update"
+	}
+	row := q.QueryRowContext(ctx, query, gid)
+	err := row.Scan(&pt.Id, &pt.Name, &pt.Phone, &pt.Discharge, &pt.CreatedAt, &pt.UpdatedAt, &pt.BloodGroup, &pt.Description, &pt.Version)
 	if err == sql.ErrNoRows {
+		return nil, &perrors.NotFound{Entity: "patient", ID: strconv.Itoa(gid)}
+	}
+	if err != nil {
+		return nil, dbError(err)
+	}
+	if err := s.decrypt(&pt); err != nil {
 		return nil, err
 	}
 	return &pt, nil
 }
 
-func (s *store) GetAll() ([]*models.Patient, error) {
-	query := "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description from patient where deletedat IS NULL;"
-	rows, err := s.db.Query(query)
+func (s *store) GetAll(ctx context.Context) ([]*models.Patient, error) {
+	query := "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL;"
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 	var patients []*models.Patient
 	defer rows.Close()
 	for rows.Next() {
 		var pt models.Patient
-		err := rows.Scan(&pt.Id, &pt.Name, &pt.Phone, &pt.Discharge, &pt.CreatedAt, &pt.UpdatedAt, &pt.BloodGroup, &pt.Description)
+		err := rows.Scan(&pt.Id, &pt.Name, &pt.Phone, &pt.Discharge, &pt.CreatedAt, &pt.UpdatedAt, &pt.BloodGroup, &pt.Description, &pt.Version)
+		if err != nil {
+			return nil, dbError(err)
+		}
+		if err := s.decrypt(&pt); err != nil {
+			return nil, err
+		}
+		patients = append(patients, &pt)
+	}
+	return patients, nil
+}
+
+const eachBatchSize = 500
+
+// Each calls fn for every live patient in id order. It reads the table in
+// keyset batches, so it holds no connection while fn runs and never loads the
+// whole registry. An error from fn stops the iteration and is returned.
+func (s *store) Each(ctx context.Context, fn func(*models.Patient) error) error {
+	lastID := 0
+	for {
+		query := "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id > ? order by id limit ?"
+		rows, err := s.db.QueryContext(ctx, query, lastID, eachBatchSize)
+		if err != nil {
+			return dbError(err)
+		}
+		var batch []*models.Patient
+		for rows.Next() {
+			var pt models.Patient
+			if err := rows.Scan(&pt.Id, &pt.Name, &pt.Phone, &pt.Discharge, &pt.CreatedAt, &pt.UpdatedAt, &pt.BloodGroup, &pt.Description, &pt.Version); err != nil {
+				rows.Close()
+				return dbError(err)
+			}
+			batch = append(batch, &pt)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return dbError(err)
+		}
+
+		for _, pt := range batch {
+			if err := s.decrypt(pt); err != nil {
+				return err
+			}
+			if err := fn(pt); err != nil {
+				return err
+			}
+		}
+		if len(batch) < eachBatchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].Id
+	}
+}
+
+func (s *store) List(ctx context.Context, opts models.ListOptions) (*models.PatientPage, error) {
+	column, ok := sortColumns[opts.Sort]
+	if !ok {
+		return nil, perrors.NewValidation("sort", "must be one of id, name, createdAt, updatedAt")
+	}
+	desc := strings.EqualFold(opts.Order, "desc")
+
+	where := []string{"deletedat IS NULL"}
+	columns := "id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version"
+	if opts.Deleted {
+		where[0] = "deletedat IS NOT NULL"
+		columns += ",deletedat"
+	}
+	var args []interface{}
+	if opts.Discharge != nil {
+		where = append(where, "discharge=?")
+		args = append(args, *opts.Discharge)
+	}
+	if opts.BloodGroup != "" {
+		where = append(where, "bloodgroup=?")
+		args = append(args, opts.BloodGroup)
+	}
+	if opts.Phone != "" {
+		where = append(where, "phoneindex=?")
+		args = append(args, s.keys.BlindIndex(opts.Phone))
+	}
+	if opts.Name != "" {
+		prefix := escapeLike(strings.ToLower(opts.Name)) + "%"
+		where = append(where, "(lower(name) like ? or lower(name) like ?)")
+		args = append(args, prefix, "% "+prefix)
+	}
+
+	var total int
+	countQuery := "select count(*) from patient where " + strings.Join(where, " and ")
+	if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, dbError(err)
+	}
+
+	if opts.Cursor != "" {
+		cond, cursorArgs, err := keysetCondition(opts.Cursor, column, desc)
+		if err != nil {
+			return nil, perrors.NewValidation("cursor", err.Error())
+		}
+		where = append(where, cond)
+		args = append(args, cursorArgs...)
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	orderBy := "id " + direction
+	if column != "id" {
+		orderBy = column + " " + direction + ", " + orderBy
+	}
+	query := "select " + columns + " from patient where " +
+		strings.Join(where, " and ") + " order by " + orderBy + " limit ?"
+	args = append(args, opts.Limit+1)
+	if opts.Cursor == "" && opts.Offset > 0 {
+		query += " offset ?"
+		args = append(args, opts.Offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+	page := &models.PatientPage{Total: total}
+	for rows.Next() {
+		var pt models.Patient
+		var deletedAt sql.NullTime
+		dest := []interface{}{&pt.Id, &pt.Name, &pt.Phone, &pt.Discharge, &pt.CreatedAt, &pt.UpdatedAt, &pt.BloodGroup, &pt.Description, &pt.Version}
+		if opts.Deleted {
+			dest = append(dest, &deletedAt)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, dbError(err)
+		}
+		if deletedAt.Valid {
+			pt.DeletedAt = &deletedAt.Time
+		}
+		if err := s.decrypt(&pt); err != nil {
+			return nil, err
+		}
+		page.Patients = append(page.Patients, &pt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+	if len(page.Patients) > opts.Limit {
+		page.Patients = page.Patients[:opts.Limit]
+		page.NextCursor = encodeCursor(page.Patients[opts.Limit-1], column)
+	}
+	return page, nil
+}
+
+// Search matches query against name and description, or against name alone
+// when descriptions are encrypted at rest or opts.NameOnly is set.
+func (s *store) Search(ctx context.Context, query string, opts models.ListOptions) (*models.PatientPage, error) {
+	tokens := searchTokens(query)
+	if len(tokens) == 0 {
+		return &models.PatientPage{}, nil
+	}
+	nameOnly := s.keys != nil || opts.NameOnly
+	noFullText := &s.noFullText
+	if nameOnly {
+		noFullText = &s.noNameFullText
+	}
+	if atomic.LoadInt32(noFullText) == 0 {
+		page, err := s.searchFullText(ctx, tokens, nameOnly, opts)
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == errNoFullTextIndex:
+			atomic.StoreInt32(noFullText, 1)
+			logging.FromContext(ctx).Warn("no FULLTEXT index on patient, falling back to LIKE search")
+		case err != nil:
+			return nil, dbError(err)
+		default:
+			return page, nil
+		}
+	}
+	page, err := s.searchLike(ctx, tokens, nameOnly, opts)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	return page, nil
+}
+
+func (s *store) searchFullText(ctx context.Context, tokens []string, nameOnly bool, opts models.ListOptions) (*models.PatientPage, error) {
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = "+" + token + "*"
+	}
+	against := strings.Join(terms, " ")
+	match := "MATCH(name, description) AGAINST(? IN BOOLEAN MODE)"
+	if nameOnly {
+		match = "MATCH(name) AGAINST(? IN BOOLEAN MODE)"
+	}
+	return s.searchQuery(ctx, match, []interface{}{against}, match, []interface{}{against}, opts)
+}
+
+func (s *store) searchLike(ctx context.Context, tokens []string, nameOnly bool, opts models.ListOptions) (*models.PatientPage, error) {
+	var conds, scores []string
+	var condArgs, scoreArgs []interface{}
+	for _, token := range tokens {
+		contains := "%" + escapeLike(token) + "%"
+		prefix := escapeLike(token) + "%"
+		if nameOnly {
+			conds = append(conds, "lower(name) like ?")
+			condArgs = append(condArgs, contains)
+			scores = append(scores, "(lower(name) like ?)*2 + (lower(name) like ?)")
+			scoreArgs = append(scoreArgs, prefix, contains)
+			continue
+		}
+		conds = append(conds, "(lower(name) like ? or lower(description) like ?)")
+		condArgs = append(condArgs, contains, contains)
+		scores = append(scores, "(lower(name) like ?)*2 + (lower(name) like ?) + (lower(description) like ?)")
+		scoreArgs = append(scoreArgs, prefix, contains, contains)
+	}
+	return s.searchQuery(ctx, strings.Join(conds, " and "), condArgs, strings.Join(scores, " + "), scoreArgs, opts)
+}
+
+func (s *store) searchQuery(ctx context.Context, cond string, condArgs []interface{}, score string, scoreArgs []interface{}, opts models.ListOptions) (*models.PatientPage, error) {
+	where := "deletedat IS NULL and " + cond
+	args := append([]interface{}{}, condArgs...)
+	if opts.Discharge != nil {
+		where += " and discharge=?"
+		args = append(args, *opts.Discharge)
+	}
+	if opts.BloodGroup != "" {
+		where += " and bloodgroup=?"
+		args = append(args, opts.BloodGroup)
+	}
+	if opts.Phone != "" {
+		where += " and phoneindex=?"
+		args = append(args, s.keys.BlindIndex(opts.Phone))
+	}
+
+	page := &models.PatientPage{}
+	if err := s.db.QueryRowContext(ctx, "select count(*) from patient where "+where, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	query := "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version, " + score + " as score from patient where " +
+		where + " order by score DESC, id ASC limit ? offset ?"
+	queryArgs := append(append([]interface{}{}, scoreArgs...), args...)
+	queryArgs = append(queryArgs, opts.Limit, opts.Offset)
+	rows, err := s.db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pt models.Patient
+		var rank float64
+		err := rows.Scan(&pt.Id, &pt.Name, &pt.Phone, &pt.Discharge, &pt.CreatedAt, &pt.UpdatedAt, &pt.BloodGroup, &pt.Description, &pt.Version, &rank)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.decrypt(&pt); err != nil {
+			return nil, err
+		}
+		page.Patients = append(page.Patients, &pt)
+	}
+	return page, rows.Err()
+}
+
+// searchTokens lowercases the query and drops characters that are operators in
+// MySQL boolean-mode full-text search.
+func searchTokens(query string) []string {
+	clean := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`+-<>()~*"@`, r) {
+			return ' '
+		}
+		return r
+	}, strings.ToLower(query))
+	return strings.Fields(clean)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func encodeCursor(pt *models.Patient, column string) string {
+	c := cursor{ID: pt.Id}
+	switch column {
+	case "name":
+		c.Value = pt.Name
+	case "createdat":
+		c.Value = pt.CreatedAt.Format(time.RFC3339Nano)
+	case "udatedat":
+		c.Value = pt.UpdatedAt.Format(time.RFC3339Nano)
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func keysetCondition(encoded string, column string, desc bool) (string, []interface{}, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, errors.New("invalid cursor")
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 {
+		return "", nil, errors.New("invalid cursor")
+	}
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	if column == "id" {
+		return "id " + op + " ?", []interface{}{c.ID}, nil
+	}
+	var value interface{} = c.Value
+	if column == "createdat" || column == "udatedat" {
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return "", nil, errors.New("invalid cursor")
+		}
+		value = t
+	}
+	cond := "(" + column + " " + op + " ? or (" + column + " = ? and id " + op + " ?))"
+	return cond, []interface{}{value, value, c.ID}, nil
+}
+
+// Update overwrites a patient, except for discharge, which follows its
+// encounters; a non-zero pt.Version makes the write conditional on the stored
+// version still matching.
+func (s *store) Update(ctx context.Context, pt *models.Patient, uid int) (*models.Patient, error) {
+	var updated *models.Patient
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := s.getByID(ctx, tx, uid, true)
+		if err != nil {
+			return err
+		}
+		if pt.Version > 0 && pt.Version != before.Version {
+			return &perrors.PreconditionFailed{Entity: "patient", ID: strconv.Itoa(uid)}
+		}
+		phone, description, err := s.encrypt(uid, pt)
+		if err != nil {
+			return err
+		}
+		query := "update patient SET name = ?, phone=?, phoneindex=?, udatedat=?,bloodgroup=?,description=?,version=version+1 where deletedat IS NULL and id=?"
+		_, err = tx.ExecContext(ctx, query, pt.Name, phone, s.phoneIndex(pt.Phone), time.Now(), pt.BloodGroup, description, uid)
+		if err != nil {
+			return dbError(err)
+		}
+		updated, err = s.getByID(ctx, tx, uid, false)
+		if err != nil {
+			return err
+		}
+		return s.writeAudit(ctx, tx, uid, "update", diffPatients(before, updated))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+var patchColumns = map[string]bool{
+	"name":        true,
+	"phone":       true,
+	"bloodgroup":  true,
+	"description": true,
+}
+
+// Patch updates only the given columns of a patient, provided it is still at version.
+func (s *store) Patch(ctx context.Context, id int, version int, columns map[string]interface{}) (*models.Patient, error) {
+	values := make(map[string]interface{}, len(columns)+1)
+	for name, value := range columns {
+		if !patchColumns[name] {
+			return nil, perrors.NewValidation(name, "cannot be patched")
+		}
+		values[name] = value
+	}
+	if phone, ok := columns["phone"].(string); ok {
+		values["phoneindex"] = s.phoneIndex(phone)
+	}
+	for name := range sensitiveFields {
+		if plaintext, ok := values[name].(string); ok {
+			encrypted, err := s.keys.EncryptFor(name, id, plaintext)
+			if err != nil {
+				return nil, dbError(err)
+			}
+			values[name] = encrypted
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sets := make([]string, 0, len(names)+1)
+	args := make([]interface{}, 0, len(names)+2)
+	for _, name := range names {
+		sets = append(sets, name+"=?")
+		args = append(args, values[name])
+	}
+	sets = append(sets, "udatedat=?", "version=version+1")
+	args = append(args, time.Now(), id)
+	query := "update patient SET " + strings.Join(sets, ", ") + " where deletedat IS NULL and id=?"
+
+	var patched *models.Patient
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := s.getByID(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		if before.Version != version {
+			return &perrors.PreconditionFailed{Entity: "patient", ID: strconv.Itoa(id)}
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return dbError(err)
+		}
+		patched, err = s.getByID(ctx, tx, id, false)
+		if err != nil {
+			return err
+		}
+		return s.writeAudit(ctx, tx, id, "update", diffPatients(before, patched))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
+
+func (s *store) Delete(ctx context.Context, did int, version int) error {
+	return sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := s.getByID(ctx, tx, did, true)
+		if err != nil {
+			return err
+		}
+		if version > 0 && version != before.Version {
+			return &perrors.PreconditionFailed{Entity: "patient", ID: strconv.Itoa(did)}
+		}
+		query := "UPDATE patient SET deletedat=? WHERE id=? AND deletedat IS NULL"
+		uDeletedAt := time.Now()
+		if _, err := tx.ExecContext(ctx, query, uDeletedAt, did); err != nil {
+			return dbError(err)
+		}
+		changes := map[string]models.FieldChange{
+			"deletedAt": {Before: nil, After: uDeletedAt},
+		}
+		if err := s.releaseBed(ctx, tx, did, changes); err != nil {
+			return err
+		}
+		return s.writeAudit(ctx, tx, did, "delete", changes)
+	})
+}
+
+// releaseBed frees the bed of a patient being deleted, if any, and adds the
+// move to changes.
+func (s *store) releaseBed(ctx context.Context, tx *sql.Tx, id int, changes map[string]models.FieldChange) error {
+	if s.beds == nil {
+		return nil
+	}
+	released, err := s.beds.ReleaseBed(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if released != nil {
+		changes["bed"] = models.FieldChange{Before: released.BedLocation}
+	}
+	return nil
+}
+
+// errBulkAborted rolls back an all-or-nothing bulk write in which an item
+// failed; the per-item results explain why.
+var errBulkAborted = errors.New("bulk write aborted")
+
+// abortBulk marks every item that has not failed as not written.
+func abortBulk(results []models.BulkResult) {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = models.BulkResult{Err: &perrors.Aborted{}}
+		}
+	}
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func intArgs(ids []int) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+// getByIDs reads the live patients among ids, keyed by id; forUpdate locks
+// them until the surrounding transaction ends.
+func (s *store) getByIDs(ctx context.Context, q sqltx.Querier, ids []int, forUpdate bool) (map[int]*models.Patient, error) {
+	query := "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id IN (" + placeholders(len(ids)) + ")"
+	if forUpdate {
+		query += " for update"
+	}
+	rows, err := q.QueryContext(ctx, query, intArgs(ids)...)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	list, err := s.scanPatients(rows)
+	if err != nil {
+		return nil, err
+	}
+	patients := make(map[int]*models.Patient, len(list))
+	for _, pt := range list {
+		patients[pt.Id] = pt
+	}
+	return patients, nil
+}
+
+// scanPatients reads and closes rows of patients, keeping their order.
+func (s *store) scanPatients(rows *sql.Rows) ([]*models.Patient, error) {
+	defer rows.Close()
+	var patients []*models.Patient
+	for rows.Next() {
+		var pt models.Patient
+		err := rows.Scan(&pt.Id, &pt.Name, &pt.Phone, &pt.Discharge, &pt.CreatedAt, &pt.UpdatedAt, &pt.BloodGroup, &pt.Description, &pt.Version)
 		if err != nil {
+			return nil, dbError(err)
+		}
+		if err := s.decrypt(&pt); err != nil {
 			return nil, err
 		}
 		patients = append(patients, &pt)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
 	return patients, nil
 }
 
-func (s *store) Update(pt *models.Patient, uid int) (*models.Patient, error) {
+// bulkInsertRows caps the rows of one INSERT statement, keeping it well under
+// the placeholder limit of MySQL.
+const bulkInsertRows = 500
+
+// BulkInsert creates every patient in one transaction, with a multi-row INSERT
+// per bulkInsertRows patients, and returns them in the same order.
+func (s *store) BulkInsert(ctx context.Context, pts []*models.Patient) ([]*models.Patient, error) {
+	if len(pts) == 0 {
+		return nil, nil
+	}
+	var created []*models.Patient
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		var step int
+		if err := tx.QueryRowContext(ctx, "select @@auto_increment_increment").Scan(&step); err != nil {
+			return dbError(err)
+		}
+		created = make([]*models.Patient, 0, len(pts))
+		for start := 0; start < len(pts); start += bulkInsertRows {
+			end := start + bulkInsertRows
+			if end > len(pts) {
+				end = len(pts)
+			}
+			inserted, err := s.insertRows(ctx, tx, pts[start:end], step)
+			if err != nil {
+				return err
+			}
+			created = append(created, inserted...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// insertRows creates pts with a single multi-row INSERT and returns them in
+// the same order. InnoDB gives the rows of one such INSERT consecutive ids,
+// step apart, and LastInsertId reports the first.
+func (s *store) insertRows(ctx context.Context, tx *sql.Tx, pts []*models.Patient, step int) ([]*models.Patient, error) {
+	values := make([]string, len(pts))
+	args := make([]interface{}, 0, 6*len(pts))
+	for i, pt := range pts {
+		phone, description, err := s.newFields(pt)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = "(?, ?, ?, ?, ?, ?)"
+		args = append(args, pt.Name, phone, s.phoneIndex(pt.Phone), pt.Discharge, pt.BloodGroup, description)
+	}
+	query := "insert into patient (name,phone,phoneindex,discharge,bloodgroup,description) values " + strings.Join(values, ", ")
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	first, err := res.LastInsertId()
+	if err != nil {
+		return nil, dbError(err)
+	}
+	ids := make([]int, len(pts))
+	for i := range pts {
+		ids[i] = int(first) + i*step
+	}
+	if err := s.sealNew(ctx, tx, ids, pts); err != nil {
+		return nil, err
+	}
+	rows, err := s.getByIDs(ctx, tx, ids, false)
+	if err != nil {
+		return nil, err
+	}
+	created := make([]*models.Patient, len(ids))
+	entries := make([]auditRow, len(ids))
+	for i, id := range ids {
+		pt, ok := rows[id]
+		if !ok {
+			return nil, dbError(fmt.Errorf("inserted patient %d not found", id))
+		}
+		created[i] = pt
+		entries[i] = auditRow{id: id, operation: "create", changes: diffPatients(nil, pt)}
+	}
+	return created, s.writeAudits(ctx, tx, entries)
+}
+
+// lockBulk locks the live patients named by ids and checks each against the
+// version it must be at, zero meaning any. It returns the rows read and the
+// indexes of the items that may be written; failed items get an error in
+// results.
+func (s *store) lockBulk(ctx context.Context, tx *sql.Tx, ids, versions []int, results []models.BulkResult) (map[int]*models.Patient, []int, error) {
+	before, err := s.getByIDs(ctx, tx, ids, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ok []int
+	for i, id := range ids {
+		current, found := before[id]
+		switch {
+		case !found:
+			results[i].Err = &perrors.NotFound{Entity: "patient", ID: strconv.Itoa(id)}
+		case versions[i] > 0 && versions[i] != current.Version:
+			results[i].Err = &perrors.PreconditionFailed{Entity: "patient", ID: strconv.Itoa(id)}
+		default:
+			ok = append(ok, i)
+		}
+	}
+	return before, ok, nil
+}
+
+var bulkUpdateColumns = []string{"name", "phone", "phoneindex", "bloodgroup", "description"}
+
+// BulkUpdate overwrites many patients, identified by their Id, with one
+// UPDATE. A non-zero Version makes an item conditional on it. Missing patients,
+// version mismatches and changes to discharge, which follows encounters, fail
+// their item; in BulkAtomic mode they abort the whole batch.
+func (s *store) BulkUpdate(ctx context.Context, pts []*models.Patient, mode models.BulkMode) ([]models.BulkResult, error) {
+	results := make([]models.BulkResult, len(pts))
+	if len(pts) == 0 {
+		return results, nil
+	}
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		ids := make([]int, len(pts))
+		versions := make([]int, len(pts))
+		for i, pt := range pts {
+			ids[i], versions[i] = pt.Id, pt.Version
+		}
+		before, locked, err := s.lockBulk(ctx, tx, ids, versions, results)
+		if err != nil {
+			return err
+		}
+		var ok []int
+		for _, i := range locked {
+			if pts[i].Discharge != before[ids[i]].Discharge {
+				results[i].Err = perrors.NewValidation("discharge", models.DischargeDerived)
+				continue
+			}
+			ok = append(ok, i)
+		}
+		if len(ok) < len(pts) && mode == models.BulkAtomic {
+			abortBulk(results)
+			return errBulkAborted
+		}
+		if len(ok) == 0 {
+			return nil
+		}
+
+		rows := make([][]interface{}, len(ok))
+		okIDs := make([]int, len(ok))
+		for j, i := range ok {
+			pt := pts[i]
+			phone, description, err := s.encrypt(pt.Id, pt)
+			if err != nil {
+				return err
+			}
+			rows[j] = []interface{}{pt.Name, phone, s.phoneIndex(pt.Phone), pt.BloodGroup, description}
+			okIDs[j] = pt.Id
+		}
+		sets := make([]string, 0, len(bulkUpdateColumns)+2)
+		var args []interface{}
+		for c, column := range bulkUpdateColumns {
+			sets = append(sets, column+" = CASE id"+strings.Repeat(" WHEN ? THEN ?", len(ok))+" END")
+			for j, id := range okIDs {
+				args = append(args, id, rows[j][c])
+			}
+		}
+		sets = append(sets, "udatedat=?", "version=version+1")
+		args = append(args, time.Now())
+		args = append(args, intArgs(okIDs)...)
+		query := "update patient SET " + strings.Join(sets, ", ") + " where deletedat IS NULL and id IN (" + placeholders(len(okIDs)) + ")"
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return dbError(err)
+		}
+
+		after, err := s.getByIDs(ctx, tx, okIDs, false)
+		if err != nil {
+			return err
+		}
+		entries := make([]auditRow, len(ok))
+		for j, i := range ok {
+			id := okIDs[j]
+			results[i].Patient = after[id]
+			entries[j] = auditRow{id: id, operation: "update", changes: diffPatients(before[id], after[id])}
+		}
+		return s.writeAudits(ctx, tx, entries)
+	})
+	if err != nil && err != errBulkAborted {
+		return nil, err
+	}
+	return results, nil
+}
+
+// BulkDelete soft-deletes many patients with one UPDATE, failing items like
+// BulkUpdate does.
+func (s *store) BulkDelete(ctx context.Context, refs []models.PatientRef, mode models.BulkMode) ([]models.BulkResult, error) {
+	results := make([]models.BulkResult, len(refs))
+	if len(refs) == 0 {
+		return results, nil
+	}
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		ids := make([]int, len(refs))
+		versions := make([]int, len(refs))
+		for i, ref := range refs {
+			ids[i], versions[i] = ref.ID, ref.Version
+		}
+		_, ok, err := s.lockBulk(ctx, tx, ids, versions, results)
+		if err != nil {
+			return err
+		}
+		if len(ok) < len(refs) && mode == models.BulkAtomic {
+			abortBulk(results)
+			return errBulkAborted
+		}
+		if len(ok) == 0 {
+			return nil
+		}
 
-	query := "update patient SET name = ?, phone=?, discharge=?,udatedat=?,bloodgroup=?,description=? where deletedat IS NULL and id=?"
-	_, err := s.db.Exec(query, &pt.Name, &pt.Phone, &pt.Discharge, time.Now(), &pt.BloodGroup, &pt.Description, uid)
+		okIDs := make([]int, len(ok))
+		for j, i := range ok {
+			okIDs[j] = ids[i]
+		}
+		deletedAt := time.Now()
+		query := "UPDATE patient SET deletedat=? WHERE deletedat IS NULL AND id IN (" + placeholders(len(okIDs)) + ")"
+		if _, err := tx.ExecContext(ctx, query, append([]interface{}{deletedAt}, intArgs(okIDs)...)...); err != nil {
+			return dbError(err)
+		}
+		entries := make([]auditRow, len(okIDs))
+		for j, id := range okIDs {
+			entries[j] = auditRow{id: id, operation: "delete", changes: map[string]models.FieldChange{
+				"deletedAt": {Before: nil, After: deletedAt},
+			}}
+			if err := s.releaseBed(ctx, tx, id, entries[j].changes); err != nil {
+				return err
+			}
+		}
+		return s.writeAudits(ctx, tx, entries)
+	})
+	if err != nil && err != errBulkAborted {
+		return nil, err
+	}
+	return results, nil
+}
 
+// Restore clears deletedat on a soft-deleted patient.
+func (s *store) Restore(ctx context.Context, id int) (*models.Patient, error) {
+	var restored *models.Patient
+	err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		var deletedAt time.Time
+		err := tx.QueryRowContext(ctx, "select deletedat from patient where deletedat IS NOT NULL and id=? for update", id).Scan(&deletedAt)
+		if err == sql.ErrNoRows {
+			return &perrors.NotFound{Entity: "deleted patient", ID: strconv.Itoa(id)}
+		}
+		if err != nil {
+			return dbError(err)
+		}
+		query := "UPDATE patient SET deletedat=NULL, udatedat=?, version=version+1 WHERE id=? AND deletedat IS NOT NULL"
+		if _, err := tx.ExecContext(ctx, query, time.Now(), id); err != nil {
+			return dbError(err)
+		}
+		restored, err = s.getByID(ctx, tx, id, false)
+		if err != nil {
+			return err
+		}
+		return s.writeAudit(ctx, tx, id, "restore", map[string]models.FieldChange{
+			"deletedAt": {Before: deletedAt, After: nil},
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
-	return s.GetByID(uid)
+	return restored, nil
 }
 
-func (s *store) Delete(did int) error {
-	query := "UPDATE patient SET deletedat=? WHERE id=? AND deletedat IS NULL"
-	uDeletedAt := time.Now()
-	_, err := s.db.Exec(query, uDeletedAt, did)
+// Purge permanently removes patients soft-deleted before the given time. Rows
+// go in batches so a large purge never holds long locks on the table; each
+// batch records a purge entry per patient in the audit trail.
+func (s *store) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	const (
+		record = "insert into patient_audit (patientid,actor,operation,changes,createdat) " +
+			"select id, ?, 'purge', '{}', ? from patient WHERE deletedat IS NOT NULL AND deletedat < ? ORDER BY id LIMIT ?"
+		purge = "DELETE FROM patient WHERE deletedat IS NOT NULL AND deletedat < ? ORDER BY id LIMIT ?"
+	)
+	var purged int64
+	for {
+		var n int64
+		err := sqltx.InTx(ctx, s.db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, record, audit.Actor(ctx), time.Now(), deletedBefore, purgeBatchSize); err != nil {
+				return dbError(err)
+			}
+			res, err := tx.ExecContext(ctx, purge, deletedBefore, purgeBatchSize)
+			if err != nil {
+				return dbError(err)
+			}
+			n, err = res.RowsAffected()
+			if err != nil {
+				return dbError(err)
+			}
+			return nil
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+		if n < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
 
-	return err
+// History returns every audit entry recorded for a patient, oldest first.
+func (s *store) History(ctx context.Context, id int) ([]*models.AuditEntry, error) {
+	query := "select id,patientid,actor,operation,changes,createdat from patient_audit where patientid=? order by id"
+	rows, err := s.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		var changes []byte
+		if err := rows.Scan(&entry.ID, &entry.PatientID, &entry.Actor, &entry.Operation, &changes, &entry.CreatedAt); err != nil {
+			return nil, dbError(err)
+		}
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, dbError(err)
+		}
+		for name, change := range entry.Changes {
+			if !sensitiveFields[name] {
+				continue
+			}
+			if change.Before, err = s.openValue(name, entry.PatientID, change.Before); err != nil {
+				return nil, err
+			}
+			if change.After, err = s.openValue(name, entry.PatientID, change.After); err != nil {
+				return nil, err
+			}
+			entry.Changes[name] = change
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+	return entries, nil
+}
+
+// auditedFields are the patient fields whose changes go into the audit trail,
+// keyed by their JSON name.
+var auditedFields = []struct {
+	name  string
+	value func(pt *models.Patient) interface{}
+}{
+	{"name", func(pt *models.Patient) interface{} { return pt.Name }},
+	{"phone", func(pt *models.Patient) interface{} { return pt.Phone }},
+	{"discharge", func(pt *models.Patient) interface{} { return pt.Discharge }},
+	{"bloodGroup", func(pt *models.Patient) interface{} { return pt.BloodGroup }},
+	{"description", func(pt *models.Patient) interface{} { return pt.Description }},
+}
+
+// diffPatients lists the audited fields that differ; a nil before or after
+// stands for a patient that does not exist yet or any more.
+func diffPatients(before, after *models.Patient) map[string]models.FieldChange {
+	changes := map[string]models.FieldChange{}
+	for _, f := range auditedFields {
+		var b, a interface{}
+		if before != nil {
+			b = f.value(before)
+		}
+		if after != nil {
+			a = f.value(after)
+		}
+		if b != a {
+			changes[f.name] = models.FieldChange{Before: b, After: a}
+		}
+	}
+	return changes
 }
 
+// AppendAudit records a change made to patient id by another store in tx, so
+// that it appears in the patient's history.
+func (s *store) AppendAudit(ctx context.Context, tx *sql.Tx, id int, operation string, changes map[string]models.FieldChange) error {
+	return s.writeAudit(ctx, tx, id, operation, changes)
+}
+
+func (s *store) writeAudit(ctx context.Context, tx *sql.Tx, id int, operation string, changes map[string]models.FieldChange) error {
+	return s.writeAudits(ctx, tx, []auditRow{{id: id, operation: operation, changes: changes}})
+}
+
+type auditRow struct {
+	id        int
+	operation string
+	changes   map[string]models.FieldChange
+}
+
+// writeAudits appends one audit entry per row with a single statement.
+func (s *store) writeAudits(ctx context.Context, tx *sql.Tx, entries []auditRow) error {
+	actor, now := audit.Actor(ctx), time.Now()
+	values := make([]string, len(entries))
+	args := make([]interface{}, 0, 5*len(entries))
+	for i, e := range entries {
+		var err error
+		for name, change := range e.changes {
+			if !sensitiveFields[name] {
+				continue
+			}
+			if change.Before, err = s.sealValue(name, e.id, change.Before); err != nil {
+				return err
+			}
+			if change.After, err = s.sealValue(name, e.id, change.After); err != nil {
+				return err
+			}
+			e.changes[name] = change
+		}
+		b, err := json.Marshal(e.changes)
+		if err != nil {
+			return dbError(err)
+		}
+		values[i] = "(?, ?, ?, ?, ?)"
+		args = append(args, e.id, actor, e.operation, string(b), now)
+	}
+	query := "insert into patient_audit (patientid,actor,operation,changes,createdat) values " + strings.Join(values, ", ")
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return dbError(err)
+	}
+	return nil
+}
+
+// sealValue and openValue encrypt and decrypt the string values of field in
+// an audit entry of patient id; absent values stay nil.
+func (s *store) sealValue(field string, id int, v interface{}) (interface{}, error) {
+	plaintext, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	encrypted, err := s.keys.EncryptFor("audit."+field, id, plaintext)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	return encrypted, nil
+}
+
+func (s *store) openValue(field string, id int, v interface{}) (interface{}, error) {
+	stored, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	plaintext, err := s.keys.DecryptFor("audit."+field, id, stored)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	return plaintext, nil
+}
+
+const reencryptBatchSize = 500
+
+// Reencrypt brings every patient row, deleted ones included, under the
+// primary key of the keyring, binds its values to the row and fills in missing
+// phone blind indexes. Without a keyring it only fills in the indexes. Each
+// row is only rewritten if it has not changed since it was read. It returns
+// the number of rows rewritten.
+func (s *store) Reencrypt(ctx context.Context) (int64, error) {
+	type row struct {
+		id          int
+		phone       string
+		phoneIndex  sql.NullString
+		description string
+	}
+	var rewritten int64
+	lastID := 0
+	for {
+		rows, err := s.db.QueryContext(ctx, "select id,phone,phoneindex,description from patient where id > ? order by id limit ?", lastID, reencryptBatchSize)
+		if err != nil {
+			return rewritten, dbError(err)
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.phone, &r.phoneIndex, &r.description); err != nil {
+				rows.Close()
+				return rewritten, dbError(err)
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return rewritten, dbError(err)
+		}
+
+		for _, r := range batch {
+			phone, err := s.keys.DecryptFor("phone", r.id, r.phone)
+			if err != nil {
+				return rewritten, dbError(err)
+			}
+			index := s.phoneIndex(phone)
+			indexCurrent := index == nil && !r.phoneIndex.Valid || r.phoneIndex.Valid && index == r.phoneIndex.String
+			if !s.keys.NeedsRotation(r.phone) && !s.keys.NeedsRotation(r.description) && indexCurrent {
+				continue
+			}
+			newPhone, err := s.keys.RotateFor("phone", r.id, r.phone)
+			if err != nil {
+				return rewritten, dbError(err)
+			}
+			newDescription, err := s.keys.RotateFor("description", r.id, r.description)
+			if err != nil {
+				return rewritten, dbError(err)
+			}
+			res, err := s.db.ExecContext(ctx, "update patient SET phone=?, phoneindex=?, description=? where id=? and phone=? and description=?",
+				newPhone, index, newDescription, r.id, r.phone, r.description)
+			if err != nil {
+				return rewritten, dbError(err)
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return rewritten, dbError(err)
+			}
+			rewritten += n
+		}
+		if len(batch) < reencryptBatchSize {
+			return rewritten, nil
+		}
+		lastID = batch[len(batch)-1].id
+	}
+}
+
+// dbError wraps err; a duplicate key means the patient already exists.
+func dbError(err error) error {
+	return sqltx.Error(err, &perrors.Conflict{Entity: "patient", Reason: "already exists"})
+}
@@ -1,17 +1,36 @@
 package patient
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aakanksha/ppms/internal/audit"
+	"github.com/aakanksha/ppms/internal/encryption"
+	perrors "github.com/aakanksha/ppms/internal/errors"
 	"github.com/aakanksha/ppms/internal/models"
+	"github.com/go-sql-driver/mysql"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
 
 var current_time = time.Now()
 
+const (
+	selectForUpdate = "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id=? for update"
+	insertAudit     = "insert into patient_audit (patientid,actor,operation,changes,createdat) values (?, ?, ?, ?, ?)"
+)
+
+func patientRow(name string, version int) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"}).
+		AddRow(1, name, "+919172681679", true, current_time, current_time, "A+", "description", version)
+}
+
 func TestInsert(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	if err != nil {
@@ -30,12 +49,17 @@ func TestInsert(t *testing.T) {
 			desc:   "success",
 			input:  &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", Discharge: true, BloodGroup: "+A", Description: "description"},
 			output: &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", Discharge: true, CreatedAt: current_time, UpdatedAt: current_time, BloodGroup: "+A", Description: "description"},
-			mockQuery: []interface{}{mock.ExpectExec("insert into patient (name,phone,discharge,bloodgroup,description) values (?, ?, ?, ?, ?)").
-				WithArgs("ZopSmart", "+919172681679", true, "+A", "description").
-				WillReturnResult(sqlmock.NewResult(1, 1)),
-				mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description from patient where deletedat IS NULL and id=?").WithArgs(1).
-					WillReturnRows(mock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description"}).
-						AddRow(1, "ZopSmart", "+919172681679", true, current_time, current_time, "+A", "description")),
+			mockQuery: []interface{}{mock.ExpectBegin(),
+				mock.ExpectExec("insert into patient (name,phone,phoneindex,discharge,bloodgroup,description) values (?, ?, ?, ?, ?, ?)").
+					WithArgs("ZopSmart", "+919172681679", sqlmock.AnyArg(), true, "+A", "description").
+					WillReturnResult(sqlmock.NewResult(1, 1)),
+				mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id=?").WithArgs(1).
+					WillReturnRows(mock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"}).
+						AddRow(1, "ZopSmart", "+919172681679", true, current_time, current_time, "+A", "description", 1)),
+				mock.ExpectExec(insertAudit).
+					WithArgs(1, "system", "create", `{"bloodGroup":{"before":null,"after":"+A"},"description":{"before":null,"after":"description"},"discharge":{"before":null,"after":true},"name":{"before":null,"after":"ZopSmart"},"phone":{"before":null,"after":"+919172681679"}}`, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1)),
+				mock.ExpectCommit(),
 			},
 			expectError: nil,
 		},
@@ -43,10 +67,10 @@ func TestInsert(t *testing.T) {
 			desc:   "failure",
 			input:  &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", Discharge: true, BloodGroup: "+A", Description: "description"},
 			output: &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", Discharge: true, CreatedAt: current_time, UpdatedAt: current_time, BloodGroup: "+A", Description: "description"},
-			mockQuery: []interface{}{mock.ExpectExec("insert into patient (name,phone,discharge,bloodgroup,description) values (?, ?, ?, ?, ?)").
-				WithArgs("ZopSmart", "+919172681679", true, "+A", "description").WillReturnError(errors.New("error in executing insert")),
-				mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description from patient where deletedat IS NULL and id=?").WithArgs(1).
-					WillReturnError(errors.New("error in executing insert")),
+			mockQuery: []interface{}{mock.ExpectBegin(),
+				mock.ExpectExec("insert into patient (name,phone,phoneindex,discharge,bloodgroup,description) values (?, ?, ?, ?, ?, ?)").
+					WithArgs("ZopSmart", "+919172681679", sqlmock.AnyArg(), true, "+A", "description").WillReturnError(errors.New("error in executing insert")),
+				mock.ExpectRollback(),
 			},
 			expectError: errors.New("error in executing insert"),
 		},
@@ -55,8 +79,8 @@ func TestInsert(t *testing.T) {
 	for _, testCase := range testcases {
 		t.Run(testCase.desc, func(t *testing.T) {
 			a := New(db)
-			_, err := a.Insert(testCase.input)
-			if err != nil && err.Error() != testCase.expectError.Error() {
+			_, err := a.Insert(context.TODO(), testCase.input)
+			if (err == nil) != (testCase.expectError == nil) || err != nil && err.Error() != testCase.expectError.Error() {
 				t.Errorf("expected error :%v, got :%v ", testCase.expectError, err)
 			}
 
@@ -84,12 +108,18 @@ func TestUpdate(t *testing.T) {
 			id:    1,
 			input: &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", Discharge: true, UpdatedAt: time.Now(), BloodGroup: "+A", Description: "description"},
 			//output: &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", Discharge: true, CreatedAt: current_time, UpdatedAt: current_time, BloodGroup: "+A", Description: "description"},
-			mockQuery: []interface{}{mock.ExpectExec("update patient SET name = ?, phone=?, discharge=?,udatedat=?,bloodgroup=?,description=? where deletedat IS NULL and id=?").
-				WithArgs("ZopSmart", "+919172681679", true, sqlmock.AnyArg(), "+A", "description", int64(1)).
-				WillReturnResult(sqlmock.NewResult(1, 1)),
-				mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description from patient where deletedat IS NULL and id=?").WithArgs(1).
-					WillReturnRows(mock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description"}).
-						AddRow(1, "ZopSmart", "+919172681679", true, time.Now(), "2022-02-22 13:23:22", "+A", "description")),
+			mockQuery: []interface{}{mock.ExpectBegin(),
+				mock.ExpectQuery(selectForUpdate).WithArgs(1).WillReturnRows(patientRow("Zop", 1)),
+				mock.ExpectExec("update patient SET name = ?, phone=?, phoneindex=?, udatedat=?,bloodgroup=?,description=?,version=version+1 where deletedat IS NULL and id=?").
+					WithArgs("ZopSmart", "+919172681679", sqlmock.AnyArg(), sqlmock.AnyArg(), "+A", "description", int64(1)).
+					WillReturnResult(sqlmock.NewResult(1, 1)),
+				mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id=?").WithArgs(1).
+					WillReturnRows(mock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"}).
+						AddRow(1, "ZopSmart", "+919172681679", true, current_time, current_time, "+A", "description", 2)),
+				mock.ExpectExec(insertAudit).
+					WithArgs(1, "system", "update", `{"bloodGroup":{"before":"A+","after":"+A"},"name":{"before":"Zop","after":"ZopSmart"}}`, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1)),
+				mock.ExpectCommit(),
 			},
 			expectError: nil,
 		},
@@ -98,11 +128,12 @@ func TestUpdate(t *testing.T) {
 			id:    1,
 			input: &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", Discharge: true, UpdatedAt: time.Now(), BloodGroup: "+A", Description: "description"},
 			//output: &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", Discharge: true, CreatedAt: current_time, UpdatedAt: current_time, BloodGroup: "+A", Description: "description"},
-			mockQuery: []interface{}{mock.ExpectExec("update patient SET name = ?, phone=?, discharge=?,udatedat=?,bloodgroup=?,description=? where deletedat IS NULL and id=?").
-				WithArgs("ZopSmart", "+919172681679", true, sqlmock.AnyArg(), "+A", "description", int64(1)).
-				WillReturnError(errors.New("error in update")),
-				mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description from patient where deletedat IS NULL and id=?").WithArgs(1).
+			mockQuery: []interface{}{mock.ExpectBegin(),
+				mock.ExpectQuery(selectForUpdate).WithArgs(1).WillReturnRows(patientRow("Zop", 1)),
+				mock.ExpectExec("update patient SET name = ?, phone=?, phoneindex=?, udatedat=?,bloodgroup=?,description=?,version=version+1 where deletedat IS NULL and id=?").
+					WithArgs("ZopSmart", "+919172681679", sqlmock.AnyArg(), sqlmock.AnyArg(), "+A", "description", int64(1)).
 					WillReturnError(errors.New("error in update")),
+				mock.ExpectRollback(),
 			},
 			expectError: errors.New("error in update"),
 		},
@@ -111,8 +142,8 @@ func TestUpdate(t *testing.T) {
 	for _, testCase := range tests {
 		t.Run(testCase.desc, func(t *testing.T) {
 			a := New(db)
-			_, err := a.Update(testCase.input, testCase.id)
-			if err != nil && err.Error() != testCase.expectError.Error() {
+			_, err := a.Update(context.TODO(), testCase.input, testCase.id)
+			if (err == nil) != (testCase.expectError == nil) || err != nil && err.Error() != testCase.expectError.Error() {
 				t.Errorf("expected error :%v, got :%v ", testCase.expectError, err)
 			}
 
@@ -138,16 +169,16 @@ func TestGetById(t *testing.T) {
 			desc:   "success",
 			id:     1,
 			output: &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", Discharge: true, CreatedAt: current_time, UpdatedAt: current_time, BloodGroup: "+A", Description: "description"},
-			mockQuery: mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description from patient where deletedat IS NULL and id=?").
-				WithArgs(1).WillReturnRows(mock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description"}).
-				AddRow(1, "ZopSmart", "+919172681679", true, current_time, current_time, "+A", "description")),
+			mockQuery: mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id=?").
+				WithArgs(1).WillReturnRows(mock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"}).
+				AddRow(1, "ZopSmart", "+919172681679", true, current_time, current_time, "+A", "description", 1)),
 			expectError: nil,
 		},
 		{
 			desc:   "failure",
 			id:     1,
 			output: &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", Discharge: true, CreatedAt: current_time, UpdatedAt: current_time, BloodGroup: "+A", Description: "description"},
-			mockQuery: mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description from patient where deletedat IS NULL and id=?").
+			mockQuery: mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id=?").
 				WithArgs(1).WillReturnError(errors.New("error in fetching row")),
 			expectError: errors.New("error in fetching row"),
 		},
@@ -155,16 +186,16 @@ func TestGetById(t *testing.T) {
 			desc:   "failure",
 			id:     1,
 			output: &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", Discharge: true, CreatedAt: current_time, UpdatedAt: current_time, BloodGroup: "+A", Description: "description"},
-			mockQuery: mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description from patient where deletedat IS NULL and id=?").
+			mockQuery: mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id=?").
 				WithArgs(1).WillReturnError(sql.ErrNoRows),
-			expectError: sql.ErrNoRows,
+			expectError: &perrors.NotFound{Entity: "patient", ID: "1"},
 		},
 	}
 
 	for _, testCase := range tests {
 		t.Run(testCase.desc, func(t *testing.T) {
 			a := New(db)
-			_, err := a.GetByID(testCase.id)
+			_, err := a.GetByID(context.TODO(), testCase.id)
 			if err != nil && err.Error() != testCase.expectError.Error() {
 				t.Errorf("expected error :%v, got :%v ", testCase.expectError, err)
 			}
@@ -182,9 +213,9 @@ func TestGetAll(t *testing.T) {
 
 	createat := time.Now()
 	updateat := time.Now()
-	rows := sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdAt", "updatedAt", "bloodGroup", "description"}).
-		AddRow(1, "P", "+916354346285", false, createat, updateat, "+b", "Cold").
-		AddRow(2, "a", "+916666555653", false, createat, updateat, "+o", "Cold")
+	rows := sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdAt", "updatedAt", "bloodGroup", "description", "version"}).
+		AddRow(1, "P", "+916354346285", false, createat, updateat, "+b", "Cold", 1).
+		AddRow(2, "a", "+916666555653", false, createat, updateat, "+o", "Cold", 1)
 
 	tests := []struct {
 		desc        string
@@ -195,19 +226,19 @@ func TestGetAll(t *testing.T) {
 		{
 			desc:        "success",
 			output:      []*models.Patient{{Id: 1, Name: "aakanksha3", Phone: "123", Discharge: true, BloodGroup: "A+", Description: "abc"}},
-			mockQuery:   mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description from patient where deletedat IS NULL;").WillReturnRows(rows),
+			mockQuery:   mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL;").WillReturnRows(rows),
 			expectError: nil,
 		},
 		{
 			desc:        "failure",
 			output:      []*models.Patient{{Id: 3, Name: "aakanksha3", Phone: "123", Discharge: true, BloodGroup: "A+", Description: "abc"}},
-			mockQuery:   mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description from patient where deletedat IS NULL;").WillReturnError(errors.New("not passesd correct data")),
+			mockQuery:   mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL;").WillReturnError(errors.New("not passesd correct data")),
 			expectError: errors.New("not passesd correct data"),
 		},
 		{
 			desc:        "failures",
 			output:      []*models.Patient{{Id: 1, Name: "aakanksha3", Phone: "123", Discharge: true, BloodGroup: "A+", Description: "abc"}},
-			mockQuery:   mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description from patient where deletedat IS NULL;").WillReturnError(errors.New("error in row scan")),
+			mockQuery:   mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL;").WillReturnError(errors.New("error in row scan")),
 			expectError: errors.New("error in row scan"),
 		},
 	}
@@ -216,7 +247,7 @@ func TestGetAll(t *testing.T) {
 		t.Run("", func(t *testing.T) {
 
 			a := New(db)
-			_, err := a.GetAll()
+			_, err := a.GetAll(context.TODO())
 
 			if err != nil && err.Error() != testCase.expectError.Error() {
 				t.Errorf("expected error :%v, got :%v ", testCase.expectError, err)
@@ -235,27 +266,51 @@ func TestDelete(t *testing.T) {
 
 	tests := []struct {
 		id          int
-		mockQuery   interface{}
+		beds        *fakeBeds
+		mockQuery   []interface{}
 		expectError error
 	}{
 		{
-			id:          1,
-			mockQuery:   mock.ExpectExec("UPDATE patient SET deletedat=? WHERE id=? AND deletedat IS NULL").WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1)),
+			id: 1,
+			mockQuery: []interface{}{mock.ExpectBegin(),
+				mock.ExpectQuery(selectForUpdate).WithArgs(1).WillReturnRows(patientRow("ZopSmart", 1)),
+				mock.ExpectExec("UPDATE patient SET deletedat=? WHERE id=? AND deletedat IS NULL").WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1)),
+				mock.ExpectExec(insertAudit).WithArgs(1, "system", "delete", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1)),
+				mock.ExpectCommit(),
+			},
 			expectError: nil,
 		},
 		{
-			id:          4,
-			mockQuery:   mock.ExpectExec("UPDATE patient SET deletedat=? WHERE id=? AND deletedat IS NULL").WithArgs(sqlmock.AnyArg(), 4).WillReturnError(errors.New("error of delete")),
+			id: 4,
+			mockQuery: []interface{}{mock.ExpectBegin(),
+				mock.ExpectQuery(selectForUpdate).WithArgs(4).WillReturnRows(patientRow("ZopSmart", 1)),
+				mock.ExpectExec("UPDATE patient SET deletedat=? WHERE id=? AND deletedat IS NULL").WithArgs(sqlmock.AnyArg(), 4).WillReturnError(errors.New("error of delete")),
+				mock.ExpectRollback(),
+			},
 			expectError: errors.New("error of delete"),
 		},
+		{
+			id:   2,
+			beds: &fakeBeds{released: map[int]*models.BedAssignment{2: {ID: 3, PatientID: 2, BedLocation: models.BedLocation{BedID: 4, WardID: 2, Ward: "ICU", Label: "A1"}}}},
+			mockQuery: []interface{}{mock.ExpectBegin(),
+				mock.ExpectQuery(selectForUpdate).WithArgs(2).WillReturnRows(patientRow("ZopSmart", 1)),
+				mock.ExpectExec("UPDATE patient SET deletedat=? WHERE id=? AND deletedat IS NULL").WithArgs(sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(1, 1)),
+				mock.ExpectExec(insertAudit).WithArgs(2, "system", "delete", containingArg(`"bed":{"before":{"bedId":4,"wardId":2,"ward":"ICU","label":"A1"},"after":null}`), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1)),
+				mock.ExpectCommit(),
+			},
+			expectError: nil,
+		},
 	}
 
 	for _, testCase := range tests {
 		t.Run("", func(t *testing.T) {
 
 			a := New(db)
+			if testCase.beds != nil {
+				a.WithBeds(testCase.beds)
+			}
 
-			err := a.Delete(testCase.id)
+			err := a.Delete(context.TODO(), testCase.id, 0)
 			fmt.Println(err)
 
 			if err != nil && err.Error() != testCase.expectError.Error() {
@@ -266,3 +321,1006 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestList(t *testing.T) {
+	const columns = "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where "
+	discharged := true
+	nameCursor := encodeCursor(&models.Patient{Id: 2, Name: "a"}, "name")
+
+	tests := []struct {
+		desc        string
+		opts        models.ListOptions
+		setup       func(mock sqlmock.Sqlmock)
+		count       int
+		nextCursor  string
+		expectError error
+	}{
+		{
+			desc: "offset with filters",
+			opts: models.ListOptions{Limit: 1, Offset: 2, Discharge: &discharged, BloodGroup: "A+"},
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL and discharge=? and bloodgroup=?").
+					WithArgs(true, "A+").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+				mock.ExpectQuery(columns+"deletedat IS NULL and discharge=? and bloodgroup=? order by id ASC limit ? offset ?").
+					WithArgs(true, "A+", 2, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"}).
+						AddRow(3, "P", "+916354346285", true, current_time, current_time, "A+", "Cold", 1).
+						AddRow(4, "a", "+916666555653", true, current_time, current_time, "A+", "Cold", 1))
+			},
+			count:      1,
+			nextCursor: encodeCursor(&models.Patient{Id: 3}, "id"),
+		},
+		{
+			desc: "keyset on name descending",
+			opts: models.ListOptions{Limit: 2, Cursor: nameCursor, Sort: "name", Order: "desc"},
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+				mock.ExpectQuery(columns+"deletedat IS NULL and (name < ? or (name = ? and id < ?)) order by name DESC, id DESC limit ?").
+					WithArgs("a", "a", 2, 3).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"}).
+						AddRow(1, "P", "+916354346285", false, current_time, current_time, "B+", "Cold", 1))
+			},
+			count: 1,
+		},
+		{
+			desc: "invalid cursor",
+			opts: models.ListOptions{Limit: 2, Cursor: "not-a-cursor"},
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+			},
+			expectError: errors.New("invalid cursor"),
+		},
+		{
+			desc: "deleted only",
+			opts: models.ListOptions{Limit: 2, Deleted: true},
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NOT NULL").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+				mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version,deletedat from patient where deletedat IS NOT NULL order by id ASC limit ?").
+					WithArgs(3).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version", "deletedat"}).
+						AddRow(1, "P", "+916354346285", false, current_time, current_time, "B+", "Cold", 2, current_time))
+			},
+			count: 1,
+		},
+		{
+			desc: "name prefix",
+			opts: models.ListOptions{Limit: 2, Name: "Ku_"},
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL and (lower(name) like ? or lower(name) like ?)").
+					WithArgs(`ku\_%`, `% ku\_%`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+				mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and (lower(name) like ? or lower(name) like ?) order by id ASC limit ?").
+					WithArgs(`ku\_%`, `% ku\_%`, 3).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"}).
+						AddRow(1, "Ram Ku_mar", "+916354346285", false, current_time, current_time, "B+", "Cold", 2))
+			},
+			count: 1,
+		},
+		{
+			desc:        "invalid sort",
+			opts:        models.ListOptions{Limit: 2, Sort: "phone"},
+			setup:       func(mock sqlmock.Sqlmock) {},
+			expectError: errors.New("invalid sort"),
+		},
+		{
+			desc: "query error",
+			opts: models.ListOptions{Limit: 2},
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL").
+					WillReturnError(errors.New("error in count"))
+			},
+			expectError: errors.New("error in count"),
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			testCase.setup(mock)
+
+			page, err := New(db).List(context.TODO(), testCase.opts)
+			if testCase.expectError != nil {
+				if err == nil || err.Error() != testCase.expectError.Error() {
+					t.Errorf("expected error :%v, got :%v ", testCase.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(page.Patients) != testCase.count || page.Total != 5 || page.NextCursor != testCase.nextCursor {
+				t.Errorf("unexpected page: %d patients, total %d, cursor %q", len(page.Patients), page.Total, page.NextCursor)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	const columns = "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version, "
+	const fullText = "MATCH(name, description) AGAINST(? IN BOOLEAN MODE)"
+	const like = "(lower(name) like ? or lower(description) like ?)"
+	const likeScore = "(lower(name) like ?)*2 + (lower(name) like ?) + (lower(description) like ?)"
+	const nameFullText = "MATCH(name) AGAINST(? IN BOOLEAN MODE)"
+	resultRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version", "score"}).
+			AddRow(1, "Ramesh", "+916354346285", false, current_time, current_time, "B+", "fever", 1, 2.5)
+	}
+
+	tests := []struct {
+		desc        string
+		query       string
+		encrypted   bool
+		nameOnly    bool
+		setup       func(mock sqlmock.Sqlmock)
+		count       int
+		expectError error
+	}{
+		{
+			desc:  "full text",
+			query: "Ram fev*",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL and " + fullText).
+					WithArgs("+ram* +fev*").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery(columns+fullText+" as score from patient where deletedat IS NULL and "+fullText+" order by score DESC, id ASC limit ? offset ?").
+					WithArgs("+ram* +fev*", "+ram* +fev*", 10, 0).WillReturnRows(resultRows())
+			},
+			count: 1,
+		},
+		{
+			desc:  "like fallback",
+			query: "50%",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL and " + fullText).
+					WithArgs("+50%*").WillReturnError(&mysql.MySQLError{Number: errNoFullTextIndex, Message: "Can't find FULLTEXT index"})
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL and "+like).
+					WithArgs(`%50\%%`, `%50\%%`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery(columns+likeScore+" as score from patient where deletedat IS NULL and "+like+" order by score DESC, id ASC limit ? offset ?").
+					WithArgs(`50\%%`, `%50\%%`, `%50\%%`, `%50\%%`, `%50\%%`, 10, 0).WillReturnRows(resultRows())
+			},
+			count: 1,
+		},
+		{
+			desc:      "encrypted descriptions match names only",
+			query:     "ram",
+			encrypted: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL and " + nameFullText).
+					WithArgs("+ram*").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery(columns+nameFullText+" as score from patient where deletedat IS NULL and "+nameFullText+" order by score DESC, id ASC limit ? offset ?").
+					WithArgs("+ram*", "+ram*", 10, 0).WillReturnRows(resultRows())
+			},
+			count: 1,
+		},
+		{
+			desc:      "encrypted like fallback",
+			query:     "ram",
+			encrypted: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL and " + nameFullText).
+					WithArgs("+ram*").WillReturnError(&mysql.MySQLError{Number: errNoFullTextIndex, Message: "Can't find FULLTEXT index"})
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL and lower(name) like ?").
+					WithArgs("%ram%").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery(columns+"(lower(name) like ?)*2 + (lower(name) like ?) as score from patient where deletedat IS NULL and lower(name) like ? order by score DESC, id ASC limit ? offset ?").
+					WithArgs("ram%", "%ram%", "%ram%", 10, 0).WillReturnRows(resultRows())
+			},
+			count: 1,
+		},
+		{
+			desc:     "names only",
+			query:    "ram",
+			nameOnly: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL and " + nameFullText).
+					WithArgs("+ram*").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectQuery(columns+nameFullText+" as score from patient where deletedat IS NULL and "+nameFullText+" order by score DESC, id ASC limit ? offset ?").
+					WithArgs("+ram*", "+ram*", 10, 0).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version", "score"}))
+			},
+			count: 0,
+		},
+		{
+			desc:  "empty after cleanup",
+			query: "+-*",
+			setup: func(mock sqlmock.Sqlmock) {},
+			count: 0,
+		},
+		{
+			desc:  "query error",
+			query: "ram",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select count(*) from patient where deletedat IS NULL and " + fullText).
+					WithArgs("+ram*").WillReturnError(errors.New("error in search"))
+			},
+			expectError: errors.New("error in search"),
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			testCase.setup(mock)
+			s := New(db)
+			if testCase.encrypted {
+				s.WithKeyring(testKeyring(t, "k1"))
+			}
+
+			page, err := s.Search(context.TODO(), testCase.query, models.ListOptions{Limit: 10, NameOnly: testCase.nameOnly})
+			if testCase.expectError != nil {
+				if err == nil || err.Error() != testCase.expectError.Error() {
+					t.Errorf("expected error :%v, got :%v ", testCase.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(page.Patients) != testCase.count {
+				t.Errorf("Expected: %v, Got: %v", testCase.count, len(page.Patients))
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestInsertConflict(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("insert into patient (name,phone,phoneindex,discharge,bloodgroup,description) values (?, ?, ?, ?, ?, ?)").
+		WithArgs("ZopSmart", "+919172681679", sqlmock.AnyArg(), true, "A+", "description").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	mock.ExpectRollback()
+
+	_, err = New(db).Insert(context.TODO(), &models.Patient{Name: "ZopSmart", Phone: "+919172681679", Discharge: true, BloodGroup: "A+", Description: "description"})
+	var conflict *perrors.Conflict
+	if !errors.As(err, &conflict) {
+		t.Errorf("expected conflict error, got :%v", err)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectForUpdate).WithArgs(1).
+		WillReturnRows(mock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"}).
+			AddRow(1, "ZopSmart", "", true, current_time, current_time, "A+", "description", 3))
+	mock.ExpectExec("update patient SET bloodgroup=?, phone=?, phoneindex=?, udatedat=?, version=version+1 where deletedat IS NULL and id=?").
+		WithArgs("A+", "+919172681679", New(db).keys.BlindIndex("+919172681679"), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id=?").WithArgs(1).
+		WillReturnRows(patientRow("ZopSmart", 4))
+	mock.ExpectExec(insertAudit).
+		WithArgs(1, "nurse", "update", `{"phone":{"before":"","after":"+919172681679"}}`, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	a := New(db)
+	pt, err := a.Patch(audit.WithActor(context.TODO(), "nurse"), 1, 3, map[string]interface{}{"phone": "+919172681679", "bloodgroup": "A+"})
+	if err != nil || pt.Phone != "+919172681679" {
+		t.Errorf("unexpected result: %+v, %v", pt, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	_, err = a.Patch(context.TODO(), 1, 3, map[string]interface{}{"deletedat": nil})
+	if err == nil || err.Error() != "invalid deletedat" {
+		t.Errorf("expected error :invalid deletedat, got :%v ", err)
+	}
+	_, err = a.Patch(context.TODO(), 1, 3, map[string]interface{}{"discharge": true})
+	if err == nil || err.Error() != "invalid discharge" {
+		t.Errorf("expected error :invalid discharge, got :%v ", err)
+	}
+}
+
+func TestConditionalWrites(t *testing.T) {
+	tests := []struct {
+		desc        string
+		setup       func(mock sqlmock.Sqlmock)
+		call        func(s *store) error
+		expectError error
+	}{
+		{
+			desc: "update with matching version",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectForUpdate).WithArgs(1).WillReturnRows(patientRow("ZopSmart", 2))
+				mock.ExpectExec("update patient SET name = ?, phone=?, phoneindex=?, udatedat=?,bloodgroup=?,description=?,version=version+1 where deletedat IS NULL and id=?").
+					WithArgs("ZopSmart", "+919172681679", sqlmock.AnyArg(), sqlmock.AnyArg(), "A+", "description", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id=?").
+					WithArgs(1).WillReturnRows(patientRow("ZopSmart", 3))
+				mock.ExpectExec(insertAudit).WithArgs(1, "system", "update", "{}", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			call: func(s *store) error {
+				_, err := s.Update(context.TODO(), &models.Patient{Name: "ZopSmart", Phone: "+919172681679", Discharge: true, BloodGroup: "A+", Description: "description", Version: 2}, 1)
+				return err
+			},
+		},
+		{
+			desc: "update with stale version",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectForUpdate).WithArgs(1).WillReturnRows(patientRow("ZopSmart", 3))
+				mock.ExpectRollback()
+			},
+			call: func(s *store) error {
+				_, err := s.Update(context.TODO(), &models.Patient{Name: "ZopSmart", Phone: "+919172681679", Discharge: true, BloodGroup: "A+", Description: "description", Version: 2}, 1)
+				return err
+			},
+			expectError: &perrors.PreconditionFailed{Entity: "patient", ID: "1"},
+		},
+		{
+			desc: "patch with stale version",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectForUpdate).WithArgs(1).WillReturnRows(patientRow("ZopSmart", 3))
+				mock.ExpectRollback()
+			},
+			call: func(s *store) error {
+				_, err := s.Patch(context.TODO(), 1, 2, map[string]interface{}{"bloodgroup": "O-"})
+				return err
+			},
+			expectError: &perrors.PreconditionFailed{Entity: "patient", ID: "1"},
+		},
+		{
+			desc: "delete with stale version",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectForUpdate).WithArgs(1).WillReturnRows(patientRow("ZopSmart", 3))
+				mock.ExpectRollback()
+			},
+			call:        func(s *store) error { return s.Delete(context.TODO(), 1, 2) },
+			expectError: &perrors.PreconditionFailed{Entity: "patient", ID: "1"},
+		},
+		{
+			desc: "delete of missing patient",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectForUpdate).WithArgs(1).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			call:        func(s *store) error { return s.Delete(context.TODO(), 1, 0) },
+			expectError: &perrors.NotFound{Entity: "patient", ID: "1"},
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			testCase.setup(mock)
+
+			err = testCase.call(New(db))
+			if !reflect.DeepEqual(err, testCase.expectError) {
+				t.Errorf("expected error :%v, got :%v ", testCase.expectError, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	const (
+		selectDeleted = "select deletedat from patient where deletedat IS NOT NULL and id=? for update"
+		restore       = "UPDATE patient SET deletedat=NULL, udatedat=?, version=version+1 WHERE id=? AND deletedat IS NOT NULL"
+	)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectDeleted).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"deletedat"}).AddRow(current_time))
+	mock.ExpectExec(restore).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id=?").WithArgs(1).
+		WillReturnRows(patientRow("ZopSmart", 3))
+	mock.ExpectExec(insertAudit).WithArgs(1, "system", "restore", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectDeleted).WithArgs(2).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	a := New(db)
+	pt, err := a.Restore(context.TODO(), 1)
+	if err != nil || pt.Version != 3 {
+		t.Errorf("unexpected result: %+v, %v", pt, err)
+	}
+	_, err = a.Restore(context.TODO(), 2)
+	expected := &perrors.NotFound{Entity: "deleted patient", ID: "2"}
+	if err == nil || err.Error() != expected.Error() {
+		t.Errorf("expected error :%v, got :%v ", expected, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	const (
+		record = "insert into patient_audit (patientid,actor,operation,changes,createdat) " +
+			"select id, ?, 'purge', '{}', ? from patient WHERE deletedat IS NOT NULL AND deletedat < ? ORDER BY id LIMIT ?"
+		purge = "DELETE FROM patient WHERE deletedat IS NOT NULL AND deletedat < ? ORDER BY id LIMIT ?"
+	)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	before := time.Now()
+	for _, n := range []int64{purgeBatchSize, 7} {
+		mock.ExpectBegin()
+		mock.ExpectExec(record).WithArgs("system", sqlmock.AnyArg(), before, purgeBatchSize).WillReturnResult(sqlmock.NewResult(0, n))
+		mock.ExpectExec(purge).WithArgs(before, purgeBatchSize).WillReturnResult(sqlmock.NewResult(0, n))
+		mock.ExpectCommit()
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(record).WithArgs("system", sqlmock.AnyArg(), before, purgeBatchSize).WillReturnError(errors.New("error of purge"))
+	mock.ExpectRollback()
+
+	a := New(db)
+	n, err := a.Purge(context.TODO(), before)
+	if err != nil || n != purgeBatchSize+7 {
+		t.Errorf("Expected: %v, Got: %v (%v)", purgeBatchSize+7, n, err)
+	}
+	_, err = a.Purge(context.TODO(), before)
+	if err == nil || err.Error() != "error of purge" {
+		t.Errorf("expected error :error of purge, got :%v ", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	const history = "select id,patientid,actor,operation,changes,createdat from patient_audit where patientid=? order by id"
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(history).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "patientid", "actor", "operation", "changes", "createdat"}).
+			AddRow(1, 1, "system", "create", `{"name":{"before":null,"after":"ZopSmart"}}`, current_time).
+			AddRow(2, 1, "nurse", "update", `{"discharge":{"before":false,"after":true}}`, current_time))
+	mock.ExpectQuery(history).WithArgs(2).WillReturnError(errors.New("error of history"))
+
+	a := New(db)
+	entries, err := a.History(context.TODO(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := models.FieldChange{Before: false, After: true}
+	if len(entries) != 2 || entries[1].Actor != "nurse" || entries[1].Changes["discharge"] != expected {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+	_, err = a.History(context.TODO(), 2)
+	if err == nil || err.Error() != "error of history" {
+		t.Errorf("expected error :error of history, got :%v ", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDiffPatients(t *testing.T) {
+	before := &models.Patient{Name: "ZopSmart", Phone: "+919172681679", BloodGroup: "A+"}
+	after := &models.Patient{Name: "ZopSmart", Phone: "+919172681670", BloodGroup: "A+", Discharge: true}
+	expected := map[string]models.FieldChange{
+		"phone":     {Before: "+919172681679", After: "+919172681670"},
+		"discharge": {Before: false, After: true},
+	}
+	if got := diffPatients(before, after); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected: %v, Got: %v", expected, got)
+	}
+	if got := diffPatients(before, before); len(got) != 0 {
+		t.Errorf("Expected no changes, Got: %v", got)
+	}
+}
+
+// fakeBeds frees the bed in released of a patient, if there is one.
+type fakeBeds struct {
+	released map[int]*models.BedAssignment
+}
+
+func (f *fakeBeds) ReleaseBed(ctx context.Context, tx *sql.Tx, patientID int) (*models.BedAssignment, error) {
+	return f.released[patientID], nil
+}
+
+// containingArg matches a string query argument containing it.
+type containingArg string
+
+func (a containingArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	return ok && strings.Contains(s, string(a))
+}
+
+// notContainingArg matches a string query argument not containing it.
+type notContainingArg string
+
+func (a notContainingArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	return ok && !strings.Contains(s, string(a))
+}
+
+// encryptedArg matches a query argument that decrypts to plaintext in column
+// of row id.
+type encryptedArg struct {
+	keys      *encryption.Keyring
+	column    string
+	id        int
+	plaintext string
+}
+
+func (a encryptedArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok || !strings.HasPrefix(s, "enc:v2:") {
+		return false
+	}
+	plaintext, err := a.keys.DecryptFor(a.column, a.id, s)
+	return err == nil && plaintext == a.plaintext
+}
+
+func testKeyring(t *testing.T, primary string) *encryption.Keyring {
+	keys, err := encryption.NewKeyring(primary, map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	}, bytes.Repeat([]byte{9}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestEncryptionAtRest(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	keys := testKeyring(t, "k1")
+	phone, _ := keys.EncryptFor("phone", 1, "+919172681679")
+	description, _ := keys.EncryptFor("description", 1, "asthma")
+	auditPhone, _ := keys.EncryptFor("audit.phone", 1, "+919172681679")
+	index := keys.BlindIndex("+919172681679")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("insert into patient (name,phone,phoneindex,discharge,bloodgroup,description) values (?, ?, ?, ?, ?, ?)").
+		WithArgs("ZopSmart", "", index, false, "A+", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("update patient SET phone = CASE id WHEN ? THEN ? END, description = CASE id WHEN ? THEN ? END where id IN (?)").
+		WithArgs(1, encryptedArg{keys, "phone", 1, "+919172681679"}, 1, encryptedArg{keys, "description", 1, "asthma"}, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id=?").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"}).
+			AddRow(1, "ZopSmart", phone, false, current_time, current_time, "A+", description, 1))
+	mock.ExpectExec(insertAudit).
+		WithArgs(1, "system", "create", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("select count(*) from patient where deletedat IS NULL and phoneindex=?").WithArgs(index).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and phoneindex=? order by id ASC limit ?").
+		WithArgs(index, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"}).
+			AddRow(1, "ZopSmart", phone, false, current_time, current_time, "A+", description, 1))
+	mock.ExpectQuery("select id,patientid,actor,operation,changes,createdat from patient_audit where patientid=? order by id").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "patientid", "actor", "operation", "changes", "createdat"}).
+			AddRow(1, 1, "system", "create", `{"phone":{"before":null,"after":"`+auditPhone+`"}}`, current_time))
+
+	a := New(db).WithKeyring(keys)
+	pt, err := a.Insert(context.TODO(), &models.Patient{Name: "ZopSmart", Phone: "+919172681679", BloodGroup: "A+", Description: "asthma"})
+	if err != nil || pt.Phone != "+919172681679" || pt.Description != "asthma" {
+		t.Errorf("unexpected result: %+v, %v", pt, err)
+	}
+	page, err := a.List(context.TODO(), models.ListOptions{Limit: 1, Phone: "+919172681679"})
+	if err != nil || len(page.Patients) != 1 || page.Patients[0].Phone != "+919172681679" {
+		t.Errorf("unexpected page: %+v, %v", page, err)
+	}
+	entries, err := a.History(context.TODO(), 1)
+	if err != nil || entries[0].Changes["phone"].After != "+919172681679" {
+		t.Errorf("unexpected entries: %+v, %v", entries, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAuditEncryptsSensitiveChanges(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	keys := testKeyring(t, "k1")
+	a := New(db).WithKeyring(keys)
+
+	sealed, err := a.sealValue("description", 1, "asthma")
+	if err != nil || !strings.HasPrefix(sealed.(string), "enc:v2:k1:") {
+		t.Fatalf("unexpected sealed value: %v, %v", sealed, err)
+	}
+	if v, _ := a.sealValue("description", 1, nil); v != nil {
+		t.Errorf("Expected: nil, Got: %v", v)
+	}
+	opened, err := a.openValue("description", 1, sealed)
+	if err != nil || opened != "asthma" {
+		t.Errorf("Expected: %v, Got: %v (%v)", "asthma", opened, err)
+	}
+	if _, err := a.openValue("description", 2, sealed); err == nil {
+		t.Error("expected error opening another patient's audit value")
+	}
+}
+
+func TestReencrypt(t *testing.T) {
+	const (
+		selectBatch = "select id,phone,phoneindex,description from patient where id > ? order by id limit ?"
+		rewrite     = "update patient SET phone=?, phoneindex=?, description=? where id=? and phone=? and description=?"
+	)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	old := testKeyring(t, "k1")
+	keys := testKeyring(t, "k2")
+	oldPhone, _ := old.EncryptFor("phone", 1, "+919172681679")
+	current, _ := keys.EncryptFor("phone", 2, "+919172681670")
+	// Sealed under k2 before values were bound to their row.
+	unbound := "enc:v1:k2:l8fSJQbUtUQlaYEVrUSo6h3F8xQ0iT1rFy4zleg/EHvx9tlcMQ+jazm1edn1aSJkqV0vZS2n/O8HnU5U:75vIrI4gc2hBzj4lBz9Hw3zUJX3Ttj/AUyN1egu4O/LLqg"
+	index := keys.BlindIndex("+919172681679")
+
+	mock.ExpectQuery(selectBatch).WithArgs(0, reencryptBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "phone", "phoneindex", "description"}).
+			AddRow(1, oldPhone, index, "").
+			AddRow(2, current, keys.BlindIndex("+919172681670"), "").
+			AddRow(3, "+919172681671", nil, "asthma").
+			AddRow(4, "", nil, unbound))
+	mock.ExpectExec(rewrite).
+		WithArgs(encryptedArg{keys, "phone", 1, "+919172681679"}, index, "", 1, oldPhone, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(rewrite).
+		WithArgs(encryptedArg{keys, "phone", 3, "+919172681671"}, keys.BlindIndex("+919172681671"), encryptedArg{keys, "description", 3, "asthma"}, 3, "+919172681671", "asthma").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(rewrite).
+		WithArgs("", nil, encryptedArg{keys, "description", 4, "asthma"}, 4, "", unbound).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := New(db).WithKeyring(keys).Reencrypt(context.TODO())
+	if err != nil || n != 3 {
+		t.Errorf("Expected: %v, Got: %v (%v)", 3, n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReencryptPlaintextIndexes(t *testing.T) {
+	const (
+		selectBatch = "select id,phone,phoneindex,description from patient where id > ? order by id limit ?"
+		rewrite     = "update patient SET phone=?, phoneindex=?, description=? where id=? and phone=? and description=?"
+	)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	var none *encryption.Keyring
+
+	mock.ExpectQuery(selectBatch).WithArgs(0, reencryptBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "phone", "phoneindex", "description"}).
+			AddRow(1, "+919172681679", nil, "asthma").
+			AddRow(2, "+919172681670", none.BlindIndex("+919172681670"), "").
+			AddRow(3, "", nil, ""))
+	mock.ExpectExec(rewrite).
+		WithArgs("+919172681679", none.BlindIndex("+919172681679"), "asthma", 1, "+919172681679", "asthma").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := New(db).Reencrypt(context.TODO())
+	if err != nil || n != 1 {
+		t.Errorf("Expected: %v, Got: %v (%v)", 1, n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEach(t *testing.T) {
+	const selectBatch = "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id > ? order by id limit ?"
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	full := sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"})
+	for id := 1; id <= eachBatchSize; id++ {
+		full.AddRow(id, "Ram", "+919172681679", false, current_time, current_time, "A+", "", 1)
+	}
+	mock.ExpectQuery(selectBatch).WithArgs(0, eachBatchSize).WillReturnRows(full)
+	mock.ExpectQuery(selectBatch).WithArgs(eachBatchSize, eachBatchSize).WillReturnRows(patientRow("Shyam", 1))
+	mock.ExpectQuery(selectBatch).WithArgs(0, eachBatchSize).WillReturnRows(bulkRows("Ram", "Shyam"))
+	mock.ExpectQuery(selectBatch).WithArgs(0, eachBatchSize).WillReturnError(errors.New("connection lost"))
+
+	a := New(db)
+	var names []string
+	err = a.Each(context.TODO(), func(pt *models.Patient) error {
+		names = append(names, pt.Name)
+		return nil
+	})
+	if err != nil || len(names) != eachBatchSize+1 || names[eachBatchSize] != "Shyam" {
+		t.Errorf("Expected: %v patients, Got: %v (%v)", eachBatchSize+1, len(names), err)
+	}
+
+	stop := errors.New("client gone")
+	calls := 0
+	err = a.Each(context.TODO(), func(pt *models.Patient) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("expected error :%v, got :%v ", stop, err)
+	}
+
+	err = a.Each(context.TODO(), func(pt *models.Patient) error { return nil })
+	var internal *perrors.Internal
+	if !errors.As(err, &internal) {
+		t.Errorf("expected error :%v, got :%v ", "internal", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func bulkRows(names ...string) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"})
+	for i, name := range names {
+		rows.AddRow(i+1, name, "+919172681679", false, current_time, current_time, "A+", "", 1)
+	}
+	return rows
+}
+
+func TestBulkInsert(t *testing.T) {
+	const (
+		insert = "insert into patient (name,phone,phoneindex,discharge,bloodgroup,description) values (?, ?, ?, ?, ?, ?), (?, ?, ?, ?, ?, ?)"
+		step   = "select @@auto_increment_increment"
+		read   = "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id IN (?, ?)"
+		audits = "insert into patient_audit (patientid,actor,operation,changes,createdat) values (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)"
+	)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	input := []*models.Patient{
+		{Name: "Ram", Phone: "+919172681679", BloodGroup: "A+"},
+		{Name: "Ram", Phone: "+919172681679", BloodGroup: "A+"},
+	}
+	mock.ExpectBegin()
+	// With auto_increment_increment=2 the second row gets id 3. Both rows
+	// share a name, so only their ids tell them apart.
+	mock.ExpectQuery(step).WillReturnRows(sqlmock.NewRows([]string{"step"}).AddRow(2))
+	mock.ExpectExec(insert).
+		WithArgs("Ram", "+919172681679", sqlmock.AnyArg(), false, "A+", "", "Ram", "+919172681679", sqlmock.AnyArg(), false, "A+", "").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectQuery(read).WithArgs(1, 3).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name", "phone", "discharge", "createdat", "udatedat", "bloodgroup", "description", "version"}).
+			AddRow(1, "Ram", "+919172681679", false, current_time, current_time, "A+", "", 1).
+			AddRow(3, "Ram", "+919172681679", false, current_time, current_time, "A+", "", 1))
+	mock.ExpectExec(audits).
+		WithArgs(1, "system", "create", sqlmock.AnyArg(), sqlmock.AnyArg(), 3, "system", "create", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(step).WillReturnRows(sqlmock.NewRows([]string{"step"}).AddRow(1))
+	mock.ExpectExec(insert).WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectQuery(read).WithArgs(1, 2).WillReturnRows(bulkRows("Ram"))
+	mock.ExpectRollback()
+
+	a := New(db)
+	created, err := a.BulkInsert(context.TODO(), input)
+	if err != nil || len(created) != 2 || created[0].Id != 1 || created[1].Id != 3 {
+		t.Errorf("unexpected result: %+v, %v", created, err)
+	}
+	_, err = a.BulkInsert(context.TODO(), input)
+	expected := "inserted patient 2 not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error :%v, got :%v ", expected, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBulkInsertStatements(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// One more patient than fits a statement takes a second INSERT in the
+	// same transaction.
+	input := make([]*models.Patient, bulkInsertRows+1)
+	names := make([]string, bulkInsertRows)
+	for i := range input {
+		input[i] = &models.Patient{Name: "Ram"}
+	}
+	for i := range names {
+		names[i] = "Ram"
+	}
+	statement := func(n int) {
+		mock.ExpectExec("insert into patient (name,phone,phoneindex,discharge,bloodgroup,description) values " +
+			strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?), ", n), ", ")).
+			WillReturnResult(sqlmock.NewResult(1, int64(n)))
+		mock.ExpectQuery("select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id IN (" + placeholders(n) + ")").
+			WillReturnRows(bulkRows(names[:n]...))
+		mock.ExpectExec("insert into patient_audit (patientid,actor,operation,changes,createdat) values " +
+			strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?), ", n), ", ")).
+			WillReturnResult(sqlmock.NewResult(1, int64(n)))
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery("select @@auto_increment_increment").WillReturnRows(sqlmock.NewRows([]string{"step"}).AddRow(1))
+	statement(bulkInsertRows)
+	statement(1)
+	mock.ExpectCommit()
+
+	created, err := New(db).BulkInsert(context.TODO(), input)
+	if err != nil || len(created) != len(input) {
+		t.Errorf("expected %d patients, got %d (%v)", len(input), len(created), err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBulkUpdate(t *testing.T) {
+	const (
+		lock   = "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id IN (?, ?) for update"
+		read   = "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id IN (?)"
+		update = "update patient SET name = CASE id WHEN ? THEN ? END, phone = CASE id WHEN ? THEN ? END, phoneindex = CASE id WHEN ? THEN ? END, " +
+			"bloodgroup = CASE id WHEN ? THEN ? END, description = CASE id WHEN ? THEN ? END, " +
+			"udatedat=?, version=version+1 where deletedat IS NULL and id IN (?)"
+	)
+	tests := []struct {
+		desc     string
+		input    []*models.Patient
+		mode     models.BulkMode
+		setup    func(mock sqlmock.Sqlmock)
+		expected []error
+	}{
+		{
+			desc:  "atomic batch with a missing patient",
+			input: []*models.Patient{{Id: 1, Name: "Ram"}, {Id: 2, Name: "Shyam"}},
+			mode:  models.BulkAtomic,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lock).WithArgs(1, 2).WillReturnRows(bulkRows("Ram"))
+				mock.ExpectRollback()
+			},
+			expected: []error{&perrors.Aborted{}, &perrors.NotFound{Entity: "patient", ID: "2"}},
+		},
+		{
+			desc:  "partial batch with a stale version",
+			input: []*models.Patient{{Id: 1, Name: "Ram Kumar", Version: 1}, {Id: 2, Name: "Shyam", Version: 4}},
+			mode:  models.BulkPartial,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lock).WithArgs(1, 2).WillReturnRows(bulkRows("Ram", "Shyam"))
+				mock.ExpectExec(update).
+					WithArgs(1, "Ram Kumar", 1, "", 1, nil, 1, "", 1, "", sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(read).WithArgs(1).WillReturnRows(bulkRows("Ram Kumar"))
+				mock.ExpectExec(insertAudit).
+					WithArgs(1, "system", "update", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			expected: []error{nil, &perrors.PreconditionFailed{Entity: "patient", ID: "2"}},
+		},
+		{
+			desc:  "discharge change",
+			input: []*models.Patient{{Id: 1, Name: "Ram"}, {Id: 2, Name: "Shyam", Discharge: true}},
+			mode:  models.BulkAtomic,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lock).WithArgs(1, 2).WillReturnRows(bulkRows("Ram", "Shyam"))
+				mock.ExpectRollback()
+			},
+			expected: []error{&perrors.Aborted{}, perrors.NewValidation("discharge", models.DischargeDerived)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			test.setup(mock)
+
+			results, err := New(db).BulkUpdate(context.TODO(), test.input, test.mode)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i, res := range results {
+				if !reflect.DeepEqual(res.Err, test.expected[i]) {
+					t.Errorf("item %d: expected error :%v, got :%v ", i, test.expected[i], res.Err)
+				}
+				if (res.Err == nil) != (res.Patient != nil) {
+					t.Errorf("item %d: Expected a patient only for written items, Got: %+v", i, res)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestBulkDelete(t *testing.T) {
+	const (
+		lock       = "select id,name,phone,discharge,createdat,udatedat,bloodgroup,description,version from patient where deletedat IS NULL and id IN (?, ?, ?) for update"
+		softDelete = "UPDATE patient SET deletedat=? WHERE deletedat IS NULL AND id IN (?, ?)"
+		audits     = "insert into patient_audit (patientid,actor,operation,changes,createdat) values (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)"
+	)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lock).WithArgs(1, 2, 9).WillReturnRows(bulkRows("Ram", "Shyam"))
+	mock.ExpectExec(softDelete).WithArgs(sqlmock.AnyArg(), 1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	bed := `"bed":{"before":{"bedId":4,"wardId":2,"ward":"ICU","label":"A1"},"after":null}`
+	mock.ExpectExec(audits).
+		WithArgs(1, "system", "delete", notContainingArg(`"bed"`), sqlmock.AnyArg(), 2, "system", "delete", containingArg(bed), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+	beds := &fakeBeds{released: map[int]*models.BedAssignment{
+		2: {ID: 3, PatientID: 2, BedLocation: models.BedLocation{BedID: 4, WardID: 2, Ward: "ICU", Label: "A1"}},
+	}}
+
+	results, err := New(db).WithBeds(beds).BulkDelete(context.TODO(), []models.PatientRef{{ID: 1}, {ID: 2, Version: 1}, {ID: 9}}, models.BulkPartial)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []models.BulkResult{{}, {}, {Err: &perrors.NotFound{Entity: "patient", ID: "9"}}}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected: %+v, Got: %+v", expected, results)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
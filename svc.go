@@ -1,64 +1,556 @@
 package patient
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	perrors "github.com/aakanksha/ppms/internal/errors"
 	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/patch"
 	"github.com/aakanksha/ppms/internal/stores"
+	"github.com/aakanksha/ppms/internal/transfer"
+	"io"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// Timeouts bounds how long each kind of operation may run; a zero field falls
+// back to Default, and a zero Default means no deadline.
+type Timeouts struct {
+	Default time.Duration
+	Read    time.Duration
+	Write   time.Duration
+	Search  time.Duration
+}
+
+// write is the deadline for writes, Write or else Default.
+func (t Timeouts) write() time.Duration {
+	if t.Write == 0 {
+		return t.Default
+	}
+	return t.Write
+}
+
+// With bounds ctx by d, or by Default when d is zero.
+func (t Timeouts) With(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d == 0 {
+		d = t.Default
+	}
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
 type Svc struct {
-	stores stores.StoreInterface
+	stores    stores.StoreInterface
+	timeouts  Timeouts
+	retention time.Duration
 }
 
 func New(stores stores.StoreInterface) *Svc {
-	return &Svc{stores}
+	return &Svc{stores: stores}
+}
+
+func (ps *Svc) WithTimeouts(t Timeouts) *Svc {
+	ps.timeouts = t
+	return ps
+}
+
+// WithRetention sets how long soft-deleted patients are kept before Purge
+// removes them for good. Zero disables purging.
+func (ps *Svc) WithRetention(d time.Duration) *Svc {
+	ps.retention = d
+	return ps
 }
 
-func (ps *Svc) GetAll() ([]*models.Patient, error) {
-	res, err := ps.stores.GetAll()
+func (ps *Svc) GetAll(ctx context.Context) ([]*models.Patient, error) {
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Read)
+	defer cancel()
+	res, err := ps.stores.GetAll(ctx)
 	return res, err
 }
 
-func (ps *Svc) GetByID(id int) (*models.Patient, error) {
+// Export calls fn for every live patient, in id order. It has no deadline of
+// its own since it runs as long as the client reads; the store only queries
+// between calls to fn.
+func (ps *Svc) Export(ctx context.Context, fn func(*models.Patient) error) error {
+	return ps.stores.Each(ctx, fn)
+}
+
+func (ps *Svc) List(ctx context.Context, opts models.ListOptions) (*models.PatientPage, error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultLimit
+	}
+	if opts.Limit < 0 || opts.Limit > maxLimit {
+		return nil, perrors.NewValidation("limit", "must be between 1 and 100")
+	}
+	if opts.Offset < 0 {
+		return nil, perrors.NewValidation("offset", "must not be negative")
+	}
+	if !validSort(opts.Sort) {
+		return nil, perrors.NewValidation("sort", "must be one of id, name, createdAt, updatedAt")
+	}
+	if !validOrder(opts.Order) {
+		return nil, perrors.NewValidation("order", "must be asc or desc")
+	}
+	if opts.BloodGroup != "" {
+		group, ok := normaliseBloodGroup(opts.BloodGroup)
+		if !ok {
+			return nil, perrors.NewValidation("bloodGroup", "must be one of A+, A-, B+, B-, AB+, AB-, O+, O-")
+		}
+		opts.BloodGroup = group
+	}
+	if opts.Phone != "" {
+		phone, ok := NormalisePhone(opts.Phone)
+		if !ok {
+			return nil, perrors.NewValidation("phone", "must be a valid phone number in E.164 format")
+		}
+		opts.Phone = phone
+	}
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Read)
+	defer cancel()
+	return ps.stores.List(ctx, opts)
+}
+
+func (ps *Svc) Search(ctx context.Context, query string, opts models.ListOptions) (*models.PatientPage, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, perrors.NewValidation("q", "must not be empty")
+	}
+	if opts.Limit == 0 {
+		opts.Limit = defaultLimit
+	}
+	if opts.Limit < 0 || opts.Limit > maxLimit {
+		return nil, perrors.NewValidation("limit", "must be between 1 and 100")
+	}
+	if opts.Offset < 0 {
+		return nil, perrors.NewValidation("offset", "must not be negative")
+	}
+	if opts.Phone != "" {
+		phone, ok := NormalisePhone(opts.Phone)
+		if !ok {
+			return nil, perrors.NewValidation("phone", "must be a valid phone number in E.164 format")
+		}
+		opts.Phone = phone
+	}
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Search)
+	defer cancel()
+	return ps.stores.Search(ctx, query, opts)
+}
+
+func (ps *Svc) GetByID(ctx context.Context, id int) (*models.Patient, error) {
 	if !validId(id) {
-		return nil, errors.New("invalid id")
+		return nil, perrors.NewValidation("id", "must be a positive integer")
 	}
-	patient, err := ps.stores.GetByID(id)
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Read)
+	defer cancel()
+	patient, err := ps.stores.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	return patient, err
 }
 
-func (ps *Svc) Insert(p *models.Patient) (*models.Patient, error) {
-	if !validatename(p.Name) {
-		return &models.Patient{}, errors.New("invalid name")
+// Insert creates a patient. A new patient has no encounter yet, so it starts
+// out discharged, and asking for anything else is rejected.
+func (ps *Svc) Insert(ctx context.Context, p *models.Patient) (*models.Patient, error) {
+	if err := validatePatient(p); err != nil {
+		return nil, err
+	}
+	if err := checkDischarge(p, true); err != nil {
+		return nil, err
 	}
-	res, err := ps.stores.Insert(p)
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Write)
+	defer cancel()
+	res, err := ps.stores.Insert(ctx, p)
 	return res, err
 }
-func (ps *Svc) Update(p *models.Patient, id int) (*models.Patient, error) {
+func (ps *Svc) Update(ctx context.Context, p *models.Patient, id int) (*models.Patient, error) {
 	if !validId(id) {
-		return nil, errors.New("invalid id")
+		return nil, perrors.NewValidation("id", "must be a positive integer")
 	}
-	result, err := ps.stores.GetByID(id)
+	if err := validatePatient(p); err != nil {
+		return nil, err
+	}
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Write)
+	defer cancel()
+	result, err := ps.stores.GetByID(ctx, id)
 
 	if result == nil {
 		return nil, err
 	}
-	update, err1 := ps.stores.Update(p, id)
+	if p.Version > 0 && p.Version != result.Version {
+		return nil, &perrors.PreconditionFailed{Entity: "patient", ID: strconv.Itoa(id)}
+	}
+	if err := checkDischarge(p, result.Discharge); err != nil {
+		return nil, err
+	}
+	update, err1 := ps.stores.Update(ctx, p, id)
 
 	return update, err1
 }
-func (ps *Svc) Delete(id int) error {
+
+// checkDischarge rejects a write of p that would change the discharge flag
+// from current.
+func checkDischarge(p *models.Patient, current bool) error {
+	if p.Discharge != current {
+		return perrors.NewValidation("discharge", models.DischargeDerived)
+	}
+	return nil
+}
+
+// newPatient validates p like Insert does, for the items of a bulk create or
+// an import, and reports every failing field at once.
+func newPatient(p *models.Patient) error {
+	err := validatePatient(p)
+	if p.Discharge {
+		return err
+	}
+	verr, _ := err.(*perrors.Validation)
+	if verr == nil {
+		verr = &perrors.Validation{}
+	}
+	verr.Add("discharge", models.DischargeDerived)
+	return verr
+}
+
+// Patch applies a merge patch or JSON patch to the stored patient, validates the
+// merged result and writes back only the fields that changed. The write is
+// conditional on the version that was read, or on version when it is non-zero.
+func (ps *Svc) Patch(ctx context.Context, id int, version int, format patch.Format, body []byte) (*models.Patient, error) {
+	if !validId(id) {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Write)
+	defer cancel()
+	current, err := ps.stores.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version > 0 && version != current.Version {
+		return nil, &perrors.PreconditionFailed{Entity: "patient", ID: strconv.Itoa(id)}
+	}
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := patch.Apply(format, doc, body)
+	if err != nil {
+		return nil, err
+	}
+	var merged models.Patient
+	if err := json.Unmarshal(patched, &merged); err != nil {
+		return nil, perrors.NewValidation("patch", "result is not a valid patient: "+err.Error())
+	}
+
+	verr := &perrors.Validation{}
+	if merged.Id != current.Id {
+		verr.Add("id", "is read-only")
+	}
+	if !merged.CreatedAt.Equal(current.CreatedAt) {
+		verr.Add("createdAt", "is read-only")
+	}
+	if !merged.UpdatedAt.Equal(current.UpdatedAt) {
+		verr.Add("updatedAt", "is read-only")
+	}
+	if merged.Version != current.Version {
+		verr.Add("version", "is read-only")
+	}
+	if merged.Discharge != current.Discharge {
+		verr.Add("discharge", models.DischargeDerived)
+	}
+	if err := validatePatient(&merged); err != nil {
+		var fields *perrors.Validation
+		if errors.As(err, &fields) {
+			verr.Fields = append(verr.Fields, fields.Fields...)
+		}
+	}
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+
+	columns := map[string]interface{}{}
+	if merged.Name != current.Name {
+		columns["name"] = merged.Name
+	}
+	if merged.Phone != current.Phone {
+		columns["phone"] = merged.Phone
+	}
+	if merged.BloodGroup != current.BloodGroup {
+		columns["bloodgroup"] = merged.BloodGroup
+	}
+	if merged.Description != current.Description {
+		columns["description"] = merged.Description
+	}
+	if len(columns) == 0 {
+		return current, nil
+	}
+	return ps.stores.Patch(ctx, id, current.Version, columns)
+}
+
+func (ps *Svc) Delete(ctx context.Context, id int, version int) error {
 	if !validId(id) {
-		return errors.New("invalid id")
+		return perrors.NewValidation("id", "must be a positive integer")
 	}
-	_, err := ps.stores.GetByID(id)
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Write)
+	defer cancel()
+	current, err := ps.stores.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
-	err = ps.stores.Delete(id)
+	if version > 0 && version != current.Version {
+		return &perrors.PreconditionFailed{Entity: "patient", ID: strconv.Itoa(id)}
+	}
+	err = ps.stores.Delete(ctx, id, version)
 	return err
 }
 
+func (ps *Svc) Restore(ctx context.Context, id int) (*models.Patient, error) {
+	if !validId(id) {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Write)
+	defer cancel()
+	return ps.stores.Restore(ctx, id)
+}
+
+// Purge permanently removes patients that were soft-deleted longer than the
+// retention window ago and reports how many were removed.
+func (ps *Svc) Purge(ctx context.Context) (int64, error) {
+	if ps.retention <= 0 {
+		return 0, perrors.NewValidation("retention", "purging is disabled; no retention window is configured")
+	}
+	return ps.stores.Purge(ctx, time.Now().Add(-ps.retention))
+}
+
+func (ps *Svc) History(ctx context.Context, id int) ([]*models.AuditEntry, error) {
+	if !validId(id) {
+		return nil, perrors.NewValidation("id", "must be a positive integer")
+	}
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Read)
+	defer cancel()
+	entries, err := ps.stores.History(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, &perrors.NotFound{Entity: "patient", ID: strconv.Itoa(id)}
+	}
+	return entries, nil
+}
+
+// maxBulkItems caps the number of patients in one bulk request.
+const maxBulkItems = 500
+
+func checkBulk(n int, mode models.BulkMode) error {
+	verr := &perrors.Validation{}
+	if n == 0 || n > maxBulkItems {
+		verr.Add("patients", "must hold between 1 and 500 items")
+	}
+	if mode != models.BulkAtomic && mode != models.BulkPartial {
+		verr.Add("mode", "must be atomic or partial")
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// settleBulk decides whether the items in ok, those that passed validation,
+// go on to the store. In BulkAtomic mode a single failure stops the batch and
+// every other item is marked as not written.
+func settleBulk(results []models.BulkResult, ok []int, mode models.BulkMode) bool {
+	if len(ok) < len(results) && mode == models.BulkAtomic {
+		for _, i := range ok {
+			results[i].Err = &perrors.Aborted{}
+		}
+		return false
+	}
+	return len(ok) > 0
+}
+
+// BulkInsert validates every patient and creates the valid ones in one
+// transaction. Item failures are reported in the results, in request order.
+func (ps *Svc) BulkInsert(ctx context.Context, pts []*models.Patient, mode models.BulkMode) ([]models.BulkResult, error) {
+	if err := checkBulk(len(pts), mode); err != nil {
+		return nil, err
+	}
+	results := make([]models.BulkResult, len(pts))
+	var ok []int
+	for i, p := range pts {
+		if err := newPatient(p); err != nil {
+			results[i].Err = err
+			continue
+		}
+		ok = append(ok, i)
+	}
+	if !settleBulk(results, ok, mode) {
+		return results, nil
+	}
+	batch := make([]*models.Patient, len(ok))
+	for j, i := range ok {
+		batch[j] = pts[i]
+	}
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Write)
+	defer cancel()
+	created, err := ps.stores.BulkInsert(ctx, batch)
+	if mode == models.BulkPartial && rowFailure(err) {
+		// One bad row, such as a duplicate, fails the whole statement; one
+		// insert per row writes the rest and tells which row it was.
+		for j, i := range ok {
+			results[i].Patient, results[i].Err = ps.stores.Insert(ctx, batch[j])
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range ok {
+		results[i].Patient = created[j]
+	}
+	return results, nil
+}
+
+// rowFailure reports whether err is one that a single row of a bulk write can
+// cause, such as a duplicate, rather than a failure of the database itself.
+func rowFailure(err error) bool {
+	var conflict *perrors.Conflict
+	var invalid *perrors.Validation
+	return errors.As(err, &conflict) || errors.As(err, &invalid)
+}
+
+// BulkUpdate overwrites the patients named by their Id in one transaction; a
+// non-zero Version makes an item conditional on it.
+func (ps *Svc) BulkUpdate(ctx context.Context, pts []*models.Patient, mode models.BulkMode) ([]models.BulkResult, error) {
+	if err := checkBulk(len(pts), mode); err != nil {
+		return nil, err
+	}
+	results := make([]models.BulkResult, len(pts))
+	seen := make(map[int]bool, len(pts))
+	var ok []int
+	for i, p := range pts {
+		err := validatePatient(p)
+		verr, _ := err.(*perrors.Validation)
+		if verr == nil {
+			verr = &perrors.Validation{}
+		}
+		switch {
+		case !validId(p.Id):
+			verr.Add("id", "must be a positive integer")
+		case seen[p.Id]:
+			verr.Add("id", "appears more than once in the batch")
+		}
+		seen[p.Id] = true
+		if len(verr.Fields) > 0 {
+			results[i].Err = verr
+			continue
+		}
+		ok = append(ok, i)
+	}
+	if !settleBulk(results, ok, mode) {
+		return results, nil
+	}
+	batch := make([]*models.Patient, len(ok))
+	for j, i := range ok {
+		batch[j] = pts[i]
+	}
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Write)
+	defer cancel()
+	updated, err := ps.stores.BulkUpdate(ctx, batch, mode)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range ok {
+		results[i] = updated[j]
+	}
+	return results, nil
+}
+
+// BulkDelete soft-deletes the referenced patients in one transaction.
+func (ps *Svc) BulkDelete(ctx context.Context, refs []models.PatientRef, mode models.BulkMode) ([]models.BulkResult, error) {
+	if err := checkBulk(len(refs), mode); err != nil {
+		return nil, err
+	}
+	results := make([]models.BulkResult, len(refs))
+	seen := make(map[int]bool, len(refs))
+	var ok []int
+	for i, ref := range refs {
+		switch {
+		case !validId(ref.ID):
+			results[i].Err = perrors.NewValidation("id", "must be a positive integer")
+		case seen[ref.ID]:
+			results[i].Err = perrors.NewValidation("id", "appears more than once in the batch")
+		default:
+			ok = append(ok, i)
+		}
+		seen[ref.ID] = true
+	}
+	if !settleBulk(results, ok, mode) {
+		return results, nil
+	}
+	batch := make([]models.PatientRef, len(ok))
+	for j, i := range ok {
+		batch[j] = refs[i]
+	}
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.Write)
+	defer cancel()
+	deleted, err := ps.stores.BulkDelete(ctx, batch, mode)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range ok {
+		results[i] = deleted[j]
+	}
+	return results, nil
+}
+
+// maxImportRows caps the rows of one import, which are held in memory until
+// the whole file has been validated.
+const maxImportRows = 50000
+
+// Import reads every row of src and validates it like Insert. Only when no
+// line has errors, and not on a dry run, are the patients created, all in one
+// transaction: a failure writes none of them.
+func (ps *Svc) Import(ctx context.Context, src transfer.Reader, dryRun bool) (*models.ImportReport, error) {
+	report := &models.ImportReport{DryRun: dryRun}
+	var valid []*models.Patient
+	for {
+		row, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		report.Rows++
+		if report.Rows > maxImportRows {
+			return nil, perrors.NewValidation("file", "must hold at most 50000 rows")
+		}
+		if row.Err == nil {
+			row.Err = newPatient(row.Patient)
+		}
+		if row.Err != nil {
+			report.Errors = append(report.Errors, models.LineError{Line: row.Line, Err: row.Err})
+			continue
+		}
+		valid = append(valid, row.Patient)
+	}
+	if dryRun || len(report.Errors) > 0 || len(valid) == 0 {
+		return report, nil
+	}
+	// The write deadline is for maxBulkItems rows, as for a bulk create.
+	batches := (len(valid) + maxBulkItems - 1) / maxBulkItems
+	ctx, cancel := ps.timeouts.With(ctx, ps.timeouts.write()*time.Duration(batches))
+	defer cancel()
+	if _, err := ps.stores.BulkInsert(ctx, valid); err != nil {
+		return nil, err
+	}
+	report.Imported = len(valid)
+	return report, nil
+}
@@ -0,0 +1,370 @@
+package patient
+
+import (
+	"context"
+	"errors"
+	perrors "github.com/aakanksha/ppms/internal/errors"
+	"github.com/aakanksha/ppms/internal/models"
+	"github.com/aakanksha/ppms/internal/patch"
+	"github.com/aakanksha/ppms/internal/stores"
+	"github.com/aakanksha/ppms/internal/transfer"
+	"github.com/golang/mock/gomock"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPatch(t *testing.T) {
+	created := time.Date(2022, 2, 22, 13, 23, 22, 0, time.UTC)
+	current := func() *models.Patient {
+		return &models.Patient{Id: 1, Name: "ZopSmart", Phone: "+919172681679", Discharge: false, CreatedAt: created, UpdatedAt: created, BloodGroup: "A+", Description: "fever", Version: 2}
+	}
+
+	tests := []struct {
+		desc        string
+		version     int
+		format      patch.Format
+		body        string
+		columns     map[string]interface{}
+		expectError error
+	}{
+		{
+			desc:    "merge patch updates only supplied fields",
+			format:  patch.MergePatch,
+			body:    `{"description": "cough", "bloodGroup": "o neg"}`,
+			columns: map[string]interface{}{"description": "cough", "bloodgroup": "O-"},
+		},
+		{
+			desc:    "json patch",
+			format:  patch.JSONPatch,
+			body:    `[{"op":"test","path":"/name","value":"ZopSmart"},{"op":"replace","path":"/phone","value":"9000000000"}]`,
+			columns: map[string]interface{}{"phone": "+919000000000"},
+		},
+		{
+			desc:   "no changes",
+			format: patch.MergePatch,
+			body:   `{"name": "ZopSmart"}`,
+		},
+		{
+			desc:        "validation on merged result",
+			format:      patch.MergePatch,
+			body:        `{"name": null, "bloodGroup": "C+"}`,
+			expectError: errors.New("invalid name, bloodGroup"),
+		},
+		{
+			desc:        "read only field",
+			format:      patch.JSONPatch,
+			body:        `[{"op":"replace","path":"/id","value":7}]`,
+			expectError: errors.New("invalid id"),
+		},
+		{
+			desc:    "matching if-match version",
+			version: 2,
+			format:  patch.MergePatch,
+			body:    `{"description": "cough"}`,
+			columns: map[string]interface{}{"description": "cough"},
+		},
+		{
+			desc:        "stale if-match version",
+			version:     1,
+			format:      patch.MergePatch,
+			body:        `{"description": "cough"}`,
+			expectError: &perrors.PreconditionFailed{Entity: "patient", ID: "1"},
+		},
+		{
+			desc:        "discharge is derived from encounters",
+			format:      patch.MergePatch,
+			body:        `{"discharge": true}`,
+			expectError: errors.New("invalid discharge"),
+		},
+		{
+			desc:        "version is read only",
+			format:      patch.MergePatch,
+			body:        `{"version": 9}`,
+			expectError: errors.New("invalid version"),
+		},
+		{
+			desc:        "failed test op",
+			format:      patch.JSONPatch,
+			body:        `[{"op":"test","path":"/name","value":"Someone"}]`,
+			expectError: &perrors.Conflict{Entity: "patient", Reason: "test failed at /name"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockStore := stores.NewMockStoreInterface(mockCtrl)
+			mockStore.EXPECT().GetByID(gomock.Any(), 1).Return(current(), nil)
+			if test.columns != nil {
+				mockStore.EXPECT().Patch(gomock.Any(), 1, 2, test.columns).Return(current(), nil)
+			}
+
+			_, err := New(mockStore).Patch(context.TODO(), 1, test.version, test.format, []byte(test.body))
+			if (err == nil) != (test.expectError == nil) || (err != nil && err.Error() != test.expectError.Error()) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestDischargeIsDerived(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockStore := stores.NewMockStoreInterface(mockCtrl)
+	svc := New(mockStore)
+	expected := "invalid discharge"
+
+	if _, err := svc.Insert(context.TODO(), &models.Patient{Name: "Ram"}); err == nil || err.Error() != expected {
+		t.Errorf("expected error :%v, got :%v ", expected, err)
+	}
+	created := &models.Patient{Name: "Ram", Discharge: true}
+	mockStore.EXPECT().Insert(gomock.Any(), created).Return(created, nil)
+	if _, err := svc.Insert(context.TODO(), &models.Patient{Name: "Ram", Discharge: true}); err != nil {
+		t.Errorf("expected error :<nil>, got :%v ", err)
+	}
+
+	mockStore.EXPECT().GetByID(gomock.Any(), 1).Return(&models.Patient{Id: 1, Name: "Ram", Discharge: true, Version: 2}, nil)
+	if _, err := svc.Update(context.TODO(), &models.Patient{Name: "Ram"}, 1); err == nil || err.Error() != expected {
+		t.Errorf("expected error :%v, got :%v ", expected, err)
+	}
+}
+
+func TestPatchNotFound(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockStore := stores.NewMockStoreInterface(mockCtrl)
+	notFound := &perrors.NotFound{Entity: "patient", ID: "2"}
+	mockStore.EXPECT().GetByID(gomock.Any(), 2).Return(nil, notFound)
+
+	_, err := New(mockStore).Patch(context.TODO(), 2, 0, patch.MergePatch, []byte(`{}`))
+	if !reflect.DeepEqual(err, notFound) {
+		t.Errorf("expected error :%v, got :%v ", notFound, err)
+	}
+}
+
+func TestBulkInsert(t *testing.T) {
+	valid := func() *models.Patient { return &models.Patient{Name: "Ram", BloodGroup: "a pos", Discharge: true} }
+	tests := []struct {
+		desc        string
+		input       []*models.Patient
+		mode        models.BulkMode
+		stored      int
+		expected    []error
+		expectError error
+	}{
+		{
+			desc:     "all valid",
+			input:    []*models.Patient{valid(), valid()},
+			mode:     models.BulkAtomic,
+			stored:   2,
+			expected: []error{nil, nil},
+		},
+		{
+			desc:     "atomic batch with an invalid item",
+			input:    []*models.Patient{valid(), {Name: " ", Discharge: true}},
+			mode:     models.BulkAtomic,
+			expected: []error{&perrors.Aborted{}, perrors.NewValidation("name", "must not be empty")},
+		},
+		{
+			desc:     "partial batch with an invalid item",
+			input:    []*models.Patient{{Name: " ", Discharge: true}, valid()},
+			mode:     models.BulkPartial,
+			stored:   1,
+			expected: []error{perrors.NewValidation("name", "must not be empty"), nil},
+		},
+		{
+			desc:   "admitted new patient",
+			input:  []*models.Patient{{Name: " "}, valid()},
+			mode:   models.BulkPartial,
+			stored: 1,
+			expected: []error{&perrors.Validation{Fields: []perrors.FieldError{
+				{Field: "name", Message: "must not be empty"},
+				{Field: "discharge", Message: models.DischargeDerived},
+			}}, nil},
+		},
+		{
+			desc:        "unknown mode",
+			input:       []*models.Patient{valid()},
+			mode:        "best-effort",
+			expectError: perrors.NewValidation("mode", "must be atomic or partial"),
+		},
+		{
+			desc:        "empty batch",
+			mode:        models.BulkAtomic,
+			expectError: perrors.NewValidation("patients", "must hold between 1 and 500 items"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockStore := stores.NewMockStoreInterface(mockCtrl)
+			if test.stored > 0 {
+				mockStore.EXPECT().BulkInsert(gomock.Any(), gomock.Len(test.stored)).
+					DoAndReturn(func(ctx context.Context, pts []*models.Patient) ([]*models.Patient, error) {
+						for i, pt := range pts {
+							if pt.BloodGroup != "A+" {
+								t.Errorf("Expected a normalised blood group, Got: %v", pt.BloodGroup)
+							}
+							pt.Id = i + 1
+						}
+						return pts, nil
+					})
+			}
+
+			results, err := New(mockStore).BulkInsert(context.TODO(), test.input, test.mode)
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if len(results) != len(test.expected) {
+				t.Fatalf("Expected: %d results, Got: %d", len(test.expected), len(results))
+			}
+			for i, res := range results {
+				if !reflect.DeepEqual(res.Err, test.expected[i]) {
+					t.Errorf("item %d: expected error :%v, got :%v ", i, test.expected[i], res.Err)
+				}
+				if (res.Err == nil) != (res.Patient != nil) {
+					t.Errorf("item %d: Expected a patient only for written items, Got: %+v", i, res)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkInsertFallback(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockStore := stores.NewMockStoreInterface(mockCtrl)
+	duplicate := &perrors.Conflict{Entity: "patient", Reason: "Duplicate entry"}
+	ram := &models.Patient{Name: "Ram", Discharge: true}
+	shyam := &models.Patient{Name: "Shyam", Discharge: true}
+	mockStore.EXPECT().BulkInsert(gomock.Any(), []*models.Patient{ram, shyam}).Return(nil, duplicate)
+	mockStore.EXPECT().Insert(gomock.Any(), ram).Return(nil, duplicate)
+	mockStore.EXPECT().Insert(gomock.Any(), shyam).Return(shyam, nil)
+
+	input := []*models.Patient{{Name: "Ram", Discharge: true}, {Name: "Shyam", Discharge: true}}
+	results, err := New(mockStore).BulkInsert(context.TODO(), input, models.BulkPartial)
+	expected := []models.BulkResult{{Err: duplicate}, {Patient: shyam}}
+	if err != nil || !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected: %+v, Got: %+v (%v)", expected, results, err)
+	}
+
+	mockStore.EXPECT().BulkInsert(gomock.Any(), gomock.Len(2)).Return(nil, duplicate)
+	if _, err := New(mockStore).BulkInsert(context.TODO(), input, models.BulkAtomic); !reflect.DeepEqual(err, duplicate) {
+		t.Errorf("expected error :%v, got :%v ", duplicate, err)
+	}
+
+	outage := &perrors.Internal{Err: context.DeadlineExceeded}
+	mockStore.EXPECT().BulkInsert(gomock.Any(), gomock.Len(2)).Return(nil, outage)
+	if _, err := New(mockStore).BulkInsert(context.TODO(), input, models.BulkPartial); err != outage {
+		t.Errorf("expected error :%v, got :%v ", outage, err)
+	}
+}
+
+func TestBulkUpdateAndDeleteIDs(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockStore := stores.NewMockStoreInterface(mockCtrl)
+	mockStore.EXPECT().BulkUpdate(gomock.Any(), gomock.Len(1), models.BulkPartial).
+		Return([]models.BulkResult{{Err: &perrors.NotFound{Entity: "patient", ID: "4"}}}, nil)
+	mockStore.EXPECT().BulkDelete(gomock.Any(), []models.PatientRef{{ID: 4}}, models.BulkPartial).
+		Return([]models.BulkResult{{}}, nil)
+	svc := New(mockStore)
+
+	results, err := svc.BulkUpdate(context.TODO(), []*models.Patient{{Name: "Ram"}, {Id: 4, Name: "Ram"}, {Id: 4, Name: "Shyam"}}, models.BulkPartial)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []error{
+		perrors.NewValidation("id", "must be a positive integer"),
+		&perrors.NotFound{Entity: "patient", ID: "4"},
+		perrors.NewValidation("id", "appears more than once in the batch"),
+	}
+	for i, res := range results {
+		if !reflect.DeepEqual(res.Err, expected[i]) {
+			t.Errorf("item %d: expected error :%v, got :%v ", i, expected[i], res.Err)
+		}
+	}
+
+	deleted, err := svc.BulkDelete(context.TODO(), []models.PatientRef{{ID: 4}, {ID: -1}}, models.BulkPartial)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleted[0].Err != nil || !reflect.DeepEqual(deleted[1].Err, perrors.NewValidation("id", "must be a positive integer")) {
+		t.Errorf("unexpected results: %+v", deleted)
+	}
+}
+
+func TestImport(t *testing.T) {
+	many := "name\n" + strings.Repeat("Ram\n", maxBulkItems+1)
+	tests := []struct {
+		desc        string
+		input       string
+		dryRun      bool
+		batch       int
+		storeErr    error
+		expected    *models.ImportReport
+		expectError error
+	}{
+		{
+			desc:     "more rows than a bulk create",
+			input:    many,
+			batch:    maxBulkItems + 1,
+			expected: &models.ImportReport{Rows: maxBulkItems + 1, Imported: maxBulkItems + 1},
+		},
+		{
+			desc:     "dry run writes nothing",
+			input:    "name,bloodGroup\nRam,a pos\n",
+			dryRun:   true,
+			expected: &models.ImportReport{Rows: 1, DryRun: true},
+		},
+		{
+			desc:  "line errors write nothing",
+			input: "name,phone\nRam,\n ,12\n",
+			expected: &models.ImportReport{Rows: 2, Errors: []models.LineError{{Line: 3, Err: &perrors.Validation{Fields: []perrors.FieldError{
+				{Field: "name", Message: "must not be empty"},
+				{Field: "phone", Message: "must be a valid phone number in E.164 format"},
+			}}}}},
+		},
+		{
+			desc:     "admitted rows",
+			input:    "name,discharge\nRam,true\nSita,false\n",
+			expected: &models.ImportReport{Rows: 2, Errors: []models.LineError{{Line: 3, Err: perrors.NewValidation("discharge", models.DischargeDerived)}}},
+		},
+		{
+			desc:        "failure writes nothing",
+			input:       many,
+			batch:       maxBulkItems + 1,
+			storeErr:    errors.New("connection lost"),
+			expectError: errors.New("connection lost"),
+		},
+		{
+			desc:        "unreadable file",
+			input:       "",
+			expectError: perrors.NewValidation("header", "file is empty"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockStore := stores.NewMockStoreInterface(mockCtrl)
+			if test.batch > 0 {
+				mockStore.EXPECT().BulkInsert(gomock.Any(), gomock.Len(test.batch)).Return(nil, test.storeErr)
+			}
+
+			report, err := New(mockStore).Import(context.TODO(), transfer.NewReader(transfer.CSV, strings.NewReader(test.input)), test.dryRun)
+			if !reflect.DeepEqual(err, test.expectError) {
+				t.Errorf("expected error :%v, got :%v ", test.expectError, err)
+			}
+			if !reflect.DeepEqual(report, test.expected) {
+				t.Errorf("Expected: %+v, Got: %+v", test.expected, report)
+			}
+		})
+	}
+}